@@ -0,0 +1,39 @@
+package account_balance
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"ethclient_tutorial/amount"
+	"ethclient_tutorial/backend"
+)
+
+// GetBalance 查询账户在指定区块的ETH余额（blockNumber为nil表示最新区块）
+func GetBalance(client backend.Client, address common.Address, blockNumber *big.Int) (*big.Int, error) {
+	balance, err := client.BalanceAt(context.Background(), address, blockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("查询余额失败: %v", err)
+	}
+	return balance, nil
+}
+
+// GetPendingBalance 查询账户包含待处理交易在内的ETH余额
+func GetPendingBalance(client backend.Client, address common.Address) (*big.Int, error) {
+	balance, err := client.PendingBalanceAt(context.Background(), address)
+	if err != nil {
+		return nil, fmt.Errorf("查询待处理余额失败: %v", err)
+	}
+	return balance, nil
+}
+
+// GetBalanceInEther 查询账户最新ETH余额并转换为ETH单位的十进制字符串（不舍入）
+func GetBalanceInEther(client backend.Client, address common.Address) (string, error) {
+	balance, err := GetBalance(client, address, nil)
+	if err != nil {
+		return "", err
+	}
+	return amount.Format(balance, amount.EtherDecimals), nil
+}
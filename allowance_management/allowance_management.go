@@ -0,0 +1,281 @@
+package allowance_management
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/contract_events"
+	"ethclient_tutorial/contracts"
+	"ethclient_tutorial/erc20"
+	"ethclient_tutorial/signer"
+	"ethclient_tutorial/utils"
+)
+
+var (
+	// ErrAllowanceChanged 授权额度与读取时不一致（spender已经使用了部分旧额度）
+	ErrAllowanceChanged = errors.New("授权额度已被改变")
+	// ErrBelowZero 减少的数量超过当前额度
+	ErrBelowZero = errors.New("减少的数量超过当前授权额度")
+)
+
+// Transfer、Approval 事件的主题
+var (
+	transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+	approvalTopic = crypto.Keccak256Hash([]byte("Approval(address,address,uint256)"))
+)
+
+// Result 一次授权变更的结果
+type Result struct {
+	Spender   common.Address
+	Previous  *big.Int    // 变更前的额度
+	Allowance *big.Int    // 变更后的额度
+	ResetTx   common.Hash // 先归零的交易（不需要归零时为空）
+	ApproveTx common.Hash // 设置新额度的交易（额度没有变化时为空）
+}
+
+// Set 把s授权给spender的额度设为value
+// 当前额度和新额度都不为0时，先把额度归零并等待确认，再设置新额度：
+// 直接从N改为M时，spender可以抢先用掉N、再在新授权生效后用掉M（approve竞争）。
+// 归零确认后核对归零交易所在区块之前的额度，并检查同一区块中排在归零交易之前、由其他账户发送的交易是否从owner转出了代币
+// （这类转出只能来自授权额度，可能属于其他spender，按已被使用处理），发现旧额度可能已被使用时返回 ErrAllowanceChanged，额度保持为0。
+// 归零交易确认之后才被打包的 transferFrom 会因额度为0而失败，不需要检查。
+// expected 不为nil时，当前额度必须等于expected。
+func Set(ctx context.Context, client backend.Client, token *erc20.Token, s signer.Signer, spender common.Address, value, expected *big.Int, wait utils.WaitOptions) (*Result, error) {
+	owner := s.Address()
+	current, err := token.Allowance(ctx, owner, spender)
+	if err != nil {
+		return nil, err
+	}
+	if expected != nil && current.Cmp(expected) != 0 {
+		return nil, fmt.Errorf("%w: 期望 %s，当前为 %s", ErrAllowanceChanged, expected, current)
+	}
+
+	result := &Result{Spender: spender, Previous: current, Allowance: current}
+	if current.Cmp(value) == 0 {
+		return result, nil
+	}
+
+	if current.Sign() != 0 && value.Sign() != 0 {
+		tx, err := token.ApproveWithSigner(ctx, s, spender, new(big.Int))
+		if err != nil {
+			return nil, fmt.Errorf("发送归零交易失败: %w", err)
+		}
+		wait.Report("✓ 归零交易已发送: %s", tx.Hash().Hex())
+		status, err := utils.WaitSuccess(ctx, client, tx, wait)
+		if status != nil {
+			result.ResetTx = status.TxHash
+		}
+		if err != nil {
+			return result, fmt.Errorf("归零交易失败: %w", err)
+		}
+		result.Allowance = new(big.Int)
+
+		parent := new(big.Int).SetUint64(status.BlockNumber - 1)
+		before, err := token.AllowanceAt(ctx, owner, spender, parent)
+		if err != nil {
+			return result, err
+		}
+		if before.Cmp(current) != 0 {
+			return result, fmt.Errorf("%w: 归零前额度从 %s 变为 %s，已停止设置新额度", ErrAllowanceChanged, current, before)
+		}
+		spent, err := spentBefore(ctx, client, token.Address, owner, status.TxHash)
+		if err != nil {
+			return result, err
+		}
+		if spent != (common.Hash{}) {
+			return result, fmt.Errorf("%w: 归零交易之前同一区块中的交易 %s 从授权账户转出了代币，已停止设置新额度", ErrAllowanceChanged, spent.Hex())
+		}
+	}
+
+	tx, err := token.ApproveWithSigner(ctx, s, spender, value)
+	if err != nil {
+		return result, fmt.Errorf("发送授权交易失败: %w", err)
+	}
+	wait.Report("✓ 授权交易已发送: %s", tx.Hash().Hex())
+	status, err := utils.WaitSuccess(ctx, client, tx, wait)
+	if status != nil {
+		result.ApproveTx = status.TxHash
+	}
+	if err != nil {
+		return result, fmt.Errorf("授权交易失败: %w", err)
+	}
+	result.Allowance = value
+	return result, nil
+}
+
+// Increase 在当前额度上增加delta（按 Set 的方式防止approve竞争）
+func Increase(ctx context.Context, client backend.Client, token *erc20.Token, s signer.Signer, spender common.Address, delta *big.Int, wait utils.WaitOptions) (*Result, error) {
+	current, err := token.Allowance(ctx, s.Address(), spender)
+	if err != nil {
+		return nil, err
+	}
+	return Set(ctx, client, token, s, spender, new(big.Int).Add(current, delta), current, wait)
+}
+
+// Decrease 在当前额度上减少delta（按 Set 的方式防止approve竞争）
+func Decrease(ctx context.Context, client backend.Client, token *erc20.Token, s signer.Signer, spender common.Address, delta *big.Int, wait utils.WaitOptions) (*Result, error) {
+	current, err := token.Allowance(ctx, s.Address(), spender)
+	if err != nil {
+		return nil, err
+	}
+	if delta.Cmp(current) > 0 {
+		return nil, fmt.Errorf("%w: 当前 %s，减少 %s", ErrBelowZero, current, delta)
+	}
+	return Set(ctx, client, token, s, spender, new(big.Int).Sub(current, delta), current, wait)
+}
+
+// Grant owner授予某个spender的授权
+type Grant struct {
+	Spender  common.Address
+	Approved *big.Int    // 最后一次 Approval 事件中的额度
+	Current  *big.Int    // 当前额度（transferFrom 消耗额度时不一定产生 Approval 事件）
+	Block    uint64      // 最后一次 Approval 事件所在区块
+	TxHash   common.Hash // 最后一次 Approval 事件所在交易
+}
+
+// Scan 重放 [fromBlock, toBlock] 区间内owner发出的 Approval 事件，返回当前额度不为0的授权（按最后授权的区块排序）
+// toBlock 为nil时扫描到最新区块；额度以链上 allowance() 为准。
+// 事件按 contract_events.Backfill 分段查询，节点提示结果过多时自动缩小分段
+func Scan(ctx context.Context, client backend.Client, tokenAddress, owner common.Address, fromBlock uint64, toBlock *uint64) ([]Grant, error) {
+	filterer, err := contracts.NewMYERC20Filterer(tokenAddress, client)
+	if err != nil {
+		return nil, err
+	}
+	var head uint64
+	if toBlock != nil {
+		head = *toBlock
+	} else if head, err = client.BlockNumber(ctx); err != nil {
+		return nil, fmt.Errorf("获取最新区块失败: %v", err)
+	}
+
+	query := ethereum.FilterQuery{
+		Addresses: []common.Address{tokenAddress},
+		Topics:    [][]common.Hash{{approvalTopic}, {common.BytesToHash(owner.Bytes())}},
+	}
+	latest := make(map[common.Address]*Grant)
+	err = contract_events.Backfill(ctx, client, query, head, contract_events.BackfillOptions{FromBlock: fromBlock}, func(vLog types.Log) error {
+		if vLog.Removed {
+			return nil
+		}
+		event, err := filterer.ParseApproval(vLog)
+		if err != nil {
+			return fmt.Errorf("解析Approval事件失败: %v", err)
+		}
+		latest[event.Spender] = &Grant{
+			Spender:  event.Spender,
+			Approved: event.Value,
+			Block:    vLog.BlockNumber,
+			TxHash:   vLog.TxHash,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("查询Approval事件失败: %w", err)
+	}
+
+	token := erc20.New(client, tokenAddress)
+	var grants []Grant
+	for spender, grant := range latest {
+		current, err := token.Allowance(ctx, owner, spender)
+		if err != nil {
+			return nil, err
+		}
+		if current.Sign() == 0 {
+			continue
+		}
+		grant.Current = current
+		grants = append(grants, *grant)
+	}
+	sort.Slice(grants, func(i, j int) bool {
+		if grants[i].Block != grants[j].Block {
+			return grants[i].Block < grants[j].Block
+		}
+		return grants[i].Spender.Hex() < grants[j].Spender.Hex()
+	})
+	return grants, nil
+}
+
+// Revocation 一笔撤销授权的交易
+type Revocation struct {
+	Spender common.Address
+	TxHash  common.Hash
+}
+
+// Revoke 把s授权给spenders的额度全部设为0
+// 先依次发送所有 approve(spender, 0) 交易再统一等待确认，额度已为0的spender跳过
+func Revoke(ctx context.Context, client backend.Client, token *erc20.Token, s signer.Signer, spenders []common.Address, wait utils.WaitOptions) ([]Revocation, error) {
+	var sent []Revocation
+	var txs []*types.Transaction
+	for _, spender := range spenders {
+		current, err := token.Allowance(ctx, s.Address(), spender)
+		if err != nil {
+			return sent, err
+		}
+		if current.Sign() == 0 {
+			continue
+		}
+		tx, err := token.ApproveWithSigner(ctx, s, spender, new(big.Int))
+		if err != nil {
+			return sent, fmt.Errorf("撤销 %s 的授权失败: %w", spender.Hex(), err)
+		}
+		wait.Report("✓ 撤销 %s 的授权: %s", spender.Hex(), tx.Hash().Hex())
+		sent = append(sent, Revocation{Spender: spender, TxHash: tx.Hash()})
+		txs = append(txs, tx)
+	}
+
+	var failed error
+	for i := range sent {
+		status, err := utils.WaitSuccess(ctx, client, txs[i], wait)
+		if status != nil {
+			sent[i].TxHash = status.TxHash
+		}
+		if err != nil && failed == nil {
+			failed = fmt.Errorf("撤销 %s 的授权失败: %w", sent[i].Spender.Hex(), err)
+		}
+	}
+	return sent, failed
+}
+
+// spentBefore 返回 resetTx 所在区块中排在它之前、由owner以外的账户发送并从owner转出代币的第一笔交易（没有时为空哈希）
+// 这类交易在同一区块中先于归零执行，AllowanceAt 上一区块的额度看不到它们消耗的额度
+func spentBefore(ctx context.Context, client backend.Client, tokenAddress, owner common.Address, resetTx common.Hash) (common.Hash, error) {
+	receipt, err := client.TransactionReceipt(ctx, resetTx)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("获取归零交易收据失败: %v", err)
+	}
+	blockHash := receipt.BlockHash
+	logs, err := client.FilterLogs(ctx, ethereum.FilterQuery{
+		BlockHash: &blockHash,
+		Addresses: []common.Address{tokenAddress},
+		Topics:    [][]common.Hash{{transferTopic}, {common.BytesToHash(owner.Bytes())}},
+	})
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("查询归零交易所在区块的Transfer事件失败: %v", err)
+	}
+	for _, vLog := range logs {
+		if vLog.TxIndex >= receipt.TransactionIndex {
+			continue
+		}
+		tx, err := client.TransactionInBlock(ctx, blockHash, vLog.TxIndex)
+		if err != nil {
+			return common.Hash{}, fmt.Errorf("获取交易失败: %v", err)
+		}
+		sender, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		if sender != owner {
+			return tx.Hash(), nil
+		}
+	}
+	return common.Hash{}, nil
+}
@@ -0,0 +1,154 @@
+package allowance_management
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/backend/backendtest"
+	"ethclient_tutorial/erc20"
+	"ethclient_tutorial/signer"
+	"ethclient_tutorial/utils"
+)
+
+// limitedClient 单次查询日志数超过limit时返回结果过多的错误
+type limitedClient struct {
+	backend.Client
+	limit    int
+	rejected bool
+}
+
+func (c *limitedClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	logs, err := c.Client.FilterLogs(ctx, q)
+	if err == nil && len(logs) > c.limit {
+		c.rejected = true
+		return nil, fmt.Errorf("query returned more than %d results", c.limit)
+	}
+	return logs, err
+}
+
+func mtk(n int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(n), big.NewInt(1e18))
+}
+
+func TestSetScanAndRevoke(t *testing.T) {
+	sim := backendtest.New(t, 3)
+	ctx := context.Background()
+	owner, spenderA, spenderB := sim.Accounts[0], sim.Accounts[1], sim.Accounts[2].Address
+
+	tokenAddress, _ := backendtest.DeployMyToken(t, sim)
+	sim.AutoMine(100 * time.Millisecond)
+	utils.PollInterval = 100 * time.Millisecond
+	wait := utils.WaitOptions{Timeout: 10 * time.Second}
+
+	token := erc20.New(sim, tokenAddress)
+	s := signer.NewKeySigner(owner.Key)
+
+	if _, err := Set(ctx, sim, token, s, spenderA.Address, mtk(10), nil, wait); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Set(ctx, sim, token, s, spenderB, mtk(5), nil, wait); err != nil {
+		t.Fatal(err)
+	}
+
+	// spender使用了部分额度（不产生Approval事件）
+	tx, err := token.TransferFrom(ctx, spenderA.Key, owner.Address, spenderA.Address, mtk(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := utils.WaitSuccess(ctx, sim, tx, wait); err != nil {
+		t.Fatal(err)
+	}
+
+	grants, err := Scan(ctx, sim, tokenAddress, owner.Address, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(grants) != 2 || grants[0].Spender != spenderA.Address || grants[0].Approved.Cmp(mtk(10)) != 0 ||
+		grants[0].Current.Cmp(mtk(7)) != 0 || grants[1].Current.Cmp(mtk(5)) != 0 {
+		t.Fatalf("grants = %+v", grants)
+	}
+	// 节点限制每次查询的日志数时分段缩小后得到相同结果
+	limited := &limitedClient{Client: sim, limit: 1}
+	if chunked, err := Scan(ctx, limited, tokenAddress, owner.Address, 0, nil); err != nil || len(chunked) != 2 || chunked[1].Current.Cmp(mtk(5)) != 0 {
+		t.Fatalf("chunked grants = %+v, %v", chunked, err)
+	}
+	if !limited.rejected {
+		t.Fatal("scan never hit the result limit")
+	}
+
+	// 从非0额度改为另一个非0额度时先归零
+	result, err := Set(ctx, sim, token, s, spenderA.Address, mtk(20), mtk(7), wait)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ResetTx == (common.Hash{}) || result.ApproveTx == (common.Hash{}) || result.Allowance.Cmp(mtk(20)) != 0 {
+		t.Fatalf("result = %+v", result)
+	}
+	if _, err := Set(ctx, sim, token, s, spenderA.Address, mtk(1), mtk(7), wait); !errors.Is(err, ErrAllowanceChanged) {
+		t.Fatalf("stale expected allowance err = %v", err)
+	}
+
+	if result, err = Increase(ctx, sim, token, s, spenderB, mtk(1), wait); err != nil || result.Allowance.Cmp(mtk(6)) != 0 {
+		t.Fatalf("increase = %+v, %v", result, err)
+	}
+	if _, err := Decrease(ctx, sim, token, s, spenderB, mtk(7), wait); !errors.Is(err, ErrBelowZero) {
+		t.Fatalf("decrease below zero err = %v", err)
+	}
+
+	revoked, err := Revoke(ctx, sim, token, s, []common.Address{spenderA.Address, spenderB, owner.Address}, wait)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revoked) != 2 {
+		t.Fatalf("revoked = %+v", revoked)
+	}
+	if grants, err := Scan(ctx, sim, tokenAddress, owner.Address, 0, nil); err != nil || len(grants) != 0 {
+		t.Fatalf("grants after revoke = %+v, %v", grants, err)
+	}
+}
+
+func TestSetDetectsSpendInResetBlock(t *testing.T) {
+	sim := backendtest.New(t, 2)
+	ctx := context.Background()
+	owner, spender := sim.Accounts[0], sim.Accounts[1]
+	tokenAddress, _ := backendtest.DeployMyToken(t, sim)
+	utils.PollInterval = 50 * time.Millisecond
+	wait := utils.WaitOptions{Timeout: 10 * time.Second}
+	token := erc20.New(sim, tokenAddress)
+
+	if _, err := token.Approve(ctx, owner.Key, spender.Address, mtk(10)); err != nil {
+		t.Fatal(err)
+	}
+	sim.Commit()
+
+	// spender的 transferFrom 与归零交易打包在同一区块且排在前面，上一区块的额度看不出变化
+	if _, err := token.TransferFrom(ctx, spender.Key, owner.Address, spender.Address, mtk(3)); err != nil {
+		t.Fatal(err)
+	}
+	nonce, _ := sim.PendingNonceAt(ctx, owner.Address)
+	done := make(chan error, 1)
+	go func() {
+		_, err := Set(ctx, sim, token, signer.NewKeySigner(owner.Key), spender.Address, mtk(5), mtk(10), wait)
+		done <- err
+	}()
+	for pending, _ := sim.PendingNonceAt(ctx, owner.Address); pending == nonce; pending, _ = sim.PendingNonceAt(ctx, owner.Address) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	sim.Commit()
+
+	if err := <-done; !errors.Is(err, ErrAllowanceChanged) {
+		t.Fatalf("same-block spend err = %v", err)
+	}
+	if allowance, _ := token.Allowance(ctx, owner.Address, spender.Address); allowance.Sign() != 0 {
+		t.Fatalf("allowance = %s, want 0", allowance)
+	}
+}
@@ -0,0 +1,216 @@
+package amount
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EtherDecimals ETH的精度（1 ether = 10^18 wei）
+const EtherDecimals = 18
+
+// maxExponent 科学计数法指数的绝对值上限，防止 "1e999999999" 之类的输入耗尽内存
+const maxExponent = 1000
+
+// ErrExcessPrecision 数量的小数位数超过了代币精度
+var ErrExcessPrecision = errors.New("数量的精度超过了代币精度")
+
+// ErrUnitDecimals 带ETH单位后缀的数量用于精度不是18位的代币
+var ErrUnitDecimals = errors.New("ETH单位只能用于18位精度")
+
+// units 以ether为基准的单位，值为相对ether的十进制指数
+var units = map[string]int{
+	"wei":        -18,
+	"kwei":       -15,
+	"babbage":    -15,
+	"mwei":       -12,
+	"lovelace":   -12,
+	"gwei":       -9,
+	"shannon":    -9,
+	"szabo":      -6,
+	"microether": -6,
+	"finney":     -3,
+	"milliether": -3,
+	"ether":      0,
+	"eth":        0,
+}
+
+// unitNames 按长度从长到短排列的单位名
+var unitNames = func() []string {
+	names := make([]string, 0, len(units))
+	for name := range units {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+	return names
+}()
+
+var numberPattern = regexp.MustCompile(`^([0-9]*)(?:\.([0-9]*))?(?:[eE]([+-]?[0-9]+))?$`)
+
+// Amount 精确的十进制数量，值为 mantissa * 10^exp（以整个代币或ether为单位）
+// 解析和换算全程使用整数运算，不经过float64，因此不会丢失18位精度代币的最低位
+type Amount struct {
+	mantissa *big.Int
+	exp      int
+	unit     string // 解析时的ETH单位后缀（如 "gwei"），没有单位时为空
+}
+
+// Parse 解析十进制数量字符串
+// 支持普通小数（"1.000000000000000001"）、科学计数法（"3e-6"）和ETH单位后缀（"2.5 gwei"、"3e-6 ether"）。
+// 不带单位时表示整个代币（或ether）的数量；带单位的数量只能换算为18位精度的最小单位
+func Parse(s string) (Amount, error) {
+	text := strings.TrimSpace(s)
+	if text == "" {
+		return Amount{}, fmt.Errorf("数量不能为空")
+	}
+	if strings.HasPrefix(text, "-") {
+		return Amount{}, fmt.Errorf("数量不能为负数: %s", s)
+	}
+	text = strings.TrimPrefix(text, "+")
+
+	// 拆分数字与单位（允许 "2.5gwei" 或 "2.5 gwei"），按单位名从长到短匹配，避免 "gwei" 被当成 "wei"
+	shift, unitName := 0, ""
+	lower := strings.ToLower(text)
+	for _, unit := range unitNames {
+		if strings.HasSuffix(lower, unit) {
+			shift, unitName = units[unit], unit
+			text = strings.TrimSpace(text[:len(text)-len(unit)])
+			break
+		}
+	}
+
+	match := numberPattern.FindStringSubmatch(text)
+	if match == nil || match[1]+match[2] == "" {
+		return Amount{}, fmt.Errorf("无效的数量: %s", s)
+	}
+	intPart, fracPart, expPart := match[1], match[2], match[3]
+
+	exp := 0
+	if expPart != "" {
+		e, err := strconv.Atoi(expPart)
+		if err != nil || e > maxExponent || e < -maxExponent {
+			return Amount{}, fmt.Errorf("指数超出范围: %s", s)
+		}
+		exp = e
+	}
+
+	mantissa, ok := new(big.Int).SetString(intPart+fracPart, 10)
+	if !ok {
+		return Amount{}, fmt.Errorf("无效的数量: %s", s)
+	}
+	return Amount{mantissa: mantissa, exp: exp - len(fracPart) + shift, unit: unitName}.normalize(), nil
+}
+
+// MustParse 解析数量，失败时panic（用于常量）
+func MustParse(s string) Amount {
+	a, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// FromBaseUnits 由最小单位的整数（如wei）和精度创建数量
+func FromBaseUnits(value *big.Int, decimals int) Amount {
+	if value == nil {
+		return Amount{}
+	}
+	return Amount{mantissa: new(big.Int).Set(value), exp: -decimals}.normalize()
+}
+
+// ToBaseUnits 换算为最小单位的整数（如18位精度代币的wei）
+// 数量的小数位数超过 decimals 时返回 ErrExcessPrecision，而不是静默截断；
+// 带ETH单位的数量用于其他精度时返回 ErrUnitDecimals（"1 gwei" 对6位精度代币没有意义）
+func (a Amount) ToBaseUnits(decimals int) (*big.Int, error) {
+	if a.unit != "" && decimals != EtherDecimals {
+		return nil, fmt.Errorf("%w: %s 不能用于 %d 位精度的代币", ErrUnitDecimals, a.unit, decimals)
+	}
+	if a.mantissa == nil || a.mantissa.Sign() == 0 {
+		return new(big.Int), nil
+	}
+	shift := a.exp + decimals
+	if shift >= 0 {
+		return new(big.Int).Mul(a.mantissa, pow10(shift)), nil
+	}
+	// normalize 已去掉末尾的0，mantissa 不可能被 10^-shift 整除
+	return nil, fmt.Errorf("%w: %s 超过 %d 位小数", ErrExcessPrecision, a.String(), decimals)
+}
+
+// Wei 换算为wei
+func (a Amount) Wei() (*big.Int, error) {
+	return a.ToBaseUnits(EtherDecimals)
+}
+
+// IsZero 是否为0
+func (a Amount) IsZero() bool {
+	return a.mantissa == nil || a.mantissa.Sign() == 0
+}
+
+// String 以十进制格式输出（以整个代币或ether为单位），不做任何舍入
+func (a Amount) String() string {
+	if a.IsZero() {
+		return "0"
+	}
+	if a.mantissa.Sign() < 0 {
+		return "-" + Amount{mantissa: new(big.Int).Neg(a.mantissa), exp: a.exp}.String()
+	}
+	digits := a.mantissa.String()
+	if a.exp >= 0 {
+		return digits + strings.Repeat("0", a.exp)
+	}
+	fracLen := -a.exp
+	if len(digits) <= fracLen {
+		return "0." + strings.Repeat("0", fracLen-len(digits)) + digits
+	}
+	return digits[:len(digits)-fracLen] + "." + digits[len(digits)-fracLen:]
+}
+
+// Set 实现 flag.Value，可直接作为命令行参数
+func (a *Amount) Set(s string) error {
+	parsed, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+// MarshalText 以十进制字符串编码（JSON中为字符串，避免精度丢失）
+func (a Amount) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText 从十进制字符串解码
+func (a *Amount) UnmarshalText(text []byte) error {
+	return a.Set(string(text))
+}
+
+// Format 将最小单位的整数按精度格式化为十进制字符串（不舍入，去掉末尾的0，负数带负号）
+func Format(value *big.Int, decimals int) string {
+	return FromBaseUnits(value, decimals).String()
+}
+
+// normalize 去掉mantissa末尾的0，使同一数值只有一种表示
+func (a Amount) normalize() Amount {
+	if a.mantissa.Sign() == 0 {
+		return Amount{}
+	}
+	ten := big.NewInt(10)
+	q, r := new(big.Int), new(big.Int)
+	for {
+		q.QuoRem(a.mantissa, ten, r)
+		if r.Sign() != 0 {
+			return a
+		}
+		a.mantissa = new(big.Int).Set(q)
+		a.exp++
+	}
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
@@ -0,0 +1,130 @@
+package amount
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestParseToBaseUnits(t *testing.T) {
+	tests := []struct {
+		input    string
+		decimals int
+		want     string
+	}{
+		{"1.000000000000000001", 18, "1000000000000000001"},
+		{"0.1", 18, "100000000000000000"},
+		{"2.5 gwei", 18, "2500000000"},
+		{"2.5gwei", 18, "2500000000"},
+		{"3e-6 ether", 18, "3000000000000"},
+		{"3E-6", 18, "3000000000000"},
+		{"1 wei", 18, "1"},
+		{"1.5 ETH", 18, "1500000000000000000"},
+		{"100", 6, "100000000"},
+		{"0.000001", 6, "1"},
+		{"1.50000", 2, "150"},
+		{".5", 1, "5"},
+		{"7.", 0, "7"},
+		{"0", 18, "0"},
+		{"1e3", 0, "1000"},
+	}
+	for _, tt := range tests {
+		a, err := Parse(tt.input)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.input, err)
+			continue
+		}
+		got, err := a.ToBaseUnits(tt.decimals)
+		if err != nil {
+			t.Errorf("Parse(%q).ToBaseUnits(%d): %v", tt.input, tt.decimals, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("Parse(%q).ToBaseUnits(%d) = %s, want %s", tt.input, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+func TestRejectExcessPrecision(t *testing.T) {
+	for _, tt := range []struct {
+		input    string
+		decimals int
+	}{
+		{"0.0000001", 6},
+		{"1.0000000000000000001", 18},
+		{"0.5 wei", 18},
+		{"0.5", 0},
+	} {
+		a, err := Parse(tt.input)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.input, err)
+		}
+		if _, err := a.ToBaseUnits(tt.decimals); !errors.Is(err, ErrExcessPrecision) {
+			t.Errorf("Parse(%q).ToBaseUnits(%d) err = %v, want ErrExcessPrecision", tt.input, tt.decimals, err)
+		}
+	}
+}
+
+func TestRejectUnitForOtherDecimals(t *testing.T) {
+	a := MustParse("2.5 gwei")
+	if _, err := a.ToBaseUnits(6); !errors.Is(err, ErrUnitDecimals) {
+		t.Fatalf("err = %v, want ErrUnitDecimals", err)
+	}
+	if _, err := a.ToBaseUnits(EtherDecimals); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MustParse("2.5").ToBaseUnits(6); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, input := range []string{"", "abc", "-1", "1.2.3", "1 foo", "e5", ".", "1e99999", "0x10"} {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("Parse(%q) should fail", input)
+		}
+	}
+}
+
+func TestFormatWithoutRounding(t *testing.T) {
+	tests := []struct {
+		value    string
+		decimals int
+		want     string
+	}{
+		{"1000000000000000001", 18, "1.000000000000000001"},
+		{"1", 18, "0.000000000000000001"},
+		{"1500000000000000000", 18, "1.5"},
+		{"100000000", 6, "100"},
+		{"0", 18, "0"},
+		{"123", 0, "123"},
+		{"-1500000000000000000", 18, "-1.5"},
+		{"-1", 18, "-0.000000000000000001"},
+		{"-100000000", 6, "-100"},
+	}
+	for _, tt := range tests {
+		value, _ := new(big.Int).SetString(tt.value, 10)
+		if got := Format(value, tt.decimals); got != tt.want {
+			t.Errorf("Format(%s, %d) = %s, want %s", tt.value, tt.decimals, got, tt.want)
+		}
+	}
+
+	if got := MustParse("2.5 gwei").String(); got != "0.0000000025" {
+		t.Errorf("String() = %s, want 0.0000000025", got)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	var decoded struct{ Value Amount }
+	if err := json.Unmarshal([]byte(`{"Value":"1.000000000000000001"}`), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(encoded) != `{"Value":"1.000000000000000001"}` {
+		t.Fatalf("encoded = %s", encoded)
+	}
+}
@@ -0,0 +1,30 @@
+package backend
+
+import (
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Client 项目统一使用的链访问接口
+// 覆盖区块/状态/交易查询、交易发送、日志过滤与订阅、Gas估算以及链ID查询。
+// *ethclient.Client 和 go-ethereum 的 simulated 后端都实现了该接口，
+// 因此所有功能包既可以连接真实节点，也可以在测试中运行于内存链上。
+type Client interface {
+	ethereum.BlockNumberReader
+	ethereum.ChainReader
+	ethereum.ChainStateReader
+	ethereum.ContractCaller
+	ethereum.GasEstimator
+	ethereum.GasPricer
+	ethereum.GasPricer1559
+	ethereum.FeeHistoryReader
+	ethereum.LogFilterer
+	ethereum.PendingStateReader
+	ethereum.PendingContractCaller
+	ethereum.TransactionReader
+	ethereum.TransactionSender
+	ethereum.ChainIDReader
+}
+
+// 编译期检查：确保 *ethclient.Client 满足 Client 接口
+var _ Client = (*ethclient.Client)(nil)
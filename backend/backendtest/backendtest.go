@@ -0,0 +1,80 @@
+// Package backendtest 测试共用的内存链辅助函数：创建内存链、部署MyToken
+package backendtest
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/contracts"
+)
+
+// New 创建预置 accounts 个账户的内存链，测试结束时自动关闭
+func New(t testing.TB, accounts int) *backend.Simulated {
+	t.Helper()
+	sim, err := backend.NewSimulated(accounts)
+	if err != nil {
+		t.Fatalf("创建内存链失败: %v", err)
+	}
+	t.Cleanup(func() { sim.Close() })
+	return sim
+}
+
+// Transactor 内存链第 i 个账户的交易签名参数
+func Transactor(sim *backend.Simulated, i int) *bind.TransactOpts {
+	auth, _ := bind.NewKeyedTransactorWithChainID(sim.Accounts[i].Key, big.NewInt(backend.SimulatedChainID))
+	return auth
+}
+
+// DeployMyToken 由账户0部署MyToken（初始代币和所有权都归账户0）并出块
+func DeployMyToken(t testing.TB, sim *backend.Simulated) (common.Address, *contracts.MYERC20) {
+	t.Helper()
+	owner := sim.Accounts[0].Address
+	address, _, token, err := contracts.DeployMYERC20(Transactor(sim, 0), sim, owner, owner)
+	if err != nil {
+		t.Fatalf("部署MyToken失败: %v", err)
+	}
+	sim.Commit()
+	return address, token
+}
+
+// TokenChain 内存链和账户0部署的MyToken
+type TokenChain struct {
+	Sim     *backend.Simulated
+	Address common.Address
+	Token   *contracts.MYERC20
+}
+
+// NewTokenChain 创建预置 accounts 个账户的内存链并部署MyToken，之后由测试决定手动出块还是自动出块
+func NewTokenChain(t testing.TB, accounts int) *TokenChain {
+	t.Helper()
+	sim := New(t, accounts)
+	address, token := DeployMyToken(t, sim)
+	return &TokenChain{Sim: sim, Address: address, Token: token}
+}
+
+// Mine 手动出块：第i个区块打包 perBlock[i] 笔账户0向账户1的转账（每笔1个最小单位，各产生一条Transfer日志）
+func (c *TokenChain) Mine(t testing.TB, perBlock ...int) {
+	t.Helper()
+	auth := Transactor(c.Sim, 0)
+	for _, n := range perBlock {
+		for i := 0; i < n; i++ {
+			if _, err := c.Token.Transfer(auth, c.Sim.Accounts[1].Address, big.NewInt(1)); err != nil {
+				t.Fatalf("发送转账失败: %v", err)
+			}
+		}
+		c.Sim.Commit()
+	}
+}
+
+// NewAutoMining 创建每100毫秒自动出块的内存链，用于等待交易确认的测试
+func NewAutoMining(t testing.TB, accounts int) *backend.Simulated {
+	t.Helper()
+	sim := New(t, accounts)
+	sim.AutoMine(100 * time.Millisecond)
+	return sim
+}
@@ -0,0 +1,186 @@
+package backend
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"ethclient_tutorial/nonce_manager"
+)
+
+// SimulatedChainID simulated 后端固定使用的链ID
+const SimulatedChainID = 1337
+
+// SimulatedAccount 内存链上预先分配了余额的测试账户
+type SimulatedAccount struct {
+	Key     *ecdsa.PrivateKey
+	Address common.Address
+}
+
+// KeyHex 返回账户私钥的十六进制字符串（不带0x前缀），与 TEST_PRIVATE_KEY 格式一致
+func (a *SimulatedAccount) KeyHex() string {
+	return hex.EncodeToString(crypto.FromECDSA(a.Key))
+}
+
+// Simulated 基于 go-ethereum ethclient/simulated 的内存链适配器
+// 它本身实现了 Client 接口，可直接传给项目中的所有功能包。
+type Simulated struct {
+	Client
+
+	sim      *simulated.Backend
+	rpc      *rpc.Client
+	ipcPath  string // 内存链节点的IPC套接字路径，未开启IPC时为空
+	nonces   *nonce_manager.Manager
+	Accounts []*SimulatedAccount
+
+	mu       sync.Mutex
+	stopMine chan struct{}
+	mineDone chan struct{}
+}
+
+// 编译期检查：Simulated 持有自己的nonce管理器
+var _ nonce_manager.Owner = (*Simulated)(nil)
+
+// simulatedSeq 为同一进程中的内存链生成不重复的IPC端点名
+var simulatedSeq atomic.Uint64
+
+// DefaultSimulatedBalance 每个测试账户的初始余额（1000 ETH）
+var DefaultSimulatedBalance = new(big.Int).Mul(big.NewInt(1000), big.NewInt(1e18))
+
+// NewSimulated 创建内存链，并为 numAccounts 个随机账户分配初始余额
+// 内存链节点在 os.TempDir() 下监听一个IPC套接字（ethclient-simulated-<进程号>-<序号>.ipc），
+// CallContext 通过它发送原始JSON-RPC请求，Close 时关闭节点并删除套接字。
+// options 可以修改 nodeConf.IPCPath 改变套接字位置，设为空字符串则不创建套接字（此时 CallContext 返回错误）
+func NewSimulated(numAccounts int, options ...func(nodeConf *node.Config, ethConf *ethconfig.Config)) (*Simulated, error) {
+	alloc := types.GenesisAlloc{}
+	accounts := make([]*SimulatedAccount, 0, numAccounts)
+	for i := 0; i < numAccounts; i++ {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			return nil, err
+		}
+		account := &SimulatedAccount{Key: key, Address: crypto.PubkeyToAddress(key.PublicKey)}
+		alloc[account.Address] = types.Account{Balance: DefaultSimulatedBalance}
+		accounts = append(accounts, account)
+	}
+
+	// 默认的套接字名放在调用方选项之前，调用方可以覆盖；最后一个选项记录最终的端点
+	var endpoint string
+	options = append([]func(*node.Config, *ethconfig.Config){func(nodeConf *node.Config, _ *ethconfig.Config) {
+		nodeConf.IPCPath = fmt.Sprintf("ethclient-simulated-%d-%d.ipc", os.Getpid(), simulatedSeq.Add(1))
+	}}, options...)
+	options = append(options, func(nodeConf *node.Config, _ *ethconfig.Config) {
+		endpoint = nodeConf.IPCEndpoint()
+	})
+	sim := simulated.NewBackend(alloc, options...)
+	s := &Simulated{
+		Client:   sim.Client(),
+		sim:      sim,
+		Accounts: accounts,
+	}
+	s.nonces = nonce_manager.NewManager(s)
+	if endpoint != "" {
+		client, err := rpc.DialIPC(context.Background(), endpoint)
+		if err != nil {
+			sim.Close()
+			return nil, fmt.Errorf("连接内存链IPC端点失败: %v", err)
+		}
+		s.rpc, s.ipcPath = client, endpoint
+	}
+	return s, nil
+}
+
+// NonceManager 返回内存链持有的共享nonce管理器，通过同一个后端发送的交易从它领取nonce
+func (s *Simulated) NonceManager() *nonce_manager.Manager {
+	return s.nonces
+}
+
+// Commit 打包待处理交易并生成一个新区块
+func (s *Simulated) Commit() common.Hash {
+	return s.sim.Commit()
+}
+
+// Rollback 丢弃所有待处理交易
+func (s *Simulated) Rollback() {
+	s.sim.Rollback()
+}
+
+// Fork 从指定祖先区块创建侧链，用于模拟链重组
+func (s *Simulated) Fork(parentHash common.Hash) error {
+	return s.sim.Fork(parentHash)
+}
+
+// CallContext 发送原始JSON-RPC请求（如 eth_newFilter），与 *rpc.Client 的同名方法一致
+func (s *Simulated) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	if s.rpc == nil {
+		return errors.New("simulated 后端不支持原始JSON-RPC调用")
+	}
+	return s.rpc.CallContext(ctx, result, method, args...)
+}
+
+// AdjustTime 调整区块时间戳并生成一个新区块
+func (s *Simulated) AdjustTime(adjustment time.Duration) error {
+	return s.sim.AdjustTime(adjustment)
+}
+
+// AutoMine 按固定间隔自动出块，模拟真实网络的出块节奏
+// 重复调用会先停止上一次的自动出块。
+func (s *Simulated) AutoMine(interval time.Duration) {
+	s.StopMining()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stop, done := make(chan struct{}), make(chan struct{})
+	s.stopMine, s.mineDone = stop, done
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				s.sim.Commit()
+			}
+		}
+	}()
+}
+
+// StopMining 停止自动出块
+func (s *Simulated) StopMining() {
+	s.mu.Lock()
+	stop, done := s.stopMine, s.mineDone
+	s.stopMine, s.mineDone = nil, nil
+	s.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+// Close 停止自动出块并关闭内存链
+func (s *Simulated) Close() error {
+	s.StopMining()
+	if s.rpc != nil {
+		s.rpc.Close()
+	}
+	return s.sim.Close()
+}
@@ -0,0 +1,69 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/node"
+
+	"ethclient_tutorial/nonce_manager"
+)
+
+func TestSimulatedCloseRemovesIPCSocket(t *testing.T) {
+	sim, err := NewSimulated(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sim.ipcPath == "" {
+		t.Fatal("simulated backend has no IPC endpoint")
+	}
+	var chainID hexutil.Big
+	if err := sim.CallContext(context.Background(), &chainID, "eth_chainId"); err != nil {
+		t.Fatal(err)
+	}
+	if chainID.ToInt().Int64() != SimulatedChainID {
+		t.Fatalf("eth_chainId = %s", chainID.ToInt())
+	}
+	if _, err := os.Stat(sim.ipcPath); err != nil {
+		t.Fatalf("socket before close: %v", err)
+	}
+
+	if err := sim.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(sim.ipcPath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("socket %s after close: %v", sim.ipcPath, err)
+	}
+}
+
+func TestSimulatedWithoutIPC(t *testing.T) {
+	sim, err := NewSimulated(1, func(nodeConf *node.Config, _ *ethconfig.Config) {
+		nodeConf.IPCPath = ""
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sim.Close()
+	if sim.ipcPath != "" {
+		t.Fatalf("ipcPath = %s, want none", sim.ipcPath)
+	}
+	var chainID hexutil.Big
+	if err := sim.CallContext(context.Background(), &chainID, "eth_chainId"); err == nil {
+		t.Fatal("CallContext without IPC succeeded")
+	}
+}
+
+func TestSimulatedOwnsNonceManager(t *testing.T) {
+	sim, err := NewSimulated(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sim.Close()
+	if nonce_manager.ForClient(sim) != sim.NonceManager() {
+		t.Fatal("ForClient did not return the backend's own nonce manager")
+	}
+}
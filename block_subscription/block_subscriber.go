@@ -6,28 +6,29 @@ import (
 	"log"
 
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
+
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/event_stream"
 )
 
-// BlockSubscription 订阅新区块，返回第一个接收到的区块（用于演示）
-func BlockSubscription(client *ethclient.Client) *types.Block {
-	//定义一个通道获取订阅头
-	ch := make(chan *types.Header)
-	sub, err := client.SubscribeNewHead(context.Background(), ch)
-	if err != nil {
+// BlockSubscription 监听新区块，返回第一个接收到的区块（用于演示）
+// WS/IPC节点使用订阅，HTTP节点自动改用 eth_newBlockFilter 或轮询
+func BlockSubscription(client backend.Client) *types.Block {
+	heads := event_stream.SubscribeHeads(context.Background(), client, event_stream.DefaultOptions)
+	defer heads.Close()
+	if err := heads.Wait(context.Background()); err != nil {
 		log.Fatalf("Failed to subscribe to new blocks: %v", err)
 	}
-	defer sub.Unsubscribe()
 
-	fmt.Println("🔔 开始监听新区块...")
+	fmt.Printf("🔔 开始监听新区块（%s）...\n", heads.Mode())
 
 	// 监听新块
 	for {
 		select {
-		case err := <-sub.Err():
+		case err := <-heads.Err():
 			log.Fatalf("Subscription error: %v", err)
-		case header := <-ch:
-			block, err := client.BlockByNumber(context.Background(), header.Number)
+		case header := <-heads.Heads():
+			block, err := client.BlockByHash(context.Background(), header.Hash())
 			if err != nil {
 				log.Fatalf("Failed to retrieve block %d: %v", header.Number, err)
 			}
@@ -0,0 +1,227 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+
+	"ethclient_tutorial/rpc_pool"
+	"ethclient_tutorial/signer"
+	"ethclient_tutorial/wallet_management"
+)
+
+// openKeystore 打开keystore目录（测试中替换为轻量scrypt参数）
+var openKeystore = wallet_management.OpenKeystore
+
+// Keystore 打开配置的keystore目录
+func (e *Env) Keystore() *wallet_management.Keystore {
+	dir := e.KeyDir
+	if dir == "" {
+		dir = e.Config().KeystoreDir
+	}
+	return openKeystore(dir)
+}
+
+// passphrases keystore口令来源：KEYSTORE_PASSWORD_FILE → KEYSTORE_PASSWORD → 终端提示
+func (e *Env) passphrases() wallet_management.PassphraseSource {
+	return wallet_management.PassphraseSource{
+		File: e.Config().KeystorePasswordFile,
+		Env:  wallet_management.DefaultPassphraseEnv,
+		In:   e.Stdin,
+		Out:  e.Stderr,
+	}
+}
+
+// keystoreAccount 发送账户地址（--from 或 KEYSTORE_ACCOUNT），未配置时返回false
+func (e *Env) keystoreAccount() (common.Address, bool, error) {
+	from := e.From
+	if from == "" {
+		from = e.Config().KeystoreAccount
+	}
+	if from == "" {
+		return common.Address{}, false, nil
+	}
+	if !common.IsHexAddress(from) {
+		return common.Address{}, false, fmt.Errorf("无效的发送账户地址: %s", from)
+	}
+	return common.HexToAddress(from), true, nil
+}
+
+// HDWallet 加载助记词文件（--mnemonic 或 MNEMONIC_FILE），未配置时返回false
+func (e *Env) HDWallet() (*wallet_management.HDWallet, bool, error) {
+	file := e.Mnemonic
+	if file == "" {
+		file = e.Config().MnemonicFile
+	}
+	if file == "" {
+		return nil, false, nil
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, false, fmt.Errorf("读取助记词文件失败: %v", err)
+	}
+	wallet, err := wallet_management.NewHDWallet(strings.TrimSpace(string(content)), e.Config().MnemonicPassphrase)
+	if err != nil {
+		return nil, false, err
+	}
+	return wallet, true, nil
+}
+
+// hdPath HD钱包的基础派生路径（HD_PATH，默认 m/44'/60'/0'/0）
+func (e *Env) hdPath() (accounts.DerivationPath, error) {
+	if path := e.Config().HDPath; path != "" {
+		return wallet_management.ParseHDPath(path)
+	}
+	return wallet_management.DefaultHDPath, nil
+}
+
+// hdIndex 发送账户在HD钱包中的索引（--index 或 HD_INDEX）
+func (e *Env) hdIndex() (uint32, error) {
+	if e.Index == "" {
+		// 与 --index 相同只接受非硬化索引（小于2^31），超出范围时报错而不是截断成其他账户
+		index := e.Config().HDIndex
+		if index >= 1<<31 {
+			return 0, fmt.Errorf("无效的HD钱包账户索引 HD_INDEX=%d（需小于2147483648）", index)
+		}
+		return uint32(index), nil
+	}
+	index, err := strconv.ParseUint(e.Index, 10, 31)
+	if err != nil {
+		return 0, fmt.Errorf("无效的HD钱包账户索引: %s", e.Index)
+	}
+	return uint32(index), nil
+}
+
+// hdAccount 从HD钱包派生发送账户，未配置助记词时返回false
+func (e *Env) hdAccount() (*wallet_management.UnlockedAccount, bool, error) {
+	wallet, ok, err := e.HDWallet()
+	if !ok || err != nil {
+		return nil, ok, err
+	}
+	base, err := e.hdPath()
+	if err != nil {
+		return nil, true, err
+	}
+	index, err := e.hdIndex()
+	if err != nil {
+		return nil, true, err
+	}
+	account, err := wallet.DeriveIndex(base, index)
+	if err != nil {
+		return nil, true, err
+	}
+	return account, true, nil
+}
+
+// remoteSignerURL 远程签名服务地址（--signer 或 SIGNER_URL）
+func (e *Env) remoteSignerURL() string {
+	if e.SignerURL != "" {
+		return e.SignerURL
+	}
+	return e.Config().SignerURL
+}
+
+// SenderAddress 发送账户地址（keystore账户不需要解锁）
+func (e *Env) SenderAddress() (common.Address, error) {
+	if e.remoteSignerURL() != "" {
+		s, err := e.Signer()
+		if err != nil {
+			return common.Address{}, err
+		}
+		return s.Address(), nil
+	}
+	address, ok, err := e.keystoreAccount()
+	if ok || err != nil {
+		return address, err
+	}
+	account, ok, err := e.hdAccount()
+	if err != nil {
+		return common.Address{}, err
+	}
+	if ok {
+		return account.Address, nil
+	}
+	account, err = e.plaintextAccount()
+	if err != nil {
+		return common.Address{}, err
+	}
+	return account.Address, nil
+}
+
+// Signer 发送账户的签名器
+// 配置了远程签名服务（--signer 或 SIGNER_URL）时私钥不进入本进程，--from 选择签名服务中的账户；
+// 否则依次使用keystore账户（--from 或 KEYSTORE_ACCOUNT）、HD钱包派生的账户（--mnemonic 或 MNEMONIC_FILE），
+// 只有设置了 ALLOW_PLAINTEXT_KEY=true 时才会使用配置中的明文私钥 TEST_PRIVATE_KEY
+func (e *Env) Signer() (signer.Signer, error) {
+	if url := e.remoteSignerURL(); url != "" {
+		return e.remoteSigner(url)
+	}
+
+	address, ok, err := e.keystoreAccount()
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		passphrase, err := e.passphrases().Read(fmt.Sprintf("请输入账户 %s 的口令: ", address.Hex()))
+		if err != nil {
+			return nil, err
+		}
+		s, err := signer.NewKeystoreSigner(e.Keystore(), address, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		e.logf("✓ 已解锁keystore账户: %s", address.Hex())
+		return s, nil
+	}
+
+	account, ok, err := e.hdAccount()
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		index, _ := e.hdIndex()
+		e.logf("✓ 使用HD钱包账户 #%d: %s", index, account.Address.Hex())
+		return signer.FromAccount(account), nil
+	}
+
+	account, err = e.plaintextAccount()
+	if err != nil {
+		return nil, err
+	}
+	return signer.FromAccount(account), nil
+}
+
+// remoteSigner 连接远程签名服务，命令结束时由 Close 断开
+func (e *Env) remoteSigner(url string) (*signer.RemoteSigner, error) {
+	var address common.Address
+	if e.From != "" {
+		if !common.IsHexAddress(e.From) {
+			return nil, fmt.Errorf("无效的发送账户地址: %s", e.From)
+		}
+		address = common.HexToAddress(e.From)
+	}
+	s, err := signer.DialRemote(context.Background(), url, address)
+	if err != nil {
+		return nil, err
+	}
+	if e.closeSigner != nil {
+		e.closeSigner()
+	}
+	e.closeSigner = s.Close
+	e.logf("✓ 使用远程签名服务 %s 的账户: %s", rpc_pool.RedactURL(url), s.Address().Hex())
+	return s, nil
+}
+
+// plaintextAccount 使用配置中的明文私钥（需要显式开启）
+func (e *Env) plaintextAccount() (*wallet_management.UnlockedAccount, error) {
+	cfg := e.Config()
+	if cfg.TestPrivateKey == "" || !cfg.AllowPlaintextKey {
+		return nil, fmt.Errorf("未配置发送账户: 请设置 KEYSTORE_ACCOUNT、MNEMONIC_FILE 或使用 --from（明文私钥 TEST_PRIVATE_KEY 需同时设置 ALLOW_PLAINTEXT_KEY=true）")
+	}
+	return wallet_management.AccountFromHex(cfg.TestPrivateKey)
+}
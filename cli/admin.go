@@ -0,0 +1,215 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"ethclient_tutorial/amount"
+	"ethclient_tutorial/erc20"
+	"ethclient_tutorial/signer"
+	"ethclient_tutorial/token_admin"
+)
+
+func init() {
+	register("admin status", "查询MyToken的owner和暂停状态", adminStatus)
+	register("admin pause", "暂停合约（owner）", adminPause)
+	register("admin unpause", "恢复合约（owner）", adminUnpause)
+	register("admin mint", "增发代币（owner）", adminMint)
+	register("admin transfer-ownership", "转移合约所有权（owner）", adminTransferOwnership)
+	register("admin renounce", "放弃合约所有权（owner，不可撤销）", adminRenounce)
+}
+
+// admin 创建管理客户端，危险操作在终端确认（--force 跳过）
+func (e *Env) admin(address *addressValue, force bool) (*token_admin.Admin, error) {
+	token, err := e.token(address)
+	if err != nil {
+		return nil, err
+	}
+	client, err := e.Client()
+	if err != nil {
+		return nil, err
+	}
+	admin, err := token_admin.New(client, token.Address)
+	if err != nil {
+		return nil, err
+	}
+	admin.Strategy = token.Strategy
+	for _, multisig := range e.Config().KnownMultisigs {
+		var a addressValue
+		if err := a.Set(multisig); err != nil {
+			return nil, fmt.Errorf("KNOWN_MULTISIGS 中的地址无效: %v", err)
+		}
+		admin.Multisigs = append(admin.Multisigs, a.Address)
+	}
+	admin.Confirm = func(reason string) error {
+		if force {
+			return nil
+		}
+		fmt.Fprintf(e.Stderr, "⚠️ %s\n", reason)
+		return e.confirm("确认继续? [y/N]: ")
+	}
+	return admin, nil
+}
+
+type adminStatusResult struct {
+	Token  string `json:"token"`
+	Owner  string `json:"owner"`
+	Paused bool   `json:"paused"`
+}
+
+// adminStatus 查询状态：ethcli admin status [--token ADDR]
+func adminStatus(env *Env, args []string) error {
+	fs := env.flags("admin status")
+	address := bindToken(fs)
+	if _, err := env.parse(fs, args); err != nil {
+		return err
+	}
+	admin, err := env.admin(address, false)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	owner, err := admin.Owner(ctx)
+	if err != nil {
+		return err
+	}
+	paused, err := admin.Paused(ctx)
+	if err != nil {
+		return err
+	}
+
+	result := adminStatusResult{Token: admin.Address.Hex(), Owner: owner.Hex(), Paused: paused}
+	return env.Emit(result, func(w io.Writer) {
+		fmt.Fprintf(w, "合约 %s\n", result.Token)
+		if owner == (common.Address{}) {
+			fmt.Fprintln(w, "   owner: 无（所有权已放弃）")
+		} else {
+			fmt.Fprintf(w, "   owner: %s\n", result.Owner)
+		}
+		if result.Paused {
+			fmt.Fprintln(w, "   状态: 已暂停")
+		} else {
+			fmt.Fprintln(w, "   状态: 正常运行")
+		}
+	})
+}
+
+type adminActionResult struct {
+	Token       string `json:"token"`
+	Method      string `json:"method"`
+	TxHash      string `json:"transactionHash"`
+	BlockNumber uint64 `json:"blockNumber"`
+	Event       string `json:"event"`
+}
+
+// adminAction 管理命令的公共流程：解析参数、创建客户端并确认后执行
+func adminAction(env *Env, name string, args []string, required []string, extra func(fs *flag.FlagSet), run func(ctx context.Context, admin *token_admin.Admin, s signer.Signer, wf waitFlags) (*token_admin.Action, error)) error {
+	fs := env.flags("admin " + name)
+	address := bindToken(fs)
+	force := fs.Bool("force", false, "危险操作不再确认")
+	var wf waitFlags
+	wf.bindSteps(fs)
+	if extra != nil {
+		extra(fs)
+	}
+	if _, err := env.parse(fs, args); err != nil {
+		return err
+	}
+	if err := env.require(fs, required...); err != nil {
+		return err
+	}
+
+	admin, err := env.admin(address, *force)
+	if err != nil {
+		return err
+	}
+	s, err := env.Signer()
+	if err != nil {
+		return err
+	}
+	client, err := env.Client()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	if err := env.ConfirmSend(ctx, client, fmt.Sprintf("admin %s（合约 %s，发送方 %s）", name, admin.Address.Hex(), s.Address().Hex())); err != nil {
+		return err
+	}
+
+	action, err := run(ctx, admin, s, wf)
+	if err != nil {
+		return err
+	}
+	result := adminActionResult{
+		Token:       admin.Address.Hex(),
+		Method:      action.Method,
+		TxHash:      action.TxHash.Hex(),
+		BlockNumber: action.BlockNumber,
+		Event:       action.Event,
+	}
+	return env.Emit(result, func(w io.Writer) {
+		fmt.Fprintf(w, "✅ %s 已确认（区块 %d）\n", result.Method, result.BlockNumber)
+		fmt.Fprintf(w, "   交易: %s\n", result.TxHash)
+		fmt.Fprintf(w, "   事件: %s\n", result.Event)
+	})
+}
+
+// adminPause 暂停：ethcli admin pause [--token ADDR]
+func adminPause(env *Env, args []string) error {
+	return adminAction(env, "pause", args, nil, nil, func(ctx context.Context, admin *token_admin.Admin, s signer.Signer, wf waitFlags) (*token_admin.Action, error) {
+		return admin.Pause(ctx, s, env.waitOptions(wf, s))
+	})
+}
+
+// adminUnpause 恢复：ethcli admin unpause [--token ADDR]
+func adminUnpause(env *Env, args []string) error {
+	return adminAction(env, "unpause", args, nil, nil, func(ctx context.Context, admin *token_admin.Admin, s signer.Signer, wf waitFlags) (*token_admin.Action, error) {
+		return admin.Unpause(ctx, s, env.waitOptions(wf, s))
+	})
+}
+
+// adminMint 增发：ethcli admin mint --to ADDR --amount 1000
+func adminMint(env *Env, args []string) error {
+	var to addressValue
+	var value amount.Amount
+	return adminAction(env, "mint", args, []string{"to", "amount"}, func(fs *flag.FlagSet) {
+		fs.Var(&to, "to", "接收地址")
+		fs.Var(&value, "amount", "增发的代币数量（以整个代币为单位）")
+	}, func(ctx context.Context, admin *token_admin.Admin, s signer.Signer, wf waitFlags) (*token_admin.Action, error) {
+		client, err := env.Client()
+		if err != nil {
+			return nil, err
+		}
+		decimals, err := erc20.New(client, admin.Address).Decimals(ctx)
+		if err != nil {
+			return nil, err
+		}
+		baseUnits, err := value.ToBaseUnits(int(decimals))
+		if err != nil {
+			return nil, err
+		}
+		return admin.Mint(ctx, s, to.Address, baseUnits, env.waitOptions(wf, s))
+	})
+}
+
+// adminTransferOwnership 转移所有权：ethcli admin transfer-ownership --to ADDR [--force]
+// 新owner是合约且不在 KNOWN_MULTISIGS 中时需要确认
+func adminTransferOwnership(env *Env, args []string) error {
+	var to addressValue
+	return adminAction(env, "transfer-ownership", args, []string{"to"}, func(fs *flag.FlagSet) {
+		fs.Var(&to, "to", "新owner")
+	}, func(ctx context.Context, admin *token_admin.Admin, s signer.Signer, wf waitFlags) (*token_admin.Action, error) {
+		return admin.TransferOwnership(ctx, s, to.Address, env.waitOptions(wf, s))
+	})
+}
+
+// adminRenounce 放弃所有权：ethcli admin renounce [--force]
+func adminRenounce(env *Env, args []string) error {
+	return adminAction(env, "renounce", args, nil, nil, func(ctx context.Context, admin *token_admin.Admin, s signer.Signer, wf waitFlags) (*token_admin.Action, error) {
+		return admin.RenounceOwnership(ctx, s, env.waitOptions(wf, s))
+	})
+}
@@ -0,0 +1,398 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"ethclient_tutorial/allowance_management"
+	"ethclient_tutorial/amount"
+	"ethclient_tutorial/erc20"
+)
+
+func init() {
+	register("allowance get", "查询授权额度", allowanceGet)
+	register("allowance set", "设置授权额度（非0改为非0时先归零）", allowanceSet)
+	register("allowance increase", "增加授权额度", allowanceIncrease)
+	register("allowance decrease", "减少授权额度", allowanceDecrease)
+	register("allowance scan", "从Approval事件重建账户授出的全部非0额度", allowanceScan)
+	register("allowance revoke", "批量撤销授权（额度设为0）", allowanceRevoke)
+}
+
+// addressListValue 可重复或以逗号分隔的地址参数
+type addressListValue []common.Address
+
+func (l *addressListValue) Set(s string) error {
+	for _, part := range strings.Split(s, ",") {
+		var a addressValue
+		if err := a.Set(strings.TrimSpace(part)); err != nil {
+			return err
+		}
+		*l = append(*l, a.Address)
+	}
+	return nil
+}
+
+func (l *addressListValue) String() string {
+	if l == nil {
+		return ""
+	}
+	hexes := make([]string, len(*l))
+	for i, a := range *l {
+		hexes[i] = a.Hex()
+	}
+	return strings.Join(hexes, ",")
+}
+
+type allowanceResult struct {
+	Token     string `json:"token"`
+	Owner     string `json:"owner"`
+	Spender   string `json:"spender"`
+	Allowance string `json:"allowance"`
+	Raw       string `json:"raw"`
+	Symbol    string `json:"symbol"`
+}
+
+// allowanceGet 查询额度：ethcli allowance get --token ADDR --spender ADDR [--owner ADDR]
+func allowanceGet(env *Env, args []string) error {
+	fs := env.flags("allowance get")
+	address := bindToken(fs)
+	var owner, spender addressValue
+	fs.Var(&owner, "owner", "授权账户（默认为发送账户）")
+	fs.Var(&spender, "spender", "被授权账户")
+	if _, err := env.parse(fs, args); err != nil {
+		return err
+	}
+	if err := env.require(fs, "spender"); err != nil {
+		return err
+	}
+	if owner.Address == (common.Address{}) {
+		sender, err := env.SenderAddress()
+		if err != nil {
+			return fmt.Errorf("缺少参数 --owner: %v", err)
+		}
+		owner.Address = sender
+	}
+
+	token, err := env.token(address)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	meta, err := token.Metadata(ctx)
+	if err != nil {
+		return err
+	}
+	allowance, err := token.Allowance(ctx, owner.Address, spender.Address)
+	if err != nil {
+		return err
+	}
+
+	result := allowanceResult{
+		Token:     meta.Address.Hex(),
+		Owner:     owner.Hex(),
+		Spender:   spender.Hex(),
+		Allowance: amount.Format(allowance, int(meta.Decimals)),
+		Raw:       allowance.String(),
+		Symbol:    meta.Symbol,
+	}
+	return env.Emit(result, func(w io.Writer) {
+		fmt.Fprintf(w, "%s 授权 %s: %s %s\n", result.Owner, result.Spender, result.Allowance, result.Symbol)
+	})
+}
+
+type allowanceChangeResult struct {
+	Token     string `json:"token"`
+	Owner     string `json:"owner"`
+	Spender   string `json:"spender"`
+	Previous  string `json:"previous"`
+	Allowance string `json:"allowance"`
+	Symbol    string `json:"symbol"`
+	ResetTx   string `json:"resetTransactionHash,omitempty"`
+	ApproveTx string `json:"approveTransactionHash,omitempty"`
+}
+
+func allowanceSet(env *Env, args []string) error {
+	return allowanceChange(env, "set", args)
+}
+
+func allowanceIncrease(env *Env, args []string) error {
+	return allowanceChange(env, "increase", args)
+}
+
+func allowanceDecrease(env *Env, args []string) error {
+	return allowanceChange(env, "decrease", args)
+}
+
+// allowanceChange 修改额度：ethcli allowance set|increase|decrease --token ADDR --spender ADDR --amount 10
+// 当前额度与新额度都不为0时先归零并确认，归零前额度已被使用则停止
+func allowanceChange(env *Env, action string, args []string) error {
+	fs := env.flags("allowance " + action)
+	address := bindToken(fs)
+	var spender addressValue
+	var value amount.Amount
+	var wf waitFlags
+	fs.Var(&spender, "spender", "被授权账户")
+	fs.Var(&value, "amount", "代币数量（set为新额度，increase/decrease为变化量）")
+	wf.bindSteps(fs)
+	if _, err := env.parse(fs, args); err != nil {
+		return err
+	}
+	if err := env.require(fs, "spender", "amount"); err != nil {
+		return err
+	}
+
+	token, err := env.token(address)
+	if err != nil {
+		return err
+	}
+	s, err := env.Signer()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	meta, err := token.Metadata(ctx)
+	if err != nil {
+		return err
+	}
+	baseUnits, err := value.ToBaseUnits(int(meta.Decimals))
+	if err != nil {
+		return err
+	}
+
+	client, err := env.Client()
+	if err != nil {
+		return err
+	}
+	summary := fmt.Sprintf("allowance %s %s %s（spender %s，代币 %s，owner %s）", action, value, meta.Symbol, spender.Hex(), meta.Address.Hex(), s.Address().Hex())
+	if err := env.ConfirmSend(ctx, client, summary); err != nil {
+		return err
+	}
+
+	opts := env.waitOptions(wf, s)
+	var changed *allowance_management.Result
+	switch action {
+	case "set":
+		changed, err = allowance_management.Set(ctx, client, token, s, spender.Address, baseUnits, nil, opts)
+	case "increase":
+		changed, err = allowance_management.Increase(ctx, client, token, s, spender.Address, baseUnits, opts)
+	default:
+		changed, err = allowance_management.Decrease(ctx, client, token, s, spender.Address, baseUnits, opts)
+	}
+	if err != nil {
+		return err
+	}
+
+	result := allowanceChangeResult{
+		Token:     meta.Address.Hex(),
+		Owner:     s.Address().Hex(),
+		Spender:   spender.Hex(),
+		Previous:  amount.Format(changed.Previous, int(meta.Decimals)),
+		Allowance: amount.Format(changed.Allowance, int(meta.Decimals)),
+		Symbol:    meta.Symbol,
+	}
+	if changed.ResetTx != (common.Hash{}) {
+		result.ResetTx = changed.ResetTx.Hex()
+	}
+	if changed.ApproveTx != (common.Hash{}) {
+		result.ApproveTx = changed.ApproveTx.Hex()
+	}
+	return env.Emit(result, func(w io.Writer) {
+		if result.ApproveTx == "" {
+			fmt.Fprintf(w, "✅ 额度已经是 %s %s，无需修改\n", result.Allowance, result.Symbol)
+			return
+		}
+		fmt.Fprintf(w, "✅ %s 的额度: %s -> %s %s\n", result.Spender, result.Previous, result.Allowance, result.Symbol)
+		if result.ResetTx != "" {
+			fmt.Fprintf(w, "   归零交易: %s\n", result.ResetTx)
+		}
+		fmt.Fprintf(w, "   授权交易: %s\n", result.ApproveTx)
+	})
+}
+
+type grantResult struct {
+	Spender  string `json:"spender"`
+	Current  string `json:"current"`
+	Approved string `json:"approved"`
+	Block    uint64 `json:"block"`
+	TxHash   string `json:"transactionHash"`
+}
+
+type allowanceScanResult struct {
+	Token  string        `json:"token"`
+	Owner  string        `json:"owner"`
+	Symbol string        `json:"symbol"`
+	Grants []grantResult `json:"grants"`
+}
+
+// blockRangeFlags 扫描事件的区块范围
+type blockRangeFlags struct {
+	from uint64
+	to   string
+}
+
+func (f *blockRangeFlags) bind(fs *flag.FlagSet) {
+	fs.Uint64Var(&f.from, "from-block", 0, "起始区块")
+	fs.StringVar(&f.to, "to-block", "latest", "结束区块（latest为最新区块）")
+}
+
+// end 结束区块，latest返回nil
+func (f *blockRangeFlags) end() (*uint64, error) {
+	n, err := parseBlockNumber(f.to)
+	if err != nil || n == nil {
+		return nil, err
+	}
+	end := n.Uint64()
+	return &end, nil
+}
+
+// scanGrants 扫描owner授出的非0额度
+func (e *Env) scanGrants(token *erc20.Token, owner common.Address, f *blockRangeFlags) ([]allowance_management.Grant, error) {
+	end, err := f.end()
+	if err != nil {
+		return nil, err
+	}
+	client, err := e.Client()
+	if err != nil {
+		return nil, err
+	}
+	return allowance_management.Scan(context.Background(), client, token.Address, owner, f.from, end)
+}
+
+// allowanceScan 扫描授权：ethcli allowance scan --token ADDR [--owner ADDR] [--from-block N] [--to-block N]
+// 额度以链上当前值为准（transferFrom 消耗额度时不一定产生 Approval 事件）
+func allowanceScan(env *Env, args []string) error {
+	fs := env.flags("allowance scan")
+	address := bindToken(fs)
+	var owner addressValue
+	var rf blockRangeFlags
+	fs.Var(&owner, "owner", "授权账户（默认为发送账户）")
+	rf.bind(fs)
+	if _, err := env.parse(fs, args); err != nil {
+		return err
+	}
+	if owner.Address == (common.Address{}) {
+		sender, err := env.SenderAddress()
+		if err != nil {
+			return fmt.Errorf("缺少参数 --owner: %v", err)
+		}
+		owner.Address = sender
+	}
+
+	token, err := env.token(address)
+	if err != nil {
+		return err
+	}
+	meta, err := token.Metadata(context.Background())
+	if err != nil {
+		return err
+	}
+	grants, err := env.scanGrants(token, owner.Address, &rf)
+	if err != nil {
+		return err
+	}
+
+	result := allowanceScanResult{Token: meta.Address.Hex(), Owner: owner.Hex(), Symbol: meta.Symbol, Grants: []grantResult{}}
+	for _, g := range grants {
+		result.Grants = append(result.Grants, grantResult{
+			Spender:  g.Spender.Hex(),
+			Current:  amount.Format(g.Current, int(meta.Decimals)),
+			Approved: amount.Format(g.Approved, int(meta.Decimals)),
+			Block:    g.Block,
+			TxHash:   g.TxHash.Hex(),
+		})
+	}
+	return env.Emit(result, func(w io.Writer) {
+		if len(result.Grants) == 0 {
+			fmt.Fprintf(w, "%s 没有未撤销的 %s 授权\n", result.Owner, result.Symbol)
+			return
+		}
+		fmt.Fprintf(w, "%s 的 %s 授权（%d 个）:\n", result.Owner, result.Symbol, len(result.Grants))
+		for _, g := range result.Grants {
+			fmt.Fprintf(w, "   %s  当前 %s（区块 %d 授权 %s）\n", g.Spender, g.Current, g.Block, g.Approved)
+		}
+	})
+}
+
+type revocationResult struct {
+	Spender string `json:"spender"`
+	TxHash  string `json:"transactionHash"`
+}
+
+type allowanceRevokeResult struct {
+	Token   string             `json:"token"`
+	Owner   string             `json:"owner"`
+	Revoked []revocationResult `json:"revoked"`
+}
+
+// allowanceRevoke 批量撤销：ethcli allowance revoke --token ADDR --spender A,B | --all [--from-block N]
+// --all 撤销扫描到的全部非0授权
+func allowanceRevoke(env *Env, args []string) error {
+	fs := env.flags("allowance revoke")
+	address := bindToken(fs)
+	var spenders addressListValue
+	var rf blockRangeFlags
+	var wf waitFlags
+	all := fs.Bool("all", false, "撤销扫描到的全部授权")
+	fs.Var(&spenders, "spender", "要撤销的被授权账户（可重复或以逗号分隔）")
+	rf.bind(fs)
+	wf.bindSteps(fs)
+	if _, err := env.parse(fs, args); err != nil {
+		return err
+	}
+	if *all == (len(spenders) > 0) {
+		return fmt.Errorf("请指定 --spender 或 --all 其中之一")
+	}
+
+	token, err := env.token(address)
+	if err != nil {
+		return err
+	}
+	s, err := env.Signer()
+	if err != nil {
+		return err
+	}
+	if *all {
+		grants, err := env.scanGrants(token, s.Address(), &rf)
+		if err != nil {
+			return err
+		}
+		for _, g := range grants {
+			spenders = append(spenders, g.Spender)
+		}
+	}
+
+	result := allowanceRevokeResult{Token: token.Address.Hex(), Owner: s.Address().Hex(), Revoked: []revocationResult{}}
+	if len(spenders) > 0 {
+		client, err := env.Client()
+		if err != nil {
+			return err
+		}
+		ctx := context.Background()
+		summary := fmt.Sprintf("撤销 %s 对 %d 个账户的授权（代币 %s）: %s", s.Address().Hex(), len(spenders), token.Address.Hex(), spenders.String())
+		if err := env.ConfirmSend(ctx, client, summary); err != nil {
+			return err
+		}
+		revoked, err := allowance_management.Revoke(ctx, client, token, s, spenders, env.waitOptions(wf, s))
+		for _, r := range revoked {
+			result.Revoked = append(result.Revoked, revocationResult{Spender: r.Spender.Hex(), TxHash: r.TxHash.Hex()})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return env.Emit(result, func(w io.Writer) {
+		if len(result.Revoked) == 0 {
+			fmt.Fprintln(w, "✅ 没有需要撤销的授权")
+			return
+		}
+		fmt.Fprintf(w, "✅ 已撤销 %d 个授权\n", len(result.Revoked))
+		for _, r := range result.Revoked {
+			fmt.Fprintf(w, "   %s  %s\n", r.Spender, r.TxHash)
+		}
+	})
+}
@@ -0,0 +1,335 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/config"
+	"ethclient_tutorial/rpc_pool"
+	"ethclient_tutorial/wallet_management"
+)
+
+// ErrAborted 用户在确认提示中取消了操作
+var ErrAborted = errors.New("操作已取消")
+
+// errUsage 参数错误（已打印用法）
+var errUsage = errors.New("参数错误")
+
+// command 子命令
+type command struct {
+	name    string // 如 "token transfer"
+	summary string
+	run     func(env *Env, args []string) error
+}
+
+// commands 全部子命令，按名称注册
+var commands = map[string]*command{}
+
+func register(name, summary string, run func(env *Env, args []string) error) {
+	commands[name] = &command{name: name, summary: summary, run: run}
+}
+
+// Env 一次命令执行的运行环境
+type Env struct {
+	Profile string // 配置档案，对应 .env.<profile>
+	RPC     string // 覆盖配置中的节点地址
+	JSON    bool   // 以JSON输出结果，进度信息输出到stderr
+	Yes     bool   // 跳过主网发送确认
+	From    string // 发送账户地址（覆盖 KEYSTORE_ACCOUNT）
+	KeyDir  string // keystore目录（覆盖 KEYSTORE_DIR）
+
+	Mnemonic  string // 助记词文件（覆盖 MNEMONIC_FILE）
+	Index     string // HD钱包账户索引（覆盖 HD_INDEX）
+	SignerURL string // 远程签名服务地址（覆盖 SIGNER_URL）
+
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	cfg         *config.Config
+	client      backend.Client
+	close       func()
+	closeSigner func()
+}
+
+// NewEnv 创建使用标准输入输出的运行环境
+func NewEnv() *Env {
+	return &Env{Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr}
+}
+
+// Run 解析参数并执行子命令，返回进程退出码
+func Run(args []string) int {
+	return NewEnv().Run(args)
+}
+
+// Run 解析全局参数并执行子命令，返回进程退出码
+func (e *Env) Run(args []string) int {
+	defer e.Close()
+
+	global := flag.NewFlagSet("ethcli", flag.ContinueOnError)
+	global.SetOutput(e.Stderr)
+	e.bindGlobal(global)
+	global.Usage = e.usage
+	if err := global.Parse(args); err != nil {
+		return 2
+	}
+	args = global.Args()
+
+	cmd, rest := lookup(args)
+	if cmd == nil {
+		if len(args) > 0 && args[0] != "help" {
+			fmt.Fprintf(e.Stderr, "❌ 未知命令: %s\n\n", strings.Join(args, " "))
+		}
+		e.usage()
+		return 2
+	}
+
+	if err := cmd.run(e, rest); err != nil {
+		if errors.Is(err, errUsage) {
+			return 2
+		}
+		fmt.Fprintf(e.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// progress 进度信息的输出位置：JSON模式下为stderr，stdout只输出结果
+func (e *Env) progress() io.Writer {
+	if e.JSON {
+		return e.Stderr
+	}
+	return e.Stdout
+}
+
+// logf 输出一行进度信息
+func (e *Env) logf(format string, args ...interface{}) {
+	fmt.Fprintf(e.progress(), format+"\n", args...)
+}
+
+// lookup 按最长匹配查找子命令（"token transfer" 优先于 "token"）
+func lookup(args []string) (*command, []string) {
+	for n := 2; n >= 1; n-- {
+		if len(args) < n {
+			continue
+		}
+		if cmd, ok := commands[strings.Join(args[:n], " ")]; ok {
+			return cmd, args[n:]
+		}
+	}
+	return nil, nil
+}
+
+func (e *Env) usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(e.Stderr, "用法: ethcli [全局参数] <命令> [参数]")
+	fmt.Fprintln(e.Stderr, "\n命令:")
+	for _, name := range names {
+		fmt.Fprintf(e.Stderr, "  %-18s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(e.Stderr, "\n全局参数（也可写在命令之后）:")
+	fmt.Fprintln(e.Stderr, "  --profile NAME     使用 .env.NAME 中的配置")
+	fmt.Fprintln(e.Stderr, "  --rpc URL          指定节点地址（覆盖 RPC_ENDPOINTS / ALCHEMY_API_KEY）")
+	fmt.Fprintln(e.Stderr, "  --json             以JSON格式输出结果")
+	fmt.Fprintln(e.Stderr, "  --yes              主网发送交易时不再确认")
+	fmt.Fprintln(e.Stderr, "  --from ADDRESS     发送账户（覆盖 KEYSTORE_ACCOUNT）")
+	fmt.Fprintln(e.Stderr, "  --keystore DIR     keystore目录（覆盖 KEYSTORE_DIR）")
+	fmt.Fprintln(e.Stderr, "\n使用 \"ethcli <命令> -h\" 查看命令参数")
+}
+
+// bindGlobal 注册全局参数
+func (e *Env) bindGlobal(fs *flag.FlagSet) {
+	fs.StringVar(&e.Profile, "profile", e.Profile, "配置档案（加载 .env.<profile>）")
+	fs.StringVar(&e.RPC, "rpc", e.RPC, "节点地址")
+	fs.BoolVar(&e.JSON, "json", e.JSON, "以JSON格式输出结果")
+	fs.BoolVar(&e.Yes, "yes", e.Yes, "主网发送交易时不再确认")
+	fs.StringVar(&e.From, "from", e.From, "发送账户地址（keystore中的账户）")
+	fs.StringVar(&e.KeyDir, "keystore", e.KeyDir, "keystore目录")
+	fs.StringVar(&e.Mnemonic, "mnemonic", e.Mnemonic, "助记词文件（使用HD钱包账户发送）")
+	fs.StringVar(&e.Index, "index", e.Index, "HD钱包账户索引（默认取 HD_INDEX）")
+	fs.StringVar(&e.SignerURL, "signer", e.SignerURL, "远程签名服务地址（Clef外部签名API）")
+}
+
+// flags 创建子命令的参数集（同时接受全局参数）
+func (e *Env) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("ethcli "+name, flag.ContinueOnError)
+	fs.SetOutput(e.Stderr)
+	e.bindGlobal(fs)
+	return fs
+}
+
+// parse 解析子命令参数，返回位置参数（参数可以写在位置参数之后）
+// -h 或参数错误时返回 errUsage
+func (e *Env) parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, errUsage
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	return positional, nil
+}
+
+// require 检查必填参数
+func (e *Env) require(fs *flag.FlagSet, names ...string) error {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for _, name := range names {
+		if !set[name] {
+			fmt.Fprintf(e.Stderr, "缺少参数 --%s\n", name)
+			fs.Usage()
+			return errUsage
+		}
+	}
+	return nil
+}
+
+// Config 按档案加载配置（只加载一次）
+func (e *Env) Config() *config.Config {
+	if e.cfg == nil {
+		e.cfg = config.LoadProfile(e.Profile)
+	}
+	return e.cfg
+}
+
+// Client 连接节点（只连接一次）
+// 总是通过节点池连接，只打印隐去密钥的节点名称
+func (e *Env) Client() (backend.Client, error) {
+	if e.client != nil {
+		return e.client, nil
+	}
+	endpoints := e.Config().GetRPCEndpoints()
+	if e.RPC != "" {
+		endpoints = []string{e.RPC}
+	}
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("未配置节点: 请设置 RPC_ENDPOINTS 或 ALCHEMY_API_KEY，或使用 --rpc")
+	}
+
+	pool, err := rpc_pool.NewPool(endpoints, rpc_pool.DefaultOptions)
+	if err != nil {
+		return nil, fmt.Errorf("创建节点池失败: %v", err)
+	}
+	pool.Start()
+	for _, stats := range pool.Stats() {
+		e.logf("🔍 节点 %s 健康: %v 延迟: %v 区块: #%d", stats.Name, stats.Healthy, stats.Latency, stats.Head)
+	}
+	e.client = pool
+	e.close = pool.Close
+	return pool, nil
+}
+
+// Close 关闭节点连接
+func (e *Env) Close() {
+	if e.close != nil {
+		e.close()
+		e.close = nil
+	}
+	if e.closeSigner != nil {
+		e.closeSigner()
+		e.closeSigner = nil
+	}
+}
+
+// ConfirmSend 在主网发送交易前要求用户确认（--yes 跳过）
+// summary 为待发送交易的说明，确认提示输出到stderr
+func (e *Env) ConfirmSend(ctx context.Context, client backend.Client, summary string) error {
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return fmt.Errorf("获取链ID失败: %v", err)
+	}
+	if !isMainnet(chainID) || e.Yes {
+		return nil
+	}
+
+	fmt.Fprintln(e.Stderr, "⚠️ 即将在以太坊主网发送交易:")
+	fmt.Fprintf(e.Stderr, "   %s\n", summary)
+	return e.confirm("确认发送? [y/N]: ")
+}
+
+// confirm 提示用户输入 y 确认，其他输入返回 ErrAborted
+func (e *Env) confirm(prompt string) error {
+	fmt.Fprint(e.Stderr, prompt)
+	line, _ := wallet_management.ReadLine(e.Stdin)
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return nil
+	default:
+		return ErrAborted
+	}
+}
+
+// isMainnet 是否为以太坊主网
+func isMainnet(chainID *big.Int) bool {
+	return chainID != nil && chainID.Cmp(params.MainnetChainConfig.ChainID) == 0
+}
+
+// Emit 输出命令结果：JSON模式下编码 result，否则调用 text 打印可读格式
+func (e *Env) Emit(result interface{}, text func(w io.Writer)) error {
+	if e.JSON {
+		encoder := json.NewEncoder(e.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+	text(e.Stdout)
+	return nil
+}
+
+// addressValue 地址参数
+type addressValue struct{ common.Address }
+
+func (a *addressValue) Set(s string) error {
+	if !common.IsHexAddress(s) {
+		return fmt.Errorf("无效的地址: %s", s)
+	}
+	a.Address = common.HexToAddress(s)
+	return nil
+}
+
+func (a *addressValue) String() string {
+	if a == nil {
+		return ""
+	}
+	return a.Hex()
+}
+
+// hashValue 交易哈希参数
+type hashValue struct{ common.Hash }
+
+func (h *hashValue) Set(s string) error {
+	b := common.FromHex(s)
+	if len(b) != common.HashLength {
+		return fmt.Errorf("无效的哈希: %s", s)
+	}
+	h.Hash = common.BytesToHash(b)
+	return nil
+}
+
+func (h *hashValue) String() string {
+	if h == nil {
+		return ""
+	}
+	return h.Hex()
+}
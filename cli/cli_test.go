@@ -0,0 +1,766 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/backend/backendtest"
+	"ethclient_tutorial/config"
+	"ethclient_tutorial/signer"
+	"ethclient_tutorial/utils"
+	"ethclient_tutorial/wallet_management"
+)
+
+// newTestEnv 创建连接内存链的运行环境，发送交易使用账户0
+func newTestEnv(t *testing.T) (*backend.Simulated, *Env) {
+	t.Helper()
+	sim := backendtest.NewAutoMining(t, 2)
+	utils.PollInterval = 100 * time.Millisecond
+
+	env := &Env{
+		Stdin:  strings.NewReader(""),
+		Stderr: new(bytes.Buffer),
+		cfg:    &config.Config{TestPrivateKey: sim.Accounts[0].KeyHex(), AllowPlaintextKey: true, FeeStrategy: "node", DefaultGasLimit: 21000},
+		client: sim,
+	}
+	return sim, env
+}
+
+// runJSON 以 --json 执行命令并解码输出
+func runJSON(t *testing.T, env *Env, result interface{}, args ...string) {
+	t.Helper()
+	stdout := new(bytes.Buffer)
+	env.Stdout = stdout
+	if code := env.Run(append(args, "--json")); code != 0 {
+		t.Fatalf("%s exit %d: %s", strings.Join(args, " "), code, env.Stderr)
+	}
+	if err := json.Unmarshal(stdout.Bytes(), result); err != nil {
+		t.Fatalf("%s output is not JSON: %v\n%s", strings.Join(args, " "), err, stdout)
+	}
+}
+
+func TestSendEthThenQueryTxAndReceipt(t *testing.T) {
+	sim, env := newTestEnv(t)
+	recipient := sim.Accounts[1].Address
+	before, _ := sim.BalanceAt(context.Background(), recipient, nil)
+
+	var sent sendEthResult
+	runJSON(t, env, &sent, "send", "eth", "--to", recipient.Hex(), "--amount", "1.000000000000000001")
+	if sent.Outcome != "confirmed" || sent.Amount != "1.000000000000000001" {
+		t.Fatalf("send result = %+v", sent)
+	}
+	after, _ := sim.BalanceAt(context.Background(), recipient, nil)
+	if delta := new(big.Int).Sub(after, before); delta.String() != "1000000000000000001" {
+		t.Fatalf("recipient received %s wei", delta)
+	}
+
+	var tx txResult
+	runJSON(t, env, &tx, "tx", "get", sent.TxHash)
+	if tx.From != sim.Accounts[0].Address.Hex() || tx.To != recipient.Hex() || tx.ValueEth != "1.000000000000000001" {
+		t.Fatalf("tx = %+v", tx)
+	}
+
+	var receipt receiptResult
+	runJSON(t, env, &receipt, "receipt", "get", "--hash", sent.TxHash)
+	if receipt.Status != 1 || receipt.BlockNumber != sent.BlockNumber {
+		t.Fatalf("receipt = %+v, sent in block %d", receipt, sent.BlockNumber)
+	}
+
+	var block blockResult
+	runJSON(t, env, &block, "block", "get", "--number", new(big.Int).SetUint64(sent.BlockNumber).String())
+	found := false
+	for _, hash := range block.Transactions {
+		found = found || hash == sent.TxHash
+	}
+	if !found {
+		t.Fatalf("block #%d does not contain %s", block.Number, sent.TxHash)
+	}
+}
+
+func TestDeployAndTransferToken(t *testing.T) {
+	sim, env := newTestEnv(t)
+	recipient := sim.Accounts[1].Address.Hex()
+
+	var deployed deployResult
+	runJSON(t, env, &deployed, "contract", "deploy")
+
+	var info tokenInfoResult
+	runJSON(t, env, &info, "token", "info", "--token", deployed.ContractAddress)
+	if info.Symbol != "MTK" || info.Decimals != 18 {
+		t.Fatalf("info = %+v", info)
+	}
+
+	var sent tokenSendResult
+	runJSON(t, env, &sent, "token", "transfer", "--token", deployed.ContractAddress, "--to", recipient, "--amount", "2.5")
+	if sent.Outcome != "confirmed" {
+		t.Fatalf("transfer = %+v", sent)
+	}
+
+	var balance tokenBalanceResult
+	runJSON(t, env, &balance, "token", "balance", "--token", deployed.ContractAddress, recipient)
+	if balance.Balance != "2.5" || balance.Raw != "2500000000000000000" {
+		t.Fatalf("balance = %+v", balance)
+	}
+
+	// 接收方是普通账户时 --and-call 退回普通转账，JSON模式下提示和进度都写到stderr
+	stderr := new(bytes.Buffer)
+	env.Stderr = stderr
+	runJSON(t, env, &sent, "token", "transfer", "--token", deployed.ContractAddress, "--to", recipient, "--amount", "0.5", "--and-call")
+	if sent.Called || sent.Outcome != "confirmed" {
+		t.Fatalf("transfer --and-call to EOA = %+v", sent)
+	}
+	for _, want := range []string{"未声明支持ERC-1363回调", "transfer交易已发送", "已被包含在区块"} {
+		if !strings.Contains(stderr.String(), want) {
+			t.Fatalf("stderr missing %q:\n%s", want, stderr)
+		}
+	}
+	env.Stdout = new(bytes.Buffer)
+	if code := env.Run([]string{"token", "transfer", "--token", deployed.ContractAddress, "--to", recipient, "--amount", "1", "--data", "0x01"}); code != 1 {
+		t.Fatalf("--data without --and-call exit = %d, want 1", code)
+	}
+
+	// 超出代币精度的数量直接拒绝
+	env.Stdout = new(bytes.Buffer)
+	if code := env.Run([]string{"token", "approve", "--token", deployed.ContractAddress, "--spender", recipient, "--amount", "0.0000000000000000001"}); code != 1 {
+		t.Fatalf("excess precision exit = %d, want 1", code)
+	}
+
+	// 已有非0额度时 approve 不能直接改为另一个非0值，归零仍然允许
+	var approved tokenSendResult
+	runJSON(t, env, &approved, "token", "approve", "--token", deployed.ContractAddress, "--spender", recipient, "--amount", "1")
+	env.Stdout = new(bytes.Buffer)
+	env.Stderr = new(bytes.Buffer)
+	if code := env.Run([]string{"token", "approve", "--token", deployed.ContractAddress, "--spender", recipient, "--amount", "2"}); code != 1 || !strings.Contains(env.Stderr.(*bytes.Buffer).String(), "allowance set") {
+		t.Fatalf("non-zero to non-zero approve exit = %d: %s", code, env.Stderr)
+	}
+	runJSON(t, env, &approved, "token", "approve", "--token", deployed.ContractAddress, "--spender", recipient, "--amount", "0")
+	if approved.Outcome != "confirmed" {
+		t.Fatalf("approve 0 = %+v", approved)
+	}
+}
+
+// mainnetClient 报告主网链ID的客户端
+type mainnetClient struct{ backend.Client }
+
+func (mainnetClient) ChainID(context.Context) (*big.Int, error) { return big.NewInt(1), nil }
+
+func TestConfirmBeforeMainnetSend(t *testing.T) {
+	sim, env := newTestEnv(t)
+	mainnet := mainnetClient{sim}
+	ctx := context.Background()
+
+	if err := env.ConfirmSend(ctx, sim, "test"); err != nil {
+		t.Fatalf("non-mainnet should not prompt: %v", err)
+	}
+
+	env.Stdin = strings.NewReader("n\n")
+	if err := env.ConfirmSend(ctx, mainnet, "test"); !errors.Is(err, ErrAborted) {
+		t.Fatalf("answer n: err = %v, want ErrAborted", err)
+	}
+	env.Stdin = strings.NewReader("y\n")
+	if err := env.ConfirmSend(ctx, mainnet, "test"); err != nil {
+		t.Fatalf("answer y: %v", err)
+	}
+	env.Stdin = strings.NewReader("")
+	env.Yes = true
+	if err := env.ConfirmSend(ctx, mainnet, "test"); err != nil {
+		t.Fatalf("--yes: %v", err)
+	}
+
+	// 取消后不发送任何交易
+	env.Yes = false
+	env.client = mainnet
+	env.Stdout = new(bytes.Buffer)
+	if code := env.Run([]string{"send", "eth", "--to", sim.Accounts[1].Address.Hex(), "--amount", "1"}); code != 1 {
+		t.Fatalf("aborted send exit = %d, want 1", code)
+	}
+	if nonce, _ := sim.PendingNonceAt(ctx, sim.Accounts[0].Address); nonce != 0 {
+		t.Fatalf("aborted send still sent a transaction (nonce %d)", nonce)
+	}
+}
+
+func TestSendFromKeystoreAccount(t *testing.T) {
+	sim, env := newTestEnv(t)
+	openKeystore = wallet_management.OpenLightKeystore
+	t.Cleanup(func() { openKeystore = wallet_management.OpenKeystore })
+	t.Setenv(wallet_management.DefaultPassphraseEnv, "secret")
+	env.KeyDir = t.TempDir()
+	sender := sim.Accounts[1]
+
+	keyFile := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(keyFile, []byte(sender.KeyHex()), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var imported walletResult
+	runJSON(t, env, &imported, "wallet", "import", "--key-file", keyFile)
+	if imported.Address != sender.Address.Hex() || imported.PrivateKey != "" {
+		t.Fatalf("import = %+v", imported)
+	}
+
+	// 明文私钥关闭后只能使用keystore账户
+	env.cfg.AllowPlaintextKey = false
+	env.Stdout = new(bytes.Buffer)
+	if code := env.Run([]string{"send", "eth", "--to", sim.Accounts[0].Address.Hex(), "--amount", "1"}); code != 1 {
+		t.Fatalf("send without account exit = %d, want 1", code)
+	}
+
+	var sent sendEthResult
+	runJSON(t, env, &sent, "send", "eth", "--from", sender.Address.Hex(), "--to", sim.Accounts[0].Address.Hex(), "--amount", "1")
+	if sent.From != sender.Address.Hex() || sent.Outcome != "confirmed" {
+		t.Fatalf("send = %+v", sent)
+	}
+
+	// 口令错误时不发送
+	t.Setenv(wallet_management.DefaultPassphraseEnv, "wrong")
+	env.Stdout = new(bytes.Buffer)
+	if code := env.Run([]string{"send", "eth", "--to", sim.Accounts[0].Address.Hex(), "--amount", "1"}); code != 1 {
+		t.Fatalf("wrong passphrase exit = %d, want 1", code)
+	}
+	if nonce, _ := sim.PendingNonceAt(context.Background(), sender.Address); nonce != 1 {
+		t.Fatalf("sender nonce = %d, want 1", nonce)
+	}
+}
+
+func TestWalletExportAndImportUseSeparatePasswords(t *testing.T) {
+	sim, env := newTestEnv(t)
+	openKeystore = wallet_management.OpenLightKeystore
+	t.Cleanup(func() { openKeystore = wallet_management.OpenKeystore })
+	t.Setenv(wallet_management.DefaultPassphraseEnv, "secret")
+	env.KeyDir = t.TempDir()
+	dir := t.TempDir()
+	keyFile, backupPassword, backup := filepath.Join(dir, "key"), filepath.Join(dir, "backup-password"), filepath.Join(dir, "backup.json")
+	os.WriteFile(keyFile, []byte(sim.Accounts[1].KeyHex()), 0600)
+	os.WriteFile(backupPassword, []byte("other\n"), 0600)
+
+	// 导出文件用 --new-password-file 的口令重新加密，而不是 KEYSTORE_PASSWORD
+	var imported, exported walletResult
+	runJSON(t, env, &imported, "wallet", "import", "--key-file", keyFile)
+	runJSON(t, env, &exported, "wallet", "export", "--address", imported.Address, "--out", backup, "--new-password-file", backupPassword)
+	keyJSON, err := os.ReadFile(backup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keystore.DecryptKey(keyJSON, "other"); err != nil {
+		t.Fatalf("export not encrypted with the new password: %v", err)
+	}
+
+	// 导入口令不同的keystore文件：文件口令取 --password-file，导入后使用 KEYSTORE_PASSWORD
+	env.KeyDir = t.TempDir()
+	runJSON(t, env, &imported, "wallet", "import", "--json-file", backup, "--password-file", backupPassword)
+	if imported.Address != sim.Accounts[1].Address.Hex() {
+		t.Fatalf("import = %+v", imported)
+	}
+	keyJSON, err = os.ReadFile(imported.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := keystore.DecryptKey(keyJSON, "secret"); err != nil {
+		t.Fatalf("imported key not encrypted with KEYSTORE_PASSWORD: %v", err)
+	}
+}
+
+func TestSendFromHDWalletIndex(t *testing.T) {
+	sim, env := newTestEnv(t)
+	mnemonicFile := filepath.Join(t.TempDir(), "mnemonic")
+	if err := os.WriteFile(mnemonicFile, []byte("test test test test test test test test test test test junk\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// 助记词 index 1 的账户
+	hdAccount := "0x70997970C51812dc3A010C7d01b50e0d17dc79C8"
+
+	var funded sendEthResult
+	runJSON(t, env, &funded, "send", "eth", "--to", hdAccount, "--amount", "2")
+
+	var derived []derivedResult
+	runJSON(t, env, &derived, "wallet", "derive", "--mnemonic", mnemonicFile, "--count", "2")
+	if len(derived) != 2 || derived[1].Address != hdAccount || derived[1].Balance != "2" || derived[1].Path != "m/44'/60'/0'/0/1" {
+		t.Fatalf("derive = %+v", derived)
+	}
+
+	var sent sendEthResult
+	runJSON(t, env, &sent, "send", "eth", "--index", "1", "--to", sim.Accounts[0].Address.Hex(), "--amount", "0.5")
+	if sent.From != hdAccount || sent.Outcome != "confirmed" {
+		t.Fatalf("send = %+v", sent)
+	}
+
+	env.Stdout = new(bytes.Buffer)
+	if code := env.Run([]string{"send", "eth", "--index", "x", "--to", hdAccount, "--amount", "1"}); code != 1 {
+		t.Fatalf("invalid index exit = %d, want 1", code)
+	}
+	// HD_INDEX 超出31位时报错，而不是截断为账户0
+	env.Index = ""
+	env.Config().HDIndex = 1 << 32
+	env.Stderr = new(bytes.Buffer)
+	if code := env.Run([]string{"send", "eth", "--to", hdAccount, "--amount", "1"}); code != 1 || !strings.Contains(env.Stderr.(*bytes.Buffer).String(), "HD_INDEX") {
+		t.Fatalf("HD_INDEX=2^32 exit = %d: %s", code, env.Stderr)
+	}
+	env.Config().HDIndex = 0
+
+	// 新生成的助记词保存到文件后可直接使用
+	generatedFile := filepath.Join(t.TempDir(), "generated")
+	var generated mnemonicResult
+	runJSON(t, env, &generated, "wallet", "mnemonic", "--words", "24", "--out", generatedFile)
+	var offline []derivedResult
+	runJSON(t, env, &offline, "wallet", "derive", "--mnemonic", generatedFile, "--count", "1", "--no-balance")
+	if generated.Mnemonic != "" || offline[0].Address != generated.Address || offline[0].Balance != "" {
+		t.Fatalf("generated = %+v, derived = %+v", generated, offline)
+	}
+}
+
+func TestSendWithRemoteSigner(t *testing.T) {
+	sim, env := newTestEnv(t)
+	standIn, err := signer.NewStandIn(sim.Accounts[0].Key, sim.Accounts[1].Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer standIn.Close()
+	// 私钥只在签名服务中
+	env.cfg.AllowPlaintextKey = false
+	env.cfg.TestPrivateKey = ""
+
+	var sent sendEthResult
+	runJSON(t, env, &sent, "send", "eth", "--signer", standIn.URL, "--from", sim.Accounts[1].Address.Hex(), "--to", sim.Accounts[0].Address.Hex(), "--amount", "1")
+	if sent.From != sim.Accounts[1].Address.Hex() || sent.Outcome != "confirmed" {
+		t.Fatalf("send = %+v", sent)
+	}
+
+	// 签名服务拒绝时不发送
+	standIn.Approve = func(string) error { return signer.ErrRequestDenied }
+	env.Stdout = new(bytes.Buffer)
+	if code := env.Run([]string{"send", "eth", "--signer", standIn.URL, "--to", sim.Accounts[1].Address.Hex(), "--amount", "1"}); code != 1 {
+		t.Fatalf("denied send exit = %d, want 1", code)
+	}
+	if nonce, _ := sim.PendingNonceAt(context.Background(), sim.Accounts[0].Address); nonce != 0 {
+		t.Fatalf("account 0 nonce = %d, want 0", nonce)
+	}
+}
+
+func TestOfflineBuildSignBroadcast(t *testing.T) {
+	sim, env := newTestEnv(t)
+	dir := t.TempDir()
+	unsignedFile := filepath.Join(dir, "unsigned.json")
+	signedFile := filepath.Join(dir, "signed.json")
+	recipient := sim.Accounts[1].Address
+
+	// 在线机器只知道发送方地址
+	var built offlineBuildResult
+	runJSON(t, env, &built, "offline", "build", "--to", recipient.Hex(), "--amount", "0.25", "--out", unsignedFile)
+	if built.From != sim.Accounts[0].Address.Hex() || built.Nonce != 0 {
+		t.Fatalf("build = %+v", built)
+	}
+
+	// 离线机器没有节点，拒绝确认时不生成签名文件
+	offline := &Env{
+		Stdin:  strings.NewReader("n\n"),
+		Stderr: new(bytes.Buffer),
+		cfg:    &config.Config{TestPrivateKey: sim.Accounts[0].KeyHex(), AllowPlaintextKey: true},
+	}
+	offline.Stdout = new(bytes.Buffer)
+	if code := offline.Run([]string{"offline", "sign", "--in", unsignedFile, "--out", signedFile}); code != 1 {
+		t.Fatalf("aborted sign exit = %d, want 1", code)
+	}
+	if _, err := os.Stat(signedFile); !os.IsNotExist(err) {
+		t.Fatalf("aborted sign wrote %s", signedFile)
+	}
+	if summary := offline.Stderr.(*bytes.Buffer).String(); !strings.Contains(summary, "0.25 ETH") || !strings.Contains(summary, recipient.Hex()) {
+		t.Fatalf("summary:\n%s", summary)
+	}
+
+	offline.Stdin = strings.NewReader("y\n")
+	var signed offlineSignResult
+	runJSON(t, offline, &signed, "offline", "sign", "--in", unsignedFile, "--out", signedFile)
+
+	var sent offlineBroadcastResult
+	runJSON(t, env, &sent, "offline", "broadcast", "--in", signedFile)
+	if sent.TxHash != signed.TxHash || sent.Outcome != "confirmed" {
+		t.Fatalf("broadcast = %+v, signed = %+v", sent, signed)
+	}
+}
+
+func TestSignAndVerifyMessagesAndTypedData(t *testing.T) {
+	sim, env := newTestEnv(t)
+	signer0 := sim.Accounts[0].Address.Hex()
+
+	var signed signResult
+	runJSON(t, env, &signed, "sign", "message", "--message", "hello")
+	if signed.Signer != signer0 {
+		t.Fatalf("sign = %+v", signed)
+	}
+	var verified verifyResult
+	runJSON(t, env, &verified, "verify", "message", "--hex", "0x68656c6c6f", "--signature", signed.Signature, "--address", signer0)
+	if !verified.Valid || verified.Signer != signer0 || verified.Hash != signed.Hash {
+		t.Fatalf("verify = %+v", verified)
+	}
+	env.Stdout = new(bytes.Buffer)
+	if code := env.Run([]string{"verify", "message", "--message", "hello", "--signature", signed.Signature, "--address", sim.Accounts[1].Address.Hex()}); code != 1 {
+		t.Fatalf("mismatched signer exit = %d, want 1", code)
+	}
+
+	// 签名域只给出合约地址时从 eip712Domain() 获取
+	var deployed deployResult
+	runJSON(t, env, &deployed, "contract", "deploy")
+	typedFile := filepath.Join(t.TempDir(), "typed.json")
+	typed := `{
+		"types": {"Greeting": [{"name": "text", "type": "string"}]},
+		"primaryType": "Greeting",
+		"domain": {"verifyingContract": "` + deployed.ContractAddress + `"},
+		"message": {"text": "hello"}
+	}`
+	if err := os.WriteFile(typedFile, []byte(typed), 0600); err != nil {
+		t.Fatal(err)
+	}
+	runJSON(t, env, &signed, "sign", "typed", "--file", typedFile)
+	runJSON(t, env, &verified, "verify", "typed", "--file", typedFile, "--signature", signed.Signature, "--address", signer0)
+	if !verified.Valid || verified.Hash != signed.Hash {
+		t.Fatalf("verify typed = %+v, signed = %+v", verified, signed)
+	}
+}
+
+func TestPermitSignAndRelay(t *testing.T) {
+	sim, env := newTestEnv(t)
+	permitFile := filepath.Join(t.TempDir(), "permit.json")
+	relayer := sim.Accounts[1].Address.Hex()
+	recipient := "0x00000000000000000000000000000000000000AA"
+
+	var deployed deployResult
+	runJSON(t, env, &deployed, "contract", "deploy")
+	var signed permitSignResult
+	runJSON(t, env, &signed, "permit", "sign", "--token", deployed.ContractAddress, "--spender", relayer, "--amount", "10", "--out", permitFile)
+	if signed.Owner != sim.Accounts[0].Address.Hex() || signed.Nonce != "0" || signed.Symbol != "MTK" {
+		t.Fatalf("sign = %+v", signed)
+	}
+
+	// owner不能替spender提交
+	env.Stdout = new(bytes.Buffer)
+	if code := env.Run([]string{"permit", "relay", "--in", permitFile, "--to", recipient}); code != 1 {
+		t.Fatalf("relay by owner exit = %d, want 1", code)
+	}
+
+	relay := &Env{
+		Stdin:  strings.NewReader(""),
+		Stderr: new(bytes.Buffer),
+		cfg:    &config.Config{TestPrivateKey: sim.Accounts[1].KeyHex(), AllowPlaintextKey: true, FeeStrategy: "node", DefaultGasLimit: 21000},
+		client: sim,
+	}
+	var relayed permitRelayResult
+	runJSON(t, relay, &relayed, "permit", "relay", "--in", permitFile, "--to", recipient, "--amount", "4")
+	if relayed.Relayer != relayer || relayed.Amount != "4" || relayed.PermitTx == relayed.TransferFromTx {
+		t.Fatalf("relay = %+v", relayed)
+	}
+
+	var balance tokenBalanceResult
+	runJSON(t, env, &balance, "token", "balance", "--token", deployed.ContractAddress, recipient)
+	if balance.Balance != "4" {
+		t.Fatalf("balance = %+v", balance)
+	}
+
+	// permit已被使用，不能再次提交
+	relay.Stdout = new(bytes.Buffer)
+	if code := relay.Run([]string{"permit", "relay", "--in", permitFile, "--to", recipient}); code != 1 {
+		t.Fatalf("replayed permit exit = %d, want 1", code)
+	}
+}
+
+func TestAllowanceSetScanRevoke(t *testing.T) {
+	sim, env := newTestEnv(t)
+	spender := sim.Accounts[1].Address.Hex()
+
+	var deployed deployResult
+	runJSON(t, env, &deployed, "contract", "deploy")
+	token := deployed.ContractAddress
+
+	var changed allowanceChangeResult
+	runJSON(t, env, &changed, "allowance", "set", "--token", token, "--spender", spender, "--amount", "10")
+	if changed.Allowance != "10" || changed.ResetTx != "" {
+		t.Fatalf("set = %+v", changed)
+	}
+	// 非0改为非0时先归零
+	runJSON(t, env, &changed, "allowance", "increase", "--token", token, "--spender", spender, "--amount", "2.5")
+	if changed.Previous != "10" || changed.Allowance != "12.5" || changed.ResetTx == "" {
+		t.Fatalf("increase = %+v", changed)
+	}
+
+	var scanned allowanceScanResult
+	runJSON(t, env, &scanned, "allowance", "scan", "--token", token)
+	if len(scanned.Grants) != 1 || scanned.Grants[0].Spender != spender || scanned.Grants[0].Current != "12.5" {
+		t.Fatalf("scan = %+v", scanned)
+	}
+
+	var revoked allowanceRevokeResult
+	runJSON(t, env, &revoked, "allowance", "revoke", "--token", token, "--all")
+	if len(revoked.Revoked) != 1 || revoked.Revoked[0].Spender != spender {
+		t.Fatalf("revoke = %+v", revoked)
+	}
+	var got allowanceResult
+	runJSON(t, env, &got, "allowance", "get", "--token", token, "--spender", spender)
+	if got.Raw != "0" {
+		t.Fatalf("allowance after revoke = %+v", got)
+	}
+}
+
+func TestAdminCommands(t *testing.T) {
+	sim, env := newTestEnv(t)
+	recipient := sim.Accounts[1].Address.Hex()
+
+	var deployed deployResult
+	runJSON(t, env, &deployed, "contract", "deploy")
+	token := deployed.ContractAddress
+
+	var action adminActionResult
+	runJSON(t, env, &action, "admin", "pause", "--token", token)
+	if action.Method != "pause" || !strings.HasPrefix(action.Event, "Paused(") {
+		t.Fatalf("pause = %+v", action)
+	}
+	var status adminStatusResult
+	runJSON(t, env, &status, "admin", "status", "--token", token)
+	if !status.Paused || status.Owner != sim.Accounts[0].Address.Hex() {
+		t.Fatalf("status = %+v", status)
+	}
+	runJSON(t, env, &action, "admin", "unpause", "--token", token)
+	runJSON(t, env, &action, "admin", "mint", "--token", token, "--to", recipient, "--amount", "5")
+	var balance tokenBalanceResult
+	runJSON(t, env, &balance, "token", "balance", "--token", token, recipient)
+	if balance.Balance != "5" {
+		t.Fatalf("balance after mint = %+v", balance)
+	}
+
+	// 放弃所有权需要确认，拒绝时不发送交易
+	env.Stdin = strings.NewReader("n\n")
+	env.Stdout = new(bytes.Buffer)
+	if code := env.Run([]string{"admin", "renounce", "--token", token}); code != 1 {
+		t.Fatalf("declined renounce exit = %d, want 1", code)
+	}
+	runJSON(t, env, &action, "admin", "renounce", "--token", token, "--force")
+	if !strings.Contains(action.Event, "newOwner=0x0000000000000000000000000000000000000000") {
+		t.Fatalf("renounce = %+v", action)
+	}
+}
+
+func TestFlashLoanCommands(t *testing.T) {
+	_, env := newTestEnv(t)
+
+	var deployed deployResult
+	runJSON(t, env, &deployed, "contract", "deploy")
+	token := deployed.ContractAddress
+
+	var quote flashQuoteResult
+	runJSON(t, env, &quote, "flash", "quote", "--token", token, "--amount", "5000")
+	if quote.Fee != "0" || quote.Symbol != "MTK" || quote.MaxLoan == "0" {
+		t.Fatalf("quote = %+v", quote)
+	}
+
+	var borrower flashBorrowerResult
+	runJSON(t, env, &borrower, "flash", "deploy-borrower", "--token", token)
+	if borrower.Mode != "repay" || borrower.Borrower == "" {
+		t.Fatalf("borrower = %+v", borrower)
+	}
+	var loan flashLoanResult
+	runJSON(t, env, &loan, "flash", "loan", "--token", token, "--borrower", borrower.Borrower, "--amount", "5000", "--data", "0xc0ffee")
+	if loan.LoanBalance != "5000" || loan.Data != "0xc0ffee" || loan.BalanceBefore != "0" || loan.BalanceAfter != "0" || loan.SupplyChange != "0" {
+		t.Fatalf("loan = %+v", loan)
+	}
+
+	// 不还款的借款合约在模拟时回滚，不发送交易
+	runJSON(t, env, &borrower, "flash", "deploy-borrower", "--token", token, "--mode", "no-repay")
+	env.Stdout = new(bytes.Buffer)
+	if code := env.Run([]string{"flash", "loan", "--token", token, "--borrower", borrower.Borrower, "--amount", "1"}); code != 1 {
+		t.Fatalf("unpaid loan exit = %d, want 1", code)
+	}
+}
+
+func TestEventsWatchBackfillsAndDecodesWithABIDir(t *testing.T) {
+	sim, env := newTestEnv(t)
+	var deployed deployResult
+	runJSON(t, env, &deployed, "contract", "deploy")
+
+	// 显式指定的ABI路径必须存在
+	env.Stdout = new(bytes.Buffer)
+	if code := env.Run([]string{"events", "watch", "--contract", deployed.ContractAddress, "--abi", filepath.Join(t.TempDir(), "missing"), "--duration", "1s"}); code != 1 {
+		t.Fatalf("missing --abi exit = %d, want 1", code)
+	}
+
+	// Foundry 编译产物中的ERC721 Transfer 与MyToken的 Transfer 签名冲突，解码时按主题数量区分
+	dir := t.TempDir()
+	artifact := `{"abi":[{"type":"event","name":"Transfer","anonymous":false,"inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"tokenId","type":"uint256","indexed":true}]}]}`
+	if err := os.WriteFile(filepath.Join(dir, "NFT.json"), []byte(artifact), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	watchEnv := &Env{Stdin: strings.NewReader(""), Stderr: new(bytes.Buffer), cfg: env.cfg, client: sim}
+	stdout := new(bytes.Buffer)
+	watchEnv.Stdout = stdout
+	done := make(chan int)
+	go func() {
+		done <- watchEnv.Run([]string{"events", "watch", "--contract", deployed.ContractAddress, "--abi", dir, "--from-block", "0", "--chunk-size", "1", "--duration", "3s", "--json"})
+	}()
+	time.Sleep(500 * time.Millisecond)
+	var sent tokenSendResult
+	runJSON(t, env, &sent, "token", "transfer", "--token", deployed.ContractAddress, "--to", sim.Accounts[1].Address.Hex(), "--amount", "2.5")
+	if code := <-done; code != 0 {
+		t.Fatalf("events watch exit = %d: %s", code, watchEnv.Stderr)
+	}
+
+	// --from-block 0 先回填部署时的事件，再收到实时的转账，每条只出现一次
+	type decoded struct {
+		Event  string            `json:"event"`
+		Args   map[string]string `json:"args"`
+		TxHash string            `json:"transactionHash"`
+	}
+	var events []decoded
+	for _, line := range bytes.Split(bytes.TrimSpace(stdout.Bytes()), []byte("\n")) {
+		var event decoded
+		if err := json.Unmarshal(line, &event); err != nil {
+			t.Fatalf("watch output: %v\n%s", err, stdout)
+		}
+		events = append(events, event)
+	}
+	if len(events) != 3 || events[0].Event != "OwnershipTransferred" || events[1].Event != "Transfer" || events[1].TxHash != deployed.TxHash {
+		t.Fatalf("events = %+v", events)
+	}
+	live := events[2]
+	if live.Event != "Transfer" || live.Args["value"] != "2500000000000000000" || live.Args["to"] != sim.Accounts[1].Address.Hex() || live.TxHash != sent.TxHash {
+		t.Fatalf("decoded = %+v", live)
+	}
+}
+
+func TestEventsWatchResumesFromCheckpoint(t *testing.T) {
+	sim, env := newTestEnv(t)
+	var deployed deployResult
+	runJSON(t, env, &deployed, "contract", "deploy")
+
+	checkpoint := filepath.Join(t.TempDir(), "checkpoints.json")
+	type keyed struct {
+		Event  string `json:"event"`
+		Key    string `json:"idempotencyKey"`
+		TxHash string `json:"transactionHash"`
+	}
+	watch := func() []keyed {
+		t.Helper()
+		stdout := new(bytes.Buffer)
+		watchEnv := &Env{Stdin: strings.NewReader(""), Stdout: stdout, Stderr: new(bytes.Buffer), cfg: env.cfg, client: sim}
+		if code := watchEnv.Run([]string{"events", "watch", "--contract", deployed.ContractAddress, "--checkpoint", checkpoint, "--from-block", "0", "--duration", "1s", "--json"}); code != 0 {
+			t.Fatalf("events watch exit = %d: %s", code, watchEnv.Stderr)
+		}
+		var events []keyed
+		for _, line := range bytes.Split(bytes.TrimSpace(stdout.Bytes()), []byte("\n")) {
+			if len(line) == 0 {
+				continue
+			}
+			var event keyed
+			if err := json.Unmarshal(line, &event); err != nil {
+				t.Fatalf("watch output: %v\n%s", err, stdout)
+			}
+			events = append(events, event)
+		}
+		return events
+	}
+
+	first := watch()
+	if len(first) != 2 || first[0].Key == "" || first[0].Key == first[1].Key {
+		t.Fatalf("first run = %+v", first)
+	}
+	if _, err := os.Stat(checkpoint); err != nil {
+		t.Fatal(err)
+	}
+
+	// 第二次运行从检查点继续（忽略 --from-block 0），只收到新的转账
+	var sent tokenSendResult
+	runJSON(t, env, &sent, "token", "transfer", "--token", deployed.ContractAddress, "--to", sim.Accounts[1].Address.Hex(), "--amount", "1")
+	second := watch()
+	if len(second) != 1 || second[0].TxHash != sent.TxHash || second[0].Event != "Transfer" {
+		t.Fatalf("second run = %+v", second)
+	}
+}
+
+func TestEventsWatchHoldsUnconfirmedLogs(t *testing.T) {
+	sim, env := newTestEnv(t)
+	var deployed deployResult
+	runJSON(t, env, &deployed, "contract", "deploy")
+
+	// 确认数远大于链高度，回填到的部署事件都不会输出
+	stdout := new(bytes.Buffer)
+	watchEnv := &Env{Stdin: strings.NewReader(""), Stdout: stdout, Stderr: new(bytes.Buffer), cfg: env.cfg, client: sim}
+	if code := watchEnv.Run([]string{"events", "watch", "--contract", deployed.ContractAddress, "--from-block", "0", "--confirmations", "1000", "--duration", "1s", "--json"}); code != 0 {
+		t.Fatalf("events watch exit = %d: %s", code, watchEnv.Stderr)
+	}
+	if out := strings.TrimSpace(stdout.String()); out != "" {
+		t.Fatalf("unconfirmed logs written: %s", out)
+	}
+}
+
+func TestEventsAndBlocksWatchByPolling(t *testing.T) {
+	sim, env := newTestEnv(t)
+	var deployed deployResult
+	runJSON(t, env, &deployed, "contract", "deploy")
+
+	// 指定轮询方式（HTTP节点不支持订阅时自动使用），与订阅方式输出相同的日志
+	stdout := new(bytes.Buffer)
+	watchEnv := &Env{Stdin: strings.NewReader(""), Stdout: stdout, Stderr: new(bytes.Buffer), cfg: env.cfg, client: sim}
+	done := make(chan int)
+	go func() {
+		done <- watchEnv.Run([]string{"events", "watch", "--contract", deployed.ContractAddress, "--mode", "poll", "--poll-interval", "50ms", "--duration", "2s", "--json"})
+	}()
+	time.Sleep(500 * time.Millisecond)
+	var sent tokenSendResult
+	runJSON(t, env, &sent, "token", "transfer", "--token", deployed.ContractAddress, "--to", sim.Accounts[1].Address.Hex(), "--amount", "1")
+	if code := <-done; code != 0 {
+		t.Fatalf("events watch exit = %d: %s", code, watchEnv.Stderr)
+	}
+	var event struct {
+		Event  string `json:"event"`
+		TxHash string `json:"transactionHash"`
+	}
+	if err := json.Unmarshal(bytes.TrimSpace(stdout.Bytes()), &event); err != nil || event.Event != "Transfer" || event.TxHash != sent.TxHash {
+		t.Fatalf("events watch output: %v\n%s", err, stdout)
+	}
+
+	// block watch 使用过滤器方式，每个新区块一行JSON
+	stdout.Reset()
+	if code := watchEnv.Run([]string{"block", "watch", "--mode", "filter", "--poll-interval", "50ms", "--duration", "1s", "--json"}); code != 0 {
+		t.Fatalf("block watch exit = %d: %s", code, watchEnv.Stderr)
+	}
+	lines := bytes.Split(bytes.TrimSpace(stdout.Bytes()), []byte("\n"))
+	var prev blockResult
+	for i, line := range lines {
+		var block blockResult
+		if err := json.Unmarshal(line, &block); err != nil {
+			t.Fatalf("block watch output: %v\n%s", err, stdout)
+		}
+		if i > 0 && block.Number <= prev.Number {
+			t.Fatalf("block #%d after #%d", block.Number, prev.Number)
+		}
+		prev = block
+	}
+	if len(lines) < 3 {
+		t.Fatalf("blocks = %d, want several", len(lines))
+	}
+
+	if code := watchEnv.Run([]string{"block", "watch", "--mode", "websocket"}); code != 2 {
+		t.Fatalf("invalid --mode exit = %d, want 2", code)
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	_, env := newTestEnv(t)
+	env.Stdout = new(bytes.Buffer)
+	for _, args := range [][]string{
+		{"nope"},
+		{"send", "eth", "--amount", "1"},
+		{"send", "eth", "--to", "not-an-address", "--amount", "1"},
+		{"tx", "get"},
+	} {
+		if code := env.Run(args); code != 2 {
+			t.Errorf("%v exit = %d, want 2", args, code)
+		}
+	}
+}
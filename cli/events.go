@@ -0,0 +1,197 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"ethclient_tutorial/contract_events"
+	"ethclient_tutorial/contracts"
+	"ethclient_tutorial/event_stream"
+)
+
+func init() {
+	register("events watch", "监听合约事件（WS/IPC节点订阅，HTTP节点自动改用过滤器或轮询）", eventsWatch)
+}
+
+// eventsWatch 监听合约事件直到 Ctrl-C 或到达 --duration
+// --from-block 先分段回填历史事件再切换到实时订阅；--checkpoint 保存处理位置，重启后从上次的位置继续；
+// --confirmations 日志达到确认数后才输出，被链重组撤销的日志输出撤销通知（JSON中 removed 为 true）；
+// --mode 指定接收方式，默认优先订阅，HTTP节点自动改用过滤器或轮询，断线后自动重连并补齐遗漏的日志；
+// JSON模式下每条日志输出一行JSON（JSON Lines），便于管道处理
+func eventsWatch(env *Env, args []string) error {
+	fs := env.flags("events watch")
+	var contract addressValue
+	fs.Var(&contract, "contract", "合约地址（默认 CONTRACT_ADDRESS）")
+	abiPath := fs.String("abi", "", "ABI文件、目录或Hardhat/Foundry编译产物（默认 CONTRACT_ABI_PATH，不存在时只使用MyToken ABI）")
+	duration := fs.Duration("duration", 0, "监听时长，0 表示直到 Ctrl-C")
+	fromBlock := fs.String("from-block", "", "先回填从该区块到最新区块的历史事件（默认只监听新事件）")
+	checkpoint := fs.String("checkpoint", "", "检查点文件：保存最后处理的区块和日志索引，重启后从该位置继续（优先于 --from-block）")
+	confirmations := fs.Uint64("confirmations", 0, "日志所在区块达到该确认数后才输出，并按区块哈希发现重组（0 表示收到即输出）")
+	chunkSize := fs.Uint64("chunk-size", contract_events.DefaultChunkSize, "回填时每次查询的初始区块数（结果过多时自动缩小）")
+	var stream streamFlags
+	stream.bind(fs)
+	if _, err := env.parse(fs, args); err != nil {
+		return err
+	}
+	var backfill *big.Int
+	if *fromBlock != "" {
+		n, err := parseBlockNumber(*fromBlock)
+		if err != nil || n == nil {
+			return fmt.Errorf("无效的 --from-block: %s", *fromBlock)
+		}
+		backfill = n
+	}
+	if contract.Address == (common.Address{}) {
+		if err := contract.Set(env.Config().ContractAddress); err != nil {
+			return fmt.Errorf("缺少参数 --contract（配置中的 CONTRACT_ADDRESS 无效）")
+		}
+	}
+
+	client, err := env.Client()
+	if err != nil {
+		return err
+	}
+	decoder, err := env.eventDecoder(*abiPath)
+	if err != nil {
+		return err
+	}
+	watcher := contract_events.NewEventWatcherWithDecoder(client, contract.Address, decoder)
+	watcher.UseStream(stream.options())
+	if *checkpoint != "" {
+		store, err := contract_events.NewFileCheckpointStore(*checkpoint)
+		if err != nil {
+			return err
+		}
+		watcher.UseCheckpoints(store)
+	}
+	if *confirmations > 0 {
+		watcher.UseReorgProtection(contract_events.ReorgOptions{Confirmations: *confirmations})
+	}
+	if env.JSON {
+		encoder := json.NewEncoder(env.Stdout)
+		watcher.OnEvent(func(vLog types.Log) {
+			encoder.Encode(decodedLog(decoder, vLog))
+		})
+		watcher.OnRevert(func(vLog types.Log) {
+			encoder.Encode(decodedLog(decoder, vLog))
+		})
+	}
+	if backfill != nil {
+		err = watcher.StartWatchingFrom(contract_events.BackfillOptions{FromBlock: backfill.Uint64(), ChunkSize: *chunkSize})
+	} else {
+		err = watcher.StartWatching()
+	}
+	if err != nil {
+		return err
+	}
+	defer watcher.Stop()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if *duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *duration)
+		defer cancel()
+		env.logf("⏰ 将在 %v 后停止监听", *duration)
+	} else {
+		env.logf("⏰ 按 Ctrl-C 停止监听")
+	}
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-watcher.Err():
+		return err
+	}
+}
+
+// streamFlags 接收日志和区块头的方式
+type streamFlags struct {
+	mode         modeValue
+	pollInterval time.Duration
+}
+
+func (f *streamFlags) bind(fs *flag.FlagSet) {
+	fs.Var(&f.mode, "mode", "接收方式: auto（WS/IPC订阅，HTTP节点改用过滤器，不支持过滤器时轮询）、subscribe、filter、poll")
+	fs.DurationVar(&f.pollInterval, "poll-interval", event_stream.DefaultOptions.PollInterval, "过滤器和轮询方式的查询间隔")
+}
+
+func (f streamFlags) options() event_stream.Options {
+	return event_stream.Options{Mode: f.mode.Mode, PollInterval: f.pollInterval}
+}
+
+// modeValue 接收方式参数
+type modeValue struct{ event_stream.Mode }
+
+func (m *modeValue) Set(s string) error {
+	mode, err := event_stream.ParseMode(s)
+	if err != nil {
+		return err
+	}
+	m.Mode = mode
+	return nil
+}
+
+func (m *modeValue) String() string {
+	if m == nil {
+		return event_stream.ModeAuto.String()
+	}
+	return m.Mode.String()
+}
+
+// eventDecoder 创建事件解码器：内嵌的MyToken ABI，加上 path（为空时取 CONTRACT_ABI_PATH）中的全部ABI
+// 显式指定的 path 必须存在；默认路径不存在时忽略
+func (e *Env) eventDecoder(path string) (*contract_events.Decoder, error) {
+	decoder, err := contract_events.DefaultDecoder()
+	if err != nil {
+		return nil, err
+	}
+	explicit := path != ""
+	if !explicit {
+		path = e.Config().ContractABIPath
+	}
+	if path == "" {
+		return decoder, nil
+	}
+	abis, err := contracts.LoadABIs(filepath.Clean(path))
+	if err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			return decoder, nil
+		}
+		return nil, fmt.Errorf("加载ABI失败: %v", err)
+	}
+	decoder.AddABIs(abis)
+	return decoder, nil
+}
+
+// decodedLog 在日志的JSON字段之外加上幂等键（idempotencyKey）和解码结果（event、signature、args），
+// 无法解码时只加幂等键
+func decodedLog(decoder *contract_events.Decoder, vLog types.Log) interface{} {
+	raw, err := json.Marshal(vLog)
+	if err != nil {
+		return vLog
+	}
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return vLog
+	}
+	fields["idempotencyKey"] = contract_events.IdempotencyKey(vLog)
+	event, err := decoder.Decode(vLog)
+	if err != nil {
+		return fields
+	}
+	fields["event"] = event.Name
+	fields["signature"] = event.Signature
+	fields["args"] = event.StringArgs()
+	return fields
+}
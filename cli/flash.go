@@ -0,0 +1,247 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"ethclient_tutorial/amount"
+	"ethclient_tutorial/erc20"
+	"ethclient_tutorial/flash_loan"
+)
+
+func init() {
+	register("flash quote", "查询闪电贷手续费和最大可借数量（ERC-3156）", flashQuote)
+	register("flash deploy-borrower", "部署测试用的闪电贷借款合约", flashDeployBorrower)
+	register("flash loan", "向借款合约发起闪电贷并报告余额变化", flashLoan)
+}
+
+// flash 创建闪电贷工具，使用代币的费用策略
+func (e *Env) flash(address *addressValue) (*flash_loan.Harness, *erc20.Token, error) {
+	token, err := e.token(address)
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := e.Client()
+	if err != nil {
+		return nil, nil, err
+	}
+	harness, err := flash_loan.New(client, token.Address)
+	if err != nil {
+		return nil, nil, err
+	}
+	harness.Strategy = token.Strategy
+	return harness, token, nil
+}
+
+type flashQuoteResult struct {
+	Token   string `json:"token"`
+	Amount  string `json:"amount"`
+	Fee     string `json:"fee"`
+	MaxLoan string `json:"maxLoan"`
+	Symbol  string `json:"symbol"`
+}
+
+// flashQuote 报价：ethcli flash quote --token ADDR --amount 1000
+func flashQuote(env *Env, args []string) error {
+	fs := env.flags("flash quote")
+	address := bindToken(fs)
+	var value amount.Amount
+	fs.Var(&value, "amount", "借款数量（以整个代币为单位）")
+	if _, err := env.parse(fs, args); err != nil {
+		return err
+	}
+	if err := env.require(fs, "amount"); err != nil {
+		return err
+	}
+	harness, token, err := env.flash(address)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	meta, err := token.Metadata(ctx)
+	if err != nil {
+		return err
+	}
+	baseUnits, err := value.ToBaseUnits(int(meta.Decimals))
+	if err != nil {
+		return err
+	}
+	quote, err := harness.Quote(ctx, baseUnits)
+	if err != nil {
+		return err
+	}
+
+	result := flashQuoteResult{
+		Token:   harness.Token.Hex(),
+		Amount:  value.String(),
+		Fee:     amount.Format(quote.Fee, int(meta.Decimals)),
+		MaxLoan: amount.Format(quote.MaxLoan, int(meta.Decimals)),
+		Symbol:  meta.Symbol,
+	}
+	return env.Emit(result, func(w io.Writer) {
+		fmt.Fprintf(w, "借 %s %s\n", result.Amount, result.Symbol)
+		fmt.Fprintf(w, "   手续费: %s %s\n", result.Fee, result.Symbol)
+		fmt.Fprintf(w, "   最大可借: %s %s\n", result.MaxLoan, result.Symbol)
+	})
+}
+
+type flashBorrowerResult struct {
+	Token    string `json:"token"`
+	Borrower string `json:"borrower"`
+	Mode     string `json:"mode"`
+	TxHash   string `json:"transactionHash"`
+}
+
+// flashDeployBorrower 部署借款合约：ethcli flash deploy-borrower --token ADDR [--mode repay|no-repay|wrong-return]
+func flashDeployBorrower(env *Env, args []string) error {
+	fs := env.flags("flash deploy-borrower")
+	address := bindToken(fs)
+	modeName := fs.String("mode", flash_loan.ModeRepay.String(), "回调行为：repay 正常还款，no-repay 不还款，wrong-return 返回错误的值")
+	var wf waitFlags
+	wf.bindSteps(fs)
+	if _, err := env.parse(fs, args); err != nil {
+		return err
+	}
+	mode, err := flash_loan.ParseMode(*modeName)
+	if err != nil {
+		return err
+	}
+	harness, _, err := env.flash(address)
+	if err != nil {
+		return err
+	}
+	s, err := env.Signer()
+	if err != nil {
+		return err
+	}
+	client, err := env.Client()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	if err := env.ConfirmSend(ctx, client, fmt.Sprintf("部署闪电贷借款合约（模式 %s，发送方 %s）", mode, s.Address().Hex())); err != nil {
+		return err
+	}
+
+	borrower, txHash, err := harness.DeployBorrower(ctx, s, mode, env.waitOptions(wf, s))
+	if err != nil {
+		return err
+	}
+	result := flashBorrowerResult{Token: harness.Token.Hex(), Borrower: borrower.Hex(), Mode: mode.String(), TxHash: txHash.Hex()}
+	return env.Emit(result, func(w io.Writer) {
+		fmt.Fprintf(w, "✅ 借款合约已部署: %s（模式 %s）\n", result.Borrower, result.Mode)
+		fmt.Fprintf(w, "   交易: %s\n", result.TxHash)
+	})
+}
+
+type flashLoanResult struct {
+	Token         string `json:"token"`
+	Borrower      string `json:"borrower"`
+	Amount        string `json:"amount"`
+	Fee           string `json:"fee"`
+	Symbol        string `json:"symbol"`
+	TxHash        string `json:"transactionHash"`
+	BlockNumber   uint64 `json:"blockNumber"`
+	BalanceBefore string `json:"balanceBefore"`
+	BalanceAfter  string `json:"balanceAfter"`
+	SupplyChange  string `json:"supplyChange"`
+	LoanBalance   string `json:"loanBalance,omitempty"` // 回调时借款合约的余额
+	Data          string `json:"data,omitempty"`        // 借款合约在回调中收到的data
+}
+
+// flashLoan 闪电贷：ethcli flash loan --token ADDR --borrower ADDR --amount 1000 [--data 0x...]
+func flashLoan(env *Env, args []string) error {
+	fs := env.flags("flash loan")
+	address := bindToken(fs)
+	var borrower addressValue
+	var value amount.Amount
+	fs.Var(&borrower, "borrower", "借款合约（实现 IERC3156FlashBorrower）")
+	fs.Var(&value, "amount", "借款数量（以整个代币为单位）")
+	dataHex := fs.String("data", "", "回调时传给借款合约的数据（十六进制）")
+	var wf waitFlags
+	wf.bindSteps(fs)
+	if _, err := env.parse(fs, args); err != nil {
+		return err
+	}
+	if err := env.require(fs, "borrower", "amount"); err != nil {
+		return err
+	}
+	var data []byte
+	if *dataHex != "" {
+		decoded, err := hexutil.Decode(*dataHex)
+		if err != nil {
+			return fmt.Errorf("无效的 --data: %v", err)
+		}
+		data = decoded
+	}
+
+	harness, token, err := env.flash(address)
+	if err != nil {
+		return err
+	}
+	s, err := env.Signer()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	meta, err := token.Metadata(ctx)
+	if err != nil {
+		return err
+	}
+	baseUnits, err := value.ToBaseUnits(int(meta.Decimals))
+	if err != nil {
+		return err
+	}
+	client, err := env.Client()
+	if err != nil {
+		return err
+	}
+	summary := fmt.Sprintf("闪电贷 %s %s -> %s（代币 %s，发送方 %s）", value, meta.Symbol, borrower.Hex(), meta.Address.Hex(), s.Address().Hex())
+	if err := env.ConfirmSend(ctx, client, summary); err != nil {
+		return err
+	}
+
+	loan, err := harness.Loan(ctx, s, borrower.Address, baseUnits, data, env.waitOptions(wf, s))
+	if err != nil {
+		return err
+	}
+	decimals := int(meta.Decimals)
+	result := flashLoanResult{
+		Token:         harness.Token.Hex(),
+		Borrower:      borrower.Hex(),
+		Amount:        value.String(),
+		Fee:           amount.Format(loan.Fee, decimals),
+		Symbol:        meta.Symbol,
+		TxHash:        loan.TxHash.Hex(),
+		BlockNumber:   loan.BlockNumber,
+		BalanceBefore: amount.Format(loan.BalanceBefore, decimals),
+		BalanceAfter:  amount.Format(loan.BalanceAfter, decimals),
+		SupplyChange:  formatSigned(loan.SupplyChange(), decimals),
+	}
+	if loan.Observed != nil {
+		result.LoanBalance = amount.Format(loan.Observed.Balance, decimals)
+		result.Data = hexutil.Encode(loan.Observed.Data)
+	}
+	return env.Emit(result, func(w io.Writer) {
+		fmt.Fprintf(w, "✅ 闪电贷已完成（区块 %d）\n", result.BlockNumber)
+		fmt.Fprintf(w, "   交易: %s\n", result.TxHash)
+		fmt.Fprintf(w, "   借款: %s %s，手续费: %s %s\n", result.Amount, result.Symbol, result.Fee, result.Symbol)
+		fmt.Fprintf(w, "   借款合约余额: %s -> %s %s\n", result.BalanceBefore, result.BalanceAfter, result.Symbol)
+		if result.LoanBalance != "" {
+			fmt.Fprintf(w, "   回调时余额: %s %s，收到数据: %s\n", result.LoanBalance, result.Symbol, result.Data)
+		}
+		fmt.Fprintf(w, "   总供应量变化: %s %s\n", result.SupplyChange, result.Symbol)
+	})
+}
+
+// formatSigned 格式化可能为负的数量
+func formatSigned(value *big.Int, decimals int) string {
+	if value.Sign() < 0 {
+		return "-" + amount.Format(new(big.Int).Neg(value), decimals)
+	}
+	return amount.Format(value, decimals)
+}
@@ -0,0 +1,223 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"ethclient_tutorial/amount"
+	"ethclient_tutorial/eth_transfer"
+	"ethclient_tutorial/offline_signing"
+)
+
+func init() {
+	register("offline build", "构造未签名交易文件（在线）", offlineBuild)
+	register("offline sign", "离线签名交易文件（不访问网络）", offlineSign)
+	register("offline broadcast", "广播签名交易并等待确认", offlineBroadcast)
+}
+
+type offlineBuildResult struct {
+	File    string `json:"file"`
+	ChainID string `json:"chainId"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Nonce   uint64 `json:"nonce"`
+	Token   string `json:"token,omitempty"`
+	Amount  string `json:"amount"`
+}
+
+// offlineBuild 构造未签名交易：ethcli offline build --to ADDR --amount 0.1 [--token ADDR] --out unsigned.json
+// 指定 --token 时构造代币转账，否则构造ETH转账；发送账户只需要地址（--from），不需要解锁
+func offlineBuild(env *Env, args []string) error {
+	fs := env.flags("offline build")
+	var to, token addressValue
+	var value amount.Amount
+	nonce := fs.Int64("nonce", -1, "指定nonce（默认取账户当前的pending nonce）")
+	out := fs.String("out", "", "未签名交易文件")
+	fs.Var(&to, "to", "接收地址")
+	fs.Var(&value, "amount", "金额（ETH，或指定 --token 时的代币数量）")
+	fs.Var(&token, "token", "代币合约地址（构造代币转账）")
+	if _, err := env.parse(fs, args); err != nil {
+		return err
+	}
+	if err := env.require(fs, "to", "amount", "out"); err != nil {
+		return err
+	}
+
+	from, err := env.SenderAddress()
+	if err != nil {
+		return err
+	}
+	client, err := env.Client()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	var unsigned *offline_signing.UnsignedTx
+	if token.Address == (common.Address{}) {
+		wei, err := eth_transfer.EtherToWei(value)
+		if err != nil {
+			return err
+		}
+		unsigned, err = offline_signing.BuildETH(ctx, client, from, to.Address, wei, env.Config())
+		if err != nil {
+			return fmt.Errorf("构造ETH转账失败: %w", err)
+		}
+	} else {
+		t, err := env.token(&token)
+		if err != nil {
+			return err
+		}
+		meta, err := t.Metadata(ctx)
+		if err != nil {
+			return err
+		}
+		baseUnits, err := value.ToBaseUnits(int(meta.Decimals))
+		if err != nil {
+			return err
+		}
+		unsigned, err = offline_signing.BuildERC20(ctx, client, t, from, to.Address, baseUnits)
+		if err != nil {
+			return fmt.Errorf("构造代币转账失败: %w", err)
+		}
+	}
+	if *nonce >= 0 {
+		unsigned.Nonce = hexutil.Uint64(uint64(*nonce))
+	}
+	if err := offline_signing.WriteFile(*out, unsigned); err != nil {
+		return err
+	}
+
+	result := offlineBuildResult{
+		File:    *out,
+		ChainID: unsigned.ChainID.ToInt().String(),
+		From:    from.Hex(),
+		To:      to.Hex(),
+		Nonce:   uint64(unsigned.Nonce),
+		Amount:  value.String(),
+	}
+	if token.Address != (common.Address{}) {
+		result.Token = token.Hex()
+	}
+	return env.Emit(result, func(w io.Writer) {
+		fmt.Fprintf(w, "✅ 未签名交易已写入 %s\n", result.File)
+		fmt.Fprint(w, indent(unsigned.Summary()))
+		fmt.Fprintln(w, "📝 将文件拷贝到离线机器执行 ethcli offline sign")
+	})
+}
+
+type offlineSignResult struct {
+	File   string `json:"file"`
+	From   string `json:"from"`
+	TxHash string `json:"transactionHash"`
+}
+
+// offlineSign 离线签名：ethcli offline sign --in unsigned.json --out signed.json
+// 签名前显示交易摘要并要求确认（--yes 跳过），使用keystore、HD钱包或明文私钥签名，不连接节点
+func offlineSign(env *Env, args []string) error {
+	fs := env.flags("offline sign")
+	in := fs.String("in", "", "未签名交易文件")
+	out := fs.String("out", "", "签名交易文件")
+	if _, err := env.parse(fs, args); err != nil {
+		return err
+	}
+	if err := env.require(fs, "in", "out"); err != nil {
+		return err
+	}
+
+	unsigned, err := offline_signing.ReadUnsigned(*in)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(env.Stderr, "📝 待签名交易:")
+	fmt.Fprint(env.Stderr, indent(unsigned.Summary()))
+	if !env.Yes {
+		if err := env.confirm("确认签名? [y/N]: "); err != nil {
+			return err
+		}
+	}
+
+	s, err := env.Signer()
+	if err != nil {
+		return err
+	}
+	signed, err := offline_signing.Sign(context.Background(), s, unsigned)
+	if err != nil {
+		return err
+	}
+	if err := offline_signing.WriteFile(*out, signed); err != nil {
+		return err
+	}
+
+	result := offlineSignResult{File: *out, From: signed.From.Hex(), TxHash: signed.Hash.Hex()}
+	return env.Emit(result, func(w io.Writer) {
+		fmt.Fprintf(w, "✅ 签名交易已写入 %s\n", result.File)
+		fmt.Fprintf(w, "   交易哈希: %s\n", result.TxHash)
+	})
+}
+
+type offlineBroadcastResult struct {
+	From string `json:"from"`
+	txOutcome
+}
+
+// offlineBroadcast 广播签名交易：ethcli offline broadcast --in signed.json
+// 本机没有私钥，等待期间不会自动加价
+func offlineBroadcast(env *Env, args []string) error {
+	fs := env.flags("offline broadcast")
+	in := fs.String("in", "", "签名交易文件")
+	var wf waitFlags
+	wf.bind(fs)
+	if _, err := env.parse(fs, args); err != nil {
+		return err
+	}
+	if err := env.require(fs, "in"); err != nil {
+		return err
+	}
+
+	signed, err := offline_signing.ReadSigned(*in)
+	if err != nil {
+		return err
+	}
+	client, err := env.Client()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	summary := fmt.Sprintf("广播 %s 签名的交易 %s", signed.From.Hex(), signed.Hash.Hex())
+	if err := env.ConfirmSend(ctx, client, summary); err != nil {
+		return err
+	}
+	tx, err := offline_signing.Broadcast(ctx, client, signed)
+	if err != nil {
+		return err
+	}
+	env.logf("✓ 交易已广播: %s", tx.Hash().Hex())
+
+	outcome, err := env.wait(client, tx.Hash(), nil, wf)
+	if err != nil {
+		return err
+	}
+	result := offlineBroadcastResult{From: signed.From.Hex(), txOutcome: outcome}
+	return env.Emit(result, func(w io.Writer) {
+		fmt.Fprintf(w, "✅ 已广播 %s 签名的交易\n", result.From)
+		outcome.print(w)
+	})
+}
+
+// indent 每行前加缩进
+func indent(text string) string {
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = "   " + line
+		}
+	}
+	return strings.Join(lines, "")
+}
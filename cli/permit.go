@@ -0,0 +1,186 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"ethclient_tutorial/amount"
+	"ethclient_tutorial/erc20"
+	"ethclient_tutorial/permit"
+)
+
+func init() {
+	register("permit sign", "owner链下签名ERC-2612 permit授权（不发送交易）", permitSign)
+	register("permit relay", "spender提交permit并用transferFrom代owner转账", permitRelay)
+}
+
+type permitSignResult struct {
+	File     string `json:"file"`
+	Token    string `json:"token"`
+	Owner    string `json:"owner"`
+	Spender  string `json:"spender"`
+	Amount   string `json:"amount"`
+	Symbol   string `json:"symbol"`
+	Nonce    string `json:"nonce"`
+	Deadline string `json:"deadline"`
+}
+
+// permitSign owner签名permit：ethcli permit sign --token ADDR --spender ADDR --amount 10 --out permit.json
+// nonce和签名域从合约读取，截止时间从最新区块时间起算
+func permitSign(env *Env, args []string) error {
+	fs := env.flags("permit sign")
+	address := bindToken(fs)
+	var spender addressValue
+	var value amount.Amount
+	validFor := fs.Duration("deadline", 30*time.Minute, "permit有效期（从最新区块时间起算）")
+	out := fs.String("out", "", "permit文件")
+	fs.Var(&spender, "spender", "被授权的账户（负责提交permit的relayer）")
+	fs.Var(&value, "amount", "授权的代币数量（以整个代币为单位）")
+	if _, err := env.parse(fs, args); err != nil {
+		return err
+	}
+	if err := env.require(fs, "spender", "amount", "out"); err != nil {
+		return err
+	}
+	if *validFor < permit.MinValidity {
+		return fmt.Errorf("--deadline 不能短于 %s", permit.MinValidity)
+	}
+
+	token, err := env.token(address)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	meta, err := token.Metadata(ctx)
+	if err != nil {
+		return err
+	}
+	baseUnits, err := value.ToBaseUnits(int(meta.Decimals))
+	if err != nil {
+		return err
+	}
+	client, err := env.Client()
+	if err != nil {
+		return err
+	}
+	deadline, err := permit.Deadline(ctx, client, *validFor)
+	if err != nil {
+		return err
+	}
+
+	s, err := env.Signer()
+	if err != nil {
+		return err
+	}
+	p, err := permit.Sign(ctx, client, s, token.Address, spender.Address, baseUnits, deadline)
+	if err != nil {
+		return fmt.Errorf("签名permit失败: %w", err)
+	}
+	if err := permit.WriteFile(*out, p); err != nil {
+		return err
+	}
+
+	result := permitSignResult{
+		File:     *out,
+		Token:    meta.Address.Hex(),
+		Owner:    p.Owner.Hex(),
+		Spender:  p.Spender.Hex(),
+		Amount:   value.String(),
+		Symbol:   meta.Symbol,
+		Nonce:    p.Nonce.ToInt().String(),
+		Deadline: time.Unix(deadline.Int64(), 0).UTC().Format(time.RFC3339),
+	}
+	return env.Emit(result, func(w io.Writer) {
+		fmt.Fprintf(w, "✅ permit已写入 %s\n", result.File)
+		fmt.Fprintf(w, "   %s 授权 %s 使用 %s %s\n", result.Owner, result.Spender, result.Amount, result.Symbol)
+		fmt.Fprintf(w, "   nonce: %s，截止时间: %s\n", result.Nonce, result.Deadline)
+		fmt.Fprintln(w, "📝 将文件交给spender执行 ethcli permit relay")
+	})
+}
+
+type permitRelayResult struct {
+	Token          string `json:"token"`
+	Owner          string `json:"owner"`
+	Relayer        string `json:"relayer"`
+	To             string `json:"to"`
+	Amount         string `json:"amount"`
+	Symbol         string `json:"symbol"`
+	PermitTx       string `json:"permitTransactionHash"`
+	TransferFromTx string `json:"transferFromTransactionHash"`
+}
+
+// permitRelay 提交permit：ethcli permit relay --in permit.json --to ADDR [--amount 5]
+// 发送账户必须是permit的spender，Gas由它支付；未指定 --amount 时转走全部授权额度
+func permitRelay(env *Env, args []string) error {
+	fs := env.flags("permit relay")
+	in := fs.String("in", "", "permit文件")
+	var to addressValue
+	var value amount.Amount
+	var wf waitFlags
+	wf.bindSteps(fs)
+	fs.Var(&to, "to", "代币接收地址")
+	fs.Var(&value, "amount", "转账的代币数量（默认为permit的全部额度）")
+	if _, err := env.parse(fs, args); err != nil {
+		return err
+	}
+	if err := env.require(fs, "in", "to"); err != nil {
+		return err
+	}
+
+	p, err := permit.ReadFile(*in)
+	if err != nil {
+		return err
+	}
+	client, err := env.Client()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	meta, err := erc20.New(client, p.Token).Metadata(ctx)
+	if err != nil {
+		return err
+	}
+	transfer := p.Value.ToInt()
+	if !value.IsZero() {
+		if transfer, err = value.ToBaseUnits(int(meta.Decimals)); err != nil {
+			return err
+		}
+	}
+
+	s, err := env.Signer()
+	if err != nil {
+		return err
+	}
+	// 提前检查，避免确认后才发现permit无效
+	if err := permit.Verify(ctx, client, p); err != nil {
+		return err
+	}
+	display := amount.Format(transfer, int(meta.Decimals))
+	summary := fmt.Sprintf("提交 %s 的permit并转账 %s %s -> %s（代币 %s，relayer %s）", p.Owner.Hex(), display, meta.Symbol, to.Hex(), meta.Address.Hex(), s.Address().Hex())
+	if err := env.ConfirmSend(ctx, client, summary); err != nil {
+		return err
+	}
+
+	relayed, err := permit.Relay(ctx, client, s, p, to.Address, transfer, env.waitOptions(wf, s))
+	if err != nil {
+		return err
+	}
+
+	result := permitRelayResult{
+		Token:          meta.Address.Hex(),
+		Owner:          p.Owner.Hex(),
+		Relayer:        s.Address().Hex(),
+		To:             to.Hex(),
+		Amount:         display,
+		Symbol:         meta.Symbol,
+		PermitTx:       relayed.PermitTx.Hex(),
+		TransferFromTx: relayed.TransferFromTx.Hex(),
+	}
+	return env.Emit(result, func(w io.Writer) {
+		fmt.Fprintf(w, "✅ 已代 %s 向 %s 转账 %s %s\n", result.Owner, result.To, result.Amount, result.Symbol)
+		fmt.Fprintf(w, "   permit交易: %s\n", result.PermitTx)
+		fmt.Fprintf(w, "   transferFrom交易: %s\n", result.TransferFromTx)
+	})
+}
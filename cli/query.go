@@ -0,0 +1,365 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"ethclient_tutorial/amount"
+	"ethclient_tutorial/event_stream"
+	"ethclient_tutorial/revert_decoder"
+)
+
+func init() {
+	register("block get", "查询区块（按区块号、哈希或最新区块）", blockGet)
+	register("block watch", "监听新区块（WS/IPC节点订阅，HTTP节点自动改用过滤器或轮询）", blockWatch)
+	register("tx get", "查询交易详情", txGet)
+	register("receipt get", "查询交易收据（失败时解码回滚原因）", receiptGet)
+}
+
+type blockResult struct {
+	Number       uint64   `json:"number"`
+	Hash         string   `json:"hash"`
+	ParentHash   string   `json:"parentHash"`
+	Timestamp    uint64   `json:"timestamp"`
+	Miner        string   `json:"miner"`
+	GasUsed      uint64   `json:"gasUsed"`
+	GasLimit     uint64   `json:"gasLimit"`
+	BaseFee      string   `json:"baseFeePerGas,omitempty"`
+	Transactions []string `json:"transactions"`
+
+	baseFee *big.Int
+}
+
+func newBlockResult(block *types.Block) blockResult {
+	result := blockResult{
+		Number:       block.NumberU64(),
+		Hash:         block.Hash().Hex(),
+		ParentHash:   block.ParentHash().Hex(),
+		Timestamp:    block.Time(),
+		Miner:        block.Coinbase().Hex(),
+		GasUsed:      block.GasUsed(),
+		GasLimit:     block.GasLimit(),
+		Transactions: make([]string, 0, len(block.Transactions())),
+		baseFee:      block.BaseFee(),
+	}
+	if block.BaseFee() != nil {
+		result.BaseFee = block.BaseFee().String()
+	}
+	for _, tx := range block.Transactions() {
+		result.Transactions = append(result.Transactions, tx.Hash().Hex())
+	}
+	return result
+}
+
+// print 打印区块信息
+func (r blockResult) print(w io.Writer) {
+	fmt.Fprintf(w, "区块 #%d\n", r.Number)
+	fmt.Fprintf(w, "   哈希: %s\n", r.Hash)
+	fmt.Fprintf(w, "   父区块: %s\n", r.ParentHash)
+	fmt.Fprintf(w, "   时间戳: %d\n", r.Timestamp)
+	fmt.Fprintf(w, "   出块者: %s\n", r.Miner)
+	fmt.Fprintf(w, "   Gas: %d / %d\n", r.GasUsed, r.GasLimit)
+	if r.baseFee != nil {
+		fmt.Fprintf(w, "   基础费用: %s Gwei\n", amount.Format(r.baseFee, 9))
+	}
+	fmt.Fprintf(w, "   交易数: %d\n", len(r.Transactions))
+}
+
+// blockGet 查询区块：ethcli block get [--number N | --hash H]，默认最新区块
+func blockGet(env *Env, args []string) error {
+	fs := env.flags("block get")
+	number := fs.String("number", "latest", "区块号或 latest")
+	var hash hashValue
+	fs.Var(&hash, "hash", "区块哈希（优先于 --number）")
+	positional, err := env.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		*number = positional[0]
+	}
+
+	client, err := env.Client()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	var block *types.Block
+	if hash.Hash != (common.Hash{}) {
+		block, err = client.BlockByHash(ctx, hash.Hash)
+	} else {
+		var blockNumber *big.Int
+		if blockNumber, err = parseBlockNumber(*number); err != nil {
+			return err
+		}
+		block, err = client.BlockByNumber(ctx, blockNumber)
+	}
+	if err != nil {
+		return fmt.Errorf("查询区块失败: %v", err)
+	}
+
+	result := newBlockResult(block)
+	return env.Emit(result, result.print)
+}
+
+// blockWatch 输出新区块直到 Ctrl-C 或到达 --duration
+// 默认优先订阅，HTTP节点自动改用 eth_newBlockFilter 或轮询，断线后自动重连；
+// 轮询时两次查询之间产生的多个区块只输出最新的一个。JSON模式下每个区块输出一行JSON
+func blockWatch(env *Env, args []string) error {
+	fs := env.flags("block watch")
+	duration := fs.Duration("duration", 0, "监听时长，0 表示直到 Ctrl-C")
+	var stream streamFlags
+	stream.bind(fs)
+	if _, err := env.parse(fs, args); err != nil {
+		return err
+	}
+	client, err := env.Client()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if *duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *duration)
+		defer cancel()
+	}
+	heads := event_stream.SubscribeHeads(ctx, client, stream.options())
+	defer heads.Close()
+	if err := heads.Wait(ctx); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+	env.logf("🔔 开始监听新区块（%s）...", heads.Mode())
+
+	encoder := json.NewEncoder(env.Stdout)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-heads.Err():
+			return err
+		case header := <-heads.Heads():
+			block, err := client.BlockByHash(ctx, header.Hash())
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return fmt.Errorf("查询区块 #%d 失败: %v", header.Number, err)
+			}
+			result := newBlockResult(block)
+			if env.JSON {
+				encoder.Encode(result)
+			} else {
+				result.print(env.Stdout)
+			}
+		}
+	}
+}
+
+// parseBlockNumber 解析区块号，latest 返回nil
+func parseBlockNumber(s string) (*big.Int, error) {
+	if s == "" || strings.EqualFold(s, "latest") {
+		return nil, nil
+	}
+	n, err := strconv.ParseUint(s, 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("无效的区块号: %s", s)
+	}
+	return new(big.Int).SetUint64(n), nil
+}
+
+type txResult struct {
+	Hash      string `json:"hash"`
+	Pending   bool   `json:"pending"`
+	Type      uint8  `json:"type"`
+	ChainID   string `json:"chainId"`
+	From      string `json:"from"`
+	To        string `json:"to,omitempty"`
+	Nonce     uint64 `json:"nonce"`
+	Value     string `json:"value"`
+	ValueEth  string `json:"valueEth"`
+	Gas       uint64 `json:"gas"`
+	GasPrice  string `json:"gasPrice,omitempty"`
+	GasTipCap string `json:"maxPriorityFeePerGas,omitempty"`
+	GasFeeCap string `json:"maxFeePerGas,omitempty"`
+	Input     string `json:"input"`
+}
+
+// txGet 查询交易：ethcli tx get <hash>
+func txGet(env *Env, args []string) error {
+	fs := env.flags("tx get")
+	var hash hashValue
+	fs.Var(&hash, "hash", "交易哈希")
+	txHash, err := hashArg(env, fs, args, &hash)
+	if err != nil {
+		return err
+	}
+
+	client, err := env.Client()
+	if err != nil {
+		return err
+	}
+	tx, pending, err := client.TransactionByHash(context.Background(), txHash)
+	if err != nil {
+		return fmt.Errorf("查询交易失败: %v", err)
+	}
+
+	result := txResult{
+		Hash:     tx.Hash().Hex(),
+		Pending:  pending,
+		Type:     tx.Type(),
+		ChainID:  tx.ChainId().String(),
+		Nonce:    tx.Nonce(),
+		Value:    tx.Value().String(),
+		ValueEth: amount.Format(tx.Value(), amount.EtherDecimals),
+		Gas:      tx.Gas(),
+		Input:    hexutil.Encode(tx.Data()),
+	}
+	if sender, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx); err == nil {
+		result.From = sender.Hex()
+	}
+	if tx.To() != nil {
+		result.To = tx.To().Hex()
+	}
+	if tx.Type() == types.DynamicFeeTxType {
+		result.GasTipCap = tx.GasTipCap().String()
+		result.GasFeeCap = tx.GasFeeCap().String()
+	} else {
+		result.GasPrice = tx.GasPrice().String()
+	}
+
+	return env.Emit(result, func(w io.Writer) {
+		fmt.Fprintf(w, "交易 %s\n", result.Hash)
+		if result.Pending {
+			fmt.Fprintln(w, "   状态: 等待打包")
+		}
+		fmt.Fprintf(w, "   类型: %d\n", result.Type)
+		fmt.Fprintf(w, "   发送方: %s\n", result.From)
+		if result.To != "" {
+			fmt.Fprintf(w, "   接收方: %s\n", result.To)
+		} else {
+			fmt.Fprintln(w, "   接收方: (合约创建)")
+		}
+		fmt.Fprintf(w, "   Nonce: %d\n", result.Nonce)
+		fmt.Fprintf(w, "   金额: %s ETH\n", result.ValueEth)
+		fmt.Fprintf(w, "   Gas上限: %d\n", result.Gas)
+		if result.GasFeeCap != "" {
+			fmt.Fprintf(w, "   最高小费: %s Gwei\n", amount.Format(tx.GasTipCap(), 9))
+			fmt.Fprintf(w, "   最高费用: %s Gwei\n", amount.Format(tx.GasFeeCap(), 9))
+		} else {
+			fmt.Fprintf(w, "   Gas价格: %s Gwei\n", amount.Format(tx.GasPrice(), 9))
+		}
+		fmt.Fprintf(w, "   数据: %d 字节\n", len(tx.Data()))
+	})
+}
+
+type receiptResult struct {
+	TxHash            string `json:"transactionHash"`
+	Status            uint64 `json:"status"`
+	BlockNumber       uint64 `json:"blockNumber"`
+	BlockHash         string `json:"blockHash"`
+	GasUsed           uint64 `json:"gasUsed"`
+	EffectiveGasPrice string `json:"effectiveGasPrice,omitempty"`
+	ContractAddress   string `json:"contractAddress,omitempty"`
+	Logs              int    `json:"logs"`
+	RevertReason      string `json:"revertReason,omitempty"`
+}
+
+// receiptGet 查询交易收据：ethcli receipt get <hash>
+func receiptGet(env *Env, args []string) error {
+	fs := env.flags("receipt get")
+	var hash hashValue
+	fs.Var(&hash, "hash", "交易哈希")
+	txHash, err := hashArg(env, fs, args, &hash)
+	if err != nil {
+		return err
+	}
+
+	client, err := env.Client()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	receipt, err := client.TransactionReceipt(ctx, txHash)
+	if err != nil {
+		return fmt.Errorf("查询交易收据失败: %v", err)
+	}
+
+	result := receiptResult{
+		TxHash:      receipt.TxHash.Hex(),
+		Status:      receipt.Status,
+		BlockNumber: receipt.BlockNumber.Uint64(),
+		BlockHash:   receipt.BlockHash.Hex(),
+		GasUsed:     receipt.GasUsed,
+		Logs:        len(receipt.Logs),
+	}
+	if receipt.EffectiveGasPrice != nil {
+		result.EffectiveGasPrice = receipt.EffectiveGasPrice.String()
+	}
+	if receipt.ContractAddress != (common.Address{}) {
+		result.ContractAddress = receipt.ContractAddress.Hex()
+	}
+	if receipt.Status == types.ReceiptStatusFailed {
+		// 在原区块重放交易以获取回滚原因
+		if revertErr, err := revert_decoder.Default().Replay(ctx, client, txHash); err == nil {
+			result.RevertReason = revertErr.Error()
+		}
+	}
+
+	return env.Emit(result, func(w io.Writer) {
+		status := "✅ 成功"
+		if result.Status == types.ReceiptStatusFailed {
+			status = "❌ 失败"
+		}
+		fmt.Fprintf(w, "收据 %s\n", result.TxHash)
+		fmt.Fprintf(w, "   状态: %s\n", status)
+		if result.RevertReason != "" {
+			fmt.Fprintf(w, "   回滚原因: %s\n", result.RevertReason)
+		}
+		fmt.Fprintf(w, "   区块: #%d (%s)\n", result.BlockNumber, result.BlockHash)
+		fmt.Fprintf(w, "   Gas使用: %d\n", result.GasUsed)
+		if receipt.EffectiveGasPrice != nil {
+			fmt.Fprintf(w, "   实际Gas价格: %s Gwei\n", amount.Format(receipt.EffectiveGasPrice, 9))
+		}
+		if result.ContractAddress != "" {
+			fmt.Fprintf(w, "   合约地址: %s\n", result.ContractAddress)
+		}
+		fmt.Fprintf(w, "   日志数: %d\n", result.Logs)
+	})
+}
+
+// hashArg 解析交易哈希：可以用 --hash 或第一个位置参数给出
+func hashArg(env *Env, fs *flag.FlagSet, args []string, hash *hashValue) (common.Hash, error) {
+	positional, err := env.parse(fs, args)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if len(positional) > 0 {
+		if err := hash.Set(positional[0]); err != nil {
+			return common.Hash{}, err
+		}
+	}
+	if hash.Hash == (common.Hash{}) {
+		fmt.Fprintln(env.Stderr, "缺少交易哈希")
+		fs.Usage()
+		return common.Hash{}, errUsage
+	}
+	return hash.Hash, nil
+}
@@ -0,0 +1,204 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"ethclient_tutorial/amount"
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/contract_deployment"
+	"ethclient_tutorial/eth_transfer"
+	"ethclient_tutorial/signer"
+	"ethclient_tutorial/tx_replacement"
+	"ethclient_tutorial/utils"
+)
+
+func init() {
+	register("send eth", "发送ETH", sendEth)
+	register("contract deploy", "部署MyToken合约", contractDeploy)
+}
+
+// waitFlags 发送交易后的等待参数
+type waitFlags struct {
+	noWait        bool
+	confirmations uint64
+	timeout       time.Duration
+}
+
+func (f *waitFlags) bind(fs *flag.FlagSet) {
+	fs.BoolVar(&f.noWait, "no-wait", false, "发送后立即返回，不等待确认")
+	fs.Uint64Var(&f.confirmations, "confirmations", 1, "等待的确认数")
+	fs.DurationVar(&f.timeout, "timeout", 3*time.Minute, "等待确认的超时时间")
+}
+
+// bindSteps 注册依次发送多笔交易时的等待参数（后一笔依赖前一笔确认，不支持 --no-wait）
+func (f *waitFlags) bindSteps(fs *flag.FlagSet) {
+	fs.Uint64Var(&f.confirmations, "confirmations", 1, "每笔交易等待的确认数")
+	fs.DurationVar(&f.timeout, "timeout", 3*time.Minute, "每笔交易等待确认的超时时间")
+}
+
+// waitOptions 等待参数，打印等待进度，交易长时间未被打包时按配置自动加价（s 为nil时不加价）
+func (e *Env) waitOptions(f waitFlags, s signer.Signer) utils.WaitOptions {
+	opts := utils.WaitOptions{Confirmations: f.confirmations, Timeout: f.timeout}
+	opts.Progress = func(message string) { fmt.Fprintln(e.progress(), message) }
+	if s != nil {
+		opts.AutoBump = tx_replacement.AutoBumpFromConfig(e.Config(), s)
+	}
+	return opts
+}
+
+// txOutcome 交易发送结果
+type txOutcome struct {
+	TxHash      string `json:"transactionHash"`
+	Outcome     string `json:"outcome,omitempty"`
+	BlockNumber uint64 `json:"blockNumber,omitempty"`
+	GasUsed     uint64 `json:"gasUsed,omitempty"`
+}
+
+// print 打印发送结果
+func (o txOutcome) print(w io.Writer) {
+	fmt.Fprintf(w, "交易哈希: %s\n", o.TxHash)
+	if o.Outcome != "" {
+		fmt.Fprintf(w, "   结果: %s\n", o.Outcome)
+		fmt.Fprintf(w, "   区块: #%d\n", o.BlockNumber)
+		fmt.Fprintf(w, "   Gas使用: %d\n", o.GasUsed)
+	}
+}
+
+// wait 按参数等待交易确认，交易长时间未被打包时按配置自动加价（s 为nil时不加价）
+// 交易执行失败、被替换、被丢弃或超时都返回错误
+func (e *Env) wait(client backend.Client, txHash common.Hash, s signer.Signer, f waitFlags) (txOutcome, error) {
+	outcome := txOutcome{TxHash: txHash.Hex()}
+	if f.noWait {
+		return outcome, nil
+	}
+
+	status, err := utils.Wait(context.Background(), client, txHash, e.waitOptions(f, s))
+	if status != nil {
+		outcome.TxHash = status.TxHash.Hex()
+		outcome.Outcome = status.Outcome.String()
+		outcome.BlockNumber = status.BlockNumber
+		outcome.GasUsed = status.GasUsed
+	}
+	if err != nil {
+		return outcome, fmt.Errorf("等待交易确认失败: %w", err)
+	}
+	if !status.Success {
+		return outcome, fmt.Errorf("交易执行失败: %s", outcome.TxHash)
+	}
+	return outcome, nil
+}
+
+type sendEthResult struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Amount string `json:"amount"`
+	txOutcome
+}
+
+// sendEth 发送ETH：ethcli send eth --to ADDR --amount 0.1
+func sendEth(env *Env, args []string) error {
+	fs := env.flags("send eth")
+	var to addressValue
+	var value amount.Amount
+	var wf waitFlags
+	fs.Var(&to, "to", "接收地址")
+	fs.Var(&value, "amount", "金额（默认单位ETH，可带单位如 \"2.5 gwei\"）")
+	wf.bind(fs)
+	if _, err := env.parse(fs, args); err != nil {
+		return err
+	}
+	if err := env.require(fs, "to", "amount"); err != nil {
+		return err
+	}
+
+	client, err := env.Client()
+	if err != nil {
+		return err
+	}
+	s, err := env.Signer()
+	if err != nil {
+		return err
+	}
+	from := s.Address()
+
+	summary := fmt.Sprintf("从 %s 向 %s 转账 %s ETH", from.Hex(), to.Hex(), value)
+	if err := env.ConfirmSend(context.Background(), client, summary); err != nil {
+		return err
+	}
+
+	txHash, err := eth_transfer.TransferETHWithSigner(client, s, to.Address, value, env.Config())
+	if err != nil {
+		return fmt.Errorf("ETH转账失败: %v", err)
+	}
+	outcome, err := env.wait(client, txHash, s, wf)
+	if err != nil {
+		return err
+	}
+
+	result := sendEthResult{From: from.Hex(), To: to.Hex(), Amount: value.String(), txOutcome: outcome}
+	return env.Emit(result, func(w io.Writer) {
+		fmt.Fprintf(w, "✅ 已从 %s 向 %s 转账 %s ETH\n", result.From, result.To, result.Amount)
+		outcome.print(w)
+	})
+}
+
+type deployResult struct {
+	ContractAddress string `json:"contractAddress"`
+	TxHash          string `json:"transactionHash"`
+	Owner           string `json:"owner"`
+	Recipient       string `json:"recipient"`
+}
+
+// contractDeploy 部署MyToken合约：ethcli contract deploy [--recipient ADDR]
+// 部署者成为合约owner，初始代币铸造给 recipient（默认为部署者）
+func contractDeploy(env *Env, args []string) error {
+	fs := env.flags("contract deploy")
+	var recipient addressValue
+	fs.Var(&recipient, "recipient", "初始代币接收地址（默认为部署者）")
+	if _, err := env.parse(fs, args); err != nil {
+		return err
+	}
+
+	client, err := env.Client()
+	if err != nil {
+		return err
+	}
+	s, err := env.Signer()
+	if err != nil {
+		return err
+	}
+	owner := s.Address()
+	if recipient.Address == (common.Address{}) {
+		recipient.Address = owner
+	}
+
+	summary := fmt.Sprintf("由 %s 部署MyToken合约，初始代币接收者 %s", owner.Hex(), recipient.Hex())
+	if err := env.ConfirmSend(context.Background(), client, summary); err != nil {
+		return err
+	}
+
+	// DeployContract 内部会等待部署确认
+	contractAddress, txHash, err := contract_deployment.DeployContractWithSigner(client, s, recipient.Address)
+	if err != nil {
+		return err
+	}
+
+	result := deployResult{
+		ContractAddress: contractAddress.Hex(),
+		TxHash:          txHash.Hex(),
+		Owner:           owner.Hex(),
+		Recipient:       recipient.Hex(),
+	}
+	return env.Emit(result, func(w io.Writer) {
+		fmt.Fprintf(w, "✅ 合约已部署: %s\n", result.ContractAddress)
+		fmt.Fprintf(w, "   交易哈希: %s\n", result.TxHash)
+		fmt.Fprintf(w, "   Owner: %s\n", result.Owner)
+		fmt.Fprintln(w, "📝 可将合约地址写入配置中的 CONTRACT_ADDRESS")
+	})
+}
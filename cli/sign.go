@@ -0,0 +1,297 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+
+	"ethclient_tutorial/message_signing"
+)
+
+func init() {
+	register("sign message", "按 personal_sign（EIP-191）签名消息", signMessage)
+	register("sign typed", "按EIP-712签名结构化数据（JSON）", signTyped)
+	register("verify message", "验证 personal_sign 签名并恢复签名者", verifyMessage)
+	register("verify typed", "验证EIP-712签名并恢复签名者", verifyTyped)
+}
+
+// messageFlags 消息来源参数：--message 文本、--hex 十六进制数据或 --file 文件内容
+type messageFlags struct {
+	text string
+	hex  string
+	file string
+}
+
+func (f *messageFlags) bind(fs *flag.FlagSet) {
+	fs.StringVar(&f.text, "message", "", "消息文本")
+	fs.StringVar(&f.hex, "hex", "", "十六进制消息数据")
+	fs.StringVar(&f.file, "file", "", "消息文件")
+}
+
+// read 读取消息内容，三种来源必须且只能指定一种
+func (f *messageFlags) read() ([]byte, error) {
+	set := 0
+	for _, v := range []string{f.text, f.hex, f.file} {
+		if v != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, fmt.Errorf("请指定 --message、--hex 或 --file 其中之一")
+	}
+	switch {
+	case f.hex != "":
+		message, err := hexutil.Decode(f.hex)
+		if err != nil {
+			return nil, fmt.Errorf("无效的十六进制消息: %v", err)
+		}
+		return message, nil
+	case f.file != "":
+		message, err := os.ReadFile(f.file)
+		if err != nil {
+			return nil, fmt.Errorf("读取消息文件失败: %v", err)
+		}
+		return message, nil
+	default:
+		return []byte(f.text), nil
+	}
+}
+
+// typedDataFlags EIP-712数据参数
+type typedDataFlags struct {
+	file     string
+	contract addressValue
+}
+
+func (f *typedDataFlags) bind(fs *flag.FlagSet) {
+	fs.StringVar(&f.file, "file", "", "EIP-712数据文件（eth_signTypedData_v4 格式的JSON）")
+	fs.Var(&f.contract, "contract", "从该合约的 eip712Domain() 获取签名域")
+}
+
+// loadTypedData 读取EIP-712数据
+// 指定 --contract，或数据中的 domain 只有 verifyingContract 时，从合约的 eip712Domain() 获取签名域
+func (e *Env) loadTypedData(f *typedDataFlags) (apitypes.TypedData, error) {
+	content, err := os.ReadFile(f.file)
+	if err != nil {
+		return apitypes.TypedData{}, fmt.Errorf("读取EIP-712数据文件失败: %v", err)
+	}
+	data, err := message_signing.ParseTypedData(content)
+	if err != nil {
+		return data, err
+	}
+
+	contract := f.contract.Address
+	if contract == (common.Address{}) && onlyVerifyingContract(data.Domain) {
+		if !common.IsHexAddress(data.Domain.VerifyingContract) {
+			return data, fmt.Errorf("无效的 verifyingContract: %s", data.Domain.VerifyingContract)
+		}
+		contract = common.HexToAddress(data.Domain.VerifyingContract)
+	}
+	if contract == (common.Address{}) {
+		return data, nil
+	}
+
+	client, err := e.Client()
+	if err != nil {
+		return data, err
+	}
+	domain, err := message_signing.FetchDomain(context.Background(), client, contract)
+	if err != nil {
+		return data, err
+	}
+	domain.Apply(&data)
+	e.logf("✓ 已从合约 %s 获取签名域: %s v%s", contract.Hex(), data.Domain.Name, data.Domain.Version)
+	return data, nil
+}
+
+// onlyVerifyingContract 签名域只给出了合约地址
+func onlyVerifyingContract(domain apitypes.TypedDataDomain) bool {
+	return domain.VerifyingContract != "" && domain.Name == "" && domain.Version == "" && domain.ChainId == nil && domain.Salt == ""
+}
+
+type signResult struct {
+	Signer    string `json:"signer"`
+	Hash      string `json:"hash"`
+	Signature string `json:"signature"`
+}
+
+func (r signResult) print(w io.Writer) {
+	fmt.Fprintf(w, "✅ 签名者: %s\n", r.Signer)
+	fmt.Fprintf(w, "   哈希: %s\n", r.Hash)
+	fmt.Fprintf(w, "   签名: %s\n", r.Signature)
+}
+
+// signMessage 签名消息：ethcli sign message --message "hello"
+func signMessage(env *Env, args []string) error {
+	fs := env.flags("sign message")
+	var mf messageFlags
+	mf.bind(fs)
+	if _, err := env.parse(fs, args); err != nil {
+		return err
+	}
+	message, err := mf.read()
+	if err != nil {
+		return err
+	}
+
+	s, err := env.Signer()
+	if err != nil {
+		return err
+	}
+	signature, err := message_signing.SignMessage(context.Background(), s, message)
+	if err != nil {
+		return err
+	}
+
+	result := signResult{
+		Signer:    s.Address().Hex(),
+		Hash:      hexutil.Encode(message_signing.HashMessage(message)),
+		Signature: hexutil.Encode(signature),
+	}
+	return env.Emit(result, result.print)
+}
+
+// signTyped 签名EIP-712数据：ethcli sign typed --file permit.json [--contract ADDR]
+func signTyped(env *Env, args []string) error {
+	fs := env.flags("sign typed")
+	var tf typedDataFlags
+	tf.bind(fs)
+	if _, err := env.parse(fs, args); err != nil {
+		return err
+	}
+	if err := env.require(fs, "file"); err != nil {
+		return err
+	}
+	data, err := env.loadTypedData(&tf)
+	if err != nil {
+		return err
+	}
+	hash, err := message_signing.HashTypedData(data)
+	if err != nil {
+		return err
+	}
+
+	s, err := env.Signer()
+	if err != nil {
+		return err
+	}
+	signature, err := message_signing.SignTypedData(context.Background(), s, data)
+	if err != nil {
+		return err
+	}
+
+	result := signResult{
+		Signer:    s.Address().Hex(),
+		Hash:      hexutil.Encode(hash),
+		Signature: hexutil.Encode(signature),
+	}
+	return env.Emit(result, result.print)
+}
+
+type verifyResult struct {
+	Signer   string `json:"signer"`
+	Hash     string `json:"hash"`
+	Expected string `json:"expected,omitempty"`
+	Valid    bool   `json:"valid"`
+}
+
+// verifyFlags 验证参数
+type verifyFlags struct {
+	signature string
+	expected  addressValue
+}
+
+func (f *verifyFlags) bind(fs *flag.FlagSet) {
+	fs.StringVar(&f.signature, "signature", "", "65字节签名（十六进制）")
+	fs.Var(&f.expected, "address", "期望的签名者地址（不一致时返回错误）")
+}
+
+// emitVerify 输出恢复出的签名者，指定了 --address 且不一致时返回错误
+func (e *Env) emitVerify(f *verifyFlags, hash []byte, recovered common.Address) error {
+	result := verifyResult{Signer: recovered.Hex(), Hash: hexutil.Encode(hash), Valid: true}
+	var mismatch error
+	if f.expected.Address != (common.Address{}) {
+		result.Expected = f.expected.Hex()
+		mismatch = message_signing.CheckSigner(recovered, f.expected.Address)
+		result.Valid = mismatch == nil
+	}
+	if err := e.Emit(result, func(w io.Writer) {
+		if result.Valid {
+			fmt.Fprintf(w, "✅ 签名者: %s\n", result.Signer)
+		} else {
+			fmt.Fprintf(w, "❌ 签名者: %s（期望 %s）\n", result.Signer, result.Expected)
+		}
+		fmt.Fprintf(w, "   哈希: %s\n", result.Hash)
+	}); err != nil {
+		return err
+	}
+	return mismatch
+}
+
+// verifyMessage 验证消息签名：ethcli verify message --message "hello" --signature 0x... [--address ADDR]
+func verifyMessage(env *Env, args []string) error {
+	fs := env.flags("verify message")
+	var mf messageFlags
+	var vf verifyFlags
+	mf.bind(fs)
+	vf.bind(fs)
+	if _, err := env.parse(fs, args); err != nil {
+		return err
+	}
+	if err := env.require(fs, "signature"); err != nil {
+		return err
+	}
+	message, err := mf.read()
+	if err != nil {
+		return err
+	}
+	signature, err := hexutil.Decode(vf.signature)
+	if err != nil {
+		return fmt.Errorf("无效的签名: %v", err)
+	}
+
+	recovered, err := message_signing.VerifyMessage(message, signature)
+	if err != nil {
+		return err
+	}
+	return env.emitVerify(&vf, message_signing.HashMessage(message), recovered)
+}
+
+// verifyTyped 验证EIP-712签名：ethcli verify typed --file permit.json --signature 0x... [--address ADDR]
+func verifyTyped(env *Env, args []string) error {
+	fs := env.flags("verify typed")
+	var tf typedDataFlags
+	var vf verifyFlags
+	tf.bind(fs)
+	vf.bind(fs)
+	if _, err := env.parse(fs, args); err != nil {
+		return err
+	}
+	if err := env.require(fs, "file", "signature"); err != nil {
+		return err
+	}
+	data, err := env.loadTypedData(&tf)
+	if err != nil {
+		return err
+	}
+	signature, err := hexutil.Decode(vf.signature)
+	if err != nil {
+		return fmt.Errorf("无效的签名: %v", err)
+	}
+
+	hash, err := message_signing.HashTypedData(data)
+	if err != nil {
+		return err
+	}
+	recovered, err := message_signing.VerifyTypedData(data, signature)
+	if err != nil {
+		return err
+	}
+	return env.emitVerify(&vf, hash, recovered)
+}
@@ -0,0 +1,318 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"ethclient_tutorial/amount"
+	"ethclient_tutorial/erc20"
+	"ethclient_tutorial/fee_strategy"
+)
+
+func init() {
+	register("token info", "查询代币名称、符号、精度和总供应量", tokenInfo)
+	register("token balance", "查询代币余额", tokenBalance)
+	register("token transfer", "转账代币", tokenTransfer)
+	register("token approve", "授权代币额度（已有非0额度时请使用 allowance set）", tokenApprove)
+}
+
+// bindToken 注册 --token 参数
+func bindToken(fs *flag.FlagSet) *addressValue {
+	var token addressValue
+	fs.Var(&token, "token", "代币合约地址（默认 CONTRACT_ADDRESS）")
+	return &token
+}
+
+// token 创建代币客户端，未指定 --token 时使用配置中的 CONTRACT_ADDRESS
+func (e *Env) token(address *addressValue) (*erc20.Token, error) {
+	if address.Address == (common.Address{}) {
+		if err := address.Set(e.Config().ContractAddress); err != nil {
+			return nil, fmt.Errorf("缺少参数 --token（配置中的 CONTRACT_ADDRESS 无效）")
+		}
+	}
+	client, err := e.Client()
+	if err != nil {
+		return nil, err
+	}
+	token := erc20.New(client, address.Address)
+	strategy, err := fee_strategy.FromConfig(e.Config())
+	if err != nil {
+		return nil, fmt.Errorf("无效的费用策略: %v", err)
+	}
+	token.Strategy = strategy
+	return token, nil
+}
+
+type tokenInfoResult struct {
+	Address     string `json:"address"`
+	ChainID     string `json:"chainId"`
+	Name        string `json:"name"`
+	Symbol      string `json:"symbol"`
+	Decimals    uint8  `json:"decimals"`
+	HasDecimals bool   `json:"hasDecimals"`
+	TotalSupply string `json:"totalSupply"`
+}
+
+// tokenInfo 查询代币元数据：ethcli token info --token ADDR
+func tokenInfo(env *Env, args []string) error {
+	fs := env.flags("token info")
+	address := bindToken(fs)
+	if _, err := env.parse(fs, args); err != nil {
+		return err
+	}
+	token, err := env.token(address)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	meta, err := token.Metadata(ctx)
+	if err != nil {
+		return err
+	}
+	totalSupply, err := token.TotalSupply(ctx)
+	if err != nil {
+		return err
+	}
+
+	result := tokenInfoResult{
+		Address:     meta.Address.Hex(),
+		ChainID:     meta.ChainID.String(),
+		Name:        meta.Name,
+		Symbol:      meta.Symbol,
+		Decimals:    meta.Decimals,
+		HasDecimals: meta.HasDecimals,
+		TotalSupply: amount.Format(totalSupply, int(meta.Decimals)),
+	}
+	return env.Emit(result, func(w io.Writer) {
+		fmt.Fprintf(w, "代币 %s\n", result.Address)
+		fmt.Fprintf(w, "   名称: %s\n", result.Name)
+		fmt.Fprintf(w, "   符号: %s\n", result.Symbol)
+		if result.HasDecimals {
+			fmt.Fprintf(w, "   精度: %d\n", result.Decimals)
+		} else {
+			fmt.Fprintln(w, "   精度: 未实现decimals()，按最小单位显示")
+		}
+		fmt.Fprintf(w, "   总供应量: %s %s\n", result.TotalSupply, result.Symbol)
+	})
+}
+
+type tokenBalanceResult struct {
+	Token   string `json:"token"`
+	Holder  string `json:"holder"`
+	Balance string `json:"balance"`
+	Raw     string `json:"raw"`
+	Symbol  string `json:"symbol"`
+}
+
+// tokenBalance 查询余额：ethcli token balance --token ADDR [--address HOLDER]
+// 未指定 --address 时查询发送账户的地址
+func tokenBalance(env *Env, args []string) error {
+	fs := env.flags("token balance")
+	address := bindToken(fs)
+	var holder addressValue
+	fs.Var(&holder, "address", "持有者地址（默认为发送账户）")
+	positional, err := env.parse(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		if err := holder.Set(positional[0]); err != nil {
+			return err
+		}
+	}
+	if holder.Address == (common.Address{}) {
+		sender, err := env.SenderAddress()
+		if err != nil {
+			return fmt.Errorf("缺少参数 --address: %v", err)
+		}
+		holder.Address = sender
+	}
+
+	token, err := env.token(address)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	meta, err := token.Metadata(ctx)
+	if err != nil {
+		return err
+	}
+	balance, err := token.BalanceOf(ctx, holder.Address)
+	if err != nil {
+		return err
+	}
+
+	result := tokenBalanceResult{
+		Token:   meta.Address.Hex(),
+		Holder:  holder.Hex(),
+		Balance: amount.Format(balance, int(meta.Decimals)),
+		Raw:     balance.String(),
+		Symbol:  meta.Symbol,
+	}
+	return env.Emit(result, func(w io.Writer) {
+		fmt.Fprintf(w, "%s 的余额: %s %s\n", result.Holder, result.Balance, result.Symbol)
+	})
+}
+
+type tokenSendResult struct {
+	Token  string `json:"token"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Amount string `json:"amount"`
+	Symbol string `json:"symbol"`
+	Called bool   `json:"andCall"` // 是否使用了ERC-1363 transferAndCall/approveAndCall
+	txOutcome
+}
+
+// tokenTransfer 转账：ethcli token transfer --token ADDR --to ADDR --amount 1.5 [--and-call [--data 0x...]]
+func tokenTransfer(env *Env, args []string) error {
+	return tokenSend(env, "transfer", "to", "接收地址", args)
+}
+
+// tokenApprove 授权：ethcli token approve --token ADDR --spender ADDR --amount 100 [--and-call [--data 0x...]]
+// 当前额度不为0时只允许归零，非0改为非0需使用 allowance set（先归零再授权）
+func tokenApprove(env *Env, args []string) error {
+	return tokenSend(env, "approve", "spender", "被授权地址", args)
+}
+
+// tokenSend transfer 和 approve 的公共流程：按代币精度换算数量、主网确认、发送并等待
+// --and-call 时，代币和目标合约都支持ERC-1363则使用 transferAndCall/approveAndCall 回调目标，否则退回普通调用
+func tokenSend(env *Env, method, target, targetUsage string, args []string) error {
+	fs := env.flags("token " + method)
+	address := bindToken(fs)
+	var to addressValue
+	var value amount.Amount
+	var wf waitFlags
+	fs.Var(&to, target, targetUsage)
+	fs.Var(&value, "amount", "代币数量（以整个代币为单位）")
+	andCall := fs.Bool("and-call", false, "目标支持ERC-1363时回调目标合约")
+	dataHex := fs.String("data", "", "回调时附带的数据（十六进制，需要 --and-call）")
+	wf.bind(fs)
+	if _, err := env.parse(fs, args); err != nil {
+		return err
+	}
+	if err := env.require(fs, target, "amount"); err != nil {
+		return err
+	}
+	var data []byte
+	if *dataHex != "" {
+		if !*andCall {
+			return fmt.Errorf("--data 需要与 --and-call 一起使用")
+		}
+		decoded, err := hexutil.Decode(*dataHex)
+		if err != nil {
+			return fmt.Errorf("无效的 --data: %v", err)
+		}
+		data = decoded
+	}
+
+	token, err := env.token(address)
+	if err != nil {
+		return err
+	}
+	s, err := env.Signer()
+	if err != nil {
+		return err
+	}
+	from := s.Address()
+
+	ctx := context.Background()
+	meta, err := token.Metadata(ctx)
+	if err != nil {
+		return err
+	}
+	baseUnits, err := value.ToBaseUnits(int(meta.Decimals))
+	if err != nil {
+		return err
+	}
+
+	// 直接把非0额度改为另一个非0值时，spender可以在两次授权之间先用掉旧额度（approve竞争）
+	if method == "approve" && baseUnits.Sign() != 0 {
+		current, err := token.Allowance(ctx, from, to.Address)
+		if err != nil {
+			return err
+		}
+		if current.Sign() != 0 {
+			return fmt.Errorf("%s 当前额度为 %s %s，直接改为非0值存在approve竞争风险，请使用 ethcli allowance set（先归零再授权）", to.Hex(), amount.Format(current, int(meta.Decimals)), meta.Symbol)
+		}
+	}
+
+	client, err := env.Client()
+	if err != nil {
+		return err
+	}
+	summary := fmt.Sprintf("%s %s %s -> %s（代币 %s，发送方 %s）", method, value, meta.Symbol, to.Hex(), meta.Address.Hex(), from.Hex())
+	if err := env.ConfirmSend(ctx, client, summary); err != nil {
+		return err
+	}
+
+	var tx *types.Transaction
+	var called bool
+	switch {
+	case method == "transfer" && *andCall:
+		tx, called, err = token.TransferOrCall(ctx, s, to.Address, baseUnits, data)
+	case method == "transfer":
+		tx, err = token.TransferWithSigner(ctx, s, to.Address, baseUnits)
+	case *andCall:
+		tx, called, err = token.ApproveOrCall(ctx, s, to.Address, baseUnits, data)
+	default:
+		tx, err = token.ApproveWithSigner(ctx, s, to.Address, baseUnits)
+	}
+	if called {
+		method += "AndCall"
+	}
+	if err != nil {
+		return fmt.Errorf("发送%s交易失败: %w", method, err)
+	}
+	if *andCall && !called {
+		warnNoCallback(ctx, env.progress(), token, to.Address, method)
+	}
+	env.logf("✓ %s交易已发送: %s", method, tx.Hash().Hex())
+
+	outcome, err := env.wait(client, tx.Hash(), s, wf)
+	if err != nil {
+		return err
+	}
+
+	result := tokenSendResult{
+		Token:     meta.Address.Hex(),
+		From:      from.Hex(),
+		To:        to.Hex(),
+		Amount:    value.String(),
+		Symbol:    meta.Symbol,
+		Called:    called,
+		txOutcome: outcome,
+	}
+	return env.Emit(result, func(w io.Writer) {
+		if strings.HasPrefix(method, "transfer") {
+			fmt.Fprintf(w, "✅ 已向 %s 转账 %s %s\n", result.To, result.Amount, result.Symbol)
+		} else {
+			fmt.Fprintf(w, "✅ 已授权 %s 使用 %s %s\n", result.To, result.Amount, result.Symbol)
+		}
+		if result.Called {
+			fmt.Fprintf(w, "   已通过ERC-1363回调 %s\n", result.To)
+		}
+		outcome.print(w)
+	})
+}
+
+// warnNoCallback 说明 --and-call 退回普通调用的原因：代币未实现ERC-1363，或目标未声明回调接口
+func warnNoCallback(ctx context.Context, w io.Writer, token *erc20.Token, target common.Address, method string) {
+	supported, err := token.SupportsERC1363(ctx)
+	switch {
+	case err != nil:
+		fmt.Fprintf(w, "⚠️ 无法确认代币 %s 是否支持ERC-1363（%v），已使用普通%s\n", token.Address.Hex(), err, method)
+	case !supported:
+		fmt.Fprintf(w, "⚠️ 代币 %s 未实现ERC-1363，已使用普通%s\n", token.Address.Hex(), method)
+	default:
+		fmt.Fprintf(w, "⚠️ %s 未声明支持ERC-1363回调，已使用普通%s\n", target.Hex(), method)
+	}
+}
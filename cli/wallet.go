@@ -0,0 +1,347 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"ethclient_tutorial/amount"
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/wallet_management"
+)
+
+func init() {
+	register("wallet new", "在keystore中创建新账户（--plaintext 只打印明文私钥）", walletNew)
+	register("wallet list", "列出keystore中的账户", walletList)
+	register("wallet import", "导入私钥文件或keystore JSON文件", walletImport)
+	register("wallet export", "导出账户的keystore JSON", walletExport)
+	register("wallet passwd", "修改账户口令", walletPasswd)
+	register("wallet mnemonic", "生成BIP-39助记词", walletMnemonic)
+	register("wallet derive", "列出HD钱包派生的账户及余额", walletDerive)
+}
+
+type walletResult struct {
+	Address    string `json:"address"`
+	File       string `json:"file,omitempty"`
+	PrivateKey string `json:"privateKey,omitempty"`
+}
+
+func (r walletResult) print(w io.Writer) {
+	fmt.Fprintf(w, "地址: %s\n", r.Address)
+	if r.File != "" {
+		fmt.Fprintf(w, "keystore文件: %s\n", r.File)
+	}
+	if r.PrivateKey != "" {
+		fmt.Fprintf(w, "私钥: %s\n", r.PrivateKey)
+		fmt.Fprintln(w, "⚠️ 请妥善保管私钥，不要泄露给任何人")
+	}
+}
+
+// walletNew 创建账户：默认生成keystore V3加密文件，--plaintext 时只打印明文私钥（不保存）
+func walletNew(env *Env, args []string) error {
+	fs := env.flags("wallet new")
+	plaintext := fs.Bool("plaintext", false, "只生成并打印明文私钥，不写入keystore")
+	if _, err := env.parse(fs, args); err != nil {
+		return err
+	}
+
+	if *plaintext {
+		account, privateKeyHex := wallet_management.CreateNewWallet()
+		result := walletResult{Address: account.Address.Hex(), PrivateKey: privateKeyHex}
+		return env.Emit(result, result.print)
+	}
+
+	passphrase, err := env.passphrases().ReadNew("请设置新账户的口令: ")
+	if err != nil {
+		return err
+	}
+	account, err := env.Keystore().Create(passphrase)
+	if err != nil {
+		return err
+	}
+	result := walletResult{Address: account.Address.Hex(), File: account.URL.Path}
+	return env.Emit(result, func(w io.Writer) {
+		result.print(w)
+		fmt.Fprintln(w, "⚠️ 请牢记口令，丢失口令将无法恢复账户")
+	})
+}
+
+// walletList 列出keystore中的账户
+func walletList(env *Env, args []string) error {
+	fs := env.flags("wallet list")
+	if _, err := env.parse(fs, args); err != nil {
+		return err
+	}
+
+	ks := env.Keystore()
+	result := make([]walletResult, 0)
+	for _, account := range ks.List() {
+		result = append(result, walletResult{Address: account.Address.Hex(), File: account.URL.Path})
+	}
+	return env.Emit(result, func(w io.Writer) {
+		if len(result) == 0 {
+			fmt.Fprintf(w, "keystore %s 中没有账户\n", ks.Dir)
+			return
+		}
+		for i, account := range result {
+			fmt.Fprintf(w, "#%d %s  %s\n", i, account.Address, account.File)
+		}
+	})
+}
+
+// walletImport 导入账户：--key-file 为包含十六进制私钥的文件，--json-file 为keystore JSON文件
+// 私钥从文件读取，避免出现在命令行历史中。导入后的口令按 KEYSTORE_PASSWORD_FILE → KEYSTORE_PASSWORD → 提示 读取，
+// keystore JSON文件自身的口令（通常与本地keystore不同）从 --password-file 或提示读取
+func walletImport(env *Env, args []string) error {
+	fs := env.flags("wallet import")
+	keyFile := fs.String("key-file", "", "包含十六进制私钥的文件")
+	jsonFile := fs.String("json-file", "", "keystore JSON文件")
+	passwordFile := fs.String("password-file", "", "包含 --json-file 口令的文件")
+	if _, err := env.parse(fs, args); err != nil {
+		return err
+	}
+	if (*keyFile == "") == (*jsonFile == "") {
+		fmt.Fprintln(env.Stderr, "需要指定 --key-file 或 --json-file 之一")
+		fs.Usage()
+		return errUsage
+	}
+
+	ks := env.Keystore()
+	var result walletResult
+	if *keyFile != "" {
+		content, err := os.ReadFile(*keyFile)
+		if err != nil {
+			return fmt.Errorf("读取私钥文件失败: %v", err)
+		}
+		passphrase, err := env.passphrases().ReadNew("请设置keystore口令: ")
+		if err != nil {
+			return err
+		}
+		account, err := ks.ImportKey(strings.TrimSpace(string(content)), passphrase)
+		if err != nil {
+			return err
+		}
+		result = walletResult{Address: account.Address.Hex(), File: account.URL.Path}
+	} else {
+		keyJSON, err := os.ReadFile(*jsonFile)
+		if err != nil {
+			return fmt.Errorf("读取keystore文件失败: %v", err)
+		}
+		source := wallet_management.PassphraseSource{File: *passwordFile, In: env.Stdin, Out: env.Stderr}
+		passphrase, err := source.Read("请输入该文件的口令: ")
+		if err != nil {
+			return err
+		}
+		newPassphrase, err := env.passphrases().ReadNew("请设置导入后的口令: ")
+		if err != nil {
+			return err
+		}
+		account, err := ks.ImportJSON(keyJSON, passphrase, newPassphrase)
+		if err != nil {
+			return err
+		}
+		result = walletResult{Address: account.Address.Hex(), File: account.URL.Path}
+	}
+	return env.Emit(result, func(w io.Writer) {
+		fmt.Fprintln(w, "✅ 导入成功")
+		result.print(w)
+	})
+}
+
+// walletExport 导出账户的keystore JSON（用新口令重新加密），--out 为空时输出到stdout
+// 账户口令按 KEYSTORE_PASSWORD_FILE → KEYSTORE_PASSWORD → 提示 读取，导出文件的口令从 --new-password-file 或提示读取
+func walletExport(env *Env, args []string) error {
+	fs := env.flags("wallet export")
+	var address addressValue
+	fs.Var(&address, "address", "账户地址")
+	out := fs.String("out", "", "输出文件（默认输出到stdout）")
+	newPasswordFile := fs.String("new-password-file", "", "包含导出文件口令的文件")
+	if _, err := env.parse(fs, args); err != nil {
+		return err
+	}
+	if err := env.require(fs, "address"); err != nil {
+		return err
+	}
+
+	passphrase, err := env.passphrases().Read(fmt.Sprintf("请输入账户 %s 的口令: ", address.Hex()))
+	if err != nil {
+		return err
+	}
+	newSource := wallet_management.PassphraseSource{File: *newPasswordFile, In: env.Stdin, Out: env.Stderr}
+	newPassphrase, err := newSource.ReadNew("请设置导出文件的口令: ")
+	if err != nil {
+		return err
+	}
+	keyJSON, err := env.Keystore().Export(address.Address, passphrase, newPassphrase)
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		_, err := fmt.Fprintln(env.Stdout, string(keyJSON))
+		return err
+	}
+	if err := os.WriteFile(*out, keyJSON, 0600); err != nil {
+		return fmt.Errorf("写入文件失败: %v", err)
+	}
+	result := walletResult{Address: address.Hex(), File: *out}
+	return env.Emit(result, func(w io.Writer) {
+		fmt.Fprintln(w, "✅ 导出成功")
+		result.print(w)
+	})
+}
+
+// walletPasswd 修改账户口令
+// 原口令按 KEYSTORE_PASSWORD_FILE → KEYSTORE_PASSWORD → 提示 读取，新口令从 --new-password-file 或提示读取
+func walletPasswd(env *Env, args []string) error {
+	fs := env.flags("wallet passwd")
+	var address addressValue
+	fs.Var(&address, "address", "账户地址")
+	newPasswordFile := fs.String("new-password-file", "", "包含新口令的文件")
+	if _, err := env.parse(fs, args); err != nil {
+		return err
+	}
+	if err := env.require(fs, "address"); err != nil {
+		return err
+	}
+
+	passphrase, err := env.passphrases().Read(fmt.Sprintf("请输入账户 %s 的原口令: ", address.Hex()))
+	if err != nil {
+		return err
+	}
+	newSource := wallet_management.PassphraseSource{File: *newPasswordFile, In: env.Stdin, Out: env.Stderr}
+	newPassphrase, err := newSource.ReadNew("请输入新口令: ")
+	if err != nil {
+		return err
+	}
+	if err := env.Keystore().ChangePassphrase(address.Address, passphrase, newPassphrase); err != nil {
+		return err
+	}
+
+	result := walletResult{Address: address.Hex()}
+	return env.Emit(result, func(w io.Writer) {
+		fmt.Fprintf(w, "✅ 已修改账户 %s 的口令\n", result.Address)
+	})
+}
+
+type mnemonicResult struct {
+	Mnemonic string `json:"mnemonic,omitempty"`
+	File     string `json:"file,omitempty"`
+	Address  string `json:"address"`
+	Path     string `json:"path"`
+}
+
+// walletMnemonic 生成助记词：ethcli wallet mnemonic [--words 24] [--out FILE]
+// 指定 --out 时写入文件（权限600）而不打印助记词
+func walletMnemonic(env *Env, args []string) error {
+	fs := env.flags("wallet mnemonic")
+	wordCount := fs.Int("words", 12, "单词数（12、15、18、21 或 24）")
+	out := fs.String("out", "", "保存助记词的文件（可作为 MNEMONIC_FILE 使用）")
+	if _, err := env.parse(fs, args); err != nil {
+		return err
+	}
+
+	mnemonic, err := wallet_management.NewMnemonic(*wordCount)
+	if err != nil {
+		return err
+	}
+	base, err := env.hdPath()
+	if err != nil {
+		return err
+	}
+	wallet, err := wallet_management.NewHDWallet(mnemonic, env.Config().MnemonicPassphrase)
+	if err != nil {
+		return err
+	}
+	first, err := wallet.DeriveIndex(base, 0)
+	if err != nil {
+		return err
+	}
+
+	result := mnemonicResult{Address: first.Address.Hex(), Path: wallet_management.HDPathAt(base, 0).String()}
+	if *out != "" {
+		if err := os.WriteFile(*out, []byte(mnemonic+"\n"), 0600); err != nil {
+			return fmt.Errorf("写入文件失败: %v", err)
+		}
+		result.File = *out
+	} else {
+		result.Mnemonic = mnemonic
+	}
+	return env.Emit(result, func(w io.Writer) {
+		if result.File != "" {
+			fmt.Fprintf(w, "✅ 助记词已保存到 %s\n", result.File)
+		} else {
+			fmt.Fprintf(w, "助记词: %s\n", result.Mnemonic)
+		}
+		fmt.Fprintf(w, "第一个账户: %s (%s)\n", result.Address, result.Path)
+		fmt.Fprintln(w, "⚠️ 请离线抄写并妥善保管助记词，任何人得到它都可以控制全部派生账户")
+	})
+}
+
+type derivedResult struct {
+	Index   uint32 `json:"index"`
+	Path    string `json:"path"`
+	Address string `json:"address"`
+	Balance string `json:"balance,omitempty"`
+}
+
+// walletDerive 列出HD钱包派生的账户：ethcli wallet derive --mnemonic FILE [--count 5] [--start 0] [--path m/44'/60'/0'/0]
+func walletDerive(env *Env, args []string) error {
+	fs := env.flags("wallet derive")
+	count := fs.Uint("count", 5, "列出的账户数")
+	start := fs.Uint("start", 0, "起始索引")
+	path := fs.String("path", "", "基础派生路径（默认取 HD_PATH）")
+	noBalance := fs.Bool("no-balance", false, "不查询余额（离线使用）")
+	if _, err := env.parse(fs, args); err != nil {
+		return err
+	}
+
+	wallet, ok, err := env.HDWallet()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("未配置助记词: 请使用 --mnemonic 或设置 MNEMONIC_FILE")
+	}
+	base, err := env.hdPath()
+	if *path != "" {
+		base, err = wallet_management.ParseHDPath(*path)
+	}
+	if err != nil {
+		return err
+	}
+
+	var client backend.Client
+	if !*noBalance {
+		if client, err = env.Client(); err != nil {
+			return err
+		}
+	}
+	if *start >= 1<<31 || *count >= 1<<31 {
+		return fmt.Errorf("--start 和 --count 需小于2147483648")
+	}
+	derived, err := wallet.List(context.Background(), client, base, uint32(*start), uint32(*count))
+	if err != nil {
+		return err
+	}
+
+	result := make([]derivedResult, 0, len(derived))
+	for _, account := range derived {
+		item := derivedResult{Index: account.Index, Path: account.Path.String(), Address: account.Address.Hex()}
+		if account.Balance != nil {
+			item.Balance = amount.Format(account.Balance, 18)
+		}
+		result = append(result, item)
+	}
+	return env.Emit(result, func(w io.Writer) {
+		for _, account := range result {
+			fmt.Fprintf(w, "#%d %s  %s", account.Index, account.Address, account.Path)
+			if account.Balance != "" {
+				fmt.Fprintf(w, "  %s ETH", account.Balance)
+			}
+			fmt.Fprintln(w)
+		}
+	})
+}
@@ -15,22 +15,52 @@ type Config struct {
 	EthereumNetwork      string
 	EthereumWSURL        string
 	EthereumHTTPURL      string
+	RPCEndpoints         []string
 	TestPrivateKey       string
+	AllowPlaintextKey    bool
+	KeystoreDir          string
+	KeystoreAccount      string
+	KeystorePasswordFile string
+	MnemonicFile         string
+	MnemonicPassphrase   string
+	HDPath               string
+	HDIndex              uint64
+	SignerURL            string
 	TestSendAddress      string
 	TestRecipientAddress string
 	ContractAddress      string
 	ContractABIPath      string
+	KnownMultisigs       []string
 	DefaultGasLimit      uint64
 	GasPriceMultiplier   float64
+	FeeStrategy          string
+	FeeFixedTipGwei      float64
+	FeeFixedCapGwei      float64
+	FeeMaxFeeGwei        float64
+	FeeMaxCostEther      float64
+	FeeBumpIntervalSec   uint64
+	FeeBumpPercent       float64
 	LogLevel             string
 	LogOutput            string
 }
 
 var GlobalConfig *Config
 
-// LoadConfig 加载配置文件
+// LoadConfig 加载配置文件（ETH_PROFILE 环境变量指定配置档案）
 func LoadConfig() *Config {
-	// 加载.env文件
+	return LoadProfile(os.Getenv("ETH_PROFILE"))
+}
+
+// LoadProfile 加载指定档案的配置
+// 档案 name 对应 .env.<name> 文件，其中的值优先于 .env；已设置的环境变量优先级最高。
+// name 为空时只加载 .env
+func LoadProfile(name string) *Config {
+	if name != "" {
+		if err := godotenv.Load(ProfileFile(name)); err != nil {
+			log.Printf("Warning: profile file %s not found", ProfileFile(name))
+		}
+	}
+	// godotenv 不会覆盖已存在的变量，因此先加载的档案文件优先
 	err := godotenv.Load()
 	if err != nil {
 		log.Println("Warning: .env file not found, using environment variables")
@@ -41,13 +71,31 @@ func LoadConfig() *Config {
 		EthereumNetwork:      getEnv("ETHEREUM_NETWORK", ""),
 		EthereumWSURL:        getEnv("ETHEREUM_WS_URL", ""),
 		EthereumHTTPURL:      getEnv("ETHEREUM_HTTP_URL", ""),
+		RPCEndpoints:         getEnvAsSlice("RPC_ENDPOINTS"),
 		TestPrivateKey:       getEnv("TEST_PRIVATE_KEY", ""),
+		AllowPlaintextKey:    getEnvAsBool("ALLOW_PLAINTEXT_KEY", false),
+		KeystoreDir:          getEnv("KEYSTORE_DIR", "./keystore"),
+		KeystoreAccount:      getEnv("KEYSTORE_ACCOUNT", ""),
+		KeystorePasswordFile: getEnv("KEYSTORE_PASSWORD_FILE", ""),
+		MnemonicFile:         getEnv("MNEMONIC_FILE", ""),
+		MnemonicPassphrase:   getEnv("MNEMONIC_PASSPHRASE", ""),
+		HDPath:               getEnv("HD_PATH", "m/44'/60'/0'/0"),
+		HDIndex:              getEnvAsUint64("HD_INDEX", 0),
+		SignerURL:            getEnv("SIGNER_URL", ""),
 		TestSendAddress:      getEnv("TEST_SEND_ADDRESS", ""),
 		TestRecipientAddress: getEnv("TEST_RECIPIENT_ADDRESS", ""),
 		ContractAddress:      getEnv("CONTRACT_ADDRESS", ""),
 		ContractABIPath:      getEnv("CONTRACT_ABI_PATH", "./contracts/abi/"),
+		KnownMultisigs:       getEnvAsSlice("KNOWN_MULTISIGS"),
 		DefaultGasLimit:      getEnvAsUint64("DEFAULT_GAS_LIMIT", 0),
 		GasPriceMultiplier:   getEnvAsFloat64("GAS_PRICE_MULTIPLIER", 1.1),
+		FeeStrategy:          getEnv("FEE_STRATEGY", "node"),
+		FeeFixedTipGwei:      getEnvAsFloat64("FEE_FIXED_TIP_GWEI", 0),
+		FeeFixedCapGwei:      getEnvAsFloat64("FEE_FIXED_CAP_GWEI", 0),
+		FeeMaxFeeGwei:        getEnvAsFloat64("FEE_MAX_FEE_GWEI", 0),
+		FeeMaxCostEther:      getEnvAsFloat64("FEE_MAX_COST_ETH", 0),
+		FeeBumpIntervalSec:   getEnvAsUint64("FEE_BUMP_INTERVAL_SEC", 0),
+		FeeBumpPercent:       getEnvAsFloat64("FEE_BUMP_PERCENT", 12.5),
 		LogLevel:             getEnv("LOG_LEVEL", "info"),
 		LogOutput:            getEnv("LOG_OUTPUT", "console"),
 	}
@@ -56,6 +104,11 @@ func LoadConfig() *Config {
 	return config
 }
 
+// ProfileFile 配置档案对应的文件名
+func ProfileFile(name string) string {
+	return ".env." + name
+}
+
 // GetEthereumURL 获取完整的以太坊连接URL（优先使用HTTP连接）
 func (c *Config) GetEthereumURL() string {
 	if c.AlchemyAPIKey == "" {
@@ -81,13 +134,31 @@ func (c *Config) GetHTTPURL() string {
 	return c.EthereumHTTPURL + c.AlchemyAPIKey
 }
 
+// GetRPCEndpoints 获取节点池使用的全部节点地址
+// 包括 RPC_ENDPOINTS 中配置的节点，以及设置了 ALCHEMY_API_KEY 时的Alchemy HTTP/WS节点
+func (c *Config) GetRPCEndpoints() []string {
+	endpoints := append([]string{}, c.RPCEndpoints...)
+	if c.AlchemyAPIKey != "" {
+		if c.EthereumHTTPURL != "" {
+			endpoints = append(endpoints, c.EthereumHTTPURL+c.AlchemyAPIKey)
+		}
+		if c.EthereumWSURL != "" {
+			endpoints = append(endpoints, c.EthereumWSURL+c.AlchemyAPIKey)
+		}
+	}
+	return endpoints
+}
+
 // ValidateConfig 验证配置
 func (c *Config) ValidateConfig() error {
-	if c.AlchemyAPIKey == "" {
-		log.Println("Warning: ALCHEMY_API_KEY not set - network functions will not work")
+	if c.AlchemyAPIKey == "" && len(c.RPCEndpoints) == 0 {
+		log.Println("Warning: neither ALCHEMY_API_KEY nor RPC_ENDPOINTS set - network functions will not work")
+	}
+	if c.SignerURL == "" && c.KeystoreAccount == "" && c.MnemonicFile == "" && !c.AllowPlaintextKey {
+		log.Println("Warning: none of SIGNER_URL, KEYSTORE_ACCOUNT or MNEMONIC_FILE set - transfer functions will not work")
 	}
-	if c.TestPrivateKey == "" {
-		log.Println("Warning: TEST_PRIVATE_KEY not set - transfer functions will not work")
+	if c.TestPrivateKey != "" && !c.AllowPlaintextKey {
+		log.Println("Warning: TEST_PRIVATE_KEY is ignored unless ALLOW_PLAINTEXT_KEY=true")
 	}
 	return nil
 }
@@ -128,6 +199,35 @@ func getEnvAsFloat64(key string, defaultValue float64) float64 {
 	return value
 }
 
+// getEnvAsBool 获取环境变量并转换为bool
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := getEnv(key, "")
+	if valueStr == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		log.Printf("Warning: Invalid value for %s, using default %v", key, defaultValue)
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvAsSlice 获取逗号分隔的环境变量并转换为字符串切片
+func getEnvAsSlice(key string) []string {
+	valueStr := getEnv(key, "")
+	if valueStr == "" {
+		return nil
+	}
+	var values []string
+	for _, value := range strings.Split(valueStr, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 // IsProductionMode 检查是否为生产模式
 func (c *Config) IsProductionMode() bool {
 	return strings.ToLower(c.EthereumNetwork) == "mainnet"
@@ -2,44 +2,51 @@ package contract_deployment
 
 import (
 	"context"
-	"crypto/ecdsa"
 	"fmt"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
 
+	"ethclient_tutorial/backend"
 	"ethclient_tutorial/contracts"
+	"ethclient_tutorial/fee_strategy"
+	"ethclient_tutorial/nonce_manager"
+	"ethclient_tutorial/signer"
 	"ethclient_tutorial/utils"
+	"ethclient_tutorial/wallet_management"
 )
 
 // DeployContract 使用ABI绑定进行EIP-1559 部署合约
-func DeployContract(client *ethclient.Client, privateKeyHex string, recipientAddress common.Address) (common.Address, common.Hash, error) {
-	fmt.Println("\n=== 开始部署 MYERC20 合约 (EIP-1559) ===")
-
-	// 1. 加载私钥并�����取发送方地址
-	privateKey, err := crypto.HexToECDSA(privateKeyHex)
+func DeployContract(client backend.Client, privateKeyHex string, recipientAddress common.Address) (common.Address, common.Hash, error) {
+	account, err := wallet_management.AccountFromHex(privateKeyHex)
 	if err != nil {
-		return common.Address{}, common.Hash{}, fmt.Errorf("加载私钥失败: %v", err)
+		return common.Address{}, common.Hash{}, err
 	}
+	return DeployContractFromAccount(client, account, recipientAddress)
+}
 
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		return common.Address{}, common.Hash{}, fmt.Errorf("公钥类型转换失败")
-	}
+// DeployContractFromAccount 使用已解锁的账户（如keystore账户）部署合约，部署者成为合约owner
+func DeployContractFromAccount(client backend.Client, account *wallet_management.UnlockedAccount, recipientAddress common.Address) (common.Address, common.Hash, error) {
+	return DeployContractWithSigner(client, signer.FromAccount(account), recipientAddress)
+}
 
-	fromAddress := crypto.PubkeyToAddress(*publicKeyECDSA)
+// DeployContractWithSigner 使用签名器部署合约，部署者成为合约owner
+func DeployContractWithSigner(client backend.Client, s signer.Signer, recipientAddress common.Address) (common.Address, common.Hash, error) {
+	fmt.Println("\n=== 开始部署 MYERC20 合约 (EIP-1559) ===")
+
+	// 1. 部署者地址
+	fromAddress := s.Address()
 	fmt.Printf("✓ 部署者地址: %s\n", fromAddress.Hex())
 	fmt.Printf("✓ 接收者地址: %s\n", recipientAddress.Hex())
 
-	// 2. 获取当前nonce
-	nonce, err := client.PendingNonceAt(context.Background(), fromAddress)
+	// 2. 从nonce管理器领取nonce
+	reservation, err := nonce_manager.ForClient(client).Reserve(context.Background(), fromAddress)
 	if err != nil {
 		return common.Address{}, common.Hash{}, fmt.Errorf("获取nonce失败: %v", err)
 	}
+	defer reservation.Release(nil) // 未成功发送时归还nonce
+	nonce := reservation.Nonce
 	fmt.Printf("✓ Nonce: %d\n", nonce)
 
 	// 3. 获取链ID
@@ -49,39 +56,22 @@ func DeployContract(client *ethclient.Client, privateKeyHex string, recipientAdd
 	}
 	fmt.Printf("✓ 链ID: %s\n", chainID.String())
 
-	// 4. 获取EIP-1559费用参数
-	header, err := client.HeaderByNumber(context.Background(), nil)
+	// 4. 按配置的费用策略获取EIP-1559费用参数
+	strategy := fee_strategy.Default()
+	fees, err := strategy.SuggestFees(context.Background(), client)
 	if err != nil {
-		return common.Address{}, common.Hash{}, fmt.Errorf("获取区块头失败: %v", err)
+		return common.Address{}, common.Hash{}, fmt.Errorf("获取费用参数失败: %v", err)
 	}
-
-	tipCap, err := client.SuggestGasTipCap(context.Background())
-	if err != nil {
-		tipCap = big.NewInt(2e9) // 2 Gwei 默认小费
-		fmt.Printf("Warning: 获取小费建议失败，使用默认值: %s Gwei\n", new(big.Float).Quo(new(big.Float).SetInt(tipCap), big.NewFloat(1e9)))
-	}
-
-	// 计算gasFeeCap = baseFee * 2 + tipCap
-	gasFeeCap := new(big.Int).Add(
-		new(big.Int).Mul(header.BaseFee, big.NewInt(2)),
-		tipCap,
-	)
-
-	fmt.Printf("✓ 基础费用: %s Gwei\n", new(big.Float).Quo(new(big.Float).SetInt(header.BaseFee), big.NewFloat(1e9)))
-	fmt.Printf("✓ 小费上限: %s Gwei\n", new(big.Float).Quo(new(big.Float).SetInt(tipCap), big.NewFloat(1e9)))
-	fmt.Printf("✓ 费用上限: %s Gwei\n", new(big.Float).Quo(new(big.Float).SetInt(gasFeeCap), big.NewFloat(1e9)))
+	fees.Print()
 
 	// 5. 设置交易选项 (EIP-1559)
-	auth, err := bind.NewKeyedTransactorWithChainID(privateKey, chainID)
-	if err != nil {
-		return common.Address{}, common.Hash{}, fmt.Errorf("创建交易授权失败: %v", err)
-	}
+	auth := signer.TransactOpts(context.Background(), s, chainID)
 
 	// 配置EIP-1559参数
 	auth.Nonce = big.NewInt(int64(nonce))
 	auth.Value = big.NewInt(0) // 合约部署ETH值为0
-	auth.GasTipCap = tipCap
-	auth.GasFeeCap = gasFeeCap
+	auth.GasTipCap = fees.GasTipCap
+	auth.GasFeeCap = fees.GasFeeCap
 
 	// 6. 基于实际字节码估算部署Gas
 	deployGasLimit, err := estimateDeploymentGas(client, fromAddress, recipientAddress)
@@ -92,12 +82,21 @@ func DeployContract(client *ethclient.Client, privateKeyHex string, recipientAdd
 
 	auth.GasLimit = deployGasLimit
 
+	// 签名前报告预计花费和最坏情况花费，并检查花费上限
+	cost, err := fee_strategy.EstimateCost(strategy, fees, deployGasLimit, nil)
+	cost.Print()
+	if err != nil {
+		return common.Address{}, common.Hash{}, fmt.Errorf("超出花费上限: %v", err)
+	}
+
 	// 7. 部署合约
 	fmt.Println("✓ 开始部署合约...")
 	contractAddress, tx, instance, err := contracts.DeployMYERC20(auth, client, recipientAddress, fromAddress)
 	if err != nil {
+		reservation.Release(err)
 		return common.Address{}, common.Hash{}, fmt.Errorf("合约部署失败: %v", err)
 	}
+	reservation.Commit(tx.Hash())
 
 	fmt.Printf("✅ 合约部署交易提��成��!\n")
 	fmt.Printf("合约地址: %s\n", contractAddress.Hex())
@@ -150,7 +149,7 @@ func DeployContract(client *ethclient.Client, privateKeyHex string, recipientAdd
 }
 
 // estimateDeploymentGas 基于实际字节码估算合约部署所需Gas
-func estimateDeploymentGas(client *ethclient.Client, fromAddress, recipientAddress common.Address) (uint64, error) {
+func estimateDeploymentGas(client backend.Client, fromAddress, recipientAddress common.Address) (uint64, error) {
 	fmt.Println("\n--- Gas估算分析 ---")
 
 	// 1. 读取合约字节码
@@ -0,0 +1,135 @@
+package contract_events
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"ethclient_tutorial/backend"
+)
+
+// 回填时默认的分段大小（区块数）
+const (
+	DefaultChunkSize    = 2000
+	DefaultMinChunkSize = 1
+	DefaultMaxChunkSize = 10000
+)
+
+// BackfillOptions 历史事件回填参数
+type BackfillOptions struct {
+	FromBlock    uint64
+	ChunkSize    uint64                 // 初始分段大小，0 使用 DefaultChunkSize
+	MinChunkSize uint64                 // 结果过多时最小缩到的分段大小，0 使用 DefaultMinChunkSize
+	MaxChunkSize uint64                 // 成功后最大扩大到的分段大小，0 使用 DefaultMaxChunkSize
+	OnProgress   func(BackfillProgress) // 每完成一段或缩小分段重试时调用
+}
+
+// BackfillProgress 一段回填的进度
+type BackfillProgress struct {
+	From, To  uint64 // 本段区块区间（含两端）
+	Logs      int    // 本段日志数
+	Total     int    // 累计日志数
+	Head      uint64 // 回填终点
+	NextChunk uint64 // 下一段使用的分段大小
+	Retry     bool   // 本段结果过多未完成，已缩小分段重试
+}
+
+func (o BackfillOptions) limits() (chunk, min, max uint64) {
+	chunk, min, max = o.ChunkSize, o.MinChunkSize, o.MaxChunkSize
+	if min == 0 {
+		min = DefaultMinChunkSize
+	}
+	if max == 0 {
+		max = DefaultMaxChunkSize
+	}
+	if max < min {
+		max = min
+	}
+	if chunk == 0 {
+		chunk = DefaultChunkSize
+	}
+	if chunk < min {
+		chunk = min
+	}
+	if chunk > max {
+		chunk = max
+	}
+	return chunk, min, max
+}
+
+// Backfill 用 FilterLogs 分段查询 [opts.FromBlock, head] 区间的历史日志，按区块顺序交给handle
+// 节点提示结果过多或区间过大时把分段减半重试，成功后分段加倍（不超过 MaxChunkSize）；
+// 分段已缩到 MinChunkSize 仍然失败时返回错误。handle 返回错误时停止回填
+func Backfill(ctx context.Context, client backend.Client, query ethereum.FilterQuery, head uint64, opts BackfillOptions, handle func(types.Log) error) error {
+	chunk, minChunk, maxChunk := opts.limits()
+	total := 0
+	for start := opts.FromBlock; start <= head; {
+		end := head
+		if chunk-1 < head-start {
+			end = start + chunk - 1
+		}
+		query.FromBlock = new(big.Int).SetUint64(start)
+		query.ToBlock = new(big.Int).SetUint64(end)
+
+		logs, err := client.FilterLogs(ctx, query)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if span := end - start + 1; IsTooManyResults(err) && span > minChunk {
+				chunk = max(span/2, minChunk)
+				if opts.OnProgress != nil {
+					opts.OnProgress(BackfillProgress{From: start, To: end, Total: total, Head: head, NextChunk: chunk, Retry: true})
+				}
+				continue
+			}
+			return fmt.Errorf("查询区块 %d-%d 的日志失败: %w", start, end, err)
+		}
+		for _, vLog := range logs {
+			if err := handle(vLog); err != nil {
+				return err
+			}
+		}
+		total += len(logs)
+		chunk = min(chunk*2, maxChunk)
+		if opts.OnProgress != nil {
+			opts.OnProgress(BackfillProgress{From: start, To: end, Logs: len(logs), Total: total, Head: head, NextChunk: chunk})
+		}
+		if end == head {
+			break
+		}
+		start = end + 1
+	}
+	return nil
+}
+
+// tooManyResults 各节点服务商在结果过多或区间过大时返回的错误信息片段
+var tooManyResults = []string{
+	"query returned more than",   // Infura、geth: query returned more than 10000 results
+	"log response size exceeded", // Alchemy
+	"response size exceeded",
+	"limit exceeded",
+	"too many results",
+	"too many logs",
+	"block range", // block range is too wide / exceed maximum block range
+	"range too large",
+	"query timeout exceeded", // 区间过大导致查询超时
+}
+
+// IsTooManyResults 错误是否表示查询结果过多或区块区间过大（缩小分段后可以重试）
+func IsTooManyResults(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, pattern := range tooManyResults {
+		if strings.Contains(msg, pattern) {
+			return true
+		}
+	}
+	return false
+}
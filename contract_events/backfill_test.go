@@ -0,0 +1,142 @@
+package contract_events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/backend/backendtest"
+)
+
+// limitedClient 模拟节点的结果数量限制
+type limitedClient struct {
+	backend.Client
+	limit int
+	calls int
+}
+
+func (c *limitedClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	c.calls++
+	logs, err := c.Client.FilterLogs(ctx, q)
+	if err == nil && len(logs) > c.limit {
+		return nil, fmt.Errorf("query returned more than %d results", c.limit)
+	}
+	return logs, err
+}
+
+// checkOrdered 日志按区块和索引严格递增（没有重复）
+func checkOrdered(t *testing.T, logs []types.Log) {
+	t.Helper()
+	for i := 1; i < len(logs); i++ {
+		prev, cur := logs[i-1], logs[i]
+		if cur.BlockNumber < prev.BlockNumber || (cur.BlockNumber == prev.BlockNumber && cur.Index <= prev.Index) {
+			t.Fatalf("log %d (#%d/%d) after #%d/%d", i, cur.BlockNumber, cur.Index, prev.BlockNumber, prev.Index)
+		}
+	}
+}
+
+func TestBackfillAdaptsChunkSize(t *testing.T) {
+	chain := backendtest.NewTokenChain(t, 2)
+	chain.Mine(t, 1, 1, 1, 1, 1, 1, 1, 1, 3, 1, 1)
+	ctx := context.Background()
+	head, _ := chain.Sim.BlockNumber(ctx)
+
+	client := &limitedClient{Client: chain.Sim, limit: 3}
+	var logs []types.Log
+	var progress []BackfillProgress
+	opts := BackfillOptions{FromBlock: 0, ChunkSize: 8, MaxChunkSize: 16, OnProgress: func(p BackfillProgress) { progress = append(progress, p) }}
+	query := ethereum.FilterQuery{Addresses: []common.Address{chain.Address}}
+	if err := Backfill(ctx, client, query, head, opts, func(vLog types.Log) error {
+		logs = append(logs, vLog)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// 部署时的 OwnershipTransferred 和铸造 Transfer + 13笔转账
+	if len(logs) != 15 {
+		t.Fatalf("backfilled %d logs, want 15", len(logs))
+	}
+	checkOrdered(t, logs)
+
+	var retries, grown int
+	var covered uint64
+	for _, p := range progress {
+		if p.Retry {
+			retries++
+			continue
+		}
+		if p.From != covered {
+			t.Fatalf("chunk %d-%d leaves a gap after %d", p.From, p.To, covered)
+		}
+		covered = p.To + 1
+		if p.To-p.From+1 < p.NextChunk {
+			grown++
+		}
+	}
+	if retries == 0 || grown == 0 || covered != head+1 {
+		t.Fatalf("retries=%d grown=%d covered=%d progress=%+v", retries, grown, covered, progress)
+	}
+
+	// 单个区块的日志就超过限制时无法再拆分
+	client = &limitedClient{Client: chain.Sim, limit: 2}
+	err := Backfill(ctx, client, query, head, BackfillOptions{}, func(types.Log) error { return nil })
+	if err == nil || !IsTooManyResults(err) {
+		t.Fatalf("single-block overflow err = %v", err)
+	}
+
+	// handle 返回的错误会中止回填
+	stop := errors.New("stop")
+	if err := Backfill(ctx, chain.Sim, query, head, BackfillOptions{}, func(types.Log) error { return stop }); !errors.Is(err, stop) {
+		t.Fatalf("handler error = %v", err)
+	}
+}
+
+func TestWatcherSwitchesFromBackfillToLive(t *testing.T) {
+	chain := backendtest.NewTokenChain(t, 2)
+	chain.Mine(t, 2, 1, 3)
+
+	watcher, err := NewEventWatcher(&limitedClient{Client: chain.Sim, limit: 3}, chain.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := make(chan types.Log, 64)
+	watcher.OnEvent(func(vLog types.Log) { received <- vLog })
+	if err := watcher.StartWatchingFrom(BackfillOptions{ChunkSize: 4}); err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Stop()
+
+	// 回填期间和回填之后产生的日志都只收到一次
+	var logs []types.Log
+	collect := func(want int) {
+		t.Helper()
+		deadline := time.After(5 * time.Second)
+		for len(logs) < want {
+			select {
+			case vLog := <-received:
+				logs = append(logs, vLog)
+			case err := <-watcher.Err():
+				t.Fatal(err)
+			case <-deadline:
+				t.Fatalf("received %d logs, want %d", len(logs), want)
+			}
+		}
+	}
+	chain.Mine(t, 1, 2)
+	collect(2 + 6 + 3) // 部署2条、回填前6笔、回填期间3笔
+	chain.Mine(t, 1)
+	collect(2 + 6 + 4)
+	select {
+	case vLog := <-received:
+		t.Fatalf("unexpected extra log #%d/%d", vLog.BlockNumber, vLog.Index)
+	case <-time.After(300 * time.Millisecond):
+	}
+	checkOrdered(t, logs)
+}
@@ -0,0 +1,156 @@
+package contract_events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Checkpoint 事件消费者最后一条处理完成的日志位置
+type Checkpoint struct {
+	BlockNumber uint64      `json:"blockNumber"`
+	BlockHash   common.Hash `json:"blockHash"`
+	LogIndex    uint        `json:"logIndex"`
+	UpdatedAt   time.Time   `json:"updatedAt"`
+}
+
+// CheckpointAt 返回日志所在位置的检查点
+func CheckpointAt(vLog types.Log) Checkpoint {
+	return Checkpoint{BlockNumber: vLog.BlockNumber, BlockHash: vLog.BlockHash, LogIndex: vLog.Index, UpdatedAt: time.Now().UTC()}
+}
+
+// Covers 日志是否位于检查点之前或就是检查点本身（已处理过）
+func (c Checkpoint) Covers(vLog types.Log) bool {
+	return vLog.BlockNumber < c.BlockNumber || (vLog.BlockNumber == c.BlockNumber && vLog.Index <= c.LogIndex)
+}
+
+// CheckpointStore 按键保存检查点；Load 在没有检查点时返回 nil, nil
+type CheckpointStore interface {
+	Load(key string) (*Checkpoint, error)
+	Save(key string, cp Checkpoint) error
+}
+
+// CheckpointKey 由合约地址和过滤条件生成检查点的键，地址或主题不同的过滤器互不影响
+// 格式为 地址[,地址...]/主题摘要，没有主题过滤时摘要为 all
+func CheckpointKey(query ethereum.FilterQuery) string {
+	addrs := make([]string, len(query.Addresses))
+	for i, addr := range query.Addresses {
+		addrs[i] = strings.ToLower(addr.Hex())
+	}
+	filter := "all"
+	if len(query.Topics) > 0 {
+		var b strings.Builder
+		for i, position := range query.Topics {
+			if i > 0 {
+				b.WriteByte('|')
+			}
+			for j, topic := range position {
+				if j > 0 {
+					b.WriteByte(',')
+				}
+				b.WriteString(topic.Hex())
+			}
+		}
+		filter = crypto.Keccak256Hash([]byte(b.String())).Hex()[2:18]
+	}
+	return strings.Join(addrs, ",") + "/" + filter
+}
+
+// IdempotencyKey 日志的幂等键（区块哈希-日志索引）
+// 重启后重新投递的同一条日志得到相同的键，处理函数可据此去重；重组后进入新区块的日志是新的键
+func IdempotencyKey(vLog types.Log) string {
+	return fmt.Sprintf("%s-%d", vLog.BlockHash.Hex(), vLog.Index)
+}
+
+// FileCheckpointStore 把全部检查点保存在一个JSON文件中，每次保存先写临时文件再重命名，进程崩溃时不会留下半个文件
+type FileCheckpointStore struct {
+	path   string
+	mu     sync.Mutex
+	points map[string]Checkpoint
+}
+
+// NewFileCheckpointStore 打开检查点文件，文件不存在时在第一次保存时创建
+func NewFileCheckpointStore(path string) (*FileCheckpointStore, error) {
+	s := &FileCheckpointStore{path: path, points: make(map[string]Checkpoint)}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取检查点文件失败: %v", err)
+	}
+	if len(content) > 0 {
+		if err := json.Unmarshal(content, &s.points); err != nil {
+			return nil, fmt.Errorf("解析检查点文件失败: %v", err)
+		}
+	}
+	return s, nil
+}
+
+// Path 检查点文件路径
+func (s *FileCheckpointStore) Path() string {
+	return s.path
+}
+
+// Load 读取检查点
+func (s *FileCheckpointStore) Load(key string) (*Checkpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cp, ok := s.points[key]
+	if !ok {
+		return nil, nil
+	}
+	return &cp, nil
+}
+
+// Save 保存检查点并同步到磁盘
+func (s *FileCheckpointStore) Save(key string, cp Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous, existed := s.points[key]
+	s.points[key] = cp
+	if err := s.write(); err != nil {
+		if existed {
+			s.points[key] = previous
+		} else {
+			delete(s.points, key)
+		}
+		return fmt.Errorf("保存检查点失败: %v", err)
+	}
+	return nil
+}
+
+// write 原子地重写检查点文件
+func (s *FileCheckpointStore) write() error {
+	content, err := json.MarshalIndent(s.points, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(content, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,202 @@
+package contract_events
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"ethclient_tutorial/backend/backendtest"
+)
+
+// collect 从监听器收取 n 条日志，之后一段时间内不应再有日志
+func collect(t *testing.T, w *EventWatcher, received <-chan types.Log, n int) []types.Log {
+	t.Helper()
+	var logs []types.Log
+	for len(logs) < n {
+		select {
+		case vLog := <-received:
+			logs = append(logs, vLog)
+		case err := <-w.Err():
+			t.Fatal(err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received %d logs, want %d", len(logs), n)
+		}
+	}
+	select {
+	case vLog := <-received:
+		t.Fatalf("unexpected extra log #%d/%d", vLog.BlockNumber, vLog.Index)
+	case <-time.After(300 * time.Millisecond):
+	}
+	return logs
+}
+
+func TestWatcherResumesFromCheckpoint(t *testing.T) {
+	chain := backendtest.NewTokenChain(t, 2)
+	chain.Mine(t, 2, 1)
+	path := filepath.Join(t.TempDir(), "checkpoints.json")
+	store, err := NewFileCheckpointStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 第一次运行：从创世区块回填，每条日志处理后保存位置
+	first, _ := NewEventWatcher(chain.Sim, chain.Address)
+	first.UseCheckpoints(store)
+	received := make(chan types.Log, 64)
+	first.OnLog(func(vLog types.Log) error {
+		received <- vLog
+		return nil
+	})
+	if err := first.StartWatchingFrom(BackfillOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	logs := collect(t, first, received, 2+3)
+	first.Stop()
+	last := logs[len(logs)-1]
+	if cp, _ := store.Load(first.CheckpointKey()); cp == nil || cp.BlockHash != last.BlockHash || cp.LogIndex != last.Index {
+		t.Fatalf("checkpoint = %+v, want #%d/%d", cp, last.BlockNumber, last.Index)
+	}
+
+	// 第二次运行：处理第二条新日志时失败，检查点停留在第一条
+	chain.Mine(t, 1, 2)
+	second, _ := NewEventWatcher(chain.Sim, chain.Address)
+	second.UseCheckpoints(store)
+	var failed types.Log
+	count := 0
+	second.OnLog(func(vLog types.Log) error {
+		if count++; count == 2 {
+			failed = vLog
+			return errors.New("下游不可用")
+		}
+		received <- vLog
+		return nil
+	})
+	if err := second.StartWatching(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-second.Err():
+		if err == nil {
+			t.Fatal("handler failure not reported")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler failure not reported")
+	}
+	second.Stop()
+	if logs := collect(t, second, received, 1); logs[0].BlockNumber <= last.BlockNumber {
+		t.Fatalf("second run redelivered #%d/%d", logs[0].BlockNumber, logs[0].Index)
+	}
+
+	// 重启（重新打开检查点文件）后从失败的日志继续，幂等键与失败时相同，再切换到实时订阅
+	store, err = NewFileCheckpointStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	third, _ := NewEventWatcher(chain.Sim, chain.Address)
+	third.UseCheckpoints(store)
+	third.OnLog(func(vLog types.Log) error {
+		received <- vLog
+		return nil
+	})
+	if err := third.StartWatching(); err != nil {
+		t.Fatal(err)
+	}
+	defer third.Stop()
+	logs = collect(t, third, received, 2)
+	chain.Mine(t, 1)
+	logs = append(logs, collect(t, third, received, 1)...)
+	if IdempotencyKey(logs[0]) != IdempotencyKey(failed) {
+		t.Fatalf("resumed at %s, want %s", IdempotencyKey(logs[0]), IdempotencyKey(failed))
+	}
+	checkOrdered(t, logs)
+
+	// 不同的过滤条件使用不同的检查点
+	filtered := ethereum.FilterQuery{Addresses: []common.Address{chain.Address}, Topics: [][]common.Hash{{last.Topics[0]}}}
+	if CheckpointKey(filtered) == third.CheckpointKey() {
+		t.Fatalf("filters share checkpoint key %s", third.CheckpointKey())
+	}
+}
+
+// checkReorgedCheckpoint 处理完所有日志后停止，停止期间从检查点所在区块以下 depth 个区块处分叉，
+// 重启后新链上的日志（包括分叉点之后每个被替换区块中的日志）都要重新投递
+func checkReorgedCheckpoint(t *testing.T, depth uint64) {
+	t.Helper()
+	chain := backendtest.NewTokenChain(t, 2)
+	chain.Mine(t, 1, 1, 1, 1)
+	store, err := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoints.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := make(chan types.Log, 64)
+	onLog := func(vLog types.Log) error {
+		received <- vLog
+		return nil
+	}
+
+	first, _ := NewEventWatcher(chain.Sim, chain.Address)
+	first.UseCheckpoints(store)
+	first.OnLog(onLog)
+	if err := first.StartWatchingFrom(BackfillOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	logs := collect(t, first, received, 2+4)
+	first.Stop()
+	last := logs[len(logs)-1]
+
+	// 新区块中的日志从索引0开始，不能因为检查点的索引而跳过
+	ctx := context.Background()
+	parent, err := chain.Sim.HeaderByNumber(ctx, new(big.Int).SetUint64(last.BlockNumber-depth))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := chain.Sim.Fork(parent.Hash()); err != nil {
+		t.Fatal(err)
+	}
+	perBlock := make([]int, depth+1)
+	for i := range perBlock {
+		perBlock[i] = 1
+	}
+	chain.Mine(t, perBlock...)
+	replaced, err := chain.Sim.FilterLogs(ctx, ethereum.FilterQuery{Addresses: []common.Address{chain.Address}, FromBlock: new(big.Int).Add(parent.Number, big.NewInt(1))})
+	if err != nil || len(replaced) == 0 || replaced[0].BlockNumber != parent.Number.Uint64()+1 {
+		t.Fatalf("replaced block logs = %d, %v", len(replaced), err)
+	}
+	for _, vLog := range logs {
+		if vLog.BlockNumber == replaced[0].BlockNumber && vLog.BlockHash == replaced[0].BlockHash {
+			t.Fatalf("block #%d was not replaced", vLog.BlockNumber)
+		}
+	}
+	want, err := chain.Sim.FilterLogs(ctx, ethereum.FilterQuery{Addresses: []common.Address{chain.Address}, FromBlock: big.NewInt(0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, _ := NewEventWatcher(chain.Sim, chain.Address)
+	second.UseCheckpoints(store)
+	second.OnLog(onLog)
+	if err := second.StartWatching(); err != nil {
+		t.Fatal(err)
+	}
+	defer second.Stop()
+	logs = collect(t, second, received, len(want))
+	for i, vLog := range logs {
+		if IdempotencyKey(vLog) != IdempotencyKey(want[i]) {
+			t.Fatalf("log %d = %s, want %s", i, IdempotencyKey(vLog), IdempotencyKey(want[i]))
+		}
+	}
+}
+
+func TestWatcherReprocessesReorgedCheckpointBlock(t *testing.T) {
+	checkReorgedCheckpoint(t, 1)
+}
+
+func TestWatcherRewindsPastDeepReorg(t *testing.T) {
+	checkReorgedCheckpoint(t, 3)
+}
@@ -0,0 +1,269 @@
+package contract_events
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"ethclient_tutorial/contracts"
+)
+
+// ErrUnknownEvent 已加载的ABI中没有与日志匹配的事件
+var ErrUnknownEvent = errors.New("未知事件")
+
+// Event 按ABI解码后的事件
+type Event struct {
+	Name      string
+	Signature string // 如 Transfer(address,address,uint256)
+	Source    string // 事件所在ABI的名称
+	Anonymous bool
+	Args      map[string]interface{} // indexed 和非 indexed 参数合并，未命名参数为 arg0、arg1……
+	Order     []string               // 参数名，按ABI中的声明顺序
+	Log       types.Log
+}
+
+// candidate 解码日志时尝试的一个事件定义
+type candidate struct {
+	source string
+	event  abi.Event
+	inputs abi.Arguments // 已补全参数名
+}
+
+// Decoder 按一个或多个ABI通用解码事件日志
+// 多个ABI中签名相同的事件（如ERC20和ERC721的 Transfer，indexed 参数个数不同）都会保留，
+// 解码时按主题数量和数据长度挑选能正确解码的定义；匿名事件没有签名主题，在签名都不匹配时再尝试
+type Decoder struct {
+	events    map[common.Hash][]candidate
+	anonymous []candidate
+}
+
+// NewDecoder 创建解码器，之后用 Add 加载ABI
+func NewDecoder() *Decoder {
+	return &Decoder{events: make(map[common.Hash][]candidate)}
+}
+
+// DefaultDecoder 使用绑定代码中内嵌的MyToken ABI创建解码器
+func DefaultDecoder() (*Decoder, error) {
+	parsed, err := contracts.MYERC20MetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	d := NewDecoder()
+	d.Add("MyToken", *parsed)
+	return d, nil
+}
+
+// Add 加载ABI中的全部事件，先加载的ABI在歧义时优先；完全相同的事件定义只保留一份
+func (d *Decoder) Add(source string, parsed abi.ABI) {
+	names := make([]string, 0, len(parsed.Events))
+	for name := range parsed.Events {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		event := parsed.Events[name]
+		c := candidate{source: source, event: event, inputs: namedInputs(event.Inputs)}
+		if event.Anonymous {
+			if !containsEvent(d.anonymous, c) {
+				d.anonymous = append(d.anonymous, c)
+			}
+			continue
+		}
+		if !containsEvent(d.events[event.ID], c) {
+			d.events[event.ID] = append(d.events[event.ID], c)
+		}
+	}
+}
+
+// AddABIs 加载 contracts.LoadABIs 返回的多个ABI
+func (d *Decoder) AddABIs(abis []contracts.NamedABI) {
+	for _, named := range abis {
+		d.Add(named.Name, named.ABI)
+	}
+}
+
+// Decode 解码日志；没有匹配的事件定义时返回 ErrUnknownEvent
+func (d *Decoder) Decode(vLog types.Log) (*Event, error) {
+	var candidates []candidate
+	if len(vLog.Topics) > 0 {
+		candidates = d.events[vLog.Topics[0]]
+	}
+	if event, ok := decodeFirst(candidates, vLog); ok {
+		return event, nil
+	}
+	if event, ok := decodeFirst(d.anonymous, vLog); ok {
+		return event, nil
+	}
+	if len(vLog.Topics) == 0 {
+		return nil, fmt.Errorf("%w: 日志没有主题", ErrUnknownEvent)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownEvent, vLog.Topics[0].Hex())
+}
+
+// decodeFirst 依次尝试候选定义，优先选择重新编码后与日志数据完全一致的定义
+func decodeFirst(candidates []candidate, vLog types.Log) (*Event, bool) {
+	var fallback *Event
+	for _, c := range candidates {
+		event, exact, err := c.decode(vLog)
+		if err != nil {
+			continue
+		}
+		if exact {
+			return event, true
+		}
+		if fallback == nil {
+			fallback = event
+		}
+	}
+	return fallback, fallback != nil
+}
+
+// decode 按事件定义解码日志，exact 表示非 indexed 参数重新编码后与日志数据完全一致
+func (c candidate) decode(vLog types.Log) (event *Event, exact bool, err error) {
+	topics := vLog.Topics
+	if !c.event.Anonymous {
+		if len(topics) == 0 {
+			return nil, false, errors.New("缺少签名主题")
+		}
+		topics = topics[1:]
+	}
+	var indexed abi.Arguments
+	for _, input := range c.inputs {
+		if input.Indexed {
+			indexed = append(indexed, input)
+		}
+	}
+	if len(indexed) != len(topics) {
+		return nil, false, fmt.Errorf("主题数量不匹配: 需要 %d，实际 %d", len(indexed), len(topics))
+	}
+
+	args := make(map[string]interface{}, len(c.inputs))
+	nonIndexed := c.inputs.NonIndexed()
+	values, err := nonIndexed.Unpack(vLog.Data)
+	if err != nil {
+		return nil, false, err
+	}
+	for i, input := range nonIndexed {
+		args[input.Name] = values[i]
+	}
+	for i, input := range indexed {
+		switch input.Type.T {
+		case abi.TupleTy, abi.StringTy, abi.BytesTy, abi.SliceTy, abi.ArrayTy:
+			// 动态类型和数组在主题中只保存keccak256哈希
+			args[input.Name] = topics[i]
+		default:
+			if err := abi.ParseTopicsIntoMap(args, abi.Arguments{input}, topics[i:i+1]); err != nil {
+				return nil, false, err
+			}
+		}
+	}
+
+	packed, err := nonIndexed.Pack(values...)
+	exact = err == nil && bytes.Equal(packed, vLog.Data)
+
+	event = &Event{
+		Name:      c.event.Name,
+		Signature: c.event.Sig,
+		Source:    c.source,
+		Anonymous: c.event.Anonymous,
+		Args:      args,
+		Log:       vLog,
+	}
+	for _, input := range c.inputs {
+		event.Order = append(event.Order, input.Name)
+	}
+	return event, exact, nil
+}
+
+// String 以 Name(arg=value, ...) 格式输出
+func (e *Event) String() string {
+	parts := make([]string, len(e.Order))
+	for i, name := range e.Order {
+		parts[i] = name + "=" + FormatValue(e.Args[name])
+	}
+	return fmt.Sprintf("%s(%s)", e.Name, strings.Join(parts, ", "))
+}
+
+// StringArgs 把参数格式化为字符串（大整数不会丢失精度），便于输出JSON
+func (e *Event) StringArgs() map[string]string {
+	formatted := make(map[string]string, len(e.Args))
+	for name, value := range e.Args {
+		formatted[name] = FormatValue(value)
+	}
+	return formatted
+}
+
+// FormatValue 把解码出的参数值格式化为字符串：地址用校验和格式，字节用十六进制，整数用十进制
+func FormatValue(value interface{}) string {
+	switch v := value.(type) {
+	case common.Address:
+		return v.Hex()
+	case common.Hash:
+		return v.Hex()
+	case *big.Int:
+		return v.String()
+	case []byte:
+		return hexutil.Encode(v)
+	case string:
+		return v
+	}
+	// bytesN 解码为定长字节数组
+	if b, ok := fixedBytes(value); ok {
+		return hexutil.Encode(b)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// fixedBytes 把 [N]byte 转为切片
+func fixedBytes(value interface{}) ([]byte, bool) {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Array || v.Type().Elem().Kind() != reflect.Uint8 {
+		return nil, false
+	}
+	b := make([]byte, v.Len())
+	reflect.Copy(reflect.ValueOf(b), v)
+	return b, true
+}
+
+// namedInputs 复制参数并给未命名的参数补上 argN
+func namedInputs(inputs abi.Arguments) abi.Arguments {
+	named := make(abi.Arguments, len(inputs))
+	for i, input := range inputs {
+		if input.Name == "" {
+			input.Name = fmt.Sprintf("arg%d", i)
+		}
+		named[i] = input
+	}
+	return named
+}
+
+// containsEvent 是否已有签名和 indexed 标记都相同的事件定义
+func containsEvent(candidates []candidate, c candidate) bool {
+	for _, existing := range candidates {
+		if existing.event.Sig == c.event.Sig && existing.event.Anonymous == c.event.Anonymous && sameIndexing(existing.inputs, c.inputs) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameIndexing(a, b abi.Arguments) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Indexed != b[i].Indexed {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,164 @@
+package contract_events
+
+import (
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"ethclient_tutorial/contracts"
+)
+
+// erc721ABI 与ERC20的 Transfer 签名相同，但第三个参数也是 indexed
+const erc721ABI = `[{"type":"event","name":"Transfer","anonymous":false,"inputs":[
+	{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"tokenId","type":"uint256","indexed":true}]}]`
+
+// extrasABI 匿名事件、未命名参数和 indexed 动态类型
+const extrasABI = `[
+	{"type":"event","name":"Ping","anonymous":true,"inputs":[{"name":"who","type":"address","indexed":true},{"name":"n","type":"uint256","indexed":false}]},
+	{"type":"event","name":"Note","anonymous":false,"inputs":[{"name":"","type":"string","indexed":true},{"name":"","type":"bytes32","indexed":false},{"name":"memo","type":"string","indexed":false}]}
+]`
+
+func mustABI(t *testing.T, definition string) abi.ABI {
+	t.Helper()
+	parsed, err := abi.JSON(strings.NewReader(definition))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func TestDecoderResolvesCollisionsAndAnonymousEvents(t *testing.T) {
+	decoder, err := DefaultDecoder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoder.Add("ERC721", mustABI(t, erc721ABI))
+	extras := mustABI(t, extrasABI)
+	decoder.Add("Extras", extras)
+	decoder.Add("ERC721", mustABI(t, erc721ABI)) // 重复加载不会产生新的候选
+
+	from, to := common.HexToAddress("0x1111111111111111111111111111111111111111"), common.HexToAddress("0x2222222222222222222222222222222222222222")
+	transferID := crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+	if n := len(decoder.events[transferID]); n != 2 {
+		t.Fatalf("Transfer candidates = %d, want 2", n)
+	}
+
+	// ERC20 Transfer：金额在data中
+	value := new(big.Int).Exp(big.NewInt(10), big.NewInt(30), nil)
+	event, err := decoder.Decode(types.Log{
+		Topics: []common.Hash{transferID, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes())},
+		Data:   common.BigToHash(value).Bytes(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Source != "MyToken" || event.Args["from"] != from || event.Args["to"] != to || event.Args["value"].(*big.Int).Cmp(value) != 0 {
+		t.Fatalf("ERC20 Transfer = %+v", event)
+	}
+	if got := event.String(); got != "Transfer(from="+from.Hex()+", to="+to.Hex()+", value="+value.String()+")" {
+		t.Fatalf("String() = %s", got)
+	}
+
+	// ERC721 Transfer：tokenId 在主题中，data为空
+	event, err = decoder.Decode(types.Log{Topics: []common.Hash{transferID, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes()), common.BigToHash(big.NewInt(42))}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Source != "ERC721" || event.Args["tokenId"].(*big.Int).Int64() != 42 {
+		t.Fatalf("ERC721 Transfer = %+v", event)
+	}
+
+	// MyToken中没有专门处理的事件也能解码
+	domainChanged := crypto.Keccak256Hash([]byte("EIP712DomainChanged()"))
+	if event, err = decoder.Decode(types.Log{Topics: []common.Hash{domainChanged}}); err != nil || event.Name != "EIP712DomainChanged" || len(event.Args) != 0 {
+		t.Fatalf("EIP712DomainChanged = %+v, %v", event, err)
+	}
+
+	// 匿名事件没有签名主题
+	event, err = decoder.Decode(types.Log{Topics: []common.Hash{common.BytesToHash(from.Bytes())}, Data: common.BigToHash(big.NewInt(7)).Bytes()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Name != "Ping" || !event.Anonymous || event.Args["who"] != from || event.Args["n"].(*big.Int).Int64() != 7 {
+		t.Fatalf("anonymous Ping = %+v", event)
+	}
+
+	// 未命名参数补全为 argN，indexed string 只能得到哈希
+	note := extras.Events["Note"]
+	data, err := note.Inputs.NonIndexed().Pack([32]byte{0xab}, "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	topic := crypto.Keccak256Hash([]byte("indexed"))
+	event, err = decoder.Decode(types.Log{Topics: []common.Hash{note.ID, topic}, Data: data})
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := event.StringArgs()
+	if args["arg0"] != topic.Hex() || !strings.HasPrefix(args["arg1"], "0xab00") || args["memo"] != "hello" || strings.Join(event.Order, ",") != "arg0,arg1,memo" {
+		t.Fatalf("Note = %+v", args)
+	}
+
+	if _, err := decoder.Decode(types.Log{Topics: []common.Hash{crypto.Keccak256Hash([]byte("Nope()"))}}); !errors.Is(err, ErrUnknownEvent) {
+		t.Fatalf("unknown event err = %v", err)
+	}
+}
+
+func TestLoadABIsFromArtifacts(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"raw/ERC721.abi": erc721ABI,
+		// Hardhat：artifacts/contracts/X.sol/X.json，旁边有不含ABI的 .dbg.json
+		"artifacts/contracts/Extras.sol/Extras.json":     `{"_format":"hh-sol-artifact-1","contractName":"Extras","abi":` + extrasABI + `,"bytecode":"0x"}`,
+		"artifacts/contracts/Extras.sol/Extras.dbg.json": `{"_format":"hh-sol-dbg-1","buildInfo":"../../build-info/1.json"}`,
+		// Foundry：out/X.sol/X.json，没有 contractName
+		"out/Token.sol/Token.json": `{"abi":` + erc721ABI + `,"bytecode":{"object":"0x"}}`,
+		"package.json":             `{"name":"contracts"}`,
+		// 不是ABI的JSON数组跳过并警告，不影响其他文件
+		"data/holders.json": `["0x0000000000000000000000000000000000000001"]`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	abis, err := contracts.LoadABIs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, named := range abis {
+		names = append(names, named.Name)
+	}
+	if got := strings.Join(names, ","); got != "Extras,Token,ERC721" {
+		t.Fatalf("loaded = %s", got)
+	}
+
+	decoder := NewDecoder()
+	decoder.AddABIs(abis)
+	if _, ok := decoder.events[abis[0].ABI.Events["Note"].ID]; !ok || len(decoder.anonymous) != 1 {
+		t.Fatalf("decoder events = %d, anonymous = %d", len(decoder.events), len(decoder.anonymous))
+	}
+
+	for _, name := range []string{"package.json", "data/holders.json"} {
+		if _, err := contracts.LoadABIFile(filepath.Join(dir, name)); !errors.Is(err, contracts.ErrNotABI) {
+			t.Fatalf("%s err = %v", name, err)
+		}
+	}
+	if abis, err := contracts.LoadABIs(filepath.Join(dir, "raw/ERC721.abi")); err != nil || len(abis) != 1 || abis[0].Name != "ERC721" {
+		t.Fatalf("single file = %+v, %v", abis, err)
+	}
+}
@@ -2,90 +2,344 @@ package contract_events
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"math/big"
-	"os"
-	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum"
-	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
+
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/event_stream"
 )
 
 // EventWatcher 事件监听器结构体
 type EventWatcher struct {
-	client          *ethclient.Client
+	client          backend.Client
 	contractAddress common.Address
-	contractABI     abi.ABI
-	subscription    ethereum.Subscription
-	logChan         chan types.Log
+	decoder         *Decoder
+	streamOpts      event_stream.Options
+	logs            *event_stream.LogStream
+	heads           *event_stream.HeadStream
 	ctx             context.Context
 	cancel          context.CancelFunc
+	handler         func(vLog types.Log) error
+	revertHandler   func(vLog types.Log)
+	guard           *reorgGuard
+	errs            chan error
+	store           CheckpointStore
+	checkpoint      *Checkpoint // 已处理完成的位置，之前的日志不再投递
+	halted          bool        // 处理或保存检查点失败后不再投递
 }
 
-// NewEventWatcher 创建新的事件监听器
-func NewEventWatcher(client *ethclient.Client, contractAddress common.Address) (*EventWatcher, error) {
-	// 读取并解析ABI文件
-	abiData, err := os.ReadFile("contracts/compiled/MyToken.abi")
+// NewEventWatcher 创建新的事件监听器，使用内嵌的MyToken ABI解码事件
+func NewEventWatcher(client backend.Client, contractAddress common.Address) (*EventWatcher, error) {
+	decoder, err := DefaultDecoder()
 	if err != nil {
-		return nil, fmt.Errorf("读取ABI文件失败: %v", err)
-	}
-
-	parsedABI, err := abi.JSON(strings.NewReader(string(abiData)))
-	if err != nil {
-		return nil, fmt.Errorf("解析ABI失败: %v", err)
+		return nil, fmt.Errorf("加载ABI失败: %v", err)
 	}
+	return NewEventWatcherWithDecoder(client, contractAddress, decoder), nil
+}
 
+// NewEventWatcherWithDecoder 创建使用指定解码器的事件监听器（可加载任意合约的ABI）
+func NewEventWatcherWithDecoder(client backend.Client, contractAddress common.Address, decoder *Decoder) *EventWatcher {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &EventWatcher{
 		client:          client,
 		contractAddress: contractAddress,
-		contractABI:     parsedABI,
-		logChan:         make(chan types.Log),
+		decoder:         decoder,
+		errs:            make(chan error, 1),
 		ctx:             ctx,
 		cancel:          cancel,
-	}, nil
+	}
+}
+
+// Decoder 返回监听器使用的解码器
+func (w *EventWatcher) Decoder() *Decoder {
+	return w.decoder
+}
+
+// OnEvent 设置事件回调，每条日志在打印解析结果后交给回调处理（需在StartWatching之前设置）
+func (w *EventWatcher) OnEvent(handler func(vLog types.Log)) {
+	w.handler = func(vLog types.Log) error {
+		handler(vLog)
+		return nil
+	}
+}
+
+// OnLog 设置可以失败的事件回调：返回错误时监听终止（错误由 Err 返回），检查点停留在上一条日志，
+// 重启后从这条日志重新投递
+func (w *EventWatcher) OnLog(handler func(vLog types.Log) error) {
+	w.handler = handler
+}
+
+// OnRevert 设置撤销回调：已投递的日志因链重组不再在主链上时调用，日志的 Removed 为 true，
+// 幂等键与投递时相同（需在StartWatching之前设置）
+func (w *EventWatcher) OnRevert(handler func(vLog types.Log)) {
+	w.revertHandler = handler
+}
+
+// UseStream 设置接收日志和区块头的方式（默认自动选择：WS/IPC订阅，HTTP节点使用过滤器或轮询），需在StartWatching之前设置
+func (w *EventWatcher) UseStream(opts event_stream.Options) {
+	w.streamOpts = opts
+}
+
+// UseReorgProtection 订阅新区块头，日志达到 opts.Confirmations 个确认后才投递，
+// 并用最近区块哈希的窗口发现重组：已投递的日志被撤销时通过 OnRevert 通知（需在StartWatching之前设置）
+func (w *EventWatcher) UseReorgProtection(opts ReorgOptions) {
+	w.guard = newReorgGuard(opts)
+}
+
+// UseCheckpoints 每条日志处理完成后把位置保存到 store，启动时从已保存的位置恢复（需在StartWatching之前设置）
+// 保存发生在回调返回之后，进程在两者之间退出时重启会再次投递这条日志（至少一次），回调可用 IdempotencyKey 去重
+func (w *EventWatcher) UseCheckpoints(store CheckpointStore) {
+	w.store = store
+}
+
+// CheckpointKey 监听器保存检查点使用的键
+func (w *EventWatcher) CheckpointKey() string {
+	return CheckpointKey(w.query())
+}
+
+// Checkpoint 最后处理完成的位置，还没有处理过日志时为nil
+func (w *EventWatcher) Checkpoint() *Checkpoint {
+	return w.checkpoint
+}
+
+// Err 监听因订阅错误或回填失败而终止时收到错误
+func (w *EventWatcher) Err() <-chan error {
+	return w.errs
 }
 
 // StartWatching 开始监听合约事件
 func (w *EventWatcher) StartWatching() error {
+	resumed, err := w.loadCheckpoint()
+	if err != nil {
+		return err
+	}
+	if resumed {
+		return w.startFrom(BackfillOptions{FromBlock: w.checkpoint.BlockNumber})
+	}
+
 	fmt.Printf("🔍 开始监听合约事件: %s\n", w.contractAddress.Hex())
 
-	// 创建过滤器查询，监听指定合约的所有事件
-	query := ethereum.FilterQuery{
-		Addresses: []common.Address{w.contractAddress},
+	if err := w.subscribe(); err != nil {
+		return err
+	}
+	fmt.Printf("✅ 事件订阅成功（%s），开始监听...\n", w.logs.Mode())
+
+	// 启动事件处理协程
+	go w.handleEvents(nil)
+
+	return nil
+}
+
+// StartWatchingFrom 先回填从 opts.FromBlock 到当前最新区块的历史事件，再无缝切换到实时订阅
+// 订阅在回填之前建立，回填期间收到的实时日志先缓存；回填完成后丢弃已由回填覆盖的区块中的日志，
+// 因此切换时既不会遗漏也不会重复。已有检查点时从检查点恢复，忽略 opts.FromBlock
+func (w *EventWatcher) StartWatchingFrom(opts BackfillOptions) error {
+	resumed, err := w.loadCheckpoint()
+	if err != nil {
+		return err
 	}
+	if resumed {
+		opts.FromBlock = w.checkpoint.BlockNumber
+	}
+	return w.startFrom(opts)
+}
 
-	// 订阅日志
-	sub, err := w.client.SubscribeFilterLogs(w.ctx, query, w.logChan)
+// loadCheckpoint 读取已保存的检查点，resumed 表示需要从检查点恢复
+func (w *EventWatcher) loadCheckpoint() (resumed bool, err error) {
+	if w.store == nil {
+		return false, nil
+	}
+	cp, err := w.store.Load(w.CheckpointKey())
 	if err != nil {
-		return fmt.Errorf("订阅日志失败: %v", err)
+		return false, fmt.Errorf("读取检查点失败: %v", err)
 	}
+	if cp == nil {
+		return false, nil
+	}
+	// 检查点所在区块已被重组替换时，分叉点可能在更早的区块，而检查点只记录了一个区块哈希，
+	// 因此回退一个重组窗口（UseReorgProtection 设置的窗口，默认 DefaultReorgWindow）重新处理，保证至少一次投递
+	if cp.BlockHash != (common.Hash{}) {
+		header, err := w.client.HeaderByNumber(w.ctx, new(big.Int).SetUint64(cp.BlockNumber))
+		if err != nil && !errors.Is(err, ethereum.NotFound) {
+			return false, fmt.Errorf("查询检查点区块 #%d 失败: %v", cp.BlockNumber, err)
+		}
+		if err != nil || header.Hash() != cp.BlockHash {
+			window := ReorgOptions{}.window()
+			if w.guard != nil {
+				window = w.guard.opts.window()
+			}
+			from := uint64(0)
+			if cp.BlockNumber > window {
+				from = cp.BlockNumber - window
+			}
+			fmt.Printf("↩️ 检查点区块 #%d (%s) 已不在主链上，回退到区块 #%d 重新处理\n", cp.BlockNumber, cp.BlockHash.Hex(), from)
+			before := checkpointBefore(from)
+			cp = &before
+		}
+	}
+	w.checkpoint = cp
+	fmt.Printf("📍 从检查点恢复: 区块 #%d，日志索引 %d\n", cp.BlockNumber, cp.LogIndex)
+	return true, nil
+}
 
-	w.subscription = sub
+// startFrom 回填并切换到实时订阅
+func (w *EventWatcher) startFrom(opts BackfillOptions) error {
+	fmt.Printf("🔍 开始监听合约事件: %s（从区块 #%d 回填）\n", w.contractAddress.Hex(), opts.FromBlock)
 
-	fmt.Println("✅ 事件订阅成功，开始监听...")
+	if err := w.subscribe(); err != nil {
+		return err
+	}
+	head, err := w.client.BlockNumber(w.ctx)
+	if err != nil {
+		w.closeStreams()
+		return fmt.Errorf("获取最新区块失败: %v", err)
+	}
+	if opts.OnProgress == nil {
+		opts.OnProgress = printProgress
+	}
 
-	// 启动事件处理协程
-	go w.handleEvents()
+	done := make(chan uint64, 1)
+	go w.handleEvents(done)
+	go func() {
+		err := Backfill(w.ctx, w.client, w.query(), head, opts, func(vLog types.Log) error {
+			if w.ctx.Err() != nil {
+				return w.ctx.Err()
+			}
+			return w.deliver(vLog)
+		})
+		if err != nil {
+			if w.ctx.Err() == nil {
+				w.fail(fmt.Errorf("回填历史事件失败: %w", err))
+			}
+			close(done)
+			return
+		}
+		fmt.Printf("✅ 历史事件回填完成（至区块 #%d），切换到实时订阅\n", head)
+		done <- head
+	}()
+	return nil
+}
+
+// query 监听的过滤条件：合约的全部事件
+func (w *EventWatcher) query() ethereum.FilterQuery {
+	return ethereum.FilterQuery{
+		Addresses: []common.Address{w.contractAddress},
+	}
+}
 
+// connectTimeout 启动监听时等待日志流连接的时间
+const connectTimeout = 30 * time.Second
+
+// subscribe 打开合约全部事件的日志流（启用防重组时还有区块头流），等待连接成功后返回
+// 节点不支持订阅时自动改用过滤器或轮询，断线后自动重连
+func (w *EventWatcher) subscribe() error {
+	w.logs = event_stream.SubscribeLogs(w.ctx, w.client, w.query(), w.streamOpts)
+	if w.guard != nil {
+		w.heads = event_stream.SubscribeHeads(w.ctx, w.client, w.streamOpts)
+	}
+	ctx, cancel := context.WithTimeout(w.ctx, connectTimeout)
+	defer cancel()
+	if err := w.logs.Wait(ctx); err != nil {
+		w.closeStreams()
+		return fmt.Errorf("订阅日志失败: %v", err)
+	}
+	if w.heads != nil {
+		if err := w.heads.Wait(ctx); err != nil {
+			w.closeStreams()
+			return fmt.Errorf("订阅新区块失败: %v", err)
+		}
+	}
 	return nil
 }
 
+// closeStreams 关闭日志流和区块头流
+func (w *EventWatcher) closeStreams() {
+	if w.logs != nil {
+		w.logs.Close()
+	}
+	if w.heads != nil {
+		w.heads.Close()
+	}
+}
+
 // handleEvents 处理接收到的事件
-func (w *EventWatcher) handleEvents() {
+// backfilled 不为nil时正在回填：实时日志先缓存，收到回填终点后只处理终点之后区块的日志（重组撤销的日志总是处理）；
+// 回填期间的新区块头直接丢弃，回填完成后用最新区块头核对
+func (w *EventWatcher) handleEvents(backfilled <-chan uint64) {
+	var pending []types.Log
+	var cutoff uint64
+	var heads <-chan *types.Header
+	var headErrs <-chan error
+	if w.heads != nil {
+		heads, headErrs = w.heads.Heads(), w.heads.Err()
+	}
 	for {
 		select {
-		case err := <-w.subscription.Err():
+		case err := <-w.logs.Err():
 			log.Printf("❌ 事件订阅错误: %v", err)
+			w.fail(fmt.Errorf("事件订阅错误: %w", err))
+			return
+
+		case err := <-headErrs:
+			log.Printf("❌ 区块头订阅错误: %v", err)
+			w.fail(fmt.Errorf("区块头订阅错误: %w", err))
 			return
 
-		case vLog := <-w.logChan:
-			w.processEvent(vLog)
+		case header := <-heads:
+			if backfilled != nil {
+				continue
+			}
+			if err := w.advance(header); err != nil {
+				w.fail(err)
+				return
+			}
+
+		case head, ok := <-backfilled:
+			if !ok {
+				return
+			}
+			backfilled, cutoff = nil, head
+			for _, vLog := range pending {
+				if vLog.Removed || vLog.BlockNumber > cutoff {
+					if err := w.deliver(vLog); err != nil {
+						w.fail(err)
+						return
+					}
+				}
+			}
+			pending = nil
+			if w.guard != nil {
+				header, err := w.client.HeaderByNumber(w.ctx, nil)
+				if err == nil {
+					err = w.advance(header)
+				}
+				if err != nil {
+					w.fail(err)
+					return
+				}
+			}
+
+		case vLog := <-w.logs.Logs():
+			if backfilled != nil {
+				pending = append(pending, vLog)
+				continue
+			}
+			if !vLog.Removed && vLog.BlockNumber <= cutoff {
+				continue
+			}
+			if err := w.deliver(vLog); err != nil {
+				w.fail(err)
+				return
+			}
 
 		case <-w.ctx.Done():
 			fmt.Println("🛑 事件监听已停止")
@@ -94,164 +348,146 @@ func (w *EventWatcher) handleEvents() {
 	}
 }
 
-// processEvent 处理单个事件
-func (w *EventWatcher) processEvent(vLog types.Log) {
-	fmt.Println("\n📧 收到新事件:")
-	fmt.Printf("   区块号: #%d\n", vLog.BlockNumber)
-	fmt.Printf("   交易哈希: %s\n", vLog.TxHash.Hex())
-	fmt.Printf("   合约地址: %s\n", vLog.Address.Hex())
-	fmt.Printf("   事件索引: %d\n", vLog.Index)
-
-	// 解析事件
-	if len(vLog.Topics) == 0 {
-		fmt.Println("   ⚠️ 事件没有主题")
-		return
+// deliver 处理一条收到的日志：被重组撤销的日志走撤销通知，检查点之前的日志直接跳过，
+// 启用防重组时先按确认数暂存
+func (w *EventWatcher) deliver(vLog types.Log) error {
+	if w.halted {
+		return nil
 	}
-
-	eventSig := vLog.Topics[0]
-	fmt.Printf("   事件签名: %s\n", eventSig.Hex())
-
-	// 根据事件签名解析不同类型的事件
-	switch eventSig {
-	case w.contractABI.Events["Transfer"].ID:
-		w.parseTransferEvent(vLog)
-	case w.contractABI.Events["Approval"].ID:
-		w.parseApprovalEvent(vLog)
-	case w.contractABI.Events["Paused"].ID:
-		w.parsePausedEvent(vLog)
-	case w.contractABI.Events["Unpaused"].ID:
-		w.parseUnpausedEvent(vLog)
-	case w.contractABI.Events["OwnershipTransferred"].ID:
-		w.parseOwnershipTransferredEvent(vLog)
-	default:
-		fmt.Printf("   ⚠️ 未知事件类型: %s\n", eventSig.Hex())
-		w.parseUnknownEvent(vLog)
+	if vLog.Removed {
+		if w.guard == nil || w.guard.removed(vLog) {
+			return w.revert(vLog)
+		}
+		return nil
 	}
+	if w.checkpoint != nil && w.checkpoint.Covers(vLog) {
+		return nil
+	}
+	if w.guard == nil {
+		return w.process(vLog)
+	}
+	for _, ready := range w.guard.add(vLog) {
+		if err := w.process(ready); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// parseTransferEvent 解析Transfer事件
-func (w *EventWatcher) parseTransferEvent(vLog types.Log) {
-	fmt.Println("   📤 Transfer 事件:")
-
-	type TransferEvent struct {
-		From  common.Address
-		To    common.Address
-		Value *big.Int
+// advance 处理新区块头：先通知撤销，再投递达到确认数的日志
+func (w *EventWatcher) advance(header *types.Header) error {
+	if w.halted {
+		return nil
 	}
-
-	var transferEvent TransferEvent
-	err := w.contractABI.UnpackIntoInterface(&transferEvent, "Transfer", vLog.Data)
+	reverted, ready, err := w.guard.advance(w.ctx, w.client, header)
 	if err != nil {
-		fmt.Printf("      ❌ 解析Transfer事件失败: %v\n", err)
-		return
+		return err
 	}
-
-	// Topics[1] = from, Topics[2] = to (indexed parameters)
-	if len(vLog.Topics) >= 3 {
-		transferEvent.From = common.HexToAddress(vLog.Topics[1].Hex())
-		transferEvent.To = common.HexToAddress(vLog.Topics[2].Hex())
+	for _, vLog := range reverted {
+		if err := w.revert(vLog); err != nil {
+			return err
+		}
 	}
-
-	fmt.Printf("      发送方: %s\n", transferEvent.From.Hex())
-	fmt.Printf("      接收方: %s\n", transferEvent.To.Hex())
-	fmt.Printf("      金额: %s\n", transferEvent.Value.String())
-
-	// 转换为可读的代币数量（假设18位小数）
-	tokenAmount := new(big.Float).Quo(new(big.Float).SetInt(transferEvent.Value), big.NewFloat(1e18))
-	fmt.Printf("      金额(代币): %s\n", tokenAmount.String())
+	for _, vLog := range ready {
+		if err := w.process(vLog); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// parseApprovalEvent 解析Approval事件
-func (w *EventWatcher) parseApprovalEvent(vLog types.Log) {
-	fmt.Println("   ✅ Approval 事件:")
-
-	type ApprovalEvent struct {
-		Owner   common.Address
-		Spender common.Address
-		Value   *big.Int
+// revert 通知日志被撤销；检查点已越过该日志时退回到它所在区块之前，重启后重新处理新链上的日志
+func (w *EventWatcher) revert(vLog types.Log) error {
+	fmt.Println("\n↩️ 日志被链重组撤销:")
+	fmt.Printf("   区块号: #%d (%s)\n", vLog.BlockNumber, vLog.BlockHash.Hex())
+	fmt.Printf("   交易哈希: %s\n", vLog.TxHash.Hex())
+	fmt.Printf("   幂等键: %s\n", IdempotencyKey(vLog))
+	if w.revertHandler != nil {
+		w.revertHandler(vLog)
 	}
-
-	var approvalEvent ApprovalEvent
-	err := w.contractABI.UnpackIntoInterface(&approvalEvent, "Approval", vLog.Data)
-	if err != nil {
-		fmt.Printf("      ❌ 解析Approval事件失败: %v\n", err)
-		return
+	if w.checkpoint == nil || !w.checkpoint.Covers(vLog) {
+		return nil
 	}
-
-	// Topics[1] = owner, Topics[2] = spender (indexed parameters)
-	if len(vLog.Topics) >= 3 {
-		approvalEvent.Owner = common.HexToAddress(vLog.Topics[1].Hex())
-		approvalEvent.Spender = common.HexToAddress(vLog.Topics[2].Hex())
+	cp := checkpointBefore(vLog.BlockNumber)
+	if w.store != nil {
+		if err := w.store.Save(w.CheckpointKey(), cp); err != nil {
+			w.halted = true
+			return err
+		}
 	}
-
-	fmt.Printf("      所有者: %s\n", approvalEvent.Owner.Hex())
-	fmt.Printf("      被授权者: %s\n", approvalEvent.Spender.Hex())
-	fmt.Printf("      授权金额: %s\n", approvalEvent.Value.String())
+	w.checkpoint = &cp
+	return nil
 }
 
-// parsePausedEvent 解析Paused事件
-func (w *EventWatcher) parsePausedEvent(vLog types.Log) {
-	fmt.Println("   ⏸️ Paused 事件:")
-
-	type PausedEvent struct {
-		Account common.Address
+// checkpointBefore 表示区块 number 之前的日志都已处理的检查点
+func checkpointBefore(number uint64) Checkpoint {
+	if number == 0 {
+		return Checkpoint{}
 	}
+	return Checkpoint{BlockNumber: number - 1, LogIndex: math.MaxUint32, UpdatedAt: time.Now().UTC()}
+}
 
-	var pausedEvent PausedEvent
-	err := w.contractABI.UnpackIntoInterface(&pausedEvent, "Paused", vLog.Data)
-	if err != nil {
-		fmt.Printf("      ❌ 解析Paused事件失败: %v\n", err)
-		return
+// process 打印事件并交给回调，回调成功后保存检查点
+func (w *EventWatcher) process(vLog types.Log) error {
+	w.processEvent(vLog)
+	if w.handler != nil {
+		if err := w.handler(vLog); err != nil {
+			w.halted = true
+			return fmt.Errorf("处理日志 %s 失败: %w", IdempotencyKey(vLog), err)
+		}
 	}
-
-	fmt.Printf("      暂停者: %s\n", pausedEvent.Account.Hex())
+	if w.store != nil {
+		cp := CheckpointAt(vLog)
+		if err := w.store.Save(w.CheckpointKey(), cp); err != nil {
+			w.halted = true
+			return err
+		}
+		w.checkpoint = &cp
+	}
+	return nil
 }
 
-// parseUnpausedEvent 解析Unpaused事件
-func (w *EventWatcher) parseUnpausedEvent(vLog types.Log) {
-	fmt.Println("   ▶️ Unpaused 事件:")
-
-	type UnpausedEvent struct {
-		Account common.Address
+// fail 报告导致监听终止的错误（只保留第一个）
+func (w *EventWatcher) fail(err error) {
+	select {
+	case w.errs <- err:
+	default:
 	}
+}
 
-	var unpausedEvent UnpausedEvent
-	err := w.contractABI.UnpackIntoInterface(&unpausedEvent, "Unpaused", vLog.Data)
-	if err != nil {
-		fmt.Printf("      ❌ 解析Unpaused事件失败: %v\n", err)
+// printProgress 默认的回填进度输出
+func printProgress(p BackfillProgress) {
+	if p.Retry {
+		fmt.Printf("⚠️ 区块 #%d-#%d 结果过多，分段缩小为 %d 个区块后重试\n", p.From, p.To, p.NextChunk)
 		return
 	}
-
-	fmt.Printf("      恢复者: %s\n", unpausedEvent.Account.Hex())
+	fmt.Printf("📦 回填区块 #%d-#%d: %d 条日志（累计 %d，终点 #%d，下一段 %d 个区块）\n", p.From, p.To, p.Logs, p.Total, p.Head, p.NextChunk)
 }
 
-// parseOwnershipTransferredEvent 解析OwnershipTransferred事件
-func (w *EventWatcher) parseOwnershipTransferredEvent(vLog types.Log) {
-	fmt.Println("   👑 OwnershipTransferred 事件:")
-
-	type OwnershipTransferredEvent struct {
-		PreviousOwner common.Address
-		NewOwner      common.Address
-	}
+// processEvent 处理单个事件
+func (w *EventWatcher) processEvent(vLog types.Log) {
+	fmt.Println("\n📧 收到新事件:")
+	fmt.Printf("   区块号: #%d\n", vLog.BlockNumber)
+	fmt.Printf("   交易哈希: %s\n", vLog.TxHash.Hex())
+	fmt.Printf("   合约地址: %s\n", vLog.Address.Hex())
+	fmt.Printf("   事件索引: %d\n", vLog.Index)
 
-	var ownershipEvent OwnershipTransferredEvent
-	err := w.contractABI.UnpackIntoInterface(&ownershipEvent, "OwnershipTransferred", vLog.Data)
+	event, err := w.decoder.Decode(vLog)
 	if err != nil {
-		fmt.Printf("      ❌ 解析OwnershipTransferred事件失败: %v\n", err)
+		fmt.Printf("   ⚠️ %v\n", err)
+		w.parseUnknownEvent(vLog)
 		return
 	}
-
-	// Topics[1] = previousOwner, Topics[2] = newOwner (indexed parameters)
-	if len(vLog.Topics) >= 3 {
-		ownershipEvent.PreviousOwner = common.HexToAddress(vLog.Topics[1].Hex())
-		ownershipEvent.NewOwner = common.HexToAddress(vLog.Topics[2].Hex())
+	fmt.Printf("   事件: %s（%s）\n", event.Signature, event.Source)
+	if event.Anonymous {
+		fmt.Println("   匿名事件")
+	}
+	for _, name := range event.Order {
+		fmt.Printf("      %s: %s\n", name, FormatValue(event.Args[name]))
 	}
-
-	fmt.Printf("      前任所有者: %s\n", ownershipEvent.PreviousOwner.Hex())
-	fmt.Printf("      新所有者: %s\n", ownershipEvent.NewOwner.Hex())
 }
 
-// parseUnknownEvent 解析未知事件
+// parseUnknownEvent 输出无法解码的事件
 func (w *EventWatcher) parseUnknownEvent(vLog types.Log) {
 	fmt.Println("   ❓ 未知事件:")
 	fmt.Printf("      主题数量: %d\n", len(vLog.Topics))
@@ -267,18 +503,15 @@ func (w *EventWatcher) parseUnknownEvent(vLog types.Log) {
 // Stop 停止事件监听
 func (w *EventWatcher) Stop() {
 	fmt.Println("🛑 正在停止事件监听...")
-	if w.subscription != nil {
-		w.subscription.Unsubscribe()
-	}
 	if w.cancel != nil {
 		w.cancel()
 	}
-	close(w.logChan)
+	w.closeStreams()
 	fmt.Println("✅ 事件监听已停止")
 }
 
 // WatchContractEvents 监听合约事件的便捷函数
-func WatchContractEvents(client *ethclient.Client, contractAddress common.Address) (*EventWatcher, error) {
+func WatchContractEvents(client backend.Client, contractAddress common.Address) (*EventWatcher, error) {
 	watcher, err := NewEventWatcher(client, contractAddress)
 	if err != nil {
 		return nil, fmt.Errorf("创建事件监听器失败: %v", err)
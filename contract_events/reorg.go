@@ -0,0 +1,231 @@
+package contract_events
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"ethclient_tutorial/backend"
+)
+
+// DefaultReorgWindow 默认保留最近多少个区块的哈希
+const DefaultReorgWindow = 64
+
+// ReorgOptions 防重组投递参数
+type ReorgOptions struct {
+	Confirmations uint64 // 日志所在区块达到该确认数（所在区块本身算1个）后才投递，0 和 1 表示收到即投递
+	Window        uint64 // 保留最近多少个区块的哈希用于发现重组，0 使用 DefaultReorgWindow，不小于 Confirmations
+}
+
+func (o ReorgOptions) window() uint64 {
+	window := o.Window
+	if window == 0 {
+		window = DefaultReorgWindow
+	}
+	return max(window, o.Confirmations)
+}
+
+// reorgGuard 按确认数暂存日志，并用最近区块哈希的窗口发现重组
+// 每个新区块头到来时沿 ParentHash 回溯到与窗口一致的区块，替换分叉后的哈希；
+// 已投递但所在区块已不在主链上的日志需要撤销，暂存中的这类日志直接丢弃。
+// 不依赖订阅推送的 Removed 日志，因此轮询时同样适用
+type reorgGuard struct {
+	opts      ReorgOptions
+	hashes    map[uint64]common.Hash // 窗口内的主链区块哈希
+	head      uint64
+	held      []types.Log            // 等待确认的日志，按区块和索引排序
+	delivered map[uint64][]types.Log // 窗口内已投递的日志
+	reverted  map[string]uint64      // 已撤销日志的幂等键 → 区块号，避免重复撤销
+}
+
+func newReorgGuard(opts ReorgOptions) *reorgGuard {
+	return &reorgGuard{
+		opts:      opts,
+		hashes:    make(map[uint64]common.Hash),
+		delivered: make(map[uint64][]types.Log),
+		reverted:  make(map[string]uint64),
+	}
+}
+
+// confirmed 日志在当前区块头下是否已达到确认数
+func (g *reorgGuard) confirmed(vLog types.Log) bool {
+	if g.opts.Confirmations <= 1 {
+		return true
+	}
+	return g.head >= vLog.BlockNumber && g.head-vLog.BlockNumber+1 >= g.opts.Confirmations
+}
+
+// canonical 日志所在区块是否与窗口中的主链哈希一致（窗口中没有该区块时视为一致）
+func (g *reorgGuard) canonical(vLog types.Log) bool {
+	if g.head > 0 && vLog.BlockNumber > g.head {
+		return true // 比当前区块头更新，等区块头到来后再核对
+	}
+	hash, ok := g.hashes[vLog.BlockNumber]
+	return !ok || hash == vLog.BlockHash
+}
+
+// add 收到新日志，返回可以立即投递的日志；重组后节点重新推送的相同日志（幂等键相同）不再重复投递
+func (g *reorgGuard) add(vLog types.Log) []types.Log {
+	key := IdempotencyKey(vLog)
+	for _, seen := range g.delivered[vLog.BlockNumber] {
+		if IdempotencyKey(seen) == key {
+			return nil
+		}
+	}
+	for _, seen := range g.held {
+		if IdempotencyKey(seen) == key {
+			return nil
+		}
+	}
+	delete(g.reverted, key)
+	if g.confirmed(vLog) && g.canonical(vLog) {
+		g.delivered[vLog.BlockNumber] = append(g.delivered[vLog.BlockNumber], vLog)
+		return []types.Log{vLog}
+	}
+	g.held = append(g.held, vLog)
+	return nil
+}
+
+// removed 订阅推送了被重组撤销的日志，返回是否需要通知撤销
+// 还在暂存中的日志直接丢弃；已经通过区块哈希窗口撤销过的日志不再重复通知；
+// 不在窗口中的日志（如上次运行时投递的）无法确认是否投递过，按已投递处理
+func (g *reorgGuard) removed(vLog types.Log) bool {
+	key := IdempotencyKey(vLog)
+	for i, held := range g.held {
+		if IdempotencyKey(held) == key {
+			g.held = append(g.held[:i], g.held[i+1:]...)
+			return false
+		}
+	}
+	if _, ok := g.reverted[key]; ok {
+		return false
+	}
+	logs := g.delivered[vLog.BlockNumber]
+	for i, delivered := range logs {
+		if IdempotencyKey(delivered) == key {
+			g.delivered[vLog.BlockNumber] = append(logs[:i], logs[i+1:]...)
+			break
+		}
+	}
+	g.reverted[key] = vLog.BlockNumber
+	return true
+}
+
+// advance 处理新的区块头：更新哈希窗口，返回需要撤销的已投递日志和达到确认数的日志
+func (g *reorgGuard) advance(ctx context.Context, client backend.Client, header *types.Header) (reverted, ready []types.Log, err error) {
+	number := header.Number.Uint64()
+	window := g.opts.window()
+
+	// 沿父哈希回溯，直到与窗口中记录的哈希一致或超出窗口；
+	// 第一个区块头时窗口为空，回溯整个窗口，之前收到的暂存日志和回填日志同样要核对区块哈希
+	lowest, tracked := g.lowest()
+	fresh := map[uint64]common.Hash{number: header.Hash()}
+	parent := header.ParentHash
+	for n := number; n > 0 && (!tracked || n-1 >= lowest) && number-(n-1) <= window; n-- {
+		if known, ok := g.hashes[n-1]; ok && known == parent {
+			break
+		}
+		fresh[n-1] = parent
+		h, err := client.HeaderByHash(ctx, parent)
+		if err != nil {
+			return nil, nil, fmt.Errorf("获取区块 #%d 失败: %v", n-1, err)
+		}
+		parent = h.ParentHash
+	}
+	for n := range g.hashes {
+		if n > number {
+			delete(g.hashes, n) // 新链更短，原来更高的区块都已不在主链上
+		}
+	}
+	for n, hash := range fresh {
+		g.hashes[n] = hash
+	}
+	g.head = number
+
+	// 已投递的日志所在区块不在主链上时撤销
+	for n, logs := range g.delivered {
+		kept := logs[:0]
+		for _, vLog := range logs {
+			if g.canonical(vLog) && vLog.BlockNumber <= number {
+				kept = append(kept, vLog)
+				continue
+			}
+			if _, ok := g.reverted[IdempotencyKey(vLog)]; !ok {
+				g.reverted[IdempotencyKey(vLog)] = vLog.BlockNumber
+				vLog.Removed = true
+				reverted = append(reverted, vLog)
+			}
+		}
+		if len(kept) == 0 {
+			delete(g.delivered, n)
+		} else {
+			g.delivered[n] = kept
+		}
+	}
+	sortLogs(reverted)
+
+	// 暂存的日志：分叉上的丢弃，达到确认数的投递
+	var held []types.Log
+	for _, vLog := range g.held {
+		switch {
+		case vLog.BlockNumber <= number && !g.canonical(vLog):
+		case g.confirmed(vLog):
+			g.delivered[vLog.BlockNumber] = append(g.delivered[vLog.BlockNumber], vLog)
+			ready = append(ready, vLog)
+		default:
+			held = append(held, vLog)
+		}
+	}
+	g.held = held
+
+	g.prune(number, window)
+	return reverted, ready, nil
+}
+
+// lowest 窗口中最低的区块号
+func (g *reorgGuard) lowest() (uint64, bool) {
+	var lowest uint64
+	tracked := false
+	for n := range g.hashes {
+		if !tracked || n < lowest {
+			lowest, tracked = n, true
+		}
+	}
+	return lowest, tracked
+}
+
+// prune 丢弃窗口之外的记录
+func (g *reorgGuard) prune(head, window uint64) {
+	if head < window {
+		return
+	}
+	floor := head - window
+	for n := range g.hashes {
+		if n < floor {
+			delete(g.hashes, n)
+		}
+	}
+	for n := range g.delivered {
+		if n < floor {
+			delete(g.delivered, n)
+		}
+	}
+	for key, n := range g.reverted {
+		if n < floor {
+			delete(g.reverted, key)
+		}
+	}
+}
+
+// sortLogs 按区块和索引排序
+func sortLogs(logs []types.Log) {
+	sort.Slice(logs, func(i, j int) bool {
+		if logs[i].BlockNumber != logs[j].BlockNumber {
+			return logs[i].BlockNumber < logs[j].BlockNumber
+		}
+		return logs[i].Index < logs[j].Index
+	})
+}
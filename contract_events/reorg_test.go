@@ -0,0 +1,266 @@
+package contract_events
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/backend/backendtest"
+	"ethclient_tutorial/event_stream"
+)
+
+// recorder 记录监听器投递和撤销的日志
+type recorder struct {
+	mu        sync.Mutex
+	delivered []types.Log
+	reverted  []types.Log
+}
+
+func (r *recorder) watch(t *testing.T, chain *backendtest.TokenChain, opts ReorgOptions) *EventWatcher {
+	t.Helper()
+	w, err := NewEventWatcher(chain.Sim, chain.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.UseReorgProtection(opts)
+	w.OnEvent(func(vLog types.Log) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.delivered = append(r.delivered, vLog)
+	})
+	w.OnRevert(func(vLog types.Log) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.reverted = append(r.reverted, vLog)
+	})
+	if err := w.StartWatchingFrom(BackfillOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(w.Stop)
+	return w
+}
+
+func (r *recorder) snapshot() (delivered, reverted []types.Log) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]types.Log(nil), r.delivered...), append([]types.Log(nil), r.reverted...)
+}
+
+// eventually 每10毫秒检查一次 cond，5秒内不成立时测试失败
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// counts 已投递和已撤销的日志数是否分别为 delivered、reverted
+func (r *recorder) counts(delivered, reverted int) func() bool {
+	return func() bool {
+		d, rv := r.snapshot()
+		return len(d) == delivered && len(rv) == reverted
+	}
+}
+
+func TestReorgRevertsDeliveredLogsAndHoldsUnconfirmed(t *testing.T) {
+	chain := backendtest.NewTokenChain(t, 2)
+	ctx := context.Background()
+	// 从创世区块回填，部署区块的2条日志也会投递；分叉后节点重新推送这些日志时不应重复投递
+	immediate, confirmed := new(recorder), new(recorder)
+	immediate.watch(t, chain, ReorgOptions{})
+	confirmed.watch(t, chain, ReorgOptions{Confirmations: 3})
+	eventually(t, "deploy logs", immediate.counts(2, 0))
+
+	parent, err := chain.Sim.HeaderByNumber(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain.Mine(t, 1)
+	eventually(t, "transfer log before reorg", immediate.counts(3, 0))
+	delivered, _ := immediate.snapshot()
+	orphaned := delivered[2]
+	if delivered, _ := confirmed.snapshot(); len(delivered) != 0 {
+		t.Fatalf("confirmed delivered %d logs with 2 confirmations, want 0", len(delivered))
+	}
+
+	// 从转账所在区块的父区块分叉出更长的链，转账回到交易池后在新链上重新打包
+	if err := chain.Sim.Fork(parent.Hash()); err != nil {
+		t.Fatal(err)
+	}
+	chain.Mine(t, 0, 0)
+	eventually(t, "revert and re-delivery", immediate.counts(4, 1))
+	chain.Mine(t, 0, 0, 0)
+	eventually(t, "confirmed logs", confirmed.counts(3, 0))
+
+	delivered, reverted := immediate.snapshot()
+	if len(reverted) != 1 || IdempotencyKey(reverted[0]) != IdempotencyKey(orphaned) || !reverted[0].Removed {
+		t.Fatalf("immediate reverted %+v, want the orphaned log once", reverted)
+	}
+	if len(delivered) != 4 || delivered[3].TxHash != orphaned.TxHash || delivered[3].BlockHash == orphaned.BlockHash {
+		t.Fatalf("immediate delivered %d logs, want deploy logs, orphaned and re-included", len(delivered))
+	}
+	reincluded := delivered[3]
+
+	// 等待确认的监听器从未投递分叉上的日志，只投递新链上达到确认数的日志
+	delivered, reverted = confirmed.snapshot()
+	if len(reverted) != 0 || len(delivered) != 3 || IdempotencyKey(delivered[2]) != IdempotencyKey(reincluded) {
+		t.Fatalf("confirmed delivered %d, reverted %d", len(delivered), len(reverted))
+	}
+	checkOrdered(t, delivered)
+}
+
+func TestReorgGuardDetectsForkFromHeaders(t *testing.T) {
+	chain := backendtest.NewTokenChain(t, 2)
+	ctx := context.Background()
+	guard := newReorgGuard(ReorgOptions{Confirmations: 2, Window: 8})
+	advance := func() (reverted, ready []types.Log) {
+		t.Helper()
+		header, err := chain.Sim.HeaderByNumber(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reverted, ready, err = guard.advance(ctx, chain.Sim, header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return reverted, ready
+	}
+
+	// 只用区块头（轮询时没有 Removed 日志）
+	advance()
+	parent, _ := chain.Sim.HeaderByNumber(ctx, nil)
+	chain.Mine(t, 1, 0)
+	logs, err := chain.Sim.FilterLogs(ctx, ethereum.FilterQuery{Addresses: []common.Address{chain.Address}, FromBlock: new(big.Int).Add(parent.Number, big.NewInt(1))})
+	if err != nil || len(logs) != 1 {
+		t.Fatalf("logs = %d, %v", len(logs), err)
+	}
+	if ready := guard.add(logs[0]); len(ready) != 0 {
+		t.Fatal("log delivered before head was known")
+	}
+	if _, ready := advance(); len(ready) != 1 {
+		t.Fatalf("ready = %d, want 1 with 2 confirmations", len(ready))
+	}
+	if ready := guard.add(logs[0]); len(ready) != 0 {
+		t.Fatal("duplicate log delivered twice")
+	}
+
+	// 新链比原链多一个区块，跳过中间区块头也能沿父哈希发现分叉
+	if err := chain.Sim.Fork(parent.Hash()); err != nil {
+		t.Fatal(err)
+	}
+	chain.Mine(t, 0, 0, 0)
+	reverted, _ := advance()
+	if len(reverted) != 1 || !reverted[0].Removed || IdempotencyKey(reverted[0]) != IdempotencyKey(logs[0]) {
+		t.Fatalf("reverted = %+v", reverted)
+	}
+	if reverted, _ := advance(); len(reverted) != 0 {
+		t.Fatal("log reverted twice")
+	}
+	if guard.removed(logs[0]) {
+		t.Fatal("subscription removal reported after header-based revert")
+	}
+}
+
+func TestReorgGuardChecksLogsHeldBeforeFirstHeader(t *testing.T) {
+	chain := backendtest.NewTokenChain(t, 2)
+	ctx := context.Background()
+	parent, _ := chain.Sim.HeaderByNumber(ctx, nil)
+	chain.Mine(t, 1)
+	stale, err := chain.Sim.FilterLogs(ctx, ethereum.FilterQuery{Addresses: []common.Address{chain.Address}, FromBlock: new(big.Int).Add(parent.Number, big.NewInt(1))})
+	if err != nil || len(stale) != 1 {
+		t.Fatalf("logs = %d, %v", len(stale), err)
+	}
+	if err := chain.Sim.Fork(parent.Hash()); err != nil {
+		t.Fatal(err)
+	}
+	chain.Mine(t, 0, 0, 0)
+
+	// 回填得到的日志所在区块在第一个区块头到来之前已被替换，不能因为窗口为空而投递
+	guard := newReorgGuard(ReorgOptions{Confirmations: 2, Window: 8})
+	if ready := guard.add(stale[0]); len(ready) != 0 {
+		t.Fatal("log delivered before head was known")
+	}
+	header, err := chain.Sim.HeaderByNumber(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ready, err := guard.advance(ctx, chain.Sim, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ready) != 0 {
+		t.Fatalf("delivered log from replaced block #%d", ready[0].BlockNumber)
+	}
+}
+
+// pollingClient 模拟只有HTTP的节点：不支持订阅，监听器只能轮询
+type pollingClient struct {
+	backend.Client
+}
+
+func (pollingClient) SubscribeFilterLogs(context.Context, ethereum.FilterQuery, chan<- types.Log) (ethereum.Subscription, error) {
+	return nil, rpc.ErrNotificationsUnsupported
+}
+
+func (pollingClient) SubscribeNewHead(context.Context, chan<- *types.Header) (ethereum.Subscription, error) {
+	return nil, rpc.ErrNotificationsUnsupported
+}
+
+func TestReorgDetectedWhilePolling(t *testing.T) {
+	chain := backendtest.NewTokenChain(t, 2)
+	ctx := context.Background()
+	w, err := NewEventWatcher(pollingClient{chain.Sim}, chain.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.UseStream(event_stream.Options{PollInterval: 50 * time.Millisecond})
+	w.UseReorgProtection(ReorgOptions{})
+	rec := new(recorder)
+	w.OnEvent(func(vLog types.Log) {
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		rec.delivered = append(rec.delivered, vLog)
+	})
+	w.OnRevert(func(vLog types.Log) {
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		rec.reverted = append(rec.reverted, vLog)
+	})
+	if err := w.StartWatching(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+	if mode := w.logs.Mode(); mode != event_stream.ModePoll {
+		t.Fatalf("mode = %s, want poll", mode)
+	}
+
+	parent, _ := chain.Sim.HeaderByNumber(ctx, nil)
+	chain.Mine(t, 1)
+	eventually(t, "polled transfer log", rec.counts(1, 0))
+	if err := chain.Sim.Fork(parent.Hash()); err != nil {
+		t.Fatal(err)
+	}
+	chain.Mine(t, 0, 0)
+	eventually(t, "revert detected by polling", rec.counts(2, 1))
+
+	// 轮询没有 Removed 日志，撤销通知来自区块哈希窗口
+	delivered, reverted := rec.snapshot()
+	if len(delivered) != 2 || len(reverted) != 1 {
+		t.Fatalf("delivered %d, reverted %d", len(delivered), len(reverted))
+	}
+	if IdempotencyKey(reverted[0]) != IdempotencyKey(delivered[0]) || delivered[1].TxHash != delivered[0].TxHash || delivered[1].BlockHash == delivered[0].BlockHash {
+		t.Fatalf("reverted %s, delivered %s then %s", IdempotencyKey(reverted[0]), IdempotencyKey(delivered[0]), IdempotencyKey(delivered[1]))
+	}
+}
@@ -0,0 +1,137 @@
+package contracts
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// MyTokenABIPath 编译产物中MyToken ABI文件的默认路径
+const MyTokenABIPath = "contracts/compiled/MyToken.abi"
+
+// ErrNotABI 文件既不是ABI数组，也不是带 abi 字段的编译产物
+var ErrNotABI = errors.New("不是ABI文件")
+
+// errNotABIArray JSON数组无法解析为ABI（如数据文件），LoadABIs 加载目录时跳过并给出警告
+var errNotABIArray = fmt.Errorf("%w: JSON数组不是合法的ABI", ErrNotABI)
+
+// LoadMyTokenABI 从ABI文件加载MyToken的ABI
+// 文件不存在时回退到绑定代码中内嵌的ABI，保证在没有编译产物的环境（如测试）中也能工作
+func LoadMyTokenABI(path string) (abi.ABI, error) {
+	abiData, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			parsed, err := MYERC20MetaData.GetAbi()
+			if err != nil {
+				return abi.ABI{}, err
+			}
+			return *parsed, nil
+		}
+		return abi.ABI{}, err
+	}
+	return abi.JSON(strings.NewReader(string(abiData)))
+}
+
+// NamedABI 带名称的ABI，名称取编译产物中的 contractName，没有时取文件名
+type NamedABI struct {
+	Name string
+	ABI  abi.ABI
+}
+
+// artifact Hardhat/Foundry编译产物中用到的字段
+type artifact struct {
+	ContractName string          `json:"contractName"`
+	ABI          json.RawMessage `json:"abi"`
+}
+
+// LoadABIFile 加载ABI文件：纯ABI数组（.abi/.json），或Hardhat/Foundry编译产物（顶层 abi 字段）
+func LoadABIFile(path string) (NamedABI, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return NamedABI{}, err
+	}
+	named := NamedABI{Name: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))}
+	data = bytes.TrimSpace(data)
+	isArtifact := len(data) > 0 && data[0] == '{'
+	if isArtifact {
+		var a artifact
+		if err := json.Unmarshal(data, &a); err != nil {
+			return NamedABI{}, fmt.Errorf("解析 %s 失败: %v", path, err)
+		}
+		if len(a.ABI) == 0 {
+			return NamedABI{}, fmt.Errorf("%w: %s", ErrNotABI, path)
+		}
+		if a.ContractName != "" {
+			named.Name = a.ContractName
+		}
+		data = a.ABI
+	} else if len(data) == 0 || data[0] != '[' {
+		return NamedABI{}, fmt.Errorf("%w: %s", ErrNotABI, path)
+	}
+	named.ABI, err = abi.JSON(bytes.NewReader(data))
+	if err != nil && !isArtifact {
+		return NamedABI{}, fmt.Errorf("%w: %s（%v）", errNotABIArray, path, err)
+	}
+	if err != nil {
+		return NamedABI{}, fmt.Errorf("解析 %s 中的ABI失败: %v", path, err)
+	}
+	return named, nil
+}
+
+// LoadABIs 加载文件或目录中的ABI
+// 目录会递归查找 .abi 和 .json 文件（如Hardhat的 artifacts/、Foundry的 out/），按路径排序；
+// 不是ABI的JSON文件（如 .dbg.json、build-info）会被跳过，无法解析为ABI的JSON数组跳过时输出警告
+func LoadABIs(path string) ([]NamedABI, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		named, err := LoadABIFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return []NamedABI{named}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ext := filepath.Ext(file); !entry.IsDir() && (ext == ".abi" || ext == ".json") {
+			files = append(files, file)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	var loaded []NamedABI
+	for _, file := range files {
+		named, err := LoadABIFile(file)
+		if errors.Is(err, errNotABIArray) {
+			log.Printf("Warning: 跳过 %v", err)
+			continue
+		}
+		if errors.Is(err, ErrNotABI) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		loaded = append(loaded, named)
+	}
+	return loaded, nil
+}
@@ -0,0 +1,227 @@
+package e2e
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"ethclient_tutorial/amount"
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/backend/backendtest"
+	"ethclient_tutorial/config"
+	"ethclient_tutorial/contract_deployment"
+	"ethclient_tutorial/contract_events"
+	"ethclient_tutorial/contracts"
+	"ethclient_tutorial/eth_transfer"
+	"ethclient_tutorial/revert_decoder"
+	"ethclient_tutorial/signer"
+	"ethclient_tutorial/token_balance"
+	"ethclient_tutorial/token_transfer"
+	"ethclient_tutorial/tx_replacement"
+	"ethclient_tutorial/utils"
+)
+
+func TestEndToEndOnSimulatedBackend(t *testing.T) {
+	sim := backendtest.NewAutoMining(t, 2)
+	owner, recipient := sim.Accounts[0], sim.Accounts[1]
+	cfg := &config.Config{DefaultGasLimit: 21000, GasPriceMultiplier: 1.1}
+
+	// 1. 部署MyToken，初始代币铸造给owner
+	tokenAddress, _, err := contract_deployment.DeployContract(sim, owner.KeyHex(), owner.Address)
+	if err != nil {
+		t.Fatalf("部署合约失败: %v", err)
+	}
+
+	info, err := token_balance.GetTokenInfo(sim, tokenAddress)
+	if err != nil {
+		t.Fatalf("查询代币信息失败: %v", err)
+	}
+	if info.Symbol != "MTK" || info.Decimals != 18 {
+		t.Fatalf("代币信息不符: %+v", info)
+	}
+
+	// 2. 启动事件监听
+	watcher, err := contract_events.NewEventWatcher(sim, tokenAddress)
+	if err != nil {
+		t.Fatalf("创建事件监听器失败: %v", err)
+	}
+	events := make(chan types.Log, 16)
+	watcher.OnEvent(func(vLog types.Log) { events <- vLog })
+	if err := watcher.StartWatching(); err != nil {
+		t.Fatalf("启动事件监听失败: %v", err)
+	}
+	defer watcher.Stop()
+
+	// 3. ETH转账（金额精确到最低位的wei）
+	ethBefore, err := sim.BalanceAt(context.Background(), recipient.Address, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txHash, err := eth_transfer.TransferETHWithConfig(sim, owner.KeyHex(), recipient.Address, amount.MustParse("1.500000000000000001"), cfg)
+	if err != nil {
+		t.Fatalf("ETH转账失败: %v", err)
+	}
+	if _, err := utils.WaitForTransactionQuick(sim, txHash); err != nil {
+		t.Fatalf("等待ETH转账失败: %v", err)
+	}
+	ethAfter, err := sim.BalanceAt(context.Background(), recipient.Address, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantDelta := new(big.Int).Add(new(big.Int).Mul(big.NewInt(15), big.NewInt(1e17)), big.NewInt(1))
+	if got := new(big.Int).Sub(ethAfter, ethBefore); got.Cmp(wantDelta) != 0 {
+		t.Fatalf("ETH到账金额错误: got %s, want %s", got, wantDelta)
+	}
+
+	// 4. 三种方式的ERC20转账
+	if _, err := token_transfer.TransferERC20WithAmount(sim, owner.KeyHex(), recipient.Address, tokenAddress, amount.MustParse("10"), 18); err != nil {
+		t.Fatalf("手动构造ERC20转账失败: %v", err)
+	}
+	if _, err := token_transfer.TransferERC20WithABI(sim, owner.KeyHex(), recipient.Address, tokenAddress, amount.MustParse("15")); err != nil {
+		t.Fatalf("ABI绑定ERC20转账失败: %v", err)
+	}
+	if _, err := token_transfer.TransferERC20WithABIFile(sim, owner.KeyHex(), recipient.Address, tokenAddress, amount.MustParse("20")); err != nil {
+		t.Fatalf("ABI文件ERC20转账失败: %v", err)
+	}
+
+	balance, err := token_balance.GetTokenBalance(sim, tokenAddress, recipient.Address)
+	if err != nil {
+		t.Fatalf("查询代币余额失败: %v", err)
+	}
+	wantBalance := new(big.Int).Mul(big.NewInt(45), big.NewInt(1e18))
+	if balance.Cmp(wantBalance) != 0 {
+		t.Fatalf("代币余额错误: got %s, want %s", balance, wantBalance)
+	}
+
+	// 5. 事件监听应收到三条Transfer事件
+	parsedABI, err := contracts.MYERC20MetaData.GetAbi()
+	if err != nil {
+		t.Fatal(err)
+	}
+	transferID := parsedABI.Events["Transfer"].ID
+	for i := 0; i < 3; i++ {
+		select {
+		case vLog := <-events:
+			if vLog.Topics[0] != transferID {
+				t.Fatalf("收到非Transfer事件: %s", vLog.Topics[0].Hex())
+			}
+			if common.BytesToAddress(vLog.Topics[2].Bytes()) != recipient.Address {
+				t.Fatalf("Transfer事件接收方错误: %s", vLog.Topics[2].Hex())
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("等待第%d条Transfer事件超时", i+1)
+		}
+	}
+}
+
+func TestConcurrentTransfersFromSameKey(t *testing.T) {
+	sim := backendtest.NewAutoMining(t, 2)
+	owner, recipient := sim.Accounts[0], sim.Accounts[1]
+	cfg := &config.Config{DefaultGasLimit: 21000, GasPriceMultiplier: 1.1}
+
+	const n = 5
+	hashes := make(chan common.Hash, n)
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			txHash, err := eth_transfer.TransferETHWithConfig(sim, owner.KeyHex(), recipient.Address, amount.MustParse("0.01"), cfg)
+			if err != nil {
+				errs <- err
+				return
+			}
+			hashes <- txHash
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		select {
+		case err := <-errs:
+			t.Fatalf("并发转账失败: %v", err)
+		case txHash := <-hashes:
+			status, err := utils.WaitForTransactionQuick(sim, txHash)
+			if err != nil || !status.Success {
+				t.Fatalf("交易 %s 未成功: %v", txHash.Hex(), err)
+			}
+		}
+	}
+
+	nonce, err := sim.NonceAt(context.Background(), owner.Address, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nonce != n {
+		t.Fatalf("确认的nonce = %d, want %d", nonce, n)
+	}
+}
+
+func TestAutoBumpReplacesStuckTransfer(t *testing.T) {
+	sim := backendtest.NewAutoMining(t, 2)
+	owner, recipient := sim.Accounts[0], sim.Accounts[1]
+
+	// 费用上限低于基础费用的交易会一直停留在交易池中
+	header, err := sim.HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chainID := big.NewInt(backend.SimulatedChainID)
+	stuck, err := types.SignNewTx(owner.Key, types.LatestSignerForChainID(chainID), &types.DynamicFeeTx{
+		ChainID:   chainID,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: new(big.Int).Div(header.BaseFee, big.NewInt(10)),
+		Gas:       21000,
+		To:        &recipient.Address,
+		Value:     big.NewInt(1e18),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sim.SendTransaction(context.Background(), stuck); err != nil {
+		t.Fatal(err)
+	}
+
+	policy := &tx_replacement.AutoBumpPolicy{Signer: signer.NewKeySigner(owner.Key), Interval: 300 * time.Millisecond}
+	status, err := utils.WaitForTransactionWithAutoBump(sim, stuck.Hash(), 1, 10*time.Second, policy)
+	if err != nil {
+		t.Fatalf("等待加价交易失败: %v", err)
+	}
+	if !status.Success {
+		t.Fatal("加价替换交易执行失败")
+	}
+	if status.TxHash == stuck.Hash() {
+		t.Fatal("应返回加价替换交易的哈希")
+	}
+}
+
+func TestFailedTransferReportsDecodedRevert(t *testing.T) {
+	sim := backendtest.NewAutoMining(t, 2)
+	owner, recipient := sim.Accounts[0], sim.Accounts[1]
+
+	tokenAddress, _, err := contract_deployment.DeployContract(sim, owner.KeyHex(), owner.Address)
+	if err != nil {
+		t.Fatalf("部署合约失败: %v", err)
+	}
+
+	// recipient 没有代币，转账在估算Gas时就会回滚
+	for _, transfer := range []func() (common.Hash, error){
+		func() (common.Hash, error) {
+			return token_transfer.TransferERC20WithABI(sim, recipient.KeyHex(), owner.Address, tokenAddress, amount.MustParse("1"))
+		},
+		func() (common.Hash, error) {
+			return token_transfer.TransferERC20WithABIFile(sim, recipient.KeyHex(), owner.Address, tokenAddress, amount.MustParse("1"))
+		},
+	} {
+		_, err := transfer()
+		var revertErr *revert_decoder.RevertError
+		if !errors.As(err, &revertErr) {
+			t.Fatalf("err = %v, want decoded revert", err)
+		}
+		if revertErr.Name != "ERC20InsufficientBalance" {
+			t.Fatalf("回滚原因 = %s, want ERC20InsufficientBalance", revertErr.Name)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package erc20
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/signer"
+)
+
+// ERC1363ABI ERC-1363 扩展接口（转账/授权后回调接收方）及ERC-165查询
+// 只包含带data参数的重载，不需要附加数据时传空data即可
+const ERC1363ABI = `[
+	{"type":"function","name":"transferAndCall","stateMutability":"nonpayable","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"},{"name":"data","type":"bytes"}],"outputs":[{"name":"","type":"bool"}]},
+	{"type":"function","name":"transferFromAndCall","stateMutability":"nonpayable","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"value","type":"uint256"},{"name":"data","type":"bytes"}],"outputs":[{"name":"","type":"bool"}]},
+	{"type":"function","name":"approveAndCall","stateMutability":"nonpayable","inputs":[{"name":"spender","type":"address"},{"name":"value","type":"uint256"},{"name":"data","type":"bytes"}],"outputs":[{"name":"","type":"bool"}]},
+	{"type":"function","name":"supportsInterface","stateMutability":"view","inputs":[{"name":"interfaceId","type":"bytes4"}],"outputs":[{"name":"","type":"bool"}]}
+]`
+
+// erc1363ABI ERC-1363 扩展接口
+var erc1363ABI = mustParseABI(ERC1363ABI)
+
+// ERC-165 接口ID
+var (
+	InterfaceERC165          = [4]byte{0x01, 0xff, 0xc9, 0xa7}
+	InterfaceERC1363         = [4]byte{0xb0, 0x20, 0x2a, 0x11}
+	InterfaceERC1363Receiver = [4]byte{0x88, 0xa7, 0xca, 0x5c} // onTransferReceived
+	InterfaceERC1363Spender  = [4]byte{0x7b, 0x04, 0xa2, 0xd0} // onApprovalReceived
+)
+
+// SupportsInterface 按ERC-165检查合约是否实现了接口：
+// 先确认合约支持ERC-165（对 0x01ffc9a7 返回true、对 0xffffffff 返回false），再查询id。
+// 普通账户、未实现、调用回滚（包括Gas耗尽）或返回数据不足都视为不支持，网络或节点错误原样返回
+func SupportsInterface(ctx context.Context, client backend.Client, contract common.Address, id [4]byte) (bool, error) {
+	code, err := client.CodeAt(ctx, contract, nil)
+	if err != nil {
+		return false, err
+	}
+	if len(code) == 0 {
+		return false, nil
+	}
+	for _, check := range []struct {
+		id   [4]byte
+		want bool
+	}{{InterfaceERC165, true}, {[4]byte{0xff, 0xff, 0xff, 0xff}, false}, {id, true}} {
+		data, err := erc1363ABI.Pack("supportsInterface", check.id)
+		if err != nil {
+			return false, err
+		}
+		// ERC-165 要求查询最多使用30000 Gas
+		result, err := client.CallContract(ctx, ethereum.CallMsg{To: &contract, Gas: 30000, Data: data}, nil)
+		if err != nil {
+			if isExecutionFailure(err) {
+				return false, nil
+			}
+			return false, fmt.Errorf("查询supportsInterface失败: %w", err)
+		}
+		if len(result) < 32 {
+			return false, nil
+		}
+		if (new(big.Int).SetBytes(result[:32]).Sign() != 0) != check.want {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// SupportsERC1363 代币是否实现了ERC-1363
+func (t *Token) SupportsERC1363(ctx context.Context) (bool, error) {
+	return SupportsInterface(ctx, t.client, t.Address, InterfaceERC1363)
+}
+
+// TransferAndCallWithSigner 转账后回调接收方的 onTransferReceived，接收方拒绝时整笔交易回滚
+func (t *Token) TransferAndCallWithSigner(ctx context.Context, s signer.Signer, to common.Address, value *big.Int, data []byte) (*types.Transaction, error) {
+	return t.transact(ctx, s, erc1363ABI, "transferAndCall", to, value, data)
+}
+
+// TransferFromAndCallWithSigner 使用授权额度从from转账给to，并回调to的 onTransferReceived
+func (t *Token) TransferFromAndCallWithSigner(ctx context.Context, s signer.Signer, from, to common.Address, value *big.Int, data []byte) (*types.Transaction, error) {
+	return t.transact(ctx, s, erc1363ABI, "transferFromAndCall", from, to, value, data)
+}
+
+// ApproveAndCallWithSigner 授权后回调spender的 onApprovalReceived
+func (t *Token) ApproveAndCallWithSigner(ctx context.Context, s signer.Signer, spender common.Address, value *big.Int, data []byte) (*types.Transaction, error) {
+	return t.transact(ctx, s, erc1363ABI, "approveAndCall", spender, value, data)
+}
+
+// TransferOrCall 代币和接收方都支持ERC-1363时使用 transferAndCall，否则退回普通 transfer（忽略data）
+// called 表示是否使用了 transferAndCall
+func (t *Token) TransferOrCall(ctx context.Context, s signer.Signer, to common.Address, value *big.Int, data []byte) (tx *types.Transaction, called bool, err error) {
+	called, err = t.canCall(ctx, to, InterfaceERC1363Receiver)
+	if err != nil {
+		return nil, false, err
+	}
+	if called {
+		tx, err = t.TransferAndCallWithSigner(ctx, s, to, value, data)
+	} else {
+		tx, err = t.TransferWithSigner(ctx, s, to, value)
+	}
+	return tx, called, err
+}
+
+// ApproveOrCall 代币和spender都支持ERC-1363时使用 approveAndCall，否则退回普通 approve（忽略data）
+func (t *Token) ApproveOrCall(ctx context.Context, s signer.Signer, spender common.Address, value *big.Int, data []byte) (tx *types.Transaction, called bool, err error) {
+	called, err = t.canCall(ctx, spender, InterfaceERC1363Spender)
+	if err != nil {
+		return nil, false, err
+	}
+	if called {
+		tx, err = t.ApproveAndCallWithSigner(ctx, s, spender, value, data)
+	} else {
+		tx, err = t.ApproveWithSigner(ctx, s, spender, value)
+	}
+	return tx, called, err
+}
+
+// canCall 代币支持ERC-1363且target声明实现了回调接口
+func (t *Token) canCall(ctx context.Context, target common.Address, callback [4]byte) (bool, error) {
+	supported, err := t.SupportsERC1363(ctx)
+	if err != nil || !supported {
+		return false, err
+	}
+	return SupportsInterface(ctx, t.client, target, callback)
+}
@@ -0,0 +1,126 @@
+package erc20
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"ethclient_tutorial/backend/backendtest"
+	"ethclient_tutorial/revert_decoder"
+	"ethclient_tutorial/signer"
+)
+
+// acceptingReceiver 手写的ERC-1363接收方合约（部署代码）：
+// supportsInterface 对 IERC165、IERC1363Receiver、IERC1363Spender 返回true，
+// onTransferReceived/onApprovalReceived 返回自身的函数选择器表示接受
+//
+//	PUSH1 0 CALLDATALOAD PUSH1 0xe0 SHR
+//	DUP1 PUSH4 supportsInterface() EQ PUSH1 @supports JUMPI
+//	DUP1 PUSH4 onTransferReceived() EQ PUSH1 @callback JUMPI
+//	DUP1 PUSH4 onApprovalReceived() EQ PUSH1 @callback JUMPI
+//	PUSH1 0 DUP1 REVERT
+//	@supports: JUMPDEST POP PUSH1 4 CALLDATALOAD PUSH1 0xe0 SHR
+//	           DUP1 PUSH4 0x01ffc9a7 EQ DUP2 PUSH4 0x88a7ca5c EQ OR SWAP1 PUSH4 0x7b04a2d0 EQ OR
+//	           PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+//	@callback: JUMPDEST PUSH1 0xe0 SHL PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+const acceptingReceiver = "0x605b80600b6000396000f360003560e01c806301ffc9a714602857806388a7ca5c14604f5780637b04a2d014604f57600080fd5b5060043560e01c806301ffc9a714816388a7ca5c141790637b04a2d0141760005260206000f35b60e01b60005260206000f3"
+
+// rejectingReceiver 与 acceptingReceiver 相同，但回调以 Error("rejected") 回滚
+//
+//	@callback: JUMPDEST PUSH4 Error(string) PUSH1 0xe0 SHL PUSH1 0 MSTORE
+//	           PUSH1 0x20 PUSH1 4 MSTORE PUSH1 8 PUSH1 0x24 MSTORE PUSH32 "rejected" PUSH1 0x44 MSTORE
+//	           PUSH1 0x64 PUSH1 0 REVERT
+const rejectingReceiver = "0x608e80600b6000396000f360003560e01c806301ffc9a714602857806388a7ca5c14604f5780637b04a2d014604f57600080fd5b5060043560e01c806301ffc9a714816388a7ca5c141790637b04a2d0141760005260206000f35b6308c379a060e01b600052602060045260086024527f72656a656374656400000000000000000000000000000000000000000000000060445260646000fd"
+
+func TestERC1363CallsSupportingReceivers(t *testing.T) {
+	sim := backendtest.New(t, 3)
+	ctx := context.Background()
+	owner, spender := sim.Accounts[0], sim.Accounts[1]
+	address, _ := backendtest.DeployMyToken(t, sim)
+	token := New(sim, address)
+	receiver := deployRaw(t, sim, acceptingReceiver)
+	plain := deployRaw(t, sim, nonCompliantToken)
+	s := signer.NewKeySigner(owner.Key)
+
+	if supported, err := token.SupportsERC1363(ctx); err != nil || !supported {
+		t.Fatalf("MyToken ERC-1363 support = %v, %v", supported, err)
+	}
+	if supported, _ := SupportsInterface(ctx, sim, receiver, InterfaceERC1363); supported {
+		t.Fatal("receiver should not claim IERC1363")
+	}
+
+	// 接收方是合约且声明了回调接口：使用 transferAndCall
+	tx, called, err := token.TransferOrCall(ctx, s, receiver, big.NewInt(100), []byte("order-42"))
+	mined(t, sim, tx, err)
+	if !called {
+		t.Fatal("transferAndCall not used for a supporting receiver")
+	}
+	// 普通账户：退回普通 transfer
+	tx, called, err = token.TransferOrCall(ctx, s, spender.Address, big.NewInt(50), nil)
+	mined(t, sim, tx, err)
+	if called {
+		t.Fatal("transferAndCall used for an EOA")
+	}
+	// 没有实现ERC-165的合约同样退回普通 transfer
+	tx, called, err = token.TransferOrCall(ctx, s, plain, big.NewInt(1), nil)
+	mined(t, sim, tx, err)
+	if called {
+		t.Fatal("transferAndCall used for a contract without ERC-165")
+	}
+	if balance, _ := token.BalanceOf(ctx, receiver); balance.Int64() != 100 {
+		t.Fatalf("receiver balance = %s", balance)
+	}
+
+	tx, called, err = token.ApproveOrCall(ctx, s, receiver, big.NewInt(30), nil)
+	mined(t, sim, tx, err)
+	if !called {
+		t.Fatal("approveAndCall not used for a supporting spender")
+	}
+
+	// transferFromAndCall：spender用授权额度转给接收方合约
+	tx, err = token.ApproveWithSigner(ctx, s, spender.Address, big.NewInt(20))
+	mined(t, sim, tx, err)
+	tx, err = token.TransferFromAndCallWithSigner(ctx, signer.NewKeySigner(spender.Key), owner.Address, receiver, big.NewInt(20), nil)
+	mined(t, sim, tx, err)
+	if balance, _ := token.BalanceOf(ctx, receiver); balance.Int64() != 120 {
+		t.Fatalf("receiver balance after transferFromAndCall = %s", balance)
+	}
+}
+
+func TestERC1363ReceiverRejectionIsDecoded(t *testing.T) {
+	sim := backendtest.New(t, 3)
+	ctx := context.Background()
+	address, _ := backendtest.DeployMyToken(t, sim)
+	token := New(sim, address)
+	receiver := deployRaw(t, sim, rejectingReceiver)
+	s := signer.NewKeySigner(sim.Accounts[0].Key)
+
+	var revertErr *revert_decoder.RevertError
+	_, called, err := token.TransferOrCall(ctx, s, receiver, big.NewInt(1), nil)
+	if !called || !errors.As(err, &revertErr) || revertErr.Name != "Error" || revertErr.Args[0].Value != "rejected" {
+		t.Fatalf("rejected transfer: called = %v, err = %v", called, err)
+	}
+
+	// 普通账户不能作为 transferAndCall 的接收方
+	_, err = token.TransferAndCallWithSigner(ctx, s, sim.Accounts[1].Address, big.NewInt(1), nil)
+	if !errors.As(err, &revertErr) || revertErr.Name != "ERC1363InvalidReceiver" {
+		t.Fatalf("transferAndCall to EOA err = %v", err)
+	}
+}
+
+func TestSupportsInterfaceReturnsCallErrors(t *testing.T) {
+	sim := backendtest.New(t, 3)
+	ctx := context.Background()
+	address, _ := backendtest.DeployMyToken(t, sim)
+	plain := deployRaw(t, sim, nonCompliantToken)
+
+	// 网络错误不能当作“不支持”，否则 TransferOrCall 会悄悄退回普通 transfer
+	if supported, err := SupportsInterface(ctx, failingCalls{sim}, address, InterfaceERC1363); err == nil {
+		t.Fatalf("supported = %v, want error", supported)
+	}
+	// 没有实现ERC-165的合约回滚，视为不支持
+	if supported, err := SupportsInterface(ctx, sim, plain, InterfaceERC1363); err != nil || supported {
+		t.Fatalf("contract without ERC-165 = %v, %v", supported, err)
+	}
+}
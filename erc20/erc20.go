@@ -0,0 +1,352 @@
+package erc20
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/fee_strategy"
+	"ethclient_tutorial/revert_decoder"
+	"ethclient_tutorial/signer"
+	"ethclient_tutorial/tx_builder"
+)
+
+// StandardABI EIP-20 标准接口
+const StandardABI = `[
+	{"type":"function","name":"name","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"string"}]},
+	{"type":"function","name":"symbol","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"string"}]},
+	{"type":"function","name":"decimals","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint8"}]},
+	{"type":"function","name":"totalSupply","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"balanceOf","stateMutability":"view","inputs":[{"name":"account","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"allowance","stateMutability":"view","inputs":[{"name":"owner","type":"address"},{"name":"spender","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"transfer","stateMutability":"nonpayable","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]},
+	{"type":"function","name":"approve","stateMutability":"nonpayable","inputs":[{"name":"spender","type":"address"},{"name":"value","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]},
+	{"type":"function","name":"transferFrom","stateMutability":"nonpayable","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"value","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]},
+	{"type":"event","name":"Transfer","anonymous":false,"inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"value","type":"uint256","indexed":false}]},
+	{"type":"event","name":"Approval","anonymous":false,"inputs":[{"name":"owner","type":"address","indexed":true},{"name":"spender","type":"address","indexed":true},{"name":"value","type":"uint256","indexed":false}]}
+]`
+
+// standardABI EIP-20 标准接口，扩展接口各自使用单独的ABI
+var standardABI = mustParseABI(StandardABI)
+
+// mustParseABI 解析包内的ABI常量，失败时panic
+func mustParseABI(definition string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(definition))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+// ErrReturnedFalse 代币的transfer/approve/transferFrom返回了false（操作未生效但没有回滚）
+var ErrReturnedFalse = errors.New("代币合约返回false")
+
+// Metadata 代币元数据
+type Metadata struct {
+	Address     common.Address
+	ChainID     *big.Int
+	Name        string
+	Symbol      string
+	Decimals    uint8
+	HasDecimals bool // 合约未实现decimals()时为false，此时Decimals为0，数量按最小单位处理
+}
+
+type cacheKey struct {
+	chainID string
+	address common.Address
+}
+
+var (
+	metadataMu    sync.Mutex
+	metadataCache = make(map[cacheKey]*Metadata)
+)
+
+// Token 与任意标准ERC20代币交互的客户端
+// 兼容常见的非标准实现：name/symbol返回bytes32、transfer等不返回值（如USDT）、未实现decimals
+type Token struct {
+	Address  common.Address
+	Strategy fee_strategy.Strategy // 发送交易时的费用策略，nil 时使用全局配置
+
+	client  backend.Client
+	chainMu sync.Mutex // 保护 chainID：同一个Token可能被多个goroutine共享（事件监听、推送等）
+	chainID *big.Int
+}
+
+// New 创建代币客户端
+func New(client backend.Client, tokenAddress common.Address) *Token {
+	return &Token{Address: tokenAddress, client: client}
+}
+
+// ChainID 查询并缓存链ID（查询失败时不缓存，下次重试）
+func (t *Token) ChainID(ctx context.Context) (*big.Int, error) {
+	t.chainMu.Lock()
+	defer t.chainMu.Unlock()
+	if t.chainID == nil {
+		chainID, err := t.client.ChainID(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("获取链ID失败: %v", err)
+		}
+		t.chainID = chainID
+	}
+	return t.chainID, nil
+}
+
+// Metadata 查询代币名称、符号和精度，结果按链ID和代币地址缓存；网络或节点错误直接返回，不会缓存不完整的结果
+func (t *Token) Metadata(ctx context.Context) (*Metadata, error) {
+	chainID, err := t.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key := cacheKey{chainID: chainID.String(), address: t.Address}
+
+	metadataMu.Lock()
+	cached, ok := metadataCache[key]
+	metadataMu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	code, err := t.client.CodeAt(ctx, t.Address, nil)
+	if err != nil {
+		return nil, fmt.Errorf("查询合约代码失败: %v", err)
+	}
+	if len(code) == 0 {
+		return nil, fmt.Errorf("地址 %s 上没有合约", t.Address.Hex())
+	}
+
+	meta := &Metadata{Address: t.Address, ChainID: chainID}
+	if meta.Name, err = t.stringOrBytes32(ctx, "name"); err != nil {
+		return nil, err
+	}
+	if meta.Symbol, err = t.stringOrBytes32(ctx, "symbol"); err != nil {
+		return nil, err
+	}
+	// 只有回滚或没有返回数据才表示未实现decimals()；网络错误不能当作精度为0，否则金额会被错误换算
+	result, err := t.call(ctx, standardABI, "decimals")
+	if err != nil && !isExecutionFailure(err) {
+		return nil, err
+	}
+	if err == nil && len(result) >= 32 {
+		decimals := new(big.Int).SetBytes(result[:32])
+		if decimals.IsUint64() && decimals.Uint64() <= 255 {
+			meta.Decimals = uint8(decimals.Uint64())
+			meta.HasDecimals = true
+		}
+	}
+
+	metadataMu.Lock()
+	metadataCache[key] = meta
+	metadataMu.Unlock()
+	return meta, nil
+}
+
+// Decimals 代币精度（未实现decimals()的代币返回0）
+func (t *Token) Decimals(ctx context.Context) (uint8, error) {
+	meta, err := t.Metadata(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return meta.Decimals, nil
+}
+
+// TotalSupply 查询总供应量
+func (t *Token) TotalSupply(ctx context.Context) (*big.Int, error) {
+	return t.callUint256(ctx, standardABI, "totalSupply")
+}
+
+// TotalSupplyAt 查询指定区块时的总供应量
+func (t *Token) TotalSupplyAt(ctx context.Context, block *big.Int) (*big.Int, error) {
+	return t.callUint256At(ctx, block, standardABI, "totalSupply")
+}
+
+// BalanceOf 查询余额
+func (t *Token) BalanceOf(ctx context.Context, account common.Address) (*big.Int, error) {
+	return t.callUint256(ctx, standardABI, "balanceOf", account)
+}
+
+// BalanceAt 查询指定区块时的余额
+func (t *Token) BalanceAt(ctx context.Context, account common.Address, block *big.Int) (*big.Int, error) {
+	return t.callUint256At(ctx, block, standardABI, "balanceOf", account)
+}
+
+// Allowance 查询owner授权给spender的额度
+func (t *Token) Allowance(ctx context.Context, owner, spender common.Address) (*big.Int, error) {
+	return t.callUint256(ctx, standardABI, "allowance", owner, spender)
+}
+
+// AllowanceAt 查询指定区块时owner授权给spender的额度
+func (t *Token) AllowanceAt(ctx context.Context, owner, spender common.Address, block *big.Int) (*big.Int, error) {
+	return t.callUint256At(ctx, block, standardABI, "allowance", owner, spender)
+}
+
+// Transfer 转账
+func (t *Token) Transfer(ctx context.Context, key *ecdsa.PrivateKey, to common.Address, value *big.Int) (*types.Transaction, error) {
+	return t.TransferWithSigner(ctx, signer.NewKeySigner(key), to, value)
+}
+
+// Approve 授权spender使用value数量的代币
+func (t *Token) Approve(ctx context.Context, key *ecdsa.PrivateKey, spender common.Address, value *big.Int) (*types.Transaction, error) {
+	return t.ApproveWithSigner(ctx, signer.NewKeySigner(key), spender, value)
+}
+
+// TransferFrom 使用授权额度从from转账给to（key为被授权的spender）
+func (t *Token) TransferFrom(ctx context.Context, key *ecdsa.PrivateKey, from, to common.Address, value *big.Int) (*types.Transaction, error) {
+	return t.TransferFromWithSigner(ctx, signer.NewKeySigner(key), from, to, value)
+}
+
+// TransferWithSigner 使用签名器转账
+func (t *Token) TransferWithSigner(ctx context.Context, s signer.Signer, to common.Address, value *big.Int) (*types.Transaction, error) {
+	return t.transact(ctx, s, standardABI, "transfer", to, value)
+}
+
+// ApproveWithSigner 使用签名器授权
+func (t *Token) ApproveWithSigner(ctx context.Context, s signer.Signer, spender common.Address, value *big.Int) (*types.Transaction, error) {
+	return t.transact(ctx, s, standardABI, "approve", spender, value)
+}
+
+// TransferFromWithSigner 使用签名器（被授权的spender）从from转账给to
+func (t *Token) TransferFromWithSigner(ctx context.Context, s signer.Signer, from, to common.Address, value *big.Int) (*types.Transaction, error) {
+	return t.transact(ctx, s, standardABI, "transferFrom", from, to, value)
+}
+
+// call 按contractABI调用只读方法，返回原始结果
+func (t *Token) call(ctx context.Context, contractABI abi.ABI, method string, args ...interface{}) ([]byte, error) {
+	return t.callAt(ctx, nil, contractABI, method, args...)
+}
+
+// callAt 在指定区块（nil为最新区块）调用只读方法
+func (t *Token) callAt(ctx context.Context, block *big.Int, contractABI abi.ABI, method string, args ...interface{}) ([]byte, error) {
+	data, err := contractABI.Pack(method, args...)
+	if err != nil {
+		return nil, fmt.Errorf("构造%s调用数据失败: %v", method, err)
+	}
+	result, err := t.client.CallContract(ctx, ethereum.CallMsg{To: &t.Address, Data: data}, block)
+	if err != nil {
+		return nil, fmt.Errorf("调用%s失败: %w", method, err)
+	}
+	return result, nil
+}
+
+// isExecutionFailure 错误是否来自合约执行本身（回滚、Gas耗尽、无效指令），而不是网络或节点故障
+func isExecutionFailure(err error) bool {
+	if _, ok := revert_decoder.Default().FromError(err); ok {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "out of gas") || strings.Contains(msg, "invalid opcode")
+}
+
+// callUint256 调用返回uint256的只读方法
+func (t *Token) callUint256(ctx context.Context, contractABI abi.ABI, method string, args ...interface{}) (*big.Int, error) {
+	return t.callUint256At(ctx, nil, contractABI, method, args...)
+}
+
+// callUint256At 在指定区块调用返回uint256的只读方法
+func (t *Token) callUint256At(ctx context.Context, block *big.Int, contractABI abi.ABI, method string, args ...interface{}) (*big.Int, error) {
+	result, err := t.callAt(ctx, block, contractABI, method, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) < 32 {
+		return nil, fmt.Errorf("%s返回数据长度异常: %d", method, len(result))
+	}
+	return new(big.Int).SetBytes(result[:32]), nil
+}
+
+// stringOrBytes32 查询name/symbol，兼容返回string和bytes32两种实现（如MKR）
+// 合约未实现该方法（调用回滚或没有返回数据）时返回空字符串，其他调用错误原样返回
+func (t *Token) stringOrBytes32(ctx context.Context, method string) (string, error) {
+	result, err := t.call(ctx, standardABI, method)
+	if err != nil && !isExecutionFailure(err) {
+		return "", err
+	}
+	if err != nil || len(result) == 0 {
+		return "", nil
+	}
+	if len(result) == 32 {
+		return string(bytes.TrimRight(result, "\x00")), nil
+	}
+	values, err := standardABI.Unpack(method, result)
+	if err != nil {
+		return "", fmt.Errorf("解析%s失败: %v", method, err)
+	}
+	return values[0].(string), nil
+}
+
+// BuildTransfer 构造未签名的transfer交易（已模拟执行、估算Gas并检查花费上限），Nonce由调用方填写
+func (t *Token) BuildTransfer(ctx context.Context, from, to common.Address, value *big.Int) (*types.DynamicFeeTx, error) {
+	return t.build(ctx, from, standardABI, "transfer", to, value)
+}
+
+// BuildApprove 构造未签名的approve交易，Nonce由调用方填写
+func (t *Token) BuildApprove(ctx context.Context, from, spender common.Address, value *big.Int) (*types.DynamicFeeTx, error) {
+	return t.build(ctx, from, standardABI, "approve", spender, value)
+}
+
+// Call 解码后的ERC20写操作调用
+type Call struct {
+	Method string         // transfer、approve 或 transferFrom
+	From   common.Address // 仅 transferFrom 有效
+	To     common.Address // 接收方（approve 为被授权地址）
+	Value  *big.Int
+}
+
+// DecodeCall 解码transfer、approve或transferFrom的调用数据
+func DecodeCall(data []byte) (*Call, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("调用数据过短")
+	}
+	method, err := standardABI.MethodById(data[:4])
+	if err != nil {
+		return nil, fmt.Errorf("不是ERC20调用: %v", err)
+	}
+	args, err := method.Inputs.Unpack(data[4:])
+	if err != nil {
+		return nil, fmt.Errorf("解码%s参数失败: %v", method.Name, err)
+	}
+	switch method.Name {
+	case "transfer", "approve":
+		return &Call{Method: method.Name, To: args[0].(common.Address), Value: args[1].(*big.Int)}, nil
+	case "transferFrom":
+		return &Call{Method: method.Name, From: args[0].(common.Address), To: args[1].(common.Address), Value: args[2].(*big.Int)}, nil
+	default:
+		return nil, fmt.Errorf("%s 不是ERC20写操作", method.Name)
+	}
+}
+
+// build 模拟执行、估算Gas并按费用策略构造未签名的写操作交易，调用数据按contractABI编码
+// 模拟执行的返回值为空（USDT等不返回bool的实现）视为成功，返回false时报错
+func (t *Token) build(ctx context.Context, from common.Address, contractABI abi.ABI, method string, args ...interface{}) (*types.DynamicFeeTx, error) {
+	data, err := contractABI.Pack(method, args...)
+	if err != nil {
+		return nil, fmt.Errorf("构造%s调用数据失败: %v", method, err)
+	}
+	tx, result, err := tx_builder.Build(ctx, t.client, t.Strategy, ethereum.CallMsg{From: from, To: &t.Address, Data: data}, method)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) >= 32 && new(big.Int).SetBytes(result[:32]).Sign() == 0 {
+		return nil, fmt.Errorf("%s: %w", method, ErrReturnedFalse)
+	}
+	return tx, nil
+}
+
+// transact 构造、签名并发送写操作交易
+func (t *Token) transact(ctx context.Context, s signer.Signer, contractABI abi.ABI, method string, args ...interface{}) (*types.Transaction, error) {
+	tx, err := t.build(ctx, s.Address(), contractABI, method, args...)
+	if err != nil {
+		return nil, err
+	}
+	return tx_builder.Send(ctx, t.client, s, tx, method)
+}
@@ -0,0 +1,223 @@
+package erc20
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/backend/backendtest"
+	"ethclient_tutorial/revert_decoder"
+)
+
+// nonCompliantToken 手写的非标准代币合约（部署代码）：
+// name()/symbol() 返回bytes32（"Maker"/"MKR"），decimals() 回滚，其他任何调用都成功且不返回数据
+//
+//	PUSH1 0 CALLDATALOAD PUSH1 0xe0 SHR
+//	DUP1 PUSH4 name() EQ PUSH1 @name JUMPI
+//	DUP1 PUSH4 symbol() EQ PUSH1 @symbol JUMPI
+//	DUP1 PUSH4 decimals() EQ PUSH1 @revert JUMPI
+//	STOP
+//	@name:   JUMPDEST PUSH32 "Maker" PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+//	@symbol: JUMPDEST PUSH32 "MKR" PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+//	@revert: JUMPDEST PUSH1 0 DUP1 REVERT
+const nonCompliantToken = "0x607e80600b6000396000f360003560e01c806306fdde0314602557806395d89b4114604f578063313ce56714607957005b7f4d616b657200000000000000000000000000000000000000000000000000000060005260206000f35b7f4d4b52000000000000000000000000000000000000000000000000000000000060005260206000f35b600080fd"
+
+func deployRaw(t *testing.T, sim *backend.Simulated, code string) common.Address {
+	t.Helper()
+	chainID := big.NewInt(backend.SimulatedChainID)
+	nonce, _ := sim.PendingNonceAt(context.Background(), sim.Accounts[0].Address)
+	tx, err := types.SignNewTx(sim.Accounts[0].Key, types.LatestSignerForChainID(chainID), &types.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     nonce,
+		GasTipCap: big.NewInt(1e9),
+		GasFeeCap: big.NewInt(10e9),
+		Gas:       200000,
+		Data:      common.FromHex(code),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sim.SendTransaction(context.Background(), tx); err != nil {
+		t.Fatal(err)
+	}
+	sim.Commit()
+	receipt, err := sim.TransactionReceipt(context.Background(), tx.Hash())
+	if err != nil || receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("deploy failed: %v", err)
+	}
+	return receipt.ContractAddress
+}
+
+// mined 出块并确认交易执行成功
+func mined(t *testing.T, sim *backend.Simulated, tx *types.Transaction, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim.Commit()
+	receipt, err := sim.TransactionReceipt(context.Background(), tx.Hash())
+	if err != nil || receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("transaction %s failed: %v", tx.Hash().Hex(), err)
+	}
+}
+
+func TestStandardTokenOperations(t *testing.T) {
+	sim := backendtest.New(t, 3)
+	ctx := context.Background()
+	owner, spender, recipient := sim.Accounts[0], sim.Accounts[1], sim.Accounts[2]
+	address, _ := backendtest.DeployMyToken(t, sim)
+	token := New(sim, address)
+
+	meta, err := token.Metadata(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Name != "MyToken" || meta.Symbol != "MTK" || meta.Decimals != 18 || !meta.HasDecimals {
+		t.Fatalf("metadata = %+v", meta)
+	}
+
+	tx, err := token.Transfer(ctx, owner.Key, recipient.Address, big.NewInt(100))
+	mined(t, sim, tx, err)
+	tx, err = token.Approve(ctx, owner.Key, spender.Address, big.NewInt(50))
+	mined(t, sim, tx, err)
+
+	allowance, err := token.Allowance(ctx, owner.Address, spender.Address)
+	if err != nil || allowance.Int64() != 50 {
+		t.Fatalf("allowance = %v, %v", allowance, err)
+	}
+
+	tx, err = token.TransferFrom(ctx, spender.Key, owner.Address, recipient.Address, big.NewInt(30))
+	mined(t, sim, tx, err)
+
+	balance, err := token.BalanceOf(ctx, recipient.Address)
+	if err != nil || balance.Int64() != 130 {
+		t.Fatalf("balance = %v, %v, want 130", balance, err)
+	}
+	allowance, _ = token.Allowance(ctx, owner.Address, spender.Address)
+	if allowance.Int64() != 20 {
+		t.Fatalf("allowance after transferFrom = %s, want 20", allowance)
+	}
+}
+
+func TestTransferFromBeyondAllowanceIsDecoded(t *testing.T) {
+	sim := backendtest.New(t, 3)
+	address, _ := backendtest.DeployMyToken(t, sim)
+	token := New(sim, address)
+
+	_, err := token.TransferFrom(context.Background(), sim.Accounts[1].Key, sim.Accounts[0].Address, sim.Accounts[2].Address, big.NewInt(1))
+	revertErr, ok := err.(*revert_decoder.RevertError)
+	if !ok || revertErr.Name != "ERC20InsufficientAllowance" {
+		t.Fatalf("err = %v, want ERC20InsufficientAllowance", err)
+	}
+}
+
+func TestNonCompliantToken(t *testing.T) {
+	sim := backendtest.New(t, 3)
+	ctx := context.Background()
+	token := New(sim, deployRaw(t, sim, nonCompliantToken))
+
+	meta, err := token.Metadata(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Name != "Maker" || meta.Symbol != "MKR" {
+		t.Fatalf("bytes32 metadata = %q/%q", meta.Name, meta.Symbol)
+	}
+	if meta.HasDecimals || meta.Decimals != 0 {
+		t.Fatalf("missing decimals reported as %d", meta.Decimals)
+	}
+
+	// transfer 不返回任何数据也应视为成功
+	tx, err := token.Transfer(ctx, sim.Accounts[0].Key, sim.Accounts[1].Address, big.NewInt(1))
+	mined(t, sim, tx, err)
+}
+
+func TestMetadataIsCachedPerChain(t *testing.T) {
+	sim := backendtest.New(t, 3)
+	address, _ := backendtest.DeployMyToken(t, sim)
+
+	first, err := New(sim, address).Metadata(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := New(sim, address).Metadata(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Fatal("metadata for the same token and chain should come from cache")
+	}
+}
+
+// failingCalls 只读调用返回网络错误的节点
+type failingCalls struct {
+	*backend.Simulated
+}
+
+func (failingCalls) CallContract(context.Context, ethereum.CallMsg, *big.Int) ([]byte, error) {
+	return nil, errors.New("dial tcp 127.0.0.1:8545: connect: connection refused")
+}
+
+func TestMetadataCallErrorIsNotCached(t *testing.T) {
+	sim := backendtest.New(t, 3)
+	address, _ := backendtest.DeployMyToken(t, sim)
+
+	// 网络错误不能当作“未实现decimals()”，否则精度按0换算金额
+	if meta, err := New(failingCalls{sim}, address).Metadata(context.Background()); err == nil {
+		t.Fatalf("metadata = %+v, want error", meta)
+	}
+	meta, err := New(sim, address).Metadata(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !meta.HasDecimals || meta.Decimals != 18 || meta.Symbol != "MTK" {
+		t.Fatalf("metadata after failed call = %+v", meta)
+	}
+}
+
+func TestSharedTokenResolvesChainIDOnce(t *testing.T) {
+	sim := backendtest.New(t, 1)
+	token := New(sim, common.Address{})
+
+	// 事件监听和推送会在多个goroutine中共享同一个Token
+	var wg sync.WaitGroup
+	ids := make([]*big.Int, 8)
+	for i := range ids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ids[i], _ = token.ChainID(context.Background())
+		}(i)
+	}
+	wg.Wait()
+	for _, id := range ids {
+		if id != ids[0] || id.Int64() != backend.SimulatedChainID {
+			t.Fatalf("chain IDs = %v, want one cached %d", ids, backend.SimulatedChainID)
+		}
+	}
+}
+
+func TestStandardABIExcludesExtensions(t *testing.T) {
+	for _, extension := range []abi.ABI{permitABI, erc1363ABI} {
+		for name := range extension.Methods {
+			if _, ok := standardABI.Methods[name]; ok {
+				t.Fatalf("standard ABI contains extension method %s", name)
+			}
+		}
+	}
+	nonces, _ := permitABI.Pack("nonces", common.Address{1})
+	transferAndCall, _ := erc1363ABI.Pack("transferAndCall", common.Address{1}, big.NewInt(1), []byte{})
+	for _, data := range [][]byte{nonces, transferAndCall} {
+		if _, err := DecodeCall(data); err == nil {
+			t.Fatalf("DecodeCall accepted extension call %x", data[:4])
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package erc20
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"ethclient_tutorial/signer"
+)
+
+// PermitABI ERC-2612 扩展接口（链下签名授权）
+const PermitABI = `[
+	{"type":"function","name":"permit","stateMutability":"nonpayable","inputs":[{"name":"owner","type":"address"},{"name":"spender","type":"address"},{"name":"value","type":"uint256"},{"name":"deadline","type":"uint256"},{"name":"v","type":"uint8"},{"name":"r","type":"bytes32"},{"name":"s","type":"bytes32"}],"outputs":[]},
+	{"type":"function","name":"nonces","stateMutability":"view","inputs":[{"name":"owner","type":"address"}],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"DOMAIN_SEPARATOR","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"bytes32"}]}
+]`
+
+// permitABI ERC-2612 扩展接口
+var permitABI = mustParseABI(PermitABI)
+
+// Nonces 查询owner当前的permit nonce（每使用一次permit加1）
+func (t *Token) Nonces(ctx context.Context, owner common.Address) (*big.Int, error) {
+	return t.callUint256(ctx, permitABI, "nonces", owner)
+}
+
+// DomainSeparator 查询合约的EIP-712域分隔符
+func (t *Token) DomainSeparator(ctx context.Context) (common.Hash, error) {
+	result, err := t.call(ctx, permitABI, "DOMAIN_SEPARATOR")
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if len(result) != 32 {
+		return common.Hash{}, fmt.Errorf("代币 %s 未实现 DOMAIN_SEPARATOR()", t.Address.Hex())
+	}
+	return common.BytesToHash(result), nil
+}
+
+// PermitWithSigner 提交owner的permit签名（65字节，V为27/28或0/1），由签名器所在账户支付Gas
+func (t *Token) PermitWithSigner(ctx context.Context, s signer.Signer, owner, spender common.Address, value, deadline *big.Int, signature []byte) (*types.Transaction, error) {
+	if len(signature) != crypto.SignatureLength {
+		return nil, fmt.Errorf("permit签名长度必须是 %d 字节，得到 %d", crypto.SignatureLength, len(signature))
+	}
+	var r, sig [32]byte
+	copy(r[:], signature[:32])
+	copy(sig[:], signature[32:64])
+	v := signature[64]
+	if v < 27 {
+		v += 27
+	}
+	return t.transact(ctx, s, permitABI, "permit", owner, spender, value, deadline, v, r, sig)
+}
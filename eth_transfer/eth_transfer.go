@@ -2,44 +2,49 @@ package eth_transfer
 
 import (
 	"context"
-	"crypto/ecdsa"
+	"errors"
 	"fmt"
 	"math/big"
+	"strings"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
 
+	"ethclient_tutorial/amount"
+	"ethclient_tutorial/backend"
 	"ethclient_tutorial/config"
+	"ethclient_tutorial/fee_strategy"
+	"ethclient_tutorial/nonce_manager"
+	"ethclient_tutorial/signer"
+	"ethclient_tutorial/wallet_management"
 )
 
 // TransferETH 发送ETH转账
-func TransferETH(client *ethclient.Client, privateKeyHex string, toAddress common.Address, amount float64) (common.Hash, error) {
-	return TransferETHWithConfig(client, privateKeyHex, toAddress, amount, config.GlobalConfig)
+func TransferETH(client backend.Client, privateKeyHex string, toAddress common.Address, ethAmount amount.Amount) (common.Hash, error) {
+	return TransferETHWithConfig(client, privateKeyHex, toAddress, ethAmount, config.GlobalConfig)
 }
 
 // TransferETHWithConfig 使用配置发送ETH转账 - 支持EIP-1559
-func TransferETHWithConfig(client *ethclient.Client, privateKeyHex string, toAddress common.Address, amount float64, cfg *config.Config) (common.Hash, error) {
-	fmt.Println("\n=== 开始ETH转账流程 ===")
-
-	// 1. 加载发送者私钥
-	privateKeyECDSA, err := crypto.HexToECDSA(privateKeyHex)
+func TransferETHWithConfig(client backend.Client, privateKeyHex string, toAddress common.Address, ethAmount amount.Amount, cfg *config.Config) (common.Hash, error) {
+	account, err := wallet_management.AccountFromHex(privateKeyHex)
 	if err != nil {
 		return common.Hash{}, fmt.Errorf("invalid private key: %v", err)
 	}
-	fmt.Println("✓ 私钥加载成功")
+	return TransferETHFromAccount(client, account, toAddress, ethAmount, cfg)
+}
 
-	// 2. 获取发送者公钥和地址
-	publicKey := privateKeyECDSA.Public()
-	// 将公钥转换为ECDSA类型
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		return common.Hash{}, fmt.Errorf("failed to cast public key to ECDSA")
-	}
-	// 使用公钥生成地址
-	address := crypto.PubkeyToAddress(*publicKeyECDSA)
+// TransferETHFromAccount 使用已解锁的账户（如keystore账户）发送ETH转账
+func TransferETHFromAccount(client backend.Client, account *wallet_management.UnlockedAccount, toAddress common.Address, ethAmount amount.Amount, cfg *config.Config) (common.Hash, error) {
+	return TransferETHWithSigner(client, signer.FromAccount(account), toAddress, ethAmount, cfg)
+}
+
+// TransferETHWithSigner 使用签名器（内存私钥、keystore或远程签名服务）发送ETH转账
+func TransferETHWithSigner(client backend.Client, s signer.Signer, toAddress common.Address, ethAmount amount.Amount, cfg *config.Config) (common.Hash, error) {
+	fmt.Println("\n=== 开始ETH转账流程 ===")
+
+	// 1-2. 发送者地址
+	address := s.Address()
 	fmt.Printf("✓ 发送方地址: %s\n", address.Hex())
 	fmt.Printf("✓ 接收方地址: %s\n", toAddress.Hex())
 
@@ -50,76 +55,40 @@ func TransferETHWithConfig(client *ethclient.Client, privateKeyHex string, toAdd
 	}
 	fmt.Printf("✓ 链ID: %s\n", chainID.String())
 
-	// 4. 根据当前地址获取nonce
-	nonce, err := client.PendingNonceAt(context.Background(), address)
+	// 4. 从nonce管理器领取nonce（同一账户并发发送时不会冲突）
+	reservation, err := nonce_manager.ForClient(client).Reserve(context.Background(), address)
 	if err != nil {
 		return common.Hash{}, fmt.Errorf("failed to get nonce: %v", err)
 	}
+	defer reservation.Release(nil) // 未成功发送时归还nonce
+	nonce := reservation.Nonce
 	fmt.Printf("✓ Nonce: %d\n", nonce)
 
 	// 5. 计算ETH转账金额(1 ETH = 10^18 wei)
-	value := EtherToWei(amount)
-	fmt.Printf("✓ 转账金额: %s ETH (%s Wei)\n",
-		new(big.Float).SetFloat64(amount),
-		value.String())
-
-	// 6. 尝试使用EIP-1559动态费用交易
-	fmt.Println("\n=== 创建EIP-1559动态费用交易 ===")
-
-	// 获取当前网络的基础费用建议
-	header, err := client.HeaderByNumber(context.Background(), nil)
+	value, err := EtherToWei(ethAmount)
 	if err != nil {
-		return common.Hash{}, fmt.Errorf("failed to get latest header: %v", err)
+		return common.Hash{}, fmt.Errorf("invalid amount: %v", err)
 	}
+	fmt.Printf("✓ 转账金额: %s ETH (%s Wei)\n", ethAmount, value.String())
 
-	// 获取建议的小费上限
-	tipCap, err := client.SuggestGasTipCap(context.Background())
+	// 6-7. 按配置的费用策略计算EIP-1559费用参数并估算GasLimit
+	fmt.Println("\n=== 创建EIP-1559动态费用交易 ===")
+	plan, err := PlanTransfer(context.Background(), client, address, toAddress, value, cfg)
 	if err != nil {
-		// 如果获取小费失败，使用默认值
-		tipCap = big.NewInt(2e9) // 2 Gwei
-		fmt.Printf("Warning: 无法获取建议小费，使用默认值: %s Gwei\n",
-			new(big.Float).Quo(new(big.Float).SetInt(tipCap), big.NewFloat(1e9)))
+		return common.Hash{}, err
 	}
-
-	// 正确计算GasFeeCap (基础费用的2倍 + 小费)
-	gasFeeCap := new(big.Int).Add(
-		new(big.Int).Mul(header.BaseFee, big.NewInt(2)), // 2倍基础费缓冲
-		tipCap,
-	)
-
-	// 应用配置的gas价格倍数
-	gasFeeCapFloat := new(big.Float).SetInt(gasFeeCap)
-	multiplier := big.NewFloat(cfg.GasPriceMultiplier)
-	adjustedGasFeeCap := new(big.Float).Mul(gasFeeCapFloat, multiplier)
-	adjustedGasFeeCap.Int(gasFeeCap)
-
-	fmt.Printf("✓ 基础费用: %s Gwei\n",
-		new(big.Float).Quo(new(big.Float).SetInt(header.BaseFee), big.NewFloat(1e9)))
-	fmt.Printf("✓ 小费上限: %s Gwei\n",
-		new(big.Float).Quo(new(big.Float).SetInt(tipCap), big.NewFloat(1e9)))
-	fmt.Printf("✓ 费用上限: %s Gwei\n",
-		new(big.Float).Quo(new(big.Float).SetInt(gasFeeCap), big.NewFloat(1e9)))
-
-	// 7. 动态估算GasLimit
-	gasLimit, err := client.EstimateGas(context.Background(), ethereum.CallMsg{
-		From:  address,
-		To:    &toAddress,
-		Value: value,
-	})
-	if err != nil {
-		// 如果估算失败，使用配置的默认值
-		gasLimit = cfg.DefaultGasLimit
-		fmt.Printf("Warning: 无法估算Gas，使用默认值: %d\n", gasLimit)
-	} else {
-		fmt.Printf("✓ 估算Gas限制: %d\n", gasLimit)
+	if plan.Fees.Legacy() {
+		fmt.Println("网络不支持EIP-1559，使用Legacy交易")
+		return createLegacyTransaction(client, s, toAddress, value, reservation, chainID, plan, cfg)
 	}
+	fees, gasLimit := plan.Fees, plan.GasLimit
 
 	// 8. 创建EIP-1559交易
 	tx := &types.DynamicFeeTx{
 		ChainID:   chainID,
 		Nonce:     nonce,
-		GasTipCap: tipCap,
-		GasFeeCap: gasFeeCap,
+		GasTipCap: fees.GasTipCap,
+		GasFeeCap: fees.GasFeeCap,
 		Gas:       gasLimit,
 		To:        &toAddress,
 		Value:     value,
@@ -132,13 +101,16 @@ func TransferETHWithConfig(client *ethclient.Client, privateKeyHex string, toAdd
 
 	// 9. 签名交易
 	fmt.Println("\n=== 签名并发送交易 ===")
-	signedTx, err := types.SignTx(newTx, types.LatestSignerForChainID(chainID), privateKeyECDSA)
+	signedTx, err := s.SignTx(context.Background(), newTx, chainID)
 	if err != nil {
-		// 如果EIP-1559签名失败，尝试Legacy交易
+		// 签名器不支持EIP-1559交易时改用Legacy交易
+		if !isTxTypeUnsupported(err) {
+			return common.Hash{}, fmt.Errorf("failed to sign transaction: %v", err)
+		}
 		fmt.Printf("EIP-1559交易签名失败: %v\n", err)
 		fmt.Println("尝试使用Legacy交易...")
 
-		return createLegacyTransaction(client, privateKeyECDSA, address, toAddress, value, nonce, chainID, gasLimit, cfg)
+		return createLegacyTransaction(client, s, toAddress, value, reservation, chainID, plan, cfg)
 	}
 	fmt.Println("✓ EIP-1559交易签名成功")
 
@@ -147,7 +119,7 @@ func TransferETHWithConfig(client *ethclient.Client, privateKeyHex string, toAdd
 	fmt.Printf("交易类型: %d (EIP-1559)\n", signedTx.Type())
 	fmt.Printf("发送方: %s\n", address.Hex())
 	fmt.Printf("接收方: %s\n", toAddress.Hex())
-	fmt.Printf("金额: %s ETH\n", new(big.Float).Quo(new(big.Float).SetInt(value), big.NewFloat(1e18)))
+	fmt.Printf("金额: %s ETH\n", amount.Format(value, amount.EtherDecimals))
 	fmt.Printf("Gas限制: %d\n", gasLimit)
 	fmt.Printf("Nonce: %d\n", nonce)
 	fmt.Printf("交易哈希: %s\n", signedTx.Hash().Hex())
@@ -155,41 +127,107 @@ func TransferETHWithConfig(client *ethclient.Client, privateKeyHex string, toAdd
 	// 11. 发送交易
 	err = client.SendTransaction(context.Background(), signedTx)
 	if err != nil {
-		// 如果EIP-1559交易发送失败，尝试Legacy交易
+		// 节点不接受EIP-1559交易类型时改用Legacy交易（nonce未被占用，可以复用）；其他错误直接返回
+		if !isTxTypeUnsupported(err) {
+			reservation.Release(err)
+			return common.Hash{}, fmt.Errorf("failed to send transaction: %v", err)
+		}
 		fmt.Printf("EIP-1559交易发送失败: %v\n", err)
 		fmt.Println("尝试使用Legacy交易...")
 
-		return createLegacyTransaction(client, privateKeyECDSA, address, toAddress, value, nonce, chainID, gasLimit, cfg)
+		return createLegacyTransaction(client, s, toAddress, value, reservation, chainID, plan, cfg)
 	}
+	reservation.Commit(signedTx.Hash())
 
 	fmt.Println("✅ EIP-1559交易发送成功!")
 	return signedTx.Hash(), nil
 }
 
+// isTxTypeUnsupported 错误是否表明签名器或节点不支持该交易类型
+func isTxTypeUnsupported(err error) bool {
+	if errors.Is(err, types.ErrTxTypeNotSupported) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "transaction type not supported") || strings.Contains(msg, "unsupported transaction type")
+}
+
+// Plan ETH转账的费用和Gas参数
+type Plan struct {
+	Fees     *fee_strategy.Fees
+	GasLimit uint64
+	Cost     *fee_strategy.CostEstimate
+}
+
+// PlanTransfer 按配置的费用策略计算费用参数、估算GasLimit（失败时使用 DEFAULT_GAS_LIMIT），
+// 并在签名前报告预计花费和最坏情况花费、检查花费上限；链不支持EIP-1559时返回Legacy交易的费用（Fees.Legacy）
+// 在线发送和离线签名的构建步骤共用这套逻辑，cfg 为nil时使用节点建议策略
+func PlanTransfer(ctx context.Context, client backend.Client, from, to common.Address, value *big.Int, cfg *config.Config) (*Plan, error) {
+	strategy, err := fee_strategy.FromConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fee strategy: %v", err)
+	}
+	fees, err := strategy.SuggestFees(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to suggest fees: %v", err)
+	}
+	fees.Print()
+
+	// 动态估算GasLimit
+	gasLimit, err := client.EstimateGas(ctx, ethereum.CallMsg{
+		From:  from,
+		To:    &to,
+		Value: value,
+	})
+	if err != nil {
+		// 如果估算失败，使用配置的默认值
+		if cfg == nil || cfg.DefaultGasLimit == 0 {
+			return nil, fmt.Errorf("failed to estimate gas and DEFAULT_GAS_LIMIT is not set: %v", err)
+		}
+		gasLimit = cfg.DefaultGasLimit
+		fmt.Printf("Warning: 无法估算Gas，使用默认值: %d\n", gasLimit)
+	} else {
+		fmt.Printf("✓ 估算Gas限制: %d\n", gasLimit)
+	}
+
+	cost, err := fee_strategy.EstimateCost(strategy, fees, gasLimit, value)
+	cost.Print()
+	if err != nil {
+		return nil, fmt.Errorf("fee ceiling exceeded: %v", err)
+	}
+	return &Plan{Fees: fees, GasLimit: gasLimit, Cost: cost}, nil
+}
+
 // createLegacyTransaction 创建Legacy交易作为后备方案
-func createLegacyTransaction(client *ethclient.Client, privateKey *ecdsa.PrivateKey, fromAddress, toAddress common.Address, value *big.Int, nonce uint64, chainID *big.Int, gasLimit uint64, cfg *config.Config) (common.Hash, error) {
+// Gas价格取费用策略给出的费用上限（maxFeePerGas），签名前按Legacy交易的实际价格重新报告花费并检查花费上限
+func createLegacyTransaction(client backend.Client, s signer.Signer, toAddress common.Address, value *big.Int, reservation *nonce_manager.Reservation, chainID *big.Int, plan *Plan, cfg *config.Config) (common.Hash, error) {
 	fmt.Println("\n=== 创建Legacy交易 ===")
 
-	// 获取建议gas价格
-	gasPrice, err := client.SuggestGasPrice(context.Background())
+	strategy, err := fee_strategy.FromConfig(cfg)
 	if err != nil {
-		return common.Hash{}, fmt.Errorf("failed to get gas price: %v", err)
+		return common.Hash{}, fmt.Errorf("invalid fee strategy: %v", err)
 	}
 
-	// 应用gas价格倍数
-	gasPriceFloat := new(big.Float).SetInt(gasPrice)
-	multiplier := big.NewFloat(cfg.GasPriceMultiplier)
-	adjustedGasPrice := new(big.Float).Mul(gasPriceFloat, multiplier)
-
-	finalGasPrice := new(big.Int)
-	adjustedGasPrice.Int(finalGasPrice)
+	// Legacy交易按gasPrice全额支付，没有基础费用与小费之分
+	finalGasPrice := plan.Fees.GasFeeCap
+	gasLimit := plan.GasLimit
+	legacyFees := &fee_strategy.Fees{
+		Strategy:  plan.Fees.Strategy,
+		BaseFee:   plan.Fees.BaseFee,
+		GasTipCap: finalGasPrice,
+		GasFeeCap: finalGasPrice,
+	}
+	cost, err := fee_strategy.EstimateCost(strategy, legacyFees, gasLimit, value)
+	cost.Print()
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("fee ceiling exceeded: %v", err)
+	}
 
-	fmt.Printf("✓ Gas价格: %s Gwei\n",
-		new(big.Float).Quo(new(big.Float).SetInt(finalGasPrice), big.NewFloat(1e9)))
+	fmt.Printf("✓ Gas价格: %s Gwei\n", fee_strategy.ToGwei(finalGasPrice))
 
 	// 创建Legacy交易
 	txData := types.LegacyTx{
-		Nonce:    nonce,
+		Nonce:    reservation.Nonce,
 		To:       &toAddress,
 		Value:    value,
 		Gas:      gasLimit,
@@ -199,7 +237,7 @@ func createLegacyTransaction(client *ethclient.Client, privateKey *ecdsa.Private
 	tx := types.NewTx(&txData)
 
 	// 签名交易
-	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(chainID), privateKey)
+	signedTx, err := s.SignTx(context.Background(), tx, chainID)
 	if err != nil {
 		return common.Hash{}, fmt.Errorf("failed to sign legacy transaction: %v", err)
 	}
@@ -207,24 +245,67 @@ func createLegacyTransaction(client *ethclient.Client, privateKey *ecdsa.Private
 
 	// 打印交易详情
 	fmt.Printf("交易类型: %d (Legacy)\n", signedTx.Type())
-	fmt.Printf("Gas价格: %s Gwei\n",
-		new(big.Float).Quo(new(big.Float).SetInt(finalGasPrice), big.NewFloat(1e9)))
+	fmt.Printf("Gas价格: %s Gwei\n", fee_strategy.ToGwei(finalGasPrice))
 	fmt.Printf("交易哈希: %s\n", signedTx.Hash().Hex())
 
 	// 发送交易
 	err = client.SendTransaction(context.Background(), signedTx)
 	if err != nil {
+		reservation.Release(err)
 		return common.Hash{}, fmt.Errorf("failed to send legacy transaction: %v", err)
 	}
+	reservation.Commit(signedTx.Hash())
 
 	fmt.Println("✅ Legacy交易发送成功!")
 	return signedTx.Hash(), nil
 }
 
-// EtherToWei 将ETH转换为Wei
-func EtherToWei(eth float64) *big.Int {
-	wei := new(big.Float).Mul(big.NewFloat(eth), big.NewFloat(1e18))
-	weiInt := new(big.Int)
-	wei.Int(weiInt)
-	return weiInt
+// FillNonceGaps 检测账户的nonce空缺，并用0金额自转账逐个填补，解除后续交易的阻塞
+func FillNonceGaps(client backend.Client, privateKeyHex string) ([]common.Hash, error) {
+	account, err := wallet_management.AccountFromHex(privateKeyHex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %v", err)
+	}
+	return FillNonceGapsWithSigner(client, signer.FromAccount(account))
+}
+
+// FillNonceGapsWithSigner 使用签名器填补账户的nonce空缺
+func FillNonceGapsWithSigner(client backend.Client, s signer.Signer) ([]common.Hash, error) {
+	address := s.Address()
+
+	chainID, err := client.ChainID(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chain ID: %v", err)
+	}
+
+	strategy := fee_strategy.Default()
+	return nonce_manager.ForClient(client).FillGaps(context.Background(), address, func(ctx context.Context, nonce uint64) (common.Hash, error) {
+		fees, err := strategy.SuggestFees(ctx, client)
+		if err != nil {
+			return common.Hash{}, fmt.Errorf("failed to suggest fees: %v", err)
+		}
+
+		signedTx, err := s.SignTx(ctx, types.NewTx(&types.DynamicFeeTx{
+			ChainID:   chainID,
+			Nonce:     nonce,
+			GasTipCap: fees.GasTipCap,
+			GasFeeCap: fees.GasFeeCap,
+			Gas:       21000,
+			To:        &address,
+			Value:     big.NewInt(0),
+		}), chainID)
+		if err != nil {
+			return common.Hash{}, fmt.Errorf("failed to sign transaction: %v", err)
+		}
+		if err := client.SendTransaction(ctx, signedTx); err != nil {
+			return common.Hash{}, fmt.Errorf("failed to send transaction: %v", err)
+		}
+		fmt.Printf("✓ 已填补nonce %d: %s\n", nonce, signedTx.Hash().Hex())
+		return signedTx.Hash(), nil
+	})
+}
+
+// EtherToWei 将ETH转换为Wei（精确换算，超过18位小数时返回错误）
+func EtherToWei(eth amount.Amount) (*big.Int, error) {
+	return eth.Wei()
 }
@@ -0,0 +1,149 @@
+package event_stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"ethclient_tutorial/backend"
+)
+
+// HeadStream 新区块头流，与 LogStream 一样按订阅、过滤器（eth_newBlockFilter）或轮询接收，断线自动重连
+// 只发出新的区块头；轮询时两次查询之间产生的多个区块只发出最新的一个，需要连续区块的调用方自行按 ParentHash 补齐
+type HeadStream struct {
+	*stream
+	client backend.Client
+	heads  chan *types.Header
+	last   common.Hash
+}
+
+// SubscribeHeads 开始接收新区块头
+func SubscribeHeads(ctx context.Context, client backend.Client, opts Options) *HeadStream {
+	s := &HeadStream{stream: newStream(ctx, opts), client: client, heads: make(chan *types.Header)}
+	go s.run("区块头流", s.session)
+	return s
+}
+
+// Heads 接收区块头的通道
+func (s *HeadStream) Heads() <-chan *types.Header {
+	return s.heads
+}
+
+func (s *HeadStream) session(ctx context.Context, mode Mode) error {
+	switch mode {
+	case ModeSubscribe:
+		return s.subscribe(ctx)
+	case ModeFilter:
+		return s.filter(ctx)
+	default:
+		return s.poll(ctx)
+	}
+}
+
+// subscribe 使用 eth_subscribe("newHeads")
+func (s *HeadStream) subscribe(ctx context.Context) error {
+	ch := make(chan *types.Header)
+	sub, err := s.client.SubscribeNewHead(ctx, ch)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+	s.ready(ModeSubscribe)
+	for {
+		select {
+		case err := <-sub.Err():
+			if err == nil {
+				err = errors.New("订阅已关闭")
+			}
+			return err
+		case header := <-ch:
+			if err := s.emit(ctx, header); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// filter 使用 eth_newBlockFilter，新区块哈希再查询区块头
+func (s *HeadStream) filter(ctx context.Context) error {
+	c, ok := caller(s.client)
+	if !ok {
+		return fmt.Errorf("%w: 客户端不支持原始JSON-RPC调用", ErrUnsupported)
+	}
+	var id string
+	if err := c.CallContext(ctx, &id, "eth_newBlockFilter"); err != nil {
+		return err
+	}
+	defer uninstall(c, id)
+	s.ready(ModeFilter)
+	ticker := time.NewTicker(s.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			var hashes []common.Hash
+			if err := filterChanges(ctx, c, id, &hashes); err != nil {
+				return err
+			}
+			for _, hash := range hashes {
+				header, err := s.client.HeaderByHash(ctx, hash)
+				if err != nil {
+					return err
+				}
+				if err := s.emit(ctx, header); err != nil {
+					return err
+				}
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// poll 定期查询最新区块头，哈希变化时发出
+func (s *HeadStream) poll(ctx context.Context) error {
+	header, err := s.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if s.last == (common.Hash{}) {
+		s.last = header.Hash() // 第一次连接只发出之后的新区块
+	}
+	s.ready(ModePoll)
+	ticker := time.NewTicker(s.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			header, err := s.client.HeaderByNumber(ctx, nil)
+			if err != nil {
+				return err
+			}
+			if err := s.emit(ctx, header); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// emit 发出区块头，跳过与上一个相同的
+func (s *HeadStream) emit(ctx context.Context, header *types.Header) error {
+	if header.Hash() == s.last {
+		return nil
+	}
+	s.last = header.Hash()
+	select {
+	case s.heads <- header:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,277 @@
+package event_stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"ethclient_tutorial/backend"
+)
+
+// seenWindow 记住最近多少个区块内已发出的日志，用于断线补齐和重新扫描时去重
+const seenWindow = 128
+
+// logKey 唯一标识一条日志（重组后进入新区块的日志是新的键）
+type logKey struct {
+	block common.Hash
+	index uint
+}
+
+// LogStream 合约日志流
+// 不论使用订阅、过滤器还是轮询，日志都从 Logs() 按区块顺序收到。断线重连后先用 FilterLogs 补齐断线期间的日志，
+// 已经发出过的日志不会重复发出。订阅和过滤器方式会收到节点推送的 Removed 日志；轮询方式发现重组后重新扫描分叉后的区块，
+// 但不会为旧链上的日志生成 Removed 日志，需要时配合区块哈希窗口（contract_events 的防重组投递）使用
+type LogStream struct {
+	*stream
+	client backend.Client
+	query  ethereum.FilterQuery
+	logs   chan types.Log
+
+	started bool
+	covered uint64                 // 已完整扫描到的区块
+	highest uint64                 // 发出过日志的最高区块
+	seen    map[logKey]uint64      // 已发出日志 → 区块号
+	hashes  map[uint64]common.Hash // 轮询方式每次看到的最新区块哈希
+}
+
+// SubscribeLogs 开始接收符合 query 的日志（只接收新日志，历史日志用 FilterLogs 或 contract_events.Backfill）
+func SubscribeLogs(ctx context.Context, client backend.Client, query ethereum.FilterQuery, opts Options) *LogStream {
+	s := &LogStream{
+		stream: newStream(ctx, opts),
+		client: client,
+		query:  query,
+		logs:   make(chan types.Log),
+		seen:   make(map[logKey]uint64),
+		hashes: make(map[uint64]common.Hash),
+	}
+	go s.run("日志流", s.session)
+	return s
+}
+
+// Logs 接收日志的通道
+func (s *LogStream) Logs() <-chan types.Log {
+	return s.logs
+}
+
+func (s *LogStream) session(ctx context.Context, mode Mode) error {
+	switch mode {
+	case ModeSubscribe:
+		return s.subscribe(ctx)
+	case ModeFilter:
+		return s.filter(ctx)
+	default:
+		return s.poll(ctx)
+	}
+}
+
+// subscribe 使用 eth_subscribe("logs")
+func (s *LogStream) subscribe(ctx context.Context) error {
+	ch := make(chan types.Log)
+	sub, err := s.client.SubscribeFilterLogs(ctx, s.query, ch)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+	if err := s.catchUp(ctx); err != nil {
+		return err
+	}
+	s.ready(ModeSubscribe)
+	for {
+		select {
+		case err := <-sub.Err():
+			if err == nil {
+				err = errors.New("订阅已关闭")
+			}
+			return err
+		case vLog := <-ch:
+			if err := s.emit(ctx, vLog); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// filter 使用 eth_newFilter，定期 eth_getFilterChanges
+func (s *LogStream) filter(ctx context.Context) error {
+	c, ok := caller(s.client)
+	if !ok {
+		return fmt.Errorf("%w: 客户端不支持原始JSON-RPC调用", ErrUnsupported)
+	}
+	var id string
+	if err := c.CallContext(ctx, &id, "eth_newFilter", filterArg(s.query)); err != nil {
+		return err
+	}
+	defer uninstall(c, id)
+	if err := s.catchUp(ctx); err != nil {
+		return err
+	}
+	s.ready(ModeFilter)
+	ticker := time.NewTicker(s.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			var logs []types.Log
+			if err := filterChanges(ctx, c, id, &logs); err != nil {
+				return err
+			}
+			for _, vLog := range logs {
+				if err := s.emit(ctx, vLog); err != nil {
+					return err
+				}
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// poll 定期查询最新区块，用 FilterLogs 拉取新区块的日志；最新区块的哈希与记录不一致时回退到分叉点重新扫描
+func (s *LogStream) poll(ctx context.Context) error {
+	if err := s.catchUp(ctx); err != nil {
+		return err
+	}
+	s.ready(ModePoll)
+	ticker := time.NewTicker(s.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			header, err := s.client.HeaderByNumber(ctx, nil)
+			if err != nil {
+				return err
+			}
+			if err := s.rewind(ctx); err != nil {
+				return err
+			}
+			number := header.Number.Uint64()
+			if number > s.covered {
+				if err := s.fill(ctx, s.covered+1, number); err != nil {
+					return err
+				}
+			}
+			s.hashes[number] = header.Hash()
+			for n := range s.hashes {
+				if n+seenWindow < number {
+					delete(s.hashes, n)
+				}
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// rewind 轮询方式发现重组：记录的区块哈希不再是主链哈希时，把扫描位置退回到最后一个仍在主链上的区块
+func (s *LogStream) rewind(ctx context.Context) error {
+	numbers := make([]uint64, 0, len(s.hashes))
+	for n := range s.hashes {
+		numbers = append(numbers, n)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] > numbers[j] })
+	for i, n := range numbers {
+		header, err := s.client.HeaderByNumber(ctx, new(big.Int).SetUint64(n))
+		if err != nil && !errors.Is(err, ethereum.NotFound) {
+			return err
+		}
+		if err == nil && header.Hash() == s.hashes[n] {
+			if i > 0 {
+				s.covered = min(s.covered, n)
+			}
+			return nil
+		}
+		delete(s.hashes, n)
+	}
+	if len(numbers) > 0 {
+		// 窗口中的区块都已不在主链上，从窗口之前重新扫描
+		s.covered = min(s.covered, numbers[len(numbers)-1]-1)
+	}
+	return nil
+}
+
+// catchUp 建立连接后调用：第一次连接从当前区块开始，重连时补齐断线期间的日志
+func (s *LogStream) catchUp(ctx context.Context) error {
+	head, err := s.client.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+	if !s.started {
+		s.started, s.covered = true, head
+		return nil
+	}
+	from := s.covered + 1
+	if s.highest > s.covered {
+		from = s.highest // 断线前的最后一个区块可能只收到了部分日志
+	}
+	if from > head {
+		return nil
+	}
+	return s.fill(ctx, from, head)
+}
+
+// fill 按 MaxBlockRange 分段拉取 [from, to] 的日志
+func (s *LogStream) fill(ctx context.Context, from, to uint64) error {
+	query := s.query
+	for start := from; start <= to; {
+		end := min(to, start+s.opts.MaxBlockRange-1)
+		query.FromBlock = new(big.Int).SetUint64(start)
+		query.ToBlock = new(big.Int).SetUint64(end)
+		logs, err := s.client.FilterLogs(ctx, query)
+		if err != nil {
+			return fmt.Errorf("查询区块 %d-%d 的日志失败: %w", start, end, err)
+		}
+		for _, vLog := range logs {
+			if err := s.emit(ctx, vLog); err != nil {
+				return err
+			}
+		}
+		s.covered = max(s.covered, end)
+		start = end + 1
+	}
+	return nil
+}
+
+// emit 发出日志，跳过已经发出过的
+func (s *LogStream) emit(ctx context.Context, vLog types.Log) error {
+	key := logKey{vLog.BlockHash, vLog.Index}
+	if vLog.Removed {
+		delete(s.seen, key)
+	} else {
+		if _, ok := s.seen[key]; ok {
+			return nil
+		}
+		s.seen[key] = vLog.BlockNumber
+		if vLog.BlockNumber > s.highest {
+			s.highest = vLog.BlockNumber
+			for k, n := range s.seen {
+				if n+seenWindow < s.highest {
+					delete(s.seen, k)
+				}
+			}
+		}
+	}
+	select {
+	case s.logs <- vLog:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// filterArg eth_newFilter 的参数
+func filterArg(q ethereum.FilterQuery) map[string]interface{} {
+	arg := map[string]interface{}{"topics": q.Topics}
+	if len(q.Addresses) > 0 {
+		arg["address"] = q.Addresses
+	}
+	return arg
+}
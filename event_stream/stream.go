@@ -0,0 +1,256 @@
+package event_stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/rpc_pool"
+)
+
+// Mode 接收日志和区块头的方式
+type Mode int
+
+const (
+	ModeAuto      Mode = iota // 优先订阅；节点不支持订阅（HTTP）时使用过滤器，过滤器也不支持时逐块轮询
+	ModeSubscribe             // eth_subscribe（WS/IPC）
+	ModeFilter                // eth_newFilter / eth_getFilterChanges 轮询
+	ModePoll                  // 定期查询最新区块，用 FilterLogs 按区块区间拉取日志
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeAuto:
+		return "auto"
+	case ModeSubscribe:
+		return "subscribe"
+	case ModeFilter:
+		return "filter"
+	case ModePoll:
+		return "poll"
+	default:
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+}
+
+// ParseMode 解析 auto、subscribe、filter、poll
+func ParseMode(s string) (Mode, error) {
+	for _, m := range []Mode{ModeAuto, ModeSubscribe, ModeFilter, ModePoll} {
+		if strings.EqualFold(s, m.String()) {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("未知的监听方式 %q（可选 auto、subscribe、filter、poll）", s)
+}
+
+// ErrUnsupported 节点不支持指定的监听方式
+var ErrUnsupported = errors.New("节点不支持该监听方式")
+
+// Options 日志流/区块头流参数
+type Options struct {
+	Mode          Mode
+	PollInterval  time.Duration // 过滤器和轮询方式的查询间隔
+	RetryDelay    time.Duration // 断线后第一次重连的等待时间，之后每次加倍
+	MaxRetryDelay time.Duration // 重连等待时间上限
+	MaxBlockRange uint64        // 轮询和断线补齐时单次 FilterLogs 的最大区块数
+}
+
+// DefaultOptions 默认参数
+var DefaultOptions = Options{
+	Mode:          ModeAuto,
+	PollInterval:  2 * time.Second,
+	RetryDelay:    time.Second,
+	MaxRetryDelay: 30 * time.Second,
+	MaxBlockRange: 1000,
+}
+
+func (o Options) withDefaults() Options {
+	if o.PollInterval == 0 {
+		o.PollInterval = DefaultOptions.PollInterval
+	}
+	if o.RetryDelay == 0 {
+		o.RetryDelay = DefaultOptions.RetryDelay
+	}
+	if o.MaxRetryDelay == 0 {
+		o.MaxRetryDelay = DefaultOptions.MaxRetryDelay
+	}
+	if o.MaxBlockRange == 0 {
+		o.MaxBlockRange = DefaultOptions.MaxBlockRange
+	}
+	return o
+}
+
+// modes 依次尝试的方式
+func (o Options) modes() []Mode {
+	if o.Mode == ModeAuto {
+		return []Mode{ModeSubscribe, ModeFilter, ModePoll}
+	}
+	return []Mode{o.Mode}
+}
+
+// Caller 发送原始JSON-RPC请求，过滤器方式需要（*rpc.Client、backend.Simulated 和 rpc_pool.Pool 都实现了该接口）
+type Caller interface {
+	CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
+}
+
+// caller 从客户端取得原始JSON-RPC调用能力，*ethclient.Client 通过 Client() 取得
+func caller(client backend.Client) (Caller, bool) {
+	if c, ok := client.(Caller); ok {
+		return c, true
+	}
+	if c, ok := client.(interface{ Client() *rpc.Client }); ok {
+		return c.Client(), true
+	}
+	return nil, false
+}
+
+// IsUnsupported 错误是否表示节点不支持订阅或过滤器（HTTP节点订阅、禁用了过滤器的服务商等）
+func IsUnsupported(err error) bool {
+	if err == nil {
+		return false
+	}
+	// rpc_pool 中没有WS节点时订阅返回 ErrNoProvider
+	if errors.Is(err, ErrUnsupported) || errors.Is(err, rpc.ErrNotificationsUnsupported) || errors.Is(err, rpc_pool.ErrNoProvider) {
+		return true
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == -32601 { // method not found
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "notifications not supported") ||
+		strings.Contains(msg, "does not exist/is not available") ||
+		strings.Contains(msg, "method not found")
+}
+
+// errFilterLost 过滤器已被节点清除（长时间未查询、节点重启或节点池切换到了其他节点），立即重新创建
+var errFilterLost = errors.New("过滤器已失效")
+
+// filterChanges 查询过滤器的新结果，过滤器不存在时返回 errFilterLost
+func filterChanges(ctx context.Context, c Caller, id string, result interface{}) error {
+	err := c.CallContext(ctx, result, "eth_getFilterChanges", id)
+	if err != nil && strings.Contains(strings.ToLower(err.Error()), "filter not found") {
+		return fmt.Errorf("%w: %v", errFilterLost, err)
+	}
+	return err
+}
+
+// stream 日志流和区块头流共用的重连循环
+type stream struct {
+	opts   Options
+	ctx    context.Context
+	cancel context.CancelFunc
+	errs   chan error
+	done   chan struct{}
+	up     chan struct{} // 第一次连接成功时关闭
+	once   sync.Once
+
+	mu        sync.Mutex
+	mode      Mode
+	connected bool
+}
+
+func newStream(ctx context.Context, opts Options) *stream {
+	ctx, cancel := context.WithCancel(ctx)
+	return &stream{opts: opts.withDefaults(), ctx: ctx, cancel: cancel, errs: make(chan error, 1), done: make(chan struct{}), up: make(chan struct{})}
+}
+
+// run 反复建立会话：会话因网络、订阅错误或过滤器失效结束后，按指数退避等待并重新连接；
+// 指定的方式不被支持时停止并通过 Err 报告。session 每次按 mode 建立连接，不支持时返回 ErrUnsupported
+func (s *stream) run(kind string, session func(ctx context.Context, mode Mode) error) {
+	defer close(s.done)
+	delay := s.opts.RetryDelay
+	for {
+		var err error
+		modes := s.opts.modes()
+		for i, mode := range modes {
+			err = session(s.ctx, mode)
+			if IsUnsupported(err) && i < len(modes)-1 {
+				continue
+			}
+			break
+		}
+		if s.ctx.Err() != nil {
+			return
+		}
+		if IsUnsupported(err) {
+			s.errs <- fmt.Errorf("%s: %w", kind, err)
+			return
+		}
+
+		s.mu.Lock()
+		if s.connected {
+			delay = s.opts.RetryDelay // 上一次连接成功过，重新开始退避
+		}
+		s.connected = false
+		s.mu.Unlock()
+		if errors.Is(err, errFilterLost) {
+			log.Printf("⚠️ %s: %v，重新创建过滤器", kind, err)
+			continue
+		}
+		log.Printf("⚠️ %s中断: %v，%v 后重新连接", kind, err, delay)
+		select {
+		case <-time.After(delay):
+		case <-s.ctx.Done():
+			return
+		}
+		delay = min(delay*2, s.opts.MaxRetryDelay)
+	}
+}
+
+// ready 会话建立成功
+func (s *stream) ready(mode Mode) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mode, s.connected = mode, true
+	s.once.Do(func() { close(s.up) })
+}
+
+// Wait 等待第一次连接成功；指定的方式不被支持时返回错误
+// 返回之后建立的订阅或过滤器已经生效，此时查询的最新区块之后的日志都会从流中收到
+func (s *stream) Wait(ctx context.Context) error {
+	select {
+	case <-s.up:
+		return nil
+	case err := <-s.errs:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Mode 当前使用的方式（还没有连接成功时为 ModeAuto）
+func (s *stream) Mode() Mode {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.connected {
+		return ModeAuto
+	}
+	return s.mode
+}
+
+// Err 无法恢复时（指定的方式不被支持）收到错误，网络错误和订阅错误会自动重连，不会出现在这里
+func (s *stream) Err() <-chan error {
+	return s.errs
+}
+
+// Close 停止接收并释放订阅或过滤器
+func (s *stream) Close() {
+	s.cancel()
+	<-s.done
+}
+
+// uninstall 删除节点上的过滤器（尽力而为）
+func uninstall(c Caller, id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var ok bool
+	c.CallContext(ctx, &ok, "eth_uninstallFilter", id)
+}
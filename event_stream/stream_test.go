@@ -0,0 +1,234 @@
+package event_stream
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/backend/backendtest"
+)
+
+// httpNode 模拟HTTP节点：不支持订阅，也不提供原始JSON-RPC调用（只能轮询）
+type httpNode struct {
+	backend.Client
+}
+
+func (httpNode) SubscribeFilterLogs(context.Context, ethereum.FilterQuery, chan<- types.Log) (ethereum.Subscription, error) {
+	return nil, rpc.ErrNotificationsUnsupported
+}
+
+func (httpNode) SubscribeNewHead(context.Context, chan<- *types.Header) (ethereum.Subscription, error) {
+	return nil, rpc.ErrNotificationsUnsupported
+}
+
+// httpFilterNode 不支持订阅但支持 eth_newFilter 的HTTP节点
+type httpFilterNode struct {
+	httpNode
+	sim *backend.Simulated
+}
+
+func (n httpFilterNode) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	return n.sim.CallContext(ctx, result, method, args...)
+}
+
+// transferQuery 只匹配 Mine 产生的账户0转出的Transfer日志
+// 部署区块的铸币和所有权日志不匹配，订阅建立后即使迟到推送也不会被收到
+func transferQuery(chain *backendtest.TokenChain) ethereum.FilterQuery {
+	return ethereum.FilterQuery{
+		Addresses: []common.Address{chain.Address},
+		Topics:    [][]common.Hash{{transferTopic}, {common.BytesToHash(chain.Sim.Accounts[0].Address.Bytes())}},
+	}
+}
+
+var transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+var fastOptions = Options{PollInterval: 50 * time.Millisecond, RetryDelay: 200 * time.Millisecond}
+
+// connected 等待流连接成功，返回使用的方式
+func connected(t *testing.T, s interface {
+	Wait(context.Context) error
+	Mode() Mode
+}) Mode {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Wait(ctx); err != nil {
+		t.Fatalf("stream did not connect: %v", err)
+	}
+	return s.Mode()
+}
+
+// receive 收取 n 条日志，之后一段时间内不应再有日志
+func receive(t *testing.T, s *LogStream, n int) []types.Log {
+	t.Helper()
+	var logs []types.Log
+	for len(logs) < n {
+		select {
+		case vLog := <-s.Logs():
+			logs = append(logs, vLog)
+		case err := <-s.Err():
+			t.Fatal(err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received %d logs, want %d", len(logs), n)
+		}
+	}
+	select {
+	case vLog := <-s.Logs():
+		t.Fatalf("unexpected extra log #%d/%d", vLog.BlockNumber, vLog.Index)
+	case <-time.After(300 * time.Millisecond):
+	}
+	for i := 1; i < len(logs); i++ {
+		if logs[i].BlockNumber < logs[i-1].BlockNumber || (logs[i].BlockNumber == logs[i-1].BlockNumber && logs[i].Index <= logs[i-1].Index) {
+			t.Fatalf("log %d out of order", i)
+		}
+	}
+	return logs
+}
+
+func TestStreamsFallBackFromSubscriptionsToPolling(t *testing.T) {
+	cases := []struct {
+		name   string
+		client func(sim *backend.Simulated) backend.Client
+		want   Mode
+	}{
+		{"ws", func(sim *backend.Simulated) backend.Client { return sim }, ModeSubscribe},
+		{"http with filters", func(sim *backend.Simulated) backend.Client { return httpFilterNode{httpNode{sim}, sim} }, ModeFilter},
+		{"http", func(sim *backend.Simulated) backend.Client { return httpNode{sim} }, ModePoll},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			chain := backendtest.NewTokenChain(t, 2)
+			client := tc.client(chain.Sim)
+			logs := SubscribeLogs(context.Background(), client, transferQuery(chain), fastOptions)
+			defer logs.Close()
+			heads := SubscribeHeads(context.Background(), client, fastOptions)
+			defer heads.Close()
+			if mode := connected(t, logs); mode != tc.want {
+				t.Fatalf("log stream mode = %s, want %s", mode, tc.want)
+			}
+			if mode := connected(t, heads); mode != tc.want {
+				t.Fatalf("head stream mode = %s, want %s", mode, tc.want)
+			}
+
+			// 只发出连接之后的新日志
+			chain.Mine(t, 2)
+			receive(t, logs, 2)
+			chain.Mine(t, 1)
+			receive(t, logs, 1)
+
+			select {
+			case header := <-heads.Heads():
+				head, _ := chain.Sim.BlockNumber(context.Background())
+				if header.Number.Uint64() < 2 || header.Number.Uint64() > head {
+					t.Fatalf("head #%d, chain head #%d", header.Number, head)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("no head received")
+			}
+		})
+	}
+
+	// 指定的方式不被支持时报告错误，不再重试
+	chain := backendtest.NewTokenChain(t, 2)
+	logs := SubscribeLogs(context.Background(), httpNode{chain.Sim}, transferQuery(chain), Options{Mode: ModeFilter})
+	defer logs.Close()
+	select {
+	case err := <-logs.Err():
+		if !errors.Is(err, ErrUnsupported) {
+			t.Fatalf("err = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("unsupported mode not reported")
+	}
+}
+
+// flakyNode 可以人为中断订阅的节点
+type flakyNode struct {
+	*backend.Simulated
+	mu   sync.Mutex
+	subs []*killableSub
+}
+
+type killableSub struct {
+	ethereum.Subscription
+	errs chan error
+}
+
+func (s *killableSub) Err() <-chan error { return s.errs }
+
+func (n *flakyNode) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	sub, err := n.Simulated.SubscribeFilterLogs(ctx, q, ch)
+	if err != nil {
+		return nil, err
+	}
+	killable := &killableSub{Subscription: sub, errs: make(chan error, 1)}
+	n.mu.Lock()
+	n.subs = append(n.subs, killable)
+	n.mu.Unlock()
+	return killable, nil
+}
+
+func (n *flakyNode) kill() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.subs[len(n.subs)-1].errs <- errors.New("websocket: close 1006 (abnormal closure)")
+	return len(n.subs)
+}
+
+func TestLogStreamResubscribesAndFillsGap(t *testing.T) {
+	chain := backendtest.NewTokenChain(t, 2)
+	node := &flakyNode{Simulated: chain.Sim}
+	logs := SubscribeLogs(context.Background(), node, transferQuery(chain), fastOptions)
+	defer logs.Close()
+	connected(t, logs)
+
+	chain.Mine(t, 1)
+	receive(t, logs, 1)
+
+	// 断线后、重连前产生的日志在重连时补齐，不重复也不遗漏
+	if subs := node.kill(); subs != 1 {
+		t.Fatalf("subscriptions = %d", subs)
+	}
+	chain.Mine(t, 2, 1)
+	got := receive(t, logs, 3)
+	chain.Mine(t, 1)
+	got = append(got, receive(t, logs, 1)...)
+	if got[3].BlockNumber <= got[2].BlockNumber {
+		t.Fatal("live log after reconnect out of order")
+	}
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	if len(node.subs) != 2 {
+		t.Fatalf("subscriptions = %d, want 2", len(node.subs))
+	}
+}
+
+func TestPollingRescansAfterReorg(t *testing.T) {
+	chain := backendtest.NewTokenChain(t, 2)
+	logs := SubscribeLogs(context.Background(), httpNode{chain.Sim}, transferQuery(chain), fastOptions)
+	defer logs.Close()
+	connected(t, logs)
+
+	parent, _ := chain.Sim.HeaderByNumber(context.Background(), nil)
+	chain.Mine(t, 1)
+	orphaned := receive(t, logs, 1)[0]
+
+	// 分叉后转账在同一高度的新区块中重新打包，轮询需要回退扫描位置才能拿到新链上的日志
+	if err := chain.Sim.Fork(parent.Hash()); err != nil {
+		t.Fatal(err)
+	}
+	chain.Mine(t, 0, 0)
+	reincluded := receive(t, logs, 1)[0]
+	if reincluded.TxHash != orphaned.TxHash || reincluded.BlockHash == orphaned.BlockHash || reincluded.Removed {
+		t.Fatalf("reincluded = #%d %s", reincluded.BlockNumber, reincluded.BlockHash.Hex())
+	}
+}
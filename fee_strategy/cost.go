@@ -0,0 +1,51 @@
+package fee_strategy
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// CostEstimate 交易签名前的花费预估
+type CostEstimate struct {
+	GasLimit  uint64
+	Value     *big.Int
+	Expected  *big.Int // 按 min(基础费用+小费, 费用上限)（Legacy交易按gasPrice）计算的预计花费（含转账金额）
+	WorstCase *big.Int // 按费用上限和Gas上限计算的最坏情况花费（含转账金额）
+}
+
+// EstimateCost 计算预计花费与最坏情况花费，并按策略检查花费上限
+func EstimateCost(strategy Strategy, fees *Fees, gasLimit uint64, value *big.Int) (*CostEstimate, error) {
+	if value == nil {
+		value = big.NewInt(0)
+	}
+	gas := new(big.Int).SetUint64(gasLimit)
+
+	// Legacy交易按 gasPrice 全额支付
+	effectivePrice := fees.GasFeeCap
+	if !fees.Legacy() {
+		effectivePrice = new(big.Int).Add(fees.BaseFee, fees.GasTipCap)
+		if effectivePrice.Cmp(fees.GasFeeCap) > 0 {
+			effectivePrice = fees.GasFeeCap
+		}
+	}
+
+	cost := &CostEstimate{
+		GasLimit:  gasLimit,
+		Value:     value,
+		Expected:  new(big.Int).Add(new(big.Int).Mul(gas, effectivePrice), value),
+		WorstCase: new(big.Int).Add(new(big.Int).Mul(gas, fees.GasFeeCap), value),
+	}
+
+	if checker, ok := strategy.(CostChecker); ok {
+		if err := checker.CheckCost(cost); err != nil {
+			return cost, err
+		}
+	}
+	return cost, nil
+}
+
+// Print 打印花费预估
+func (c *CostEstimate) Print() {
+	fmt.Printf("✓ 预计花费: %s ETH\n", ToEther(c.Expected))
+	fmt.Printf("✓ 最高花费: %s ETH (Gas上限 %d)\n", ToEther(c.WorstCase), c.GasLimit)
+}
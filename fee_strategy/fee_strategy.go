@@ -0,0 +1,345 @@
+package fee_strategy
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+
+	"ethclient_tutorial/amount"
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/config"
+)
+
+// Fees 一笔EIP-1559交易的费用参数
+// 链不支持EIP-1559时 BaseFee 为nil，GasFeeCap 和 GasTipCap 都是Legacy交易的 gasPrice
+type Fees struct {
+	Strategy  string
+	BaseFee   *big.Int // 预计下一个区块的基础费用
+	GasTipCap *big.Int // maxPriorityFeePerGas
+	GasFeeCap *big.Int // maxFeePerGas
+}
+
+// Legacy 费用是否针对不支持EIP-1559的链（应发送Legacy交易）
+func (f *Fees) Legacy() bool {
+	return f.BaseFee == nil
+}
+
+// legacyFees 构造Legacy交易的费用参数
+func legacyFees(strategy string, gasPrice *big.Int) *Fees {
+	return &Fees{
+		Strategy:  strategy,
+		GasTipCap: new(big.Int).Set(gasPrice),
+		GasFeeCap: new(big.Int).Set(gasPrice),
+	}
+}
+
+// Strategy 费用策略
+type Strategy interface {
+	Name() string
+	SuggestFees(ctx context.Context, client backend.Client) (*Fees, error)
+}
+
+// CostChecker 可选接口：在签名前检查交易的总花费
+type CostChecker interface {
+	CheckCost(cost *CostEstimate) error
+}
+
+// defaultTipCap 节点无法给出小费建议时使用的默认小费（2 Gwei）
+var defaultTipCap = big.NewInt(2e9)
+
+// NodeStrategy 使用节点的 eth_maxPriorityFeePerGas 建议小费，费用上限为 基础费用*2 + 小费
+// 链不支持EIP-1559时改用节点建议的 gasPrice
+type NodeStrategy struct {
+	Multiplier float64 // 对费用上限额外乘的系数（对应 GAS_PRICE_MULTIPLIER），0 表示不调整
+}
+
+// Name 策略名称
+func (s *NodeStrategy) Name() string { return "node" }
+
+// SuggestFees 计算费用参数
+func (s *NodeStrategy) SuggestFees(ctx context.Context, client backend.Client) (*Fees, error) {
+	header, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取区块头失败: %v", err)
+	}
+	if header.BaseFee == nil {
+		gasPrice, err := client.SuggestGasPrice(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("获取Gas价格失败: %v", err)
+		}
+		if s.Multiplier > 0 && s.Multiplier != 1 {
+			gasPrice = mulFloat(gasPrice, s.Multiplier)
+		}
+		return legacyFees(s.Name(), gasPrice), nil
+	}
+
+	tipCap, err := client.SuggestGasTipCap(ctx)
+	if err != nil {
+		tipCap = new(big.Int).Set(defaultTipCap)
+		fmt.Printf("Warning: 获取小费建议失败，使用默认值: %s Gwei\n", ToGwei(tipCap))
+	}
+
+	return &Fees{
+		Strategy:  s.Name(),
+		BaseFee:   header.BaseFee,
+		GasTipCap: tipCap,
+		GasFeeCap: feeCap(header.BaseFee, 2, tipCap, s.Multiplier),
+	}, nil
+}
+
+// FeeHistoryStrategy 基于 eth_feeHistory 最近区块的小费分位数定价
+type FeeHistoryStrategy struct {
+	Label             string
+	Percentile        float64 // 小费分位数（0-100）
+	BlockCount        uint64  // 参考的历史区块数量
+	BaseFeeMultiplier float64 // 基础费用缓冲倍数，用于应对接下来几个区块的基础费用上涨
+	Multiplier        float64 // 对费用上限额外乘的系数，0 表示不调整
+}
+
+// 预设的慢/标准/快速策略
+var (
+	Slow     = &FeeHistoryStrategy{Label: "slow", Percentile: 10, BlockCount: 20, BaseFeeMultiplier: 1.25}
+	Standard = &FeeHistoryStrategy{Label: "standard", Percentile: 50, BlockCount: 20, BaseFeeMultiplier: 2}
+	Fast     = &FeeHistoryStrategy{Label: "fast", Percentile: 90, BlockCount: 20, BaseFeeMultiplier: 2.5}
+)
+
+// Name 策略名称
+func (s *FeeHistoryStrategy) Name() string { return s.Label }
+
+// SuggestFees 查询历史费用并按分位数计算费用参数
+func (s *FeeHistoryStrategy) SuggestFees(ctx context.Context, client backend.Client) (*Fees, error) {
+	history, err := client.FeeHistory(ctx, s.BlockCount, nil, []float64{s.Percentile})
+	if err != nil {
+		return nil, fmt.Errorf("查询费用历史失败: %v", err)
+	}
+	if len(history.BaseFee) == 0 {
+		return nil, fmt.Errorf("费用历史为空")
+	}
+	// BaseFee 的最后一项是下一个区块的基础费用
+	baseFee := history.BaseFee[len(history.BaseFee)-1]
+
+	// 取各区块分位数小费的中位数，空区块的0小费不参与计算
+	var tips []*big.Int
+	for _, rewards := range history.Reward {
+		if len(rewards) > 0 && rewards[0] != nil && rewards[0].Sign() > 0 {
+			tips = append(tips, rewards[0])
+		}
+	}
+	var tipCap *big.Int
+	if len(tips) > 0 {
+		sort.Slice(tips, func(i, j int) bool { return tips[i].Cmp(tips[j]) < 0 })
+		tipCap = new(big.Int).Set(tips[len(tips)/2])
+	} else {
+		// 最近区块没有交易可供参考，退回节点建议
+		tipCap, err = client.SuggestGasTipCap(ctx)
+		if err != nil {
+			tipCap = new(big.Int).Set(defaultTipCap)
+		}
+	}
+
+	return &Fees{
+		Strategy:  s.Name(),
+		BaseFee:   baseFee,
+		GasTipCap: tipCap,
+		GasFeeCap: feeCap(baseFee, s.BaseFeeMultiplier, tipCap, s.Multiplier),
+	}, nil
+}
+
+// FixedStrategy 固定的小费和费用上限
+type FixedStrategy struct {
+	GasTipCap *big.Int
+	GasFeeCap *big.Int
+}
+
+// Name 策略名称
+func (s *FixedStrategy) Name() string { return "fixed" }
+
+// SuggestFees 返回固定费用，若小费高于费用上限或费用上限低于当前基础费用则报错（交易会被节点拒绝或不会被打包）
+// 链不支持EIP-1559时以固定费用上限作为 gasPrice
+func (s *FixedStrategy) SuggestFees(ctx context.Context, client backend.Client) (*Fees, error) {
+	if s.GasTipCap.Cmp(s.GasFeeCap) > 0 {
+		return nil, fmt.Errorf("固定小费 %s Gwei 高于费用上限 %s Gwei", ToGwei(s.GasTipCap), ToGwei(s.GasFeeCap))
+	}
+	header, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取区块头失败: %v", err)
+	}
+	if header.BaseFee == nil {
+		return legacyFees(s.Name(), s.GasFeeCap), nil
+	}
+	if s.GasFeeCap.Cmp(header.BaseFee) < 0 {
+		return nil, fmt.Errorf("固定费用上限 %s Gwei 低于当前基础费用 %s Gwei", ToGwei(s.GasFeeCap), ToGwei(header.BaseFee))
+	}
+	return &Fees{
+		Strategy:  s.Name(),
+		BaseFee:   header.BaseFee,
+		GasTipCap: new(big.Int).Set(s.GasTipCap),
+		GasFeeCap: new(big.Int).Set(s.GasFeeCap),
+	}, nil
+}
+
+// CeilingStrategy 为任意策略加上花费上限
+// MaxFeePerGas 限制单位Gas的最高价格，MaxCost 限制整笔交易最坏情况下的总花费（含转账金额）
+type CeilingStrategy struct {
+	Inner        Strategy
+	MaxFeePerGas *big.Int
+	MaxCost      *big.Int
+}
+
+// Name 策略名称
+func (s *CeilingStrategy) Name() string { return s.Inner.Name() + "+ceiling" }
+
+// SuggestFees 计算内层策略的费用并截断到上限
+func (s *CeilingStrategy) SuggestFees(ctx context.Context, client backend.Client) (*Fees, error) {
+	fees, err := s.Inner.SuggestFees(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	fees.Strategy = s.Name()
+	if s.MaxFeePerGas != nil && fees.GasFeeCap.Cmp(s.MaxFeePerGas) > 0 {
+		if !fees.Legacy() && s.MaxFeePerGas.Cmp(fees.BaseFee) < 0 {
+			return nil, fmt.Errorf("费用上限 %s Gwei 低于当前基础费用 %s Gwei，交易无法被打包", ToGwei(s.MaxFeePerGas), ToGwei(fees.BaseFee))
+		}
+		fees.GasFeeCap = new(big.Int).Set(s.MaxFeePerGas)
+		if fees.GasTipCap.Cmp(fees.GasFeeCap) > 0 {
+			fees.GasTipCap = new(big.Int).Set(fees.GasFeeCap)
+		}
+	}
+	return fees, nil
+}
+
+// CheckCost 检查最坏情况下的总花费是否超过上限
+func (s *CeilingStrategy) CheckCost(cost *CostEstimate) error {
+	if s.MaxCost != nil && cost.WorstCase.Cmp(s.MaxCost) > 0 {
+		return fmt.Errorf("最坏情况花费 %s ETH 超过上限 %s ETH", ToEther(cost.WorstCase), ToEther(s.MaxCost))
+	}
+	return nil
+}
+
+// ByName 按名称获取预设策略（slow/standard/fast/node）
+func ByName(name string, multiplier float64) (Strategy, error) {
+	withMultiplier := func(s *FeeHistoryStrategy) Strategy {
+		copied := *s
+		copied.Multiplier = multiplier
+		return &copied
+	}
+	switch strings.ToLower(name) {
+	case "", "node":
+		return &NodeStrategy{Multiplier: multiplier}, nil
+	case "slow":
+		return withMultiplier(Slow), nil
+	case "standard":
+		return withMultiplier(Standard), nil
+	case "fast":
+		return withMultiplier(Fast), nil
+	default:
+		return nil, fmt.Errorf("未知的费用策略: %s", name)
+	}
+}
+
+// FromConfig 根据配置创建费用策略
+// FEE_STRATEGY 选择 slow/standard/fast/node/fixed，FEE_MAX_FEE_GWEI 和 FEE_MAX_COST_ETH 设置花费上限
+func FromConfig(cfg *config.Config) (Strategy, error) {
+	if cfg == nil {
+		return &NodeStrategy{}, nil
+	}
+
+	var strategy Strategy
+	if strings.ToLower(cfg.FeeStrategy) == "fixed" {
+		if cfg.FeeFixedTipGwei <= 0 || cfg.FeeFixedCapGwei <= 0 {
+			return nil, fmt.Errorf("fixed 策略需要设置 FEE_FIXED_TIP_GWEI 和 FEE_FIXED_CAP_GWEI")
+		}
+		if cfg.FeeFixedTipGwei > cfg.FeeFixedCapGwei {
+			return nil, fmt.Errorf("FEE_FIXED_TIP_GWEI (%v) 不能高于 FEE_FIXED_CAP_GWEI (%v)", cfg.FeeFixedTipGwei, cfg.FeeFixedCapGwei)
+		}
+		strategy = &FixedStrategy{
+			GasTipCap: FromGwei(cfg.FeeFixedTipGwei),
+			GasFeeCap: FromGwei(cfg.FeeFixedCapGwei),
+		}
+	} else {
+		var err error
+		strategy, err = ByName(cfg.FeeStrategy, cfg.GasPriceMultiplier)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.FeeMaxFeeGwei > 0 || cfg.FeeMaxCostEther > 0 {
+		ceiling := &CeilingStrategy{Inner: strategy}
+		if cfg.FeeMaxFeeGwei > 0 {
+			ceiling.MaxFeePerGas = FromGwei(cfg.FeeMaxFeeGwei)
+		}
+		if cfg.FeeMaxCostEther > 0 {
+			ceiling.MaxCost = fromFloat(cfg.FeeMaxCostEther, 1e18)
+		}
+		strategy = ceiling
+	}
+	return strategy, nil
+}
+
+// invalidConfigWarning 保证配置无效的警告只打印一次
+var invalidConfigWarning sync.Once
+
+// Default 使用全局配置创建费用策略，配置无效时退回节点建议策略（警告只在第一次打印）
+func Default() Strategy {
+	strategy, err := FromConfig(config.GlobalConfig)
+	if err != nil {
+		invalidConfigWarning.Do(func() {
+			fmt.Printf("Warning: 费用策略配置无效，使用节点建议: %v\n", err)
+		})
+		return &NodeStrategy{}
+	}
+	return strategy
+}
+
+// Print 打印费用参数
+func (f *Fees) Print() {
+	fmt.Printf("✓ 费用策略: %s\n", f.Strategy)
+	if f.Legacy() {
+		fmt.Printf("✓ Gas价格: %s Gwei (网络不支持EIP-1559)\n", ToGwei(f.GasFeeCap))
+		return
+	}
+	fmt.Printf("✓ 基础费用: %s Gwei\n", ToGwei(f.BaseFee))
+	fmt.Printf("✓ 小费上限: %s Gwei\n", ToGwei(f.GasTipCap))
+	fmt.Printf("✓ 费用上限: %s Gwei\n", ToGwei(f.GasFeeCap))
+}
+
+// feeCap 计算 基础费用*baseMultiplier + 小费，再乘以 multiplier
+func feeCap(baseFee *big.Int, baseMultiplier float64, tipCap *big.Int, multiplier float64) *big.Int {
+	result := new(big.Int).Add(mulFloat(baseFee, baseMultiplier), tipCap)
+	if multiplier > 0 && multiplier != 1 {
+		result = mulFloat(result, multiplier)
+	}
+	return result
+}
+
+// mulFloat 大整数乘以浮点系数（向下取整）
+func mulFloat(x *big.Int, factor float64) *big.Int {
+	product := new(big.Float).Mul(new(big.Float).SetInt(x), big.NewFloat(factor))
+	result, _ := product.Int(nil)
+	return result
+}
+
+// fromFloat 将浮点数按单位转换为整数（如 Gwei -> Wei）
+func fromFloat(value float64, unit float64) *big.Int {
+	result, _ := new(big.Float).Mul(big.NewFloat(value), big.NewFloat(unit)).Int(nil)
+	return result
+}
+
+// FromGwei 将Gwei转换为Wei
+func FromGwei(gwei float64) *big.Int {
+	return fromFloat(gwei, 1e9)
+}
+
+// ToGwei 将Wei格式化为Gwei的十进制字符串（用于显示，不舍入）
+func ToGwei(wei *big.Int) string {
+	return amount.Format(wei, 9)
+}
+
+// ToEther 将Wei格式化为ETH的十进制字符串（用于显示，不舍入）
+func ToEther(wei *big.Int) string {
+	return amount.Format(wei, amount.EtherDecimals)
+}
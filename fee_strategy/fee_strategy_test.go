@@ -0,0 +1,137 @@
+package fee_strategy
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/backend/backendtest"
+	"ethclient_tutorial/config"
+)
+
+func TestPresetsAreOrdered(t *testing.T) {
+	sim := backendtest.New(t, 1)
+
+	var caps []*big.Int
+	for _, s := range []Strategy{Slow, Standard, Fast} {
+		fees, err := s.SuggestFees(context.Background(), sim)
+		if err != nil {
+			t.Fatalf("%s: %v", s.Name(), err)
+		}
+		if fees.GasFeeCap.Cmp(fees.BaseFee) < 0 {
+			t.Fatalf("%s: fee cap %s below base fee %s", s.Name(), fees.GasFeeCap, fees.BaseFee)
+		}
+		caps = append(caps, fees.GasFeeCap)
+	}
+	if caps[0].Cmp(caps[1]) > 0 || caps[1].Cmp(caps[2]) > 0 {
+		t.Fatalf("fee caps not ordered slow <= standard <= fast: %v", caps)
+	}
+}
+
+func TestCeilingClampsFeeCap(t *testing.T) {
+	sim := backendtest.New(t, 1)
+
+	inner := &FixedStrategy{GasTipCap: FromGwei(50), GasFeeCap: FromGwei(100)}
+	ceiling := &CeilingStrategy{Inner: inner, MaxFeePerGas: FromGwei(10)}
+	fees, err := ceiling.SuggestFees(context.Background(), sim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fees.GasFeeCap.Cmp(FromGwei(10)) != 0 {
+		t.Fatalf("fee cap = %s, want 10 gwei", fees.GasFeeCap)
+	}
+	if fees.GasTipCap.Cmp(fees.GasFeeCap) > 0 {
+		t.Fatalf("tip cap %s exceeds fee cap %s", fees.GasTipCap, fees.GasFeeCap)
+	}
+}
+
+func TestMaxCostRejectsExpensiveTransaction(t *testing.T) {
+	sim := backendtest.New(t, 1)
+
+	strategy, err := FromConfig(&config.Config{FeeStrategy: "fixed", FeeFixedTipGwei: 1, FeeFixedCapGwei: 10, FeeMaxCostEther: 0.001})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fees, err := strategy.SuggestFees(context.Background(), sim)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 21000 * 10 gwei = 0.00021 ETH，在上限内
+	if _, err := EstimateCost(strategy, fees, 21000, nil); err != nil {
+		t.Fatalf("cheap transfer rejected: %v", err)
+	}
+	// 转账金额同样计入最坏情况花费
+	if _, err := EstimateCost(strategy, fees, 21000, big.NewInt(1e15)); err == nil {
+		t.Fatal("expected max cost error")
+	}
+}
+
+func TestFixedBelowBaseFeeFails(t *testing.T) {
+	sim := backendtest.New(t, 1)
+
+	fixed := &FixedStrategy{GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(1)}
+	if _, err := fixed.SuggestFees(context.Background(), sim); err == nil {
+		t.Fatal("expected error for fee cap below base fee")
+	}
+}
+
+// legacyChain 不支持EIP-1559的链：区块头没有基础费用
+type legacyChain struct {
+	*backend.Simulated
+}
+
+func (c legacyChain) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	header, err := c.Simulated.HeaderByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	header.BaseFee = nil
+	return header, nil
+}
+
+func TestFixedRejectsInvalidFees(t *testing.T) {
+	sim := backendtest.New(t, 1)
+
+	if _, err := FromConfig(&config.Config{FeeStrategy: "fixed", FeeFixedTipGwei: 20, FeeFixedCapGwei: 10}); err == nil {
+		t.Fatal("expected error for tip above fee cap in config")
+	}
+	fixed := &FixedStrategy{GasTipCap: FromGwei(20), GasFeeCap: FromGwei(10)}
+	if _, err := fixed.SuggestFees(context.Background(), sim); err == nil {
+		t.Fatal("expected error for tip above fee cap")
+	}
+}
+
+func TestLegacyChainFees(t *testing.T) {
+	sim := backendtest.New(t, 1)
+	chain := legacyChain{sim}
+
+	// 没有基础费用的链返回Legacy交易的gasPrice而不是报错
+	fixed := &FixedStrategy{GasTipCap: FromGwei(1), GasFeeCap: FromGwei(10)}
+	ceiling := &CeilingStrategy{Inner: fixed, MaxFeePerGas: FromGwei(8), MaxCost: big.NewInt(21000 * 7e9)}
+	fees, err := ceiling.SuggestFees(context.Background(), chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fees.Legacy() || fees.GasFeeCap.Cmp(FromGwei(8)) != 0 {
+		t.Fatalf("fees = %+v, want legacy gas price of 8 gwei", fees)
+	}
+	cost, err := EstimateCost(ceiling, fees, 21000, nil)
+	if err == nil {
+		t.Fatal("expected max cost error")
+	}
+	if cost.Expected.Cmp(cost.WorstCase) != 0 {
+		t.Fatalf("expected %s != worst case %s for a legacy gas price", cost.Expected, cost.WorstCase)
+	}
+
+	node, err := (&NodeStrategy{}).SuggestFees(context.Background(), chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !node.Legacy() || node.GasFeeCap.Sign() <= 0 {
+		t.Fatalf("node fees = %+v, want legacy gas price", node)
+	}
+}
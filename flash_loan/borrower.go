@@ -0,0 +1,90 @@
+package flash_loan
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// BorrowerABI 测试借款合约（IERC3156FlashBorrower）的接口
+// 构造参数 mode 决定回调的行为，回调时发出 FlashLoanObserved 事件记录借款期间看到的数据
+const BorrowerABI = `[
+	{"type":"constructor","stateMutability":"nonpayable","inputs":[{"name":"mode","type":"uint8"}]},
+	{"type":"function","name":"onFlashLoan","stateMutability":"nonpayable","inputs":[{"name":"initiator","type":"address"},{"name":"token","type":"address"},{"name":"amount","type":"uint256"},{"name":"fee","type":"uint256"},{"name":"data","type":"bytes"}],"outputs":[{"name":"","type":"bytes32"}]},
+	{"type":"event","name":"FlashLoanObserved","anonymous":false,"inputs":[{"name":"initiator","type":"address","indexed":false},{"name":"amount","type":"uint256","indexed":false},{"name":"fee","type":"uint256","indexed":false},{"name":"balance","type":"uint256","indexed":false},{"name":"data","type":"bytes","indexed":false}]}
+]`
+
+// BorrowerBytecode 测试借款合约的部署字节码（手写汇编，构造参数 mode 存在slot 0）
+//
+// 构造函数：
+//
+//	PUSH1 0x20 DUP1 CODESIZE SUB PUSH1 0 CODECOPY PUSH1 0 MLOAD PUSH1 0 SSTORE
+//	PUSH2 len(runtime) DUP1 PUSH1 0x1a PUSH1 0 CODECOPY PUSH1 0 RETURN
+//
+// 运行时代码：只接受 onFlashLoan，且调用方必须是参数中的token（出借方即代币合约）
+//
+//	PUSH1 0 CALLDATALOAD PUSH1 0xe0 SHR PUSH4 onFlashLoan() EQ PUSH2 @loan JUMPI PUSH1 0 DUP1 REVERT
+//	@loan:   JUMPDEST PUSH1 0x24 CALLDATALOAD CALLER EQ PUSH2 @lender JUMPI PUSH1 0 DUP1 REVERT
+//	@lender: JUMPDEST                                          ; balance = token.balanceOf(this) -> mem[0x60]
+//	         PUSH4 balanceOf() PUSH1 0xe0 SHL PUSH1 0 MSTORE ADDRESS PUSH1 4 MSTORE
+//	         PUSH1 0x20 PUSH1 0x60 PUSH1 0x24 PUSH1 0 PUSH1 0x24 CALLDATALOAD GAS STATICCALL ISZERO PUSH2 @fail JUMPI
+//	         PUSH1 0 SLOAD DUP1 PUSH1 1 EQ PUSH2 @log JUMPI     ; ModeNoRepay 不授权还款
+//	         PUSH4 approve() PUSH1 0xe0 SHL PUSH2 0x100 MSTORE   ; token.approve(token, amount+fee)
+//	         PUSH1 0x24 CALLDATALOAD PUSH2 0x104 MSTORE
+//	         PUSH1 0x64 CALLDATALOAD PUSH1 0x44 CALLDATALOAD ADD PUSH2 0x124 MSTORE
+//	         PUSH1 0 PUSH1 0 PUSH1 0x44 PUSH2 0x100 PUSH1 0 PUSH1 0x24 CALLDATALOAD GAS CALL ISZERO PUSH2 @fail JUMPI
+//	@log:    JUMPDEST                                          ; FlashLoanObserved(initiator, amount, fee, balance, data)
+//	         PUSH1 4 CALLDATALOAD PUSH1 0 MSTORE PUSH1 0x44 CALLDATALOAD PUSH1 0x20 MSTORE
+//	         PUSH1 0x64 CALLDATALOAD PUSH1 0x40 MSTORE PUSH1 0xa0 PUSH1 0x80 MSTORE
+//	         PUSH1 0xa4 CALLDATASIZE SUB DUP1 PUSH1 0xa4 PUSH1 0xa0 CALLDATACOPY
+//	         PUSH1 0xa0 ADD PUSH32 topic SWAP1 PUSH1 0 LOG1
+//	         PUSH1 2 EQ PUSH2 @wrong JUMPI                     ; ModeWrongReturn 返回错误的值
+//	         PUSH32 keccak256("ERC3156FlashBorrower.onFlashLoan") PUSH1 0 MSTORE PUSH1 0x20 PUSH1 0 RETURN
+//	@wrong:  JUMPDEST PUSH1 0 PUSH1 0 MSTORE PUSH1 0x20 PUSH1 0 RETURN
+//	@fail:   JUMPDEST RETURNDATASIZE PUSH1 0 DUP1 RETURNDATACOPY RETURNDATASIZE PUSH1 0 REVERT
+const BorrowerBytecode = "0x602080380360003960005160005561011180601a6000396000f360003560e01c6323e30c8b1461001457600080fd5b602435331461002257600080fd5b6370a0823160e01b6000523060045260206060602460006024355afa1561010757600054806001146100825763095ea7b360e01b6101005260243561010452606435604435016101245260006000604461010060006024355af115610107575b60043560005260443560205260643560405260a060805260a436038060a460a03760a0017f1a861cb7e007b505acf0a76818f2c1fa46b7e65702b05b6ec262ad597a73fb2b906000a16002146100fc577f439148f0bbc682ca079e46d6e2c2f0c1e3b820f1a291b069d8882abf8cf18dd960005260206000f35b600060005260206000f35b3d6000803e3d6000fd"
+
+// Mode 借款合约在回调中的行为
+type Mode uint8
+
+const (
+	// ModeRepay 授权出借方收回 amount+fee 并返回约定值（正常还款）
+	ModeRepay Mode = iota
+	// ModeNoRepay 不授权还款，出借方收回时因额度不足回滚（ERC20InsufficientAllowance）
+	ModeNoRepay
+	// ModeWrongReturn 授权还款但返回错误的值，出借方以 ERC3156InvalidReceiver 回滚
+	ModeWrongReturn
+)
+
+var modeNames = map[Mode]string{
+	ModeRepay:       "repay",
+	ModeNoRepay:     "no-repay",
+	ModeWrongReturn: "wrong-return",
+}
+
+func (m Mode) String() string {
+	if name, ok := modeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("Mode(%d)", uint8(m))
+}
+
+// ParseMode 解析模式名称：repay、no-repay、wrong-return
+func ParseMode(name string) (Mode, error) {
+	for mode, n := range modeNames {
+		if n == name {
+			return mode, nil
+		}
+	}
+	return 0, fmt.Errorf("未知的借款合约模式: %s（可选 repay、no-repay、wrong-return）", name)
+}
+
+// Observation 借款合约在回调中记录的数据（FlashLoanObserved 事件）
+type Observation struct {
+	Initiator common.Address
+	Amount    *big.Int
+	Fee       *big.Int
+	Balance   *big.Int // 回调时借款合约的代币余额（已包含借到的数量）
+	Data      []byte
+}
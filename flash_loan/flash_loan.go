@@ -0,0 +1,217 @@
+package flash_loan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/contracts"
+	"ethclient_tutorial/erc20"
+	"ethclient_tutorial/fee_strategy"
+	"ethclient_tutorial/revert_decoder"
+	"ethclient_tutorial/signer"
+	"ethclient_tutorial/tx_builder"
+	"ethclient_tutorial/utils"
+)
+
+var (
+	// ErrExceedsMaxLoan 借款数量超过 maxFlashLoan
+	ErrExceedsMaxLoan = errors.New("借款数量超过最大可借数量")
+	// ErrInsufficientFee 借款合约的余额不足以支付手续费
+	ErrInsufficientFee = errors.New("借款合约余额不足以支付手续费")
+	// ErrNotContract 借款方没有合约代码（回调普通账户会无原因回滚）
+	ErrNotContract = errors.New("借款方不是合约")
+)
+
+var borrowerABI = mustParseABI(BorrowerABI)
+
+func mustParseABI(definition string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(definition))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+// Harness 针对实现了ERC-3156（ERC20FlashMint）的代币执行闪电贷
+type Harness struct {
+	Token    common.Address
+	Strategy fee_strategy.Strategy // 为nil时使用默认策略
+
+	client backend.Client
+	abi    *abi.ABI
+	lender *contracts.MYERC20
+}
+
+// New 创建闪电贷工具，token 同时是出借方
+func New(client backend.Client, token common.Address) (*Harness, error) {
+	parsed, err := contracts.MYERC20MetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	lender, err := contracts.NewMYERC20(token, client)
+	if err != nil {
+		return nil, fmt.Errorf("创建合约实例失败: %v", err)
+	}
+	return &Harness{
+		Token:  token,
+		client: client,
+		abi:    parsed,
+		lender: lender,
+	}, nil
+}
+
+// Quote 闪电贷报价
+type Quote struct {
+	Amount  *big.Int
+	Fee     *big.Int // flashFee(token, amount)
+	MaxLoan *big.Int // maxFlashLoan(token)
+}
+
+// Quote 查询借amount数量（最小单位）时的手续费和最大可借数量
+func (h *Harness) Quote(ctx context.Context, amount *big.Int) (*Quote, error) {
+	opts := &bind.CallOpts{Context: ctx}
+	maxLoan, err := h.lender.MaxFlashLoan(opts, h.Token)
+	if err != nil {
+		return nil, fmt.Errorf("查询maxFlashLoan失败: %v", err)
+	}
+	quote := &Quote{Amount: amount, MaxLoan: maxLoan}
+	if amount.Cmp(maxLoan) > 0 {
+		return quote, fmt.Errorf("%w: 借 %s，最多 %s", ErrExceedsMaxLoan, amount, maxLoan)
+	}
+	fee, err := h.lender.FlashFee(opts, h.Token, amount)
+	if err != nil {
+		if revertErr, ok := revert_decoder.Default().FromError(err); ok {
+			return quote, revertErr
+		}
+		return quote, fmt.Errorf("查询flashFee失败: %v", err)
+	}
+	quote.Fee = fee
+	return quote, nil
+}
+
+// DeployBorrower 部署测试借款合约，mode 决定回调时是否还款
+func (h *Harness) DeployBorrower(ctx context.Context, s signer.Signer, mode Mode, wait utils.WaitOptions) (common.Address, common.Hash, error) {
+	args, err := borrowerABI.Pack("", uint8(mode))
+	if err != nil {
+		return common.Address{}, common.Hash{}, fmt.Errorf("构造部署参数失败: %v", err)
+	}
+	bytecode := common.FromHex(BorrowerBytecode)
+	msg := ethereum.CallMsg{Data: append(append([]byte{}, bytecode...), args...)}
+
+	status, err := tx_builder.TransactAndWait(ctx, h.client, h.Strategy, s, msg, "部署借款合约", wait)
+	if err != nil {
+		var txHash common.Hash
+		if status != nil {
+			txHash = status.TxHash
+		}
+		return common.Address{}, txHash, err
+	}
+	return status.Receipt.ContractAddress, status.TxHash, nil
+}
+
+// Result 一次闪电贷的结果，余额和总供应量分别在交易前一个区块和交易所在区块读取
+type Result struct {
+	TxHash      common.Hash
+	BlockNumber uint64
+	Borrower    common.Address
+	Amount      *big.Int
+	Fee         *big.Int // 发送前报价的手续费
+
+	BalanceBefore *big.Int // 借款合约余额
+	BalanceAfter  *big.Int
+	SupplyBefore  *big.Int // 代币总供应量（借出时铸造、归还时销毁）
+	SupplyAfter   *big.Int
+
+	Observed *Observation // 借款合约发出的 FlashLoanObserved 事件，非本工具部署的借款合约为nil
+}
+
+// BalanceChange 借款合约余额的变化（还款后应为 -fee）
+func (r *Result) BalanceChange() *big.Int {
+	return new(big.Int).Sub(r.BalanceAfter, r.BalanceBefore)
+}
+
+// SupplyChange 总供应量的变化（手续费被销毁或转给收款地址）
+func (r *Result) SupplyChange() *big.Int {
+	return new(big.Int).Sub(r.SupplyAfter, r.SupplyBefore)
+}
+
+// Loan 向borrower发起闪电贷：借出amount数量（最小单位），回调时把data原样传给借款合约
+// 发送前检查借款方是否为合约、最大可借数量、借款合约能否支付手续费，并模拟执行以解码回滚原因
+func (h *Harness) Loan(ctx context.Context, s signer.Signer, borrower common.Address, amount *big.Int, data []byte, wait utils.WaitOptions) (*Result, error) {
+	code, err := h.client.CodeAt(ctx, borrower, nil)
+	if err != nil {
+		return nil, fmt.Errorf("查询 %s 的代码失败: %v", borrower.Hex(), err)
+	}
+	if len(code) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNotContract, borrower.Hex())
+	}
+	quote, err := h.Quote(ctx, amount)
+	if err != nil {
+		return nil, err
+	}
+	token := erc20.New(h.client, h.Token)
+	balance, err := token.BalanceOf(ctx, borrower)
+	if err != nil {
+		return nil, err
+	}
+	if balance.Cmp(quote.Fee) < 0 {
+		return nil, fmt.Errorf("%w: 手续费 %s，借款合约余额 %s（请先向 %s 转入代币）", ErrInsufficientFee, quote.Fee, balance, borrower.Hex())
+	}
+
+	packed, err := h.abi.Pack("flashLoan", borrower, h.Token, amount, data)
+	if err != nil {
+		return nil, fmt.Errorf("构造flashLoan调用数据失败: %v", err)
+	}
+	msg := ethereum.CallMsg{To: &h.Token, Data: packed}
+	status, err := tx_builder.TransactAndWait(ctx, h.client, h.Strategy, s, msg, "flashLoan", wait)
+	result := &Result{Borrower: borrower, Amount: amount, Fee: quote.Fee}
+	if status != nil {
+		result.TxHash = status.TxHash
+		result.BlockNumber = status.BlockNumber
+	}
+	if err != nil {
+		return result, err
+	}
+
+	after := new(big.Int).SetUint64(status.BlockNumber)
+	before := new(big.Int).SetUint64(status.BlockNumber - 1)
+	if result.BalanceBefore, err = token.BalanceAt(ctx, borrower, before); err != nil {
+		return result, err
+	}
+	if result.BalanceAfter, err = token.BalanceAt(ctx, borrower, after); err != nil {
+		return result, err
+	}
+	if result.SupplyBefore, err = token.TotalSupplyAt(ctx, before); err != nil {
+		return result, err
+	}
+	if result.SupplyAfter, err = token.TotalSupplyAt(ctx, after); err != nil {
+		return result, err
+	}
+	result.Observed = observation(status.Receipt, borrower)
+	return result, nil
+}
+
+// observation 从收据中读取借款合约的 FlashLoanObserved 事件
+func observation(receipt *types.Receipt, borrower common.Address) *Observation {
+	event := borrowerABI.Events["FlashLoanObserved"]
+	for _, log := range receipt.Logs {
+		if log.Address != borrower || len(log.Topics) == 0 || log.Topics[0] != event.ID {
+			continue
+		}
+		var observed Observation
+		if err := borrowerABI.UnpackIntoInterface(&observed, event.Name, log.Data); err == nil {
+			return &observed
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package flash_loan
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+
+	"ethclient_tutorial/backend/backendtest"
+	"ethclient_tutorial/erc20"
+	"ethclient_tutorial/revert_decoder"
+	"ethclient_tutorial/signer"
+	"ethclient_tutorial/utils"
+)
+
+func TestFlashLoanRepaid(t *testing.T) {
+	chain := backendtest.NewTokenChain(t, 2)
+	sim := chain.Sim
+	sim.AutoMine(100 * time.Millisecond)
+	harness, err := New(sim, chain.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := signer.NewKeySigner(sim.Accounts[1].Key)
+	ctx := context.Background()
+	wait := utils.WaitOptions{Timeout: 10 * time.Second}
+	token := erc20.New(sim, harness.Token)
+
+	amount := new(big.Int).Mul(big.NewInt(5000), big.NewInt(1e18)) // 超过初始供应量，由闪电铸造提供
+	quote, err := harness.Quote(ctx, amount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	supply, _ := token.TotalSupply(ctx)
+	if quote.Fee.Sign() != 0 || quote.MaxLoan.Cmp(new(big.Int).Sub(math.MaxBig256, supply)) != 0 {
+		t.Fatalf("quote = %+v", quote)
+	}
+
+	borrower, deployTx, err := harness.DeployBorrower(ctx, s, ModeRepay, wait)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if borrower == (common.Address{}) || deployTx == (common.Hash{}) {
+		t.Fatalf("deploy = %s, %s", borrower.Hex(), deployTx.Hex())
+	}
+	// 借款合约持有少量代币，借款期间余额应为持有量加借款数量
+	held := big.NewInt(7)
+	tx, err := token.Transfer(ctx, sim.Accounts[0].Key, borrower, held)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := utils.Wait(ctx, sim, tx.Hash(), wait); err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte("arbitrage route: A -> B -> A, with calldata longer than one word")
+	result, err := harness.Loan(ctx, s, borrower, amount, data, wait)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.BalanceBefore.Cmp(held) != 0 || result.BalanceChange().Sign() != 0 || result.SupplyChange().Sign() != 0 {
+		t.Fatalf("balances = %+v", result)
+	}
+	observed := result.Observed
+	if observed == nil || observed.Initiator != s.Address() || observed.Amount.Cmp(amount) != 0 || observed.Fee.Sign() != 0 ||
+		observed.Balance.Cmp(new(big.Int).Add(amount, held)) != 0 || !bytes.Equal(observed.Data, data) {
+		t.Fatalf("observed = %+v", observed)
+	}
+
+	// 空data也能原样传递
+	if result, err = harness.Loan(ctx, s, borrower, big.NewInt(1), nil, wait); err != nil || result.Observed == nil || len(result.Observed.Data) != 0 {
+		t.Fatalf("empty data loan = %+v, %v", result, err)
+	}
+}
+
+func TestFlashLoanFailures(t *testing.T) {
+	chain := backendtest.NewTokenChain(t, 2)
+	sim := chain.Sim
+	sim.AutoMine(100 * time.Millisecond)
+	harness, err := New(sim, chain.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := signer.NewKeySigner(sim.Accounts[1].Key)
+	ctx := context.Background()
+	wait := utils.WaitOptions{Timeout: 10 * time.Second}
+
+	if _, err := harness.Quote(ctx, math.MaxBig256); !errors.Is(err, ErrExceedsMaxLoan) {
+		t.Fatalf("quote above max err = %v", err)
+	}
+
+	cases := []struct {
+		mode Mode
+		want string
+	}{
+		{ModeNoRepay, "ERC20InsufficientAllowance"},
+		{ModeWrongReturn, "ERC3156InvalidReceiver"},
+	}
+	for _, c := range cases {
+		borrower, _, err := harness.DeployBorrower(ctx, s, c.mode, wait)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var revertErr *revert_decoder.RevertError
+		if _, err := harness.Loan(ctx, s, borrower, big.NewInt(1e18), nil, wait); !errors.As(err, &revertErr) || revertErr.Name != c.want {
+			t.Fatalf("%s loan err = %v", c.mode, err)
+		}
+	}
+
+	// 普通账户不能作为借款方
+	if _, err := harness.Loan(ctx, s, sim.Accounts[0].Address, big.NewInt(1), nil, wait); !errors.Is(err, ErrNotContract) {
+		t.Fatalf("EOA loan err = %v", err)
+	}
+
+	if mode, err := ParseMode("wrong-return"); err != nil || mode != ModeWrongReturn {
+		t.Fatalf("ParseMode = %v, %v", mode, err)
+	}
+	if _, err := ParseMode("steal"); err == nil {
+		t.Fatal("ParseMode accepted unknown mode")
+	}
+}
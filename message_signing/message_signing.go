@@ -0,0 +1,176 @@
+package message_signing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/signer"
+)
+
+// eip712DomainABI ERC-5267 eip712Domain() 接口
+const eip712DomainABI = `[
+	{"type":"function","name":"eip712Domain","stateMutability":"view","inputs":[],"outputs":[
+		{"name":"fields","type":"bytes1"},
+		{"name":"name","type":"string"},
+		{"name":"version","type":"string"},
+		{"name":"chainId","type":"uint256"},
+		{"name":"verifyingContract","type":"address"},
+		{"name":"salt","type":"bytes32"},
+		{"name":"extensions","type":"uint256[]"}
+	]}
+]`
+
+var domainABI = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(eip712DomainABI))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// ErrSignerMismatch 签名恢复出的地址与期望的地址不一致
+var ErrSignerMismatch = errors.New("签名者与期望地址不一致")
+
+// ERC-5267 fields 位图中各域字段的位置，顺序与 EIP712Domain 类型中的字段顺序一致
+var domainFields = []apitypes.Type{
+	{Name: "name", Type: "string"},
+	{Name: "version", Type: "string"},
+	{Name: "chainId", Type: "uint256"},
+	{Name: "verifyingContract", Type: "address"},
+	{Name: "salt", Type: "bytes32"},
+}
+
+// HashMessage EIP-191 personal_sign 消息哈希：keccak256("\x19Ethereum Signed Message:\n" + len + message)
+func HashMessage(message []byte) []byte {
+	return accounts.TextHash(message)
+}
+
+// SignMessage 按 personal_sign 签名消息，返回65字节签名（V为27或28）
+func SignMessage(ctx context.Context, s signer.Signer, message []byte) ([]byte, error) {
+	return s.SignMessage(ctx, message)
+}
+
+// VerifyMessage 从 personal_sign 签名恢复签名者地址
+func VerifyMessage(message, signature []byte) (common.Address, error) {
+	return signer.RecoverAddress(HashMessage(message), signature)
+}
+
+// ParseTypedData 解析 eth_signTypedData_v4 格式的JSON（types、primaryType、domain、message）
+func ParseTypedData(content []byte) (apitypes.TypedData, error) {
+	var data apitypes.TypedData
+	if err := json.Unmarshal(content, &data); err != nil {
+		return data, fmt.Errorf("解析EIP-712数据失败: %v", err)
+	}
+	if data.PrimaryType == "" {
+		return data, fmt.Errorf("EIP-712数据缺少 primaryType")
+	}
+	if _, ok := data.Types[data.PrimaryType]; !ok {
+		return data, fmt.Errorf("EIP-712数据缺少类型 %s 的定义", data.PrimaryType)
+	}
+	return data, nil
+}
+
+// HashTypedData EIP-712 签名哈希：keccak256("\x19\x01" || domainSeparator || hashStruct(message))
+func HashTypedData(data apitypes.TypedData) ([]byte, error) {
+	hash, _, err := apitypes.TypedDataAndHash(data)
+	if err != nil {
+		return nil, fmt.Errorf("计算EIP-712哈希失败: %v", err)
+	}
+	return hash, nil
+}
+
+// SignTypedData 按EIP-712签名结构化数据，返回65字节签名（V为27或28）
+func SignTypedData(ctx context.Context, s signer.Signer, data apitypes.TypedData) ([]byte, error) {
+	return s.SignTypedData(ctx, data)
+}
+
+// VerifyTypedData 从EIP-712签名恢复签名者地址
+func VerifyTypedData(data apitypes.TypedData, signature []byte) (common.Address, error) {
+	hash, err := HashTypedData(data)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return signer.RecoverAddress(hash, signature)
+}
+
+// CheckSigner 检查恢复出的签名者是否为期望地址
+func CheckSigner(recovered, expected common.Address) error {
+	if recovered != expected {
+		return fmt.Errorf("%w: 期望 %s，恢复出 %s", ErrSignerMismatch, expected.Hex(), recovered.Hex())
+	}
+	return nil
+}
+
+// Domain 合约通过 eip712Domain()（ERC-5267）公布的签名域
+type Domain struct {
+	Domain apitypes.TypedDataDomain
+	Types  []apitypes.Type // 与 fields 位图对应的 EIP712Domain 类型定义
+}
+
+// FetchDomain 调用合约的 eip712Domain() 获取签名域
+// 只支持没有扩展字段（extensions为空）的签名域
+func FetchDomain(ctx context.Context, client backend.Client, contract common.Address) (*Domain, error) {
+	data, err := domainABI.Pack("eip712Domain")
+	if err != nil {
+		return nil, err
+	}
+	result, err := client.CallContract(ctx, ethereum.CallMsg{To: &contract, Data: data}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("调用 eip712Domain() 失败: %v", err)
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("合约 %s 未实现 eip712Domain()", contract.Hex())
+	}
+	values, err := domainABI.Unpack("eip712Domain", result)
+	if err != nil {
+		return nil, fmt.Errorf("解码 eip712Domain() 返回值失败: %v", err)
+	}
+	fields := values[0].([1]byte)[0]
+	if extensions := values[6].([]*big.Int); len(extensions) > 0 {
+		return nil, fmt.Errorf("不支持带扩展字段的签名域 %v", extensions)
+	}
+
+	domain := &Domain{}
+	for i, field := range domainFields {
+		if fields&(1<<i) == 0 {
+			continue
+		}
+		domain.Types = append(domain.Types, field)
+		switch field.Name {
+		case "name":
+			domain.Domain.Name = values[1].(string)
+		case "version":
+			domain.Domain.Version = values[2].(string)
+		case "chainId":
+			domain.Domain.ChainId = (*math.HexOrDecimal256)(values[3].(*big.Int))
+		case "verifyingContract":
+			domain.Domain.VerifyingContract = values[4].(common.Address).Hex()
+		case "salt":
+			salt := values[5].([32]byte)
+			domain.Domain.Salt = hexutil.Encode(salt[:])
+		}
+	}
+	return domain, nil
+}
+
+// Apply 用合约的签名域替换数据中的 domain 和 EIP712Domain 类型
+func (d *Domain) Apply(data *apitypes.TypedData) {
+	if data.Types == nil {
+		data.Types = apitypes.Types{}
+	}
+	data.Types["EIP712Domain"] = d.Types
+	data.Domain = d.Domain
+}
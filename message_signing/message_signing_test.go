@@ -0,0 +1,127 @@
+package message_signing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/backend/backendtest"
+	"ethclient_tutorial/signer"
+)
+
+// mailJSON EIP-712 规范中的 Mail 示例（domain 由测试替换为合约的签名域）
+const mailJSON = `{
+	"types": {
+		"EIP712Domain": [{"name": "name", "type": "string"}],
+		"Person": [{"name": "name", "type": "string"}, {"name": "wallet", "type": "address"}],
+		"Mail": [{"name": "from", "type": "Person"}, {"name": "to", "type": "Person"}, {"name": "contents", "type": "string"}]
+	},
+	"primaryType": "Mail",
+	"domain": {"name": "placeholder"},
+	"message": {
+		"from": {"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+		"to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+		"contents": "Hello, Bob!"
+	}
+}`
+
+func TestPersonalMessage(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	s := signer.NewKeySigner(key)
+	message := []byte("登录 ethclient-tutorial")
+
+	signature, err := SignMessage(context.Background(), s, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recovered, err := VerifyMessage(message, signature)
+	if err != nil || CheckSigner(recovered, s.Address()) != nil {
+		t.Fatalf("recovered %s, %v", recovered.Hex(), err)
+	}
+
+	// V为0/1的签名同样可以验证
+	raw := common.CopyBytes(signature)
+	raw[64] -= 27
+	if recovered, _ := VerifyMessage(message, raw); recovered != s.Address() {
+		t.Fatal("signature with V=0/1 not accepted")
+	}
+
+	// 消息被修改后恢复出其他地址
+	recovered, err = VerifyMessage([]byte("登录 ethclient-tutorial!"), signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckSigner(recovered, s.Address()); !errors.Is(err, ErrSignerMismatch) {
+		t.Fatalf("tampered message err = %v", err)
+	}
+}
+
+func TestTypedDataWithContractDomain(t *testing.T) {
+	sim := backendtest.New(t, 1)
+	ctx := context.Background()
+	owner := sim.Accounts[0]
+
+	tokenAddress, token := backendtest.DeployMyToken(t, sim)
+
+	domain, err := FetchDomain(ctx, sim, tokenAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if domain.Domain.Name != "MyToken" || domain.Domain.Version != "1" || len(domain.Types) != 4 ||
+		(*big.Int)(domain.Domain.ChainId).Int64() != backend.SimulatedChainID ||
+		common.HexToAddress(domain.Domain.VerifyingContract) != tokenAddress {
+		t.Fatalf("domain = %+v", domain)
+	}
+
+	data, err := ParseTypedData([]byte(mailJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	domain.Apply(&data)
+
+	// 按合约签名域计算的域分隔符与合约中的 DOMAIN_SEPARATOR 一致
+	separator, err := data.HashStruct("EIP712Domain", data.Domain.Map())
+	if err != nil {
+		t.Fatal(err)
+	}
+	onChain, err := token.DOMAINSEPARATOR(nil)
+	if err != nil || !bytes.Equal(separator, onChain[:]) {
+		t.Fatalf("domain separator = %x, contract = %x (%v)", separator, onChain, err)
+	}
+
+	s := signer.NewKeySigner(owner.Key)
+	signature, err := SignTypedData(ctx, s, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recovered, err := VerifyTypedData(data, signature)
+	if err != nil || recovered != owner.Address {
+		t.Fatalf("recovered %s, %v", recovered.Hex(), err)
+	}
+
+	// 换一个签名域后签名不再有效
+	data.Domain.Version = "2"
+	if recovered, _ := VerifyTypedData(data, signature); recovered == owner.Address {
+		t.Fatal("signature should not verify under a different domain")
+	}
+
+	// 普通账户没有 eip712Domain()
+	if _, err := FetchDomain(ctx, sim, owner.Address); err == nil {
+		t.Fatal("EOA should not have an EIP-712 domain")
+	}
+}
+
+func TestParseTypedDataRequiresPrimaryType(t *testing.T) {
+	if _, err := ParseTypedData([]byte(`{"types": {}, "primaryType": "Mail"}`)); err == nil {
+		t.Fatal("missing type definition should be rejected")
+	}
+	if _, err := ParseTypedData([]byte(`not json`)); err == nil {
+		t.Fatal("invalid JSON should be rejected")
+	}
+}
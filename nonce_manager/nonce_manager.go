@@ -0,0 +1,300 @@
+package nonce_manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Client 管理器查询链上nonce和交易所需的节点接口，backend.Client 满足该接口
+// （backend.Simulated 持有自己的管理器，因此这里不能引用 backend 包）
+type Client interface {
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	TransactionByHash(ctx context.Context, hash common.Hash) (tx *types.Transaction, isPending bool, err error)
+}
+
+// Manager 按账户在本地分配nonce的管理器
+// 同一账户的并发交易从本地计数器依次领取nonce，不再各自调用PendingNonceAt导致冲突；
+// 首次使用时与链上状态同步，遇到nonce相关错误后重新同步，并能发现和回收空缺的nonce。
+type Manager struct {
+	client Client
+
+	mu       sync.Mutex
+	accounts map[common.Address]*accountState
+}
+
+// accountState 单个账户的nonce状态
+type accountState struct {
+	mu       sync.Mutex
+	synced   bool
+	next     uint64                 // 下一个全新的nonce
+	reserved map[uint64]bool        // 已领取、尚未发送的nonce
+	released []uint64               // 已领取但未成功发送、等待复用的nonce（升序）
+	inflight map[uint64]common.Hash // 已发送但尚未确认的交易
+}
+
+// Reservation 一次nonce领取，发送成功后调用Commit，失败后调用Release
+type Reservation struct {
+	Nonce   uint64
+	Account common.Address
+
+	manager *Manager
+	done    bool
+}
+
+// Owner 持有自己的共享nonce管理器的客户端（backend.Simulated、rpc_pool.Pool），管理器随客户端创建和释放
+type Owner interface {
+	NonceManager() *Manager
+}
+
+// ForClient 返回client持有的共享nonce管理器，所有交易构建函数都通过它领取nonce，
+// 因此经同一个 Owner 对同一账户的并发发送不会冲突；
+// client不是 Owner 时返回新的管理器，每次领取前都与链上pending状态同步
+func ForClient(client Client) *Manager {
+	if owner, ok := client.(Owner); ok {
+		return owner.NonceManager()
+	}
+	return NewManager(client)
+}
+
+// NewManager 创建独立的nonce管理器
+func NewManager(client Client) *Manager {
+	return &Manager{
+		client:   client,
+		accounts: make(map[common.Address]*accountState),
+	}
+}
+
+// state 获取账户状态（不存在则创建）
+func (m *Manager) state(account common.Address) *accountState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st, ok := m.accounts[account]
+	if !ok {
+		st = &accountState{
+			reserved: make(map[uint64]bool),
+			inflight: make(map[uint64]common.Hash),
+		}
+		m.accounts[account] = st
+	}
+	return st
+}
+
+// Reserve 为账户领取一个nonce，优先复用之前释放的空缺nonce
+func (m *Manager) Reserve(ctx context.Context, account common.Address) (*Reservation, error) {
+	st := m.state(account)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if !st.synced {
+		if err := m.syncLocked(ctx, account, st); err != nil {
+			return nil, err
+		}
+	}
+
+	var nonce uint64
+	if len(st.released) > 0 {
+		nonce = st.released[0]
+		st.released = st.released[1:]
+	} else {
+		nonce = st.next
+		st.next++
+	}
+	st.reserved[nonce] = true
+	return &Reservation{Nonce: nonce, Account: account, manager: m}, nil
+}
+
+// Commit 标记nonce对应的交易已成功广播
+func (r *Reservation) Commit(txHash common.Hash) {
+	if r.done {
+		return
+	}
+	r.done = true
+	st := r.manager.state(r.Account)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	delete(st.reserved, r.Nonce)
+	st.inflight[r.Nonce] = txHash
+}
+
+// Release 交易未能发送时归还nonce，对已Commit或已Release的领取无效
+// 因此可以在领取后直接 defer r.Release(nil) 兜底所有提前返回的路径。
+// 若错误表明本地nonce与链上不一致（nonce过低、替换交易费用不足等），会在下次领取前重新同步
+func (r *Reservation) Release(sendErr error) {
+	if r.done {
+		return
+	}
+	r.done = true
+	st := r.manager.state(r.Account)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	delete(st.reserved, r.Nonce)
+
+	if IsNonceError(sendErr) {
+		st.synced = false
+		return
+	}
+	st.released = insertSorted(st.released, r.Nonce)
+}
+
+// Replace 记录同一nonce的替换交易（加速或取消），使空缺检测跟踪新的交易哈希
+func (m *Manager) Replace(account common.Address, nonce uint64, txHash common.Hash) {
+	st := m.state(account)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if _, ok := st.inflight[nonce]; ok {
+		st.inflight[nonce] = txHash
+	}
+}
+
+// Resync 立即与链上状态重新同步账户nonce
+func (m *Manager) Resync(ctx context.Context, account common.Address) error {
+	st := m.state(account)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return m.syncLocked(ctx, account, st)
+}
+
+// syncLocked 与链上pending nonce对齐（调用方需持有st.mu）
+func (m *Manager) syncLocked(ctx context.Context, account common.Address, st *accountState) error {
+	pending, err := m.client.PendingNonceAt(ctx, account)
+	if err != nil {
+		return fmt.Errorf("同步nonce失败: %v", err)
+	}
+
+	// 链上已经超过本地计数（例如同一私钥在其他地方发送了交易），以链上为准
+	if pending > st.next {
+		st.next = pending
+	}
+
+	// 本地已分配、但既未被领取中也未在途的nonce属于空缺，放回待复用列表
+	for nonce := pending; nonce < st.next; nonce++ {
+		if _, ok := st.inflight[nonce]; !ok && !st.reserved[nonce] {
+			st.released = insertSorted(st.released, nonce)
+		}
+	}
+
+	// 低于链上pending nonce的已释放nonce和在途交易都已被占用，不能再复用
+	released := st.released[:0]
+	for _, nonce := range st.released {
+		if nonce >= pending {
+			released = append(released, nonce)
+		}
+	}
+	st.released = released
+	for nonce := range st.inflight {
+		if nonce < pending {
+			delete(st.inflight, nonce)
+		}
+	}
+
+	st.synced = true
+	return nil
+}
+
+// Gap 账户nonce序列中的空缺
+type Gap struct {
+	Nonce  uint64
+	TxHash common.Hash // 被丢弃的交易哈希（nonce从未成功发送时为空）
+}
+
+// DetectGaps 检测账户nonce序列中的空缺
+// 空缺来自两类情况：领取后未成功发送的nonce，以及已广播但被交易池丢弃的交易。
+// 任何一个空缺都会阻塞其后所有nonce的交易被打包。
+func (m *Manager) DetectGaps(ctx context.Context, account common.Address) ([]Gap, error) {
+	st := m.state(account)
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if !st.synced {
+		if err := m.syncLocked(ctx, account, st); err != nil {
+			return nil, err
+		}
+	}
+
+	confirmed, err := m.client.NonceAt(ctx, account, nil)
+	if err != nil {
+		return nil, fmt.Errorf("查询已确认nonce失败: %v", err)
+	}
+
+	var gaps []Gap
+	for _, nonce := range st.released {
+		if nonce >= confirmed {
+			gaps = append(gaps, Gap{Nonce: nonce})
+		}
+	}
+	for nonce, txHash := range st.inflight {
+		if nonce < confirmed {
+			delete(st.inflight, nonce)
+			continue
+		}
+		_, _, err := m.client.TransactionByHash(ctx, txHash)
+		if errors.Is(err, ethereum.NotFound) {
+			// 交易已被节点丢弃，从在途列表移除，nonce等待复用
+			delete(st.inflight, nonce)
+			st.released = insertSorted(st.released, nonce)
+			gaps = append(gaps, Gap{Nonce: nonce, TxHash: txHash})
+		} else if err != nil {
+			return nil, fmt.Errorf("查询交易 %s 失败: %v", txHash.Hex(), err)
+		}
+	}
+
+	sort.Slice(gaps, func(i, j int) bool { return gaps[i].Nonce < gaps[j].Nonce })
+	return gaps, nil
+}
+
+// FillGaps 检测空缺并调用fill逐个补齐（通常发送一笔0金额的自转账）
+// fill使用传入的nonce签名并广播交易，返回交易哈希
+func (m *Manager) FillGaps(ctx context.Context, account common.Address, fill func(ctx context.Context, nonce uint64) (common.Hash, error)) ([]common.Hash, error) {
+	gaps, err := m.DetectGaps(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	var hashes []common.Hash
+	for range gaps {
+		r, err := m.Reserve(ctx, account)
+		if err != nil {
+			return hashes, err
+		}
+		txHash, err := fill(ctx, r.Nonce)
+		if err != nil {
+			r.Release(err)
+			return hashes, fmt.Errorf("填补nonce %d 失败: %v", r.Nonce, err)
+		}
+		r.Commit(txHash)
+		hashes = append(hashes, txHash)
+	}
+	return hashes, nil
+}
+
+// IsNonceError 判断发送错误是否表明本地nonce与链上状态不一致
+func IsNonceError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "nonce too low") ||
+		strings.Contains(msg, "nonce too high") ||
+		strings.Contains(msg, "replacement transaction underpriced")
+}
+
+// insertSorted 将nonce按升序插入切片（忽略重复）
+func insertSorted(nonces []uint64, nonce uint64) []uint64 {
+	i := sort.Search(len(nonces), func(i int) bool { return nonces[i] >= nonce })
+	if i < len(nonces) && nonces[i] == nonce {
+		return nonces
+	}
+	nonces = append(nonces, 0)
+	copy(nonces[i+1:], nonces[i:])
+	nonces[i] = nonce
+	return nonces
+}
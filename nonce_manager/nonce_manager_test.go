@@ -0,0 +1,163 @@
+package nonce_manager_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/backend/backendtest"
+	"ethclient_tutorial/nonce_manager"
+)
+
+// sendSelfTransfer 使用指定nonce发送一笔0金额自转账
+func sendSelfTransfer(t *testing.T, sim *backend.Simulated, nonce uint64) common.Hash {
+	t.Helper()
+	account := sim.Accounts[0]
+	tx, err := types.SignNewTx(account.Key, types.LatestSignerForChainID(big.NewInt(backend.SimulatedChainID)), &types.DynamicFeeTx{
+		ChainID:   big.NewInt(backend.SimulatedChainID),
+		Nonce:     nonce,
+		GasTipCap: big.NewInt(1e9),
+		GasFeeCap: big.NewInt(10e9),
+		Gas:       21000,
+		To:        &account.Address,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sim.SendTransaction(context.Background(), tx); err != nil {
+		t.Fatalf("send nonce %d: %v", nonce, err)
+	}
+	return tx.Hash()
+}
+
+func TestConcurrentReservationsAreUnique(t *testing.T) {
+	sim := backendtest.New(t, 1)
+	m := nonce_manager.NewManager(sim)
+	account := sim.Accounts[0].Address
+
+	const n = 32
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		nonces = make(map[uint64]bool)
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, err := m.Reserve(context.Background(), account)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if nonces[r.Nonce] {
+				t.Errorf("nonce %d handed out twice", r.Nonce)
+			}
+			nonces[r.Nonce] = true
+			r.Commit(common.Hash{})
+		}()
+	}
+	wg.Wait()
+	for i := uint64(0); i < n; i++ {
+		if !nonces[i] {
+			t.Fatalf("nonce %d missing, sequence has a gap", i)
+		}
+	}
+}
+
+func TestReleasedNonceIsReused(t *testing.T) {
+	sim := backendtest.New(t, 1)
+	m := nonce_manager.NewManager(sim)
+	account := sim.Accounts[0].Address
+
+	first, _ := m.Reserve(context.Background(), account)
+	second, _ := m.Reserve(context.Background(), account)
+	first.Release(errors.New("insufficient funds"))
+	second.Commit(common.Hash{})
+
+	third, err := m.Reserve(context.Background(), account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.Nonce != first.Nonce {
+		t.Fatalf("nonce = %d, want released nonce %d", third.Nonce, first.Nonce)
+	}
+}
+
+func TestResyncAfterNonceTooLow(t *testing.T) {
+	sim := backendtest.New(t, 1)
+	m := nonce_manager.NewManager(sim)
+	account := sim.Accounts[0].Address
+
+	r, _ := m.Reserve(context.Background(), account)
+	// 同一私钥在管理器之外发送了交易，占用了nonce 0和1
+	sendSelfTransfer(t, sim, 0)
+	sendSelfTransfer(t, sim, 1)
+	sim.Commit()
+	r.Release(errors.New("nonce too low: next nonce 2, tx nonce 0"))
+
+	next, err := m.Reserve(context.Background(), account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.Nonce != 2 {
+		t.Fatalf("nonce = %d, want 2 after resync", next.Nonce)
+	}
+}
+
+func TestDetectAndFillGaps(t *testing.T) {
+	sim := backendtest.New(t, 1)
+	m := nonce_manager.NewManager(sim)
+	account := sim.Accounts[0].Address
+
+	gap, _ := m.Reserve(context.Background(), account)
+	later, _ := m.Reserve(context.Background(), account)
+	later.Commit(sendSelfTransfer(t, sim, later.Nonce))
+	// nonce 0 从未发送，nonce 1 的交易将一直卡在交易池
+	gap.Release(nil)
+
+	gaps, err := m.DetectGaps(context.Background(), account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gaps) != 1 || gaps[0].Nonce != 0 {
+		t.Fatalf("gaps = %+v, want nonce 0", gaps)
+	}
+
+	hashes, err := m.FillGaps(context.Background(), account, func(ctx context.Context, nonce uint64) (common.Hash, error) {
+		return sendSelfTransfer(t, sim, nonce), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hashes) != 1 {
+		t.Fatalf("filled %d gaps, want 1", len(hashes))
+	}
+
+	sim.Commit()
+	confirmed, err := sim.NonceAt(context.Background(), account, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if confirmed != 2 {
+		t.Fatalf("confirmed nonce = %d, want 2 once the gap is filled", confirmed)
+	}
+}
+
+func TestAlreadyKnownIsNotNonceError(t *testing.T) {
+	// rpc_pool 把"already known"视为发送成功，不能触发重新同步
+	if nonce_manager.IsNonceError(errors.New("already known")) {
+		t.Fatal("already known treated as a nonce error")
+	}
+	if !nonce_manager.IsNonceError(errors.New("nonce too low: next nonce 2, tx nonce 0")) {
+		t.Fatal("nonce too low not treated as a nonce error")
+	}
+}
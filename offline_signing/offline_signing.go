@@ -0,0 +1,318 @@
+package offline_signing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"ethclient_tutorial/amount"
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/config"
+	"ethclient_tutorial/erc20"
+	"ethclient_tutorial/eth_transfer"
+	"ethclient_tutorial/fee_strategy"
+	"ethclient_tutorial/signer"
+)
+
+// FormatVersion 交易文件格式版本
+const FormatVersion = 1
+
+// ErrChainMismatch 交易的链ID与节点不一致
+var ErrChainMismatch = errors.New("链ID不匹配")
+
+// UnsignedTx 在线步骤构造的未签名EIP-1559交易
+// 字段与JSON-RPC交易对象一致（数值为十六进制），可以在不同机器之间拷贝
+type UnsignedTx struct {
+	Version              int            `json:"version"`
+	ChainID              *hexutil.Big   `json:"chainId"`
+	From                 common.Address `json:"from"`
+	To                   common.Address `json:"to"`
+	Nonce                hexutil.Uint64 `json:"nonce"`
+	Gas                  hexutil.Uint64 `json:"gas"`
+	MaxFeePerGas         *hexutil.Big   `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big   `json:"maxPriorityFeePerGas"`
+	Value                *hexutil.Big   `json:"value"`
+	Input                hexutil.Bytes  `json:"input"`
+
+	// Token 代币转账时的代币信息（只用于显示摘要，To 为代币合约地址）
+	Token *TokenInfo `json:"token,omitempty"`
+}
+
+// TokenInfo 代币符号和精度
+type TokenInfo struct {
+	Symbol   string `json:"symbol"`
+	Decimals uint8  `json:"decimals"`
+}
+
+// SignedTx 离线步骤签名后的交易
+type SignedTx struct {
+	Version int            `json:"version"`
+	ChainID *hexutil.Big   `json:"chainId"`
+	From    common.Address `json:"from"`
+	Hash    common.Hash    `json:"hash"`
+	Raw     hexutil.Bytes  `json:"raw"`
+}
+
+// BuildETH 构造未签名的ETH转账，费用和Gas与 eth_transfer 在线发送的逻辑相同
+// Nonce 取账户当前的pending nonce，需要时可在签名前修改
+func BuildETH(ctx context.Context, client backend.Client, from, to common.Address, value *big.Int, cfg *config.Config) (*UnsignedTx, error) {
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取链ID失败: %v", err)
+	}
+	plan, err := eth_transfer.PlanTransfer(ctx, client, from, to, value, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if plan.Fees.Legacy() {
+		return nil, fmt.Errorf("网络不支持EIP-1559，离线签名只支持EIP-1559交易")
+	}
+	return newUnsigned(ctx, client, from, &types.DynamicFeeTx{
+		ChainID:   chainID,
+		GasTipCap: plan.Fees.GasTipCap,
+		GasFeeCap: plan.Fees.GasFeeCap,
+		Gas:       plan.GasLimit,
+		To:        &to,
+		Value:     value,
+	})
+}
+
+// BuildERC20 构造未签名的代币转账，会先模拟执行，费用和Gas与 erc20 在线发送的逻辑相同
+func BuildERC20(ctx context.Context, client backend.Client, token *erc20.Token, from, to common.Address, value *big.Int) (*UnsignedTx, error) {
+	meta, err := token.Metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := token.BuildTransfer(ctx, from, to, value)
+	if err != nil {
+		return nil, err
+	}
+	unsigned, err := newUnsigned(ctx, client, from, tx)
+	if err != nil {
+		return nil, err
+	}
+	unsigned.Token = &TokenInfo{Symbol: meta.Symbol, Decimals: meta.Decimals}
+	return unsigned, nil
+}
+
+// newUnsigned 填写nonce并转换为文件格式
+// nonce 直接取链上pending nonce，不经过 nonce_manager：交易离开本进程后在离线设备上签名，
+// 通常由另一次 broadcast 命令发送，本进程无法在发送成功或失败时 Commit/Release 领取，
+// 一直未完成的领取会让 nonce_manager 把这个nonce当作空缺补齐，反而与离线交易冲突
+func newUnsigned(ctx context.Context, client backend.Client, from common.Address, tx *types.DynamicFeeTx) (*UnsignedTx, error) {
+	nonce, err := client.PendingNonceAt(ctx, from)
+	if err != nil {
+		return nil, fmt.Errorf("获取nonce失败: %v", err)
+	}
+	value := tx.Value
+	if value == nil {
+		value = new(big.Int)
+	}
+	return &UnsignedTx{
+		Version:              FormatVersion,
+		ChainID:              (*hexutil.Big)(tx.ChainID),
+		From:                 from,
+		To:                   *tx.To,
+		Nonce:                hexutil.Uint64(nonce),
+		Gas:                  hexutil.Uint64(tx.Gas),
+		MaxFeePerGas:         (*hexutil.Big)(tx.GasFeeCap),
+		MaxPriorityFeePerGas: (*hexutil.Big)(tx.GasTipCap),
+		Value:                (*hexutil.Big)(value),
+		Input:                tx.Data,
+	}, nil
+}
+
+// Transaction 转换为待签名的交易
+func (u *UnsignedTx) Transaction() (*types.Transaction, error) {
+	if u.Version != FormatVersion {
+		return nil, fmt.Errorf("不支持的交易文件版本 %d", u.Version)
+	}
+	if u.ChainID == nil || u.MaxFeePerGas == nil || u.MaxPriorityFeePerGas == nil || u.Value == nil {
+		return nil, fmt.Errorf("交易文件缺少 chainId、maxFeePerGas、maxPriorityFeePerGas 或 value")
+	}
+	if u.Gas == 0 {
+		return nil, fmt.Errorf("交易文件缺少 gas")
+	}
+	to := u.To
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   u.ChainID.ToInt(),
+		Nonce:     uint64(u.Nonce),
+		GasTipCap: u.MaxPriorityFeePerGas.ToInt(),
+		GasFeeCap: u.MaxFeePerGas.ToInt(),
+		Gas:       uint64(u.Gas),
+		To:        &to,
+		Value:     u.Value.ToInt(),
+		Data:      u.Input,
+	}), nil
+}
+
+// Summary 签名前显示的交易摘要，代币调用数据会被解码
+func (u *UnsignedTx) Summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "链ID: %s\n", u.ChainID.ToInt())
+	fmt.Fprintf(&b, "发送方: %s\n", u.From.Hex())
+	fmt.Fprintf(&b, "Nonce: %d\n", uint64(u.Nonce))
+
+	if len(u.Input) == 0 {
+		fmt.Fprintf(&b, "接收方: %s\n", u.To.Hex())
+		fmt.Fprintf(&b, "金额: %s ETH\n", amount.Format(u.Value.ToInt(), amount.EtherDecimals))
+	} else if call, err := erc20.DecodeCall(u.Input); err == nil {
+		symbol, decimals := "(最小单位)", 0
+		if u.Token != nil {
+			symbol, decimals = u.Token.Symbol, int(u.Token.Decimals)
+		}
+		fmt.Fprintf(&b, "代币合约: %s\n", u.To.Hex())
+		fmt.Fprintf(&b, "ERC20调用: %s\n", call.Method)
+		if call.Method == "transferFrom" {
+			fmt.Fprintf(&b, "   从: %s\n", call.From.Hex())
+		}
+		if call.Method == "approve" {
+			fmt.Fprintf(&b, "   被授权地址: %s\n", call.To.Hex())
+		} else {
+			fmt.Fprintf(&b, "   接收方: %s\n", call.To.Hex())
+		}
+		fmt.Fprintf(&b, "   数量: %s %s\n", amount.Format(call.Value, decimals), symbol)
+		if u.Value.ToInt().Sign() > 0 {
+			fmt.Fprintf(&b, "附带ETH: %s ETH\n", amount.Format(u.Value.ToInt(), amount.EtherDecimals))
+		}
+	} else {
+		fmt.Fprintf(&b, "合约: %s\n", u.To.Hex())
+		fmt.Fprintf(&b, "金额: %s ETH\n", amount.Format(u.Value.ToInt(), amount.EtherDecimals))
+		fmt.Fprintf(&b, "调用数据: %s（无法解码: %v）\n", u.Input, err)
+	}
+
+	worstCase := new(big.Int).Mul(u.MaxFeePerGas.ToInt(), new(big.Int).SetUint64(uint64(u.Gas)))
+	fmt.Fprintf(&b, "Gas上限: %d\n", uint64(u.Gas))
+	fmt.Fprintf(&b, "费用上限: %s Gwei（小费 %s Gwei）\n", fee_strategy.ToGwei(u.MaxFeePerGas.ToInt()), fee_strategy.ToGwei(u.MaxPriorityFeePerGas.ToInt()))
+	fmt.Fprintf(&b, "最高手续费: %s ETH\n", fee_strategy.ToEther(worstCase))
+	return b.String()
+}
+
+// Sign 离线签名，签名账户必须是交易文件中的发送方，不访问网络
+func Sign(ctx context.Context, s signer.Signer, u *UnsignedTx) (*SignedTx, error) {
+	if s.Address() != u.From {
+		return nil, fmt.Errorf("%w: 交易发送方为 %s，签名账户为 %s", signer.ErrWrongSigner, u.From.Hex(), s.Address().Hex())
+	}
+	tx, err := u.Transaction()
+	if err != nil {
+		return nil, err
+	}
+	signedTx, err := s.SignTx(ctx, tx, u.ChainID.ToInt())
+	if err != nil {
+		return nil, err
+	}
+	sender, err := types.Sender(types.LatestSignerForChainID(u.ChainID.ToInt()), signedTx)
+	if err != nil {
+		return nil, fmt.Errorf("解析签名交易发送方失败: %v", err)
+	}
+	if sender != u.From {
+		return nil, fmt.Errorf("%w: 期望 %s，得到 %s", signer.ErrWrongSigner, u.From.Hex(), sender.Hex())
+	}
+	raw, err := signedTx.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("编码签名交易失败: %v", err)
+	}
+	return &SignedTx{
+		Version: FormatVersion,
+		ChainID: u.ChainID,
+		From:    u.From,
+		Hash:    signedTx.Hash(),
+		Raw:     raw,
+	}, nil
+}
+
+// Transaction 解码签名交易，并校验哈希和发送方与文件一致
+func (s *SignedTx) Transaction() (*types.Transaction, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(s.Raw); err != nil {
+		return nil, fmt.Errorf("解析签名交易失败: %v", err)
+	}
+	if tx.Hash() != s.Hash {
+		return nil, fmt.Errorf("签名交易哈希 %s 与文件中的 %s 不一致", tx.Hash().Hex(), s.Hash.Hex())
+	}
+	sender, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return nil, fmt.Errorf("解析签名交易发送方失败: %v", err)
+	}
+	if sender != s.From {
+		return nil, fmt.Errorf("%w: 文件中为 %s，签名为 %s", signer.ErrWrongSigner, s.From.Hex(), sender.Hex())
+	}
+	return tx, nil
+}
+
+// Broadcast 广播签名交易，交易的链ID必须与节点一致
+func Broadcast(ctx context.Context, client backend.Client, s *SignedTx) (*types.Transaction, error) {
+	tx, err := s.Transaction()
+	if err != nil {
+		return nil, err
+	}
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取链ID失败: %v", err)
+	}
+	if tx.ChainId().Cmp(chainID) != 0 {
+		return nil, fmt.Errorf("%w: 交易为 %s，节点为 %s", ErrChainMismatch, tx.ChainId(), chainID)
+	}
+	if err := client.SendTransaction(ctx, tx); err != nil {
+		return nil, fmt.Errorf("广播交易失败: %v", err)
+	}
+	return tx, nil
+}
+
+// WriteFile 把交易写入JSON文件（权限600）
+func WriteFile(path string, v interface{}) error {
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(content, '\n'), 0600); err != nil {
+		return fmt.Errorf("写入交易文件失败: %v", err)
+	}
+	return nil
+}
+
+// ReadUnsigned 读取未签名交易文件
+func ReadUnsigned(path string) (*UnsignedTx, error) {
+	var u UnsignedTx
+	if err := readFile(path, &u); err != nil {
+		return nil, err
+	}
+	if _, err := u.Transaction(); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// ReadSigned 读取签名交易文件
+func ReadSigned(path string) (*SignedTx, error) {
+	var s SignedTx
+	if err := readFile(path, &s); err != nil {
+		return nil, err
+	}
+	if s.Version != FormatVersion {
+		return nil, fmt.Errorf("不支持的交易文件版本 %d", s.Version)
+	}
+	if len(s.Raw) == 0 {
+		return nil, fmt.Errorf("交易文件缺少 raw，可能是未签名的交易")
+	}
+	return &s, nil
+}
+
+func readFile(path string, v interface{}) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取交易文件失败: %v", err)
+	}
+	if err := json.Unmarshal(content, v); err != nil {
+		return fmt.Errorf("解析交易文件失败: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package offline_signing
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/backend/backendtest"
+	"ethclient_tutorial/config"
+	"ethclient_tutorial/erc20"
+	"ethclient_tutorial/signer"
+)
+
+// roundTrip 写入再读取文件，模拟在机器之间拷贝
+func roundTrip(t *testing.T, unsigned *UnsignedTx) *UnsignedTx {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "unsigned.json")
+	if err := WriteFile(path, unsigned); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := ReadUnsigned(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loaded
+}
+
+// broadcastAndMine 广播签名交易文件并确认执行成功
+func broadcastAndMine(t *testing.T, sim *backend.Simulated, signed *SignedTx) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "signed.json")
+	if err := WriteFile(path, signed); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := ReadSigned(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := Broadcast(context.Background(), sim, loaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim.Commit()
+	receipt, err := sim.TransactionReceipt(context.Background(), tx.Hash())
+	if err != nil || receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("receipt = %+v, %v", receipt, err)
+	}
+}
+
+func TestOfflineETHTransfer(t *testing.T) {
+	sim := backendtest.New(t, 2)
+	ctx := context.Background()
+	from, to := sim.Accounts[0], sim.Accounts[1].Address
+	cfg := &config.Config{FeeStrategy: "node", DefaultGasLimit: 21000}
+
+	unsigned, err := BuildETH(ctx, sim, from.Address, to, big.NewInt(12345), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsigned = roundTrip(t, unsigned)
+	if unsigned.Gas != 21000 || unsigned.Nonce != 0 || unsigned.ChainID.ToInt().Int64() != backend.SimulatedChainID {
+		t.Fatalf("unsigned = %+v", unsigned)
+	}
+	if summary := unsigned.Summary(); !strings.Contains(summary, "0.000000000000012345 ETH") {
+		t.Fatalf("summary:\n%s", summary)
+	}
+
+	// 签名账户必须是发送方
+	if _, err := Sign(ctx, signer.NewKeySigner(sim.Accounts[1].Key), unsigned); !errors.Is(err, signer.ErrWrongSigner) {
+		t.Fatalf("wrong signer err = %v", err)
+	}
+	signed, err := Sign(ctx, signer.NewKeySigner(from.Key), unsigned)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before, _ := sim.BalanceAt(ctx, to, nil)
+	broadcastAndMine(t, sim, signed)
+	after, _ := sim.BalanceAt(ctx, to, nil)
+	if delta := new(big.Int).Sub(after, before); delta.Int64() != 12345 {
+		t.Fatalf("recipient received %s wei", delta)
+	}
+}
+
+func TestOfflineERC20Transfer(t *testing.T) {
+	sim := backendtest.New(t, 2)
+	ctx := context.Background()
+	owner, recipient := sim.Accounts[0], sim.Accounts[1].Address
+
+	tokenAddress, _ := backendtest.DeployMyToken(t, sim)
+	token := erc20.New(sim, tokenAddress)
+
+	value := new(big.Int).Mul(big.NewInt(15), big.NewInt(1e17)) // 1.5 MTK
+	unsigned, err := BuildERC20(ctx, sim, token, owner.Address, recipient, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsigned = roundTrip(t, unsigned)
+	summary := unsigned.Summary()
+	for _, want := range []string{"ERC20调用: transfer", recipient.Hex(), "1.5 MTK"} {
+		if !strings.Contains(summary, want) {
+			t.Fatalf("summary missing %q:\n%s", want, summary)
+		}
+	}
+
+	signed, err := Sign(ctx, signer.NewKeySigner(owner.Key), unsigned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	broadcastAndMine(t, sim, signed)
+	balance, err := token.BalanceOf(ctx, recipient)
+	if err != nil || balance.Cmp(value) != 0 {
+		t.Fatalf("balance = %s, %v", balance, err)
+	}
+
+	// 超出余额的转账在构造时就被模拟执行拒绝
+	if _, err := BuildERC20(ctx, sim, token, recipient, owner.Address, new(big.Int).Mul(value, big.NewInt(2))); err == nil {
+		t.Fatal("transfer beyond balance should fail to build")
+	}
+}
+
+func TestBroadcastRejectsTamperedOrForeignTransactions(t *testing.T) {
+	sim := backendtest.New(t, 2)
+	ctx := context.Background()
+	from := sim.Accounts[0]
+	cfg := &config.Config{FeeStrategy: "node", DefaultGasLimit: 21000}
+
+	unsigned, err := BuildETH(ctx, sim, from.Address, sim.Accounts[1].Address, big.NewInt(1), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 其他链的交易
+	foreign := *unsigned
+	foreign.ChainID = (*hexutil.Big)(big.NewInt(1))
+	signed, err := Sign(ctx, signer.NewKeySigner(from.Key), &foreign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Broadcast(ctx, sim, signed); !errors.Is(err, ErrChainMismatch) {
+		t.Fatalf("foreign chain err = %v", err)
+	}
+
+	// 文件中的发送方与签名不一致
+	signed, err = Sign(ctx, signer.NewKeySigner(from.Key), unsigned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed.From = sim.Accounts[1].Address
+	if _, err := Broadcast(ctx, sim, signed); !errors.Is(err, signer.ErrWrongSigner) {
+		t.Fatalf("tampered sender err = %v", err)
+	}
+
+	// 未签名的文件不能广播
+	path := filepath.Join(t.TempDir(), "unsigned.json")
+	if err := WriteFile(path, unsigned); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadSigned(path); err == nil {
+		t.Fatal("unsigned file should not be accepted for broadcast")
+	}
+}
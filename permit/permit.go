@@ -0,0 +1,259 @@
+package permit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/erc20"
+	"ethclient_tutorial/message_signing"
+	"ethclient_tutorial/signer"
+	"ethclient_tutorial/utils"
+)
+
+var (
+	ErrExpired          = errors.New("permit已过期")
+	ErrNonceUsed        = errors.New("permit的nonce已被使用")
+	ErrInvalidSignature = errors.New("permit签名不是由owner产生的")
+	ErrDomainMismatch   = errors.New("EIP-712签名域与合约的 DOMAIN_SEPARATOR 不一致")
+	ErrWrongRelayer     = errors.New("提交账户不是permit的spender")
+	ErrExceedsPermit    = errors.New("转账数量超过permit授权额度")
+)
+
+// MinValidity 提交前permit至少还要有效多久（留出交易被打包的时间）
+var MinValidity = 60 * time.Second
+
+// Permit owner签名的ERC-2612授权，可以拷贝给relayer提交
+type Permit struct {
+	Token     common.Address `json:"token"`
+	ChainID   *hexutil.Big   `json:"chainId"`
+	Owner     common.Address `json:"owner"`
+	Spender   common.Address `json:"spender"`
+	Value     *hexutil.Big   `json:"value"`
+	Nonce     *hexutil.Big   `json:"nonce"`
+	Deadline  *hexutil.Big   `json:"deadline"` // 区块时间戳（秒）
+	Signature hexutil.Bytes  `json:"signature"`
+}
+
+// Deadline 以最新区块时间为起点计算截止时间
+func Deadline(ctx context.Context, client backend.Client, validFor time.Duration) (*big.Int, error) {
+	head, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取最新区块失败: %v", err)
+	}
+	return new(big.Int).SetUint64(head.Time + uint64(validFor/time.Second)), nil
+}
+
+// TypedData permit对应的EIP-712结构化数据
+func (p *Permit) TypedData(domain *message_signing.Domain) apitypes.TypedData {
+	data := apitypes.TypedData{
+		Types: apitypes.Types{
+			"Permit": {
+				{Name: "owner", Type: "address"},
+				{Name: "spender", Type: "address"},
+				{Name: "value", Type: "uint256"},
+				{Name: "nonce", Type: "uint256"},
+				{Name: "deadline", Type: "uint256"},
+			},
+		},
+		PrimaryType: "Permit",
+		Message: apitypes.TypedDataMessage{
+			"owner":    p.Owner.Hex(),
+			"spender":  p.Spender.Hex(),
+			"value":    (*math.HexOrDecimal256)(p.Value.ToInt()),
+			"nonce":    (*math.HexOrDecimal256)(p.Nonce.ToInt()),
+			"deadline": (*math.HexOrDecimal256)(p.Deadline.ToInt()),
+		},
+	}
+	domain.Apply(&data)
+	return data
+}
+
+// fetchDomain 从合约获取签名域，并核对它与合约的 DOMAIN_SEPARATOR 一致
+func fetchDomain(ctx context.Context, client backend.Client, token *erc20.Token) (*message_signing.Domain, error) {
+	domain, err := message_signing.FetchDomain(ctx, client, token.Address)
+	if err != nil {
+		return nil, err
+	}
+	onChain, err := token.DomainSeparator(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data := apitypes.TypedData{Types: apitypes.Types{"EIP712Domain": domain.Types}, Domain: domain.Domain}
+	separator, err := data.HashStruct("EIP712Domain", data.Domain.Map())
+	if err != nil {
+		return nil, fmt.Errorf("计算域分隔符失败: %v", err)
+	}
+	if !bytes.Equal(separator, onChain[:]) {
+		return nil, ErrDomainMismatch
+	}
+	return domain, nil
+}
+
+// Sign owner链下签名permit，授权spender使用value数量的代币，截止时间为deadline
+// nonce读取自合约的 nonces(owner)，签名域读取自 eip712Domain() 并与 DOMAIN_SEPARATOR 核对
+func Sign(ctx context.Context, client backend.Client, owner signer.Signer, tokenAddress, spender common.Address, value, deadline *big.Int) (*Permit, error) {
+	token := erc20.New(client, tokenAddress)
+	chainID, err := token.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	d, err := fetchDomain(ctx, client, token)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := token.Nonces(ctx, owner.Address())
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Permit{
+		Token:    tokenAddress,
+		ChainID:  (*hexutil.Big)(chainID),
+		Owner:    owner.Address(),
+		Spender:  spender,
+		Value:    (*hexutil.Big)(value),
+		Nonce:    (*hexutil.Big)(nonce),
+		Deadline: (*hexutil.Big)(deadline),
+	}
+	p.Signature, err = owner.SignTypedData(ctx, p.TypedData(d))
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Verify 提交前检查permit：签名由owner产生、nonce未被使用、距离截止时间至少还有 MinValidity
+func Verify(ctx context.Context, client backend.Client, p *Permit) error {
+	if p.ChainID == nil || p.Value == nil || p.Nonce == nil || p.Deadline == nil {
+		return fmt.Errorf("permit缺少 chainId、value、nonce 或 deadline")
+	}
+	token := erc20.New(client, p.Token)
+	chainID, err := token.ChainID(ctx)
+	if err != nil {
+		return err
+	}
+	if chainID.Cmp(p.ChainID.ToInt()) != 0 {
+		return fmt.Errorf("permit的链ID为 %s，节点为 %s", p.ChainID.ToInt(), chainID)
+	}
+
+	head, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("获取最新区块失败: %v", err)
+	}
+	minDeadline := new(big.Int).SetUint64(head.Time + uint64(MinValidity/time.Second))
+	if p.Deadline.ToInt().Cmp(minDeadline) < 0 {
+		return fmt.Errorf("%w: 截止时间 %s，当前区块时间 %d", ErrExpired, p.Deadline.ToInt(), head.Time)
+	}
+
+	nonce, err := token.Nonces(ctx, p.Owner)
+	if err != nil {
+		return err
+	}
+	if nonce.Cmp(p.Nonce.ToInt()) != 0 {
+		return fmt.Errorf("%w: permit为 %s，当前为 %s", ErrNonceUsed, p.Nonce.ToInt(), nonce)
+	}
+
+	d, err := fetchDomain(ctx, client, token)
+	if err != nil {
+		return err
+	}
+	recovered, err := message_signing.VerifyTypedData(p.TypedData(d), p.Signature)
+	if err != nil {
+		return err
+	}
+	if recovered != p.Owner {
+		return fmt.Errorf("%w: 恢复出 %s", ErrInvalidSignature, recovered.Hex())
+	}
+	return nil
+}
+
+// Result relayer提交的两笔交易
+type Result struct {
+	PermitTx       common.Hash
+	TransferFromTx common.Hash
+}
+
+// Relay relayer（permit的spender）代owner提交permit，确认后用 transferFrom 把amount数量的代币从owner转给to
+// 提交前会检查permit；owner不需要持有ETH，Gas由relayer支付
+func Relay(ctx context.Context, client backend.Client, relayer signer.Signer, p *Permit, to common.Address, amount *big.Int, wait utils.WaitOptions) (*Result, error) {
+	if relayer.Address() != p.Spender {
+		return nil, fmt.Errorf("%w: spender为 %s，提交账户为 %s", ErrWrongRelayer, p.Spender.Hex(), relayer.Address().Hex())
+	}
+	if err := Verify(ctx, client, p); err != nil {
+		return nil, err
+	}
+	if amount.Cmp(p.Value.ToInt()) > 0 {
+		return nil, fmt.Errorf("%w: 授权 %s，转账 %s", ErrExceedsPermit, p.Value.ToInt(), amount)
+	}
+
+	token := erc20.New(client, p.Token)
+	result := &Result{}
+	permitTx, err := token.PermitWithSigner(ctx, relayer, p.Owner, p.Spender, p.Value.ToInt(), p.Deadline.ToInt(), p.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("提交permit失败: %w", err)
+	}
+	wait.Report("✓ permit交易已发送: %s", permitTx.Hash().Hex())
+	status, err := utils.WaitSuccess(ctx, client, permitTx, wait)
+	result.PermitTx = minedHash(permitTx, status)
+	if err != nil {
+		return result, fmt.Errorf("permit交易失败: %w", err)
+	}
+
+	transferTx, err := token.TransferFromWithSigner(ctx, relayer, p.Owner, to, amount)
+	if err != nil {
+		return result, fmt.Errorf("提交transferFrom失败: %w", err)
+	}
+	wait.Report("✓ transferFrom交易已发送: %s", transferTx.Hash().Hex())
+	status, err = utils.WaitSuccess(ctx, client, transferTx, wait)
+	result.TransferFromTx = minedHash(transferTx, status)
+	if err != nil {
+		return result, fmt.Errorf("transferFrom交易失败: %w", err)
+	}
+	return result, nil
+}
+
+// minedHash 实际被打包的交易哈希（自动加价后与发送的交易不同），还没有等待结果时为发送的交易
+func minedHash(tx *types.Transaction, status *utils.TransactionStatus) common.Hash {
+	if status != nil {
+		return status.TxHash
+	}
+	return tx.Hash()
+}
+
+// WriteFile 把permit写入JSON文件（权限600）
+func WriteFile(path string, p *Permit) error {
+	content, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(content, '\n'), 0600); err != nil {
+		return fmt.Errorf("写入permit文件失败: %v", err)
+	}
+	return nil
+}
+
+// ReadFile 读取permit文件
+func ReadFile(path string) (*Permit, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取permit文件失败: %v", err)
+	}
+	var p Permit
+	if err := json.Unmarshal(content, &p); err != nil {
+		return nil, fmt.Errorf("解析permit文件失败: %v", err)
+	}
+	return &p, nil
+}
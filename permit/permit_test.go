@@ -0,0 +1,107 @@
+package permit
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"ethclient_tutorial/backend/backendtest"
+	"ethclient_tutorial/erc20"
+	"ethclient_tutorial/signer"
+	"ethclient_tutorial/utils"
+)
+
+func TestPermitRelayedByThirdParty(t *testing.T) {
+	sim := backendtest.New(t, 3)
+	ctx := context.Background()
+	owner, relayer, recipient := sim.Accounts[0], sim.Accounts[1], sim.Accounts[2].Address
+
+	tokenAddress, _ := backendtest.DeployMyToken(t, sim)
+	sim.AutoMine(100 * time.Millisecond)
+	utils.PollInterval = 100 * time.Millisecond
+	wait := utils.WaitOptions{Timeout: 10 * time.Second}
+
+	value := big.NewInt(5e18)
+	deadline, err := Deadline(ctx, sim, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := Sign(ctx, sim, signer.NewKeySigner(owner.Key), tokenAddress, relayer.Address, value, deadline)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 经过文件传给relayer
+	path := filepath.Join(t.TempDir(), "permit.json")
+	if err := WriteFile(path, p); err != nil {
+		t.Fatal(err)
+	}
+	if p, err = ReadFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// 只有spender可以提交，且不能超出授权额度
+	if _, err := Relay(ctx, sim, signer.NewKeySigner(owner.Key), p, recipient, value, wait); !errors.Is(err, ErrWrongRelayer) {
+		t.Fatalf("wrong relayer err = %v", err)
+	}
+	if _, err := Relay(ctx, sim, signer.NewKeySigner(relayer.Key), p, recipient, new(big.Int).Add(value, big.NewInt(1)), wait); !errors.Is(err, ErrExceedsPermit) {
+		t.Fatalf("exceeding amount err = %v", err)
+	}
+
+	// 被篡改的额度签名校验失败
+	tampered := *p
+	tampered.Value = (*hexutil.Big)(new(big.Int).Mul(value, big.NewInt(2)))
+	if err := Verify(ctx, sim, &tampered); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("tampered value err = %v", err)
+	}
+
+	ownerEth, _ := sim.BalanceAt(ctx, owner.Address, nil)
+	amount := big.NewInt(3e18)
+	result, err := Relay(ctx, sim, signer.NewKeySigner(relayer.Key), p, recipient, amount, wait)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.PermitTx == result.TransferFromTx {
+		t.Fatalf("result = %+v", result)
+	}
+
+	token := erc20.New(sim, tokenAddress)
+	if balance, _ := token.BalanceOf(ctx, recipient); balance.Cmp(amount) != 0 {
+		t.Fatalf("recipient balance = %s", balance)
+	}
+	if allowance, _ := token.Allowance(ctx, owner.Address, relayer.Address); allowance.Cmp(new(big.Int).Sub(value, amount)) != 0 {
+		t.Fatalf("remaining allowance = %s", allowance)
+	}
+	// owner没有支付Gas
+	if after, _ := sim.BalanceAt(ctx, owner.Address, nil); after.Cmp(ownerEth) != 0 {
+		t.Fatalf("owner paid gas: %s -> %s", ownerEth, after)
+	}
+
+	// 同一permit不能重复使用
+	if err := Verify(ctx, sim, p); !errors.Is(err, ErrNonceUsed) {
+		t.Fatalf("replay err = %v", err)
+	}
+}
+
+func TestExpiredPermitIsRejected(t *testing.T) {
+	sim := backendtest.New(t, 2)
+	ctx := context.Background()
+	owner := sim.Accounts[0]
+
+	tokenAddress, _ := backendtest.DeployMyToken(t, sim)
+
+	// 截止时间早于 MinValidity
+	deadline, _ := Deadline(ctx, sim, MinValidity/2)
+	p, err := Sign(ctx, sim, signer.NewKeySigner(owner.Key), tokenAddress, sim.Accounts[1].Address, big.NewInt(1), deadline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(ctx, sim, p); !errors.Is(err, ErrExpired) {
+		t.Fatalf("expired permit err = %v", err)
+	}
+}
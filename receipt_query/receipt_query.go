@@ -6,11 +6,12 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
+
+	"ethclient_tutorial/backend"
 )
 
 // GetTransactionReceipt 获取交易收据
-func GetTransactionReceipt(client *ethclient.Client, txHash common.Hash) *types.Receipt {
+func GetTransactionReceipt(client backend.Client, txHash common.Hash) *types.Receipt {
 	receipt, err := client.TransactionReceipt(context.Background(), txHash)
 	if err != nil {
 		log.Fatalf("Failed to retrieve receipt for tx %s: %v", txHash.Hex(), err)
@@ -19,7 +20,7 @@ func GetTransactionReceipt(client *ethclient.Client, txHash common.Hash) *types.
 }
 
 // CheckTransactionStatus 检查交易状态
-func CheckTransactionStatus(client *ethclient.Client, txHash common.Hash) string {
+func CheckTransactionStatus(client backend.Client, txHash common.Hash) string {
 	receipt := GetTransactionReceipt(client, txHash)
 
 	switch receipt.Status {
@@ -0,0 +1,249 @@
+package revert_decoder
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/contracts"
+)
+
+var (
+	// errorSelector Error(string) 的选择器，require/revert 带消息时使用
+	errorSelector = []byte{0x08, 0xc3, 0x79, 0xa0}
+	// panicSelector Panic(uint256) 的选择器，assert失败、算术溢出等
+	panicSelector = []byte{0x4e, 0x48, 0x7b, 0x71}
+)
+
+// panicReasons Solidity Panic错误码的含义
+var panicReasons = map[uint64]string{
+	0x00: "通用编译器插入的panic",
+	0x01: "assert失败",
+	0x11: "算术溢出或下溢",
+	0x12: "除以零或对零取模",
+	0x21: "无效的枚举值",
+	0x22: "存储字节数组编码错误",
+	0x31: "对空数组调用pop",
+	0x32: "数组越界访问",
+	0x41: "内存分配过大",
+	0x51: "调用未初始化的内部函数",
+}
+
+// Arg 回滚错误的一个参数
+type Arg struct {
+	Name  string
+	Type  string
+	Value interface{}
+}
+
+// RevertError 解码后的合约回滚原因
+type RevertError struct {
+	Name        string // 错误名称，如 ERC20InsufficientBalance、Error、Panic；无法识别时为空
+	Signature   string // 错误签名，如 ERC20InsufficientBalance(address,uint256,uint256)
+	Args        []Arg
+	Description string // Panic 错误码的含义
+	Data        []byte // 原始回滚数据
+
+	TxHash      common.Hash // 回放的交易（Gas估算失败时为空）
+	BlockNumber *big.Int    // 回放所在区块
+}
+
+// Error 实现error接口，格式如 execution reverted: ERC20InsufficientBalance(sender=0x..., balance=0, needed=1)
+func (e *RevertError) Error() string {
+	switch {
+	case e.Name == "Error" && len(e.Args) == 1:
+		return fmt.Sprintf("execution reverted: %v", e.Args[0].Value)
+	case e.Name == "Panic" && len(e.Args) == 1:
+		return fmt.Sprintf("execution reverted: Panic(0x%x) %s", e.Args[0].Value, e.Description)
+	case e.Name != "":
+		args := make([]string, len(e.Args))
+		for i, arg := range e.Args {
+			args[i] = fmt.Sprintf("%s=%s", arg.Name, formatValue(arg.Value))
+		}
+		return fmt.Sprintf("execution reverted: %s(%s)", e.Name, strings.Join(args, ", "))
+	case len(e.Data) > 0:
+		return fmt.Sprintf("execution reverted: 未知错误 %s", hexutil.Encode(e.Data))
+	default:
+		return "execution reverted (无回滚数据)"
+	}
+}
+
+// Arg 按名称获取错误参数
+func (e *RevertError) Arg(name string) (interface{}, bool) {
+	for _, arg := range e.Args {
+		if arg.Name == name {
+			return arg.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Decoder 按ABI中的自定义错误解码回滚数据
+type Decoder struct {
+	errors map[[4]byte]abi.Error
+}
+
+// NewDecoder 使用一个或多个合约ABI创建解码器
+func NewDecoder(abis ...abi.ABI) *Decoder {
+	d := &Decoder{errors: make(map[[4]byte]abi.Error)}
+	for _, parsed := range abis {
+		for _, abiErr := range parsed.Errors {
+			var selector [4]byte
+			copy(selector[:], abiErr.ID[:4])
+			d.errors[selector] = abiErr
+		}
+	}
+	return d
+}
+
+// Default 使用MyToken ABI（包含OpenZeppelin ERC20/Pausable/Ownable等自定义错误）创建解码器
+func Default() *Decoder {
+	parsed, err := contracts.MYERC20MetaData.GetAbi()
+	if err != nil || parsed == nil {
+		return NewDecoder()
+	}
+	return NewDecoder(*parsed)
+}
+
+// Decode 解码回滚数据
+func (d *Decoder) Decode(data []byte) *RevertError {
+	result := &RevertError{Data: data}
+	if len(data) < 4 {
+		return result
+	}
+
+	switch selector, payload := data[:4], data[4:]; {
+	case bytes.Equal(selector, errorSelector):
+		if reason, err := abi.UnpackRevert(data); err == nil {
+			result.Name = "Error"
+			result.Signature = "Error(string)"
+			result.Args = []Arg{{Name: "reason", Type: "string", Value: reason}}
+		}
+	case bytes.Equal(selector, panicSelector):
+		if len(payload) == 32 {
+			code := new(big.Int).SetBytes(payload)
+			result.Name = "Panic"
+			result.Signature = "Panic(uint256)"
+			result.Args = []Arg{{Name: "code", Type: "uint256", Value: code}}
+			result.Description = panicReasons[code.Uint64()]
+		}
+	default:
+		var key [4]byte
+		copy(key[:], selector)
+		abiErr, ok := d.errors[key]
+		if !ok {
+			return result
+		}
+		values, err := abiErr.Inputs.Unpack(payload)
+		if err != nil {
+			return result
+		}
+		result.Name = abiErr.Name
+		result.Signature = abiErr.Sig
+		for i, input := range abiErr.Inputs {
+			result.Args = append(result.Args, Arg{Name: input.Name, Type: input.Type.String(), Value: values[i]})
+		}
+	}
+	return result
+}
+
+// FromError 从eth_call/eth_estimateGas返回的错误中提取并解码回滚数据
+// 错误不是合约回滚（网络错误、nonce错误等）时返回false
+func (d *Decoder) FromError(err error) (*RevertError, bool) {
+	if err == nil {
+		return nil, false
+	}
+	var revertErr *RevertError
+	if errors.As(err, &revertErr) {
+		return revertErr, true
+	}
+
+	var dataErr rpc.DataError
+	if errors.As(err, &dataErr) {
+		if hexData, ok := dataErr.ErrorData().(string); ok {
+			if data, decodeErr := hexutil.Decode(hexData); decodeErr == nil {
+				return d.Decode(data), true
+			}
+		}
+	}
+	if strings.Contains(err.Error(), "execution reverted") {
+		return &RevertError{}, true
+	}
+	return nil, false
+}
+
+// Replay 用eth_call回放失败交易，取得并解码回滚原因
+// 回放使用交易所在区块的父区块状态，即交易所在区块执行前的状态；同一区块中排在它之前的交易不包含在内
+func (d *Decoder) Replay(ctx context.Context, client backend.Client, txHash common.Hash) (*RevertError, error) {
+	tx, _, err := client.TransactionByHash(ctx, txHash)
+	if err != nil {
+		return nil, fmt.Errorf("查询交易失败: %v", err)
+	}
+	receipt, err := client.TransactionReceipt(ctx, txHash)
+	if err != nil {
+		return nil, fmt.Errorf("查询交易收据失败: %v", err)
+	}
+	if receipt.Status == types.ReceiptStatusSuccessful {
+		return nil, fmt.Errorf("交易 %s 执行成功，没有回滚原因", txHash.Hex())
+	}
+
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取链ID失败: %v", err)
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(chainID), tx)
+	if err != nil {
+		return nil, fmt.Errorf("解析交易发送方失败: %v", err)
+	}
+
+	// 不设置Gas价格，避免回放时因余额检查失败
+	msg := ethereum.CallMsg{
+		From:       from,
+		To:         tx.To(),
+		Gas:        tx.Gas(),
+		Value:      tx.Value(),
+		Data:       tx.Data(),
+		AccessList: tx.AccessList(),
+	}
+	parent := new(big.Int).Sub(receipt.BlockNumber, big.NewInt(1))
+	_, callErr := client.CallContract(ctx, msg, parent)
+	if callErr == nil {
+		if receipt.GasUsed >= tx.Gas() {
+			return nil, fmt.Errorf("回放未复现回滚，交易用尽了Gas上限 %d，可能是Gas不足", tx.Gas())
+		}
+		return nil, fmt.Errorf("回放未复现回滚，失败可能依赖同一区块中排在它之前的交易的状态")
+	}
+
+	revertErr, ok := d.FromError(callErr)
+	if !ok {
+		return nil, fmt.Errorf("回放交易失败: %v", callErr)
+	}
+	revertErr.TxHash = txHash
+	revertErr.BlockNumber = receipt.BlockNumber
+	return revertErr, nil
+}
+
+// formatValue 格式化参数值用于显示
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case common.Address:
+		return v.Hex()
+	case []byte:
+		return hexutil.Encode(v)
+	case [32]byte:
+		return hexutil.Encode(v[:])
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
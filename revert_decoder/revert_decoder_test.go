@@ -0,0 +1,139 @@
+package revert_decoder
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/backend/backendtest"
+	"ethclient_tutorial/contracts"
+)
+
+func transferData(t *testing.T, to common.Address, amount *big.Int) []byte {
+	t.Helper()
+	parsed, _ := contracts.MYERC20MetaData.GetAbi()
+	data, err := parsed.Pack("transfer", to, amount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDecodeInsufficientBalanceFromEstimate(t *testing.T) {
+	sim := backendtest.New(t, 2)
+	token, _ := backendtest.DeployMyToken(t, sim)
+	poor := sim.Accounts[1].Address
+
+	_, err := sim.EstimateGas(context.Background(), ethereum.CallMsg{
+		From: poor,
+		To:   &token,
+		Data: transferData(t, sim.Accounts[0].Address, big.NewInt(5)),
+	})
+	revertErr, ok := Default().FromError(err)
+	if !ok {
+		t.Fatalf("expected revert, got %v", err)
+	}
+	if revertErr.Name != "ERC20InsufficientBalance" {
+		t.Fatalf("name = %q, want ERC20InsufficientBalance (%v)", revertErr.Name, revertErr)
+	}
+	if sender, _ := revertErr.Arg("sender"); sender != poor {
+		t.Fatalf("sender = %v, want %s", sender, poor.Hex())
+	}
+	if needed, _ := revertErr.Arg("needed"); needed.(*big.Int).Int64() != 5 {
+		t.Fatalf("needed = %v, want 5", needed)
+	}
+}
+
+func TestDecodeOwnableAndPause(t *testing.T) {
+	sim := backendtest.New(t, 2)
+	tokenAddress, token := backendtest.DeployMyToken(t, sim)
+	parsed, _ := contracts.MYERC20MetaData.GetAbi()
+	pauseData, _ := parsed.Pack("pause")
+
+	// 非owner调用pause
+	_, err := sim.CallContract(context.Background(), ethereum.CallMsg{From: sim.Accounts[1].Address, To: &tokenAddress, Data: pauseData}, nil)
+	revertErr, ok := Default().FromError(err)
+	if !ok || revertErr.Name != "OwnableUnauthorizedAccount" {
+		t.Fatalf("got %v, want OwnableUnauthorizedAccount", err)
+	}
+
+	// owner暂停后转账
+	auth := backendtest.Transactor(sim, 0)
+	if _, err := token.Pause(auth); err != nil {
+		t.Fatal(err)
+	}
+	sim.Commit()
+	_, err = sim.CallContract(context.Background(), ethereum.CallMsg{
+		From: sim.Accounts[0].Address,
+		To:   &tokenAddress,
+		Data: transferData(t, sim.Accounts[1].Address, big.NewInt(1)),
+	}, nil)
+	revertErr, ok = Default().FromError(err)
+	if !ok || revertErr.Name != "EnforcedPause" {
+		t.Fatalf("got %v, want EnforcedPause", err)
+	}
+}
+
+func TestReplayFailedTransaction(t *testing.T) {
+	sim := backendtest.New(t, 2)
+	token, tokenContract := backendtest.DeployMyToken(t, sim)
+	poor := sim.Accounts[1]
+
+	// 手动指定Gas上限，跳过估算，让失败的交易上链
+	chainID := big.NewInt(backend.SimulatedChainID)
+	tx, err := types.SignNewTx(poor.Key, types.LatestSignerForChainID(chainID), &types.DynamicFeeTx{
+		ChainID:   chainID,
+		GasTipCap: big.NewInt(5e9),
+		GasFeeCap: big.NewInt(10e9),
+		Gas:       100000,
+		To:        &token,
+		Data:      transferData(t, sim.Accounts[0].Address, big.NewInt(7)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sim.SendTransaction(context.Background(), tx); err != nil {
+		t.Fatal(err)
+	}
+	// 同一区块中排在失败交易之后（小费更低）的转账让账户1有了足够的代币，区块执行后的状态无法复现回滚
+	auth := backendtest.Transactor(sim, 0)
+	auth.GasTipCap = big.NewInt(1e9)
+	if _, err := tokenContract.Transfer(auth, poor.Address, big.NewInt(7)); err != nil {
+		t.Fatal(err)
+	}
+	sim.Commit()
+
+	revertErr, err := Default().Replay(context.Background(), sim, tx.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revertErr.Name != "ERC20InsufficientBalance" || revertErr.TxHash != tx.Hash() {
+		t.Fatalf("got %+v", revertErr)
+	}
+}
+
+func TestDecodeErrorStringAndPanic(t *testing.T) {
+	stringType, _ := abi.NewType("string", "", nil)
+	reason, _ := abi.Arguments{{Type: stringType}}.Pack("InvalidTransaction")
+	revertErr := Default().Decode(append(append([]byte{}, errorSelector...), reason...))
+	if revertErr.Name != "Error" || revertErr.Error() != "execution reverted: InvalidTransaction" {
+		t.Fatalf("got %q", revertErr.Error())
+	}
+
+	code := common.LeftPadBytes([]byte{0x11}, 32)
+	revertErr = Default().Decode(append(append([]byte{}, panicSelector...), code...))
+	if revertErr.Name != "Panic" || revertErr.Description != panicReasons[0x11] {
+		t.Fatalf("got %+v", revertErr)
+	}
+
+	unknown := Default().Decode([]byte{0xde, 0xad, 0xbe, 0xef})
+	if unknown.Name != "" {
+		t.Fatalf("unknown selector decoded as %q", unknown.Name)
+	}
+}
@@ -0,0 +1,236 @@
+package rpc_pool
+
+import (
+	"context"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// BlockNumber 查询最新区块号
+func (p *Pool) BlockNumber(ctx context.Context) (uint64, error) {
+	return call(p, ctx, false, func(ctx context.Context, client *ethclient.Client) (uint64, error) {
+		return client.BlockNumber(ctx)
+	})
+}
+
+// ChainID 查询链ID
+func (p *Pool) ChainID(ctx context.Context) (*big.Int, error) {
+	return call(p, ctx, false, func(ctx context.Context, client *ethclient.Client) (*big.Int, error) {
+		return client.ChainID(ctx)
+	})
+}
+
+// BlockByHash 按哈希查询区块
+func (p *Pool) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	return call(p, ctx, false, func(ctx context.Context, client *ethclient.Client) (*types.Block, error) {
+		return client.BlockByHash(ctx, hash)
+	})
+}
+
+// BlockByNumber 按区块号查询区块
+func (p *Pool) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	return call(p, ctx, false, func(ctx context.Context, client *ethclient.Client) (*types.Block, error) {
+		return client.BlockByNumber(ctx, number)
+	})
+}
+
+// HeaderByHash 按哈希查询区块头
+func (p *Pool) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	return call(p, ctx, false, func(ctx context.Context, client *ethclient.Client) (*types.Header, error) {
+		return client.HeaderByHash(ctx, hash)
+	})
+}
+
+// HeaderByNumber 按区块号查询区块头
+func (p *Pool) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return call(p, ctx, false, func(ctx context.Context, client *ethclient.Client) (*types.Header, error) {
+		return client.HeaderByNumber(ctx, number)
+	})
+}
+
+// TransactionCount 查询区块内交易数量
+func (p *Pool) TransactionCount(ctx context.Context, blockHash common.Hash) (uint, error) {
+	return call(p, ctx, false, func(ctx context.Context, client *ethclient.Client) (uint, error) {
+		return client.TransactionCount(ctx, blockHash)
+	})
+}
+
+// TransactionInBlock 按索引查询区块内交易
+func (p *Pool) TransactionInBlock(ctx context.Context, blockHash common.Hash, index uint) (*types.Transaction, error) {
+	return call(p, ctx, false, func(ctx context.Context, client *ethclient.Client) (*types.Transaction, error) {
+		return client.TransactionInBlock(ctx, blockHash, index)
+	})
+}
+
+// BalanceAt 查询账户余额
+func (p *Pool) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	return call(p, ctx, false, func(ctx context.Context, client *ethclient.Client) (*big.Int, error) {
+		return client.BalanceAt(ctx, account, blockNumber)
+	})
+}
+
+// StorageAt 查询合约存储槽
+func (p *Pool) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	return call(p, ctx, false, func(ctx context.Context, client *ethclient.Client) ([]byte, error) {
+		return client.StorageAt(ctx, account, key, blockNumber)
+	})
+}
+
+// CodeAt 查询合约代码
+func (p *Pool) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	return call(p, ctx, false, func(ctx context.Context, client *ethclient.Client) ([]byte, error) {
+		return client.CodeAt(ctx, account, blockNumber)
+	})
+}
+
+// NonceAt 查询账户nonce
+func (p *Pool) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	return call(p, ctx, false, func(ctx context.Context, client *ethclient.Client) (uint64, error) {
+		return client.NonceAt(ctx, account, blockNumber)
+	})
+}
+
+// PendingBalanceAt 查询包含待处理交易的余额
+func (p *Pool) PendingBalanceAt(ctx context.Context, account common.Address) (*big.Int, error) {
+	return call(p, ctx, false, func(ctx context.Context, client *ethclient.Client) (*big.Int, error) {
+		return client.PendingBalanceAt(ctx, account)
+	})
+}
+
+// PendingStorageAt 查询待处理状态的存储槽
+func (p *Pool) PendingStorageAt(ctx context.Context, account common.Address, key common.Hash) ([]byte, error) {
+	return call(p, ctx, false, func(ctx context.Context, client *ethclient.Client) ([]byte, error) {
+		return client.PendingStorageAt(ctx, account, key)
+	})
+}
+
+// PendingCodeAt 查询待处理状态的合约代码
+func (p *Pool) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	return call(p, ctx, false, func(ctx context.Context, client *ethclient.Client) ([]byte, error) {
+		return client.PendingCodeAt(ctx, account)
+	})
+}
+
+// PendingNonceAt 查询包含待处理交易的nonce
+func (p *Pool) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return call(p, ctx, false, func(ctx context.Context, client *ethclient.Client) (uint64, error) {
+		return client.PendingNonceAt(ctx, account)
+	})
+}
+
+// PendingTransactionCount 查询待处理交易数量
+func (p *Pool) PendingTransactionCount(ctx context.Context) (uint, error) {
+	return call(p, ctx, false, func(ctx context.Context, client *ethclient.Client) (uint, error) {
+		return client.PendingTransactionCount(ctx)
+	})
+}
+
+// CallContract 执行只读合约调用
+func (p *Pool) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return call(p, ctx, false, func(ctx context.Context, client *ethclient.Client) ([]byte, error) {
+		return client.CallContract(ctx, msg, blockNumber)
+	})
+}
+
+// PendingCallContract 在待处理状态上执行只读合约调用
+func (p *Pool) PendingCallContract(ctx context.Context, msg ethereum.CallMsg) ([]byte, error) {
+	return call(p, ctx, false, func(ctx context.Context, client *ethclient.Client) ([]byte, error) {
+		return client.PendingCallContract(ctx, msg)
+	})
+}
+
+// EstimateGas 估算Gas
+func (p *Pool) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	return call(p, ctx, false, func(ctx context.Context, client *ethclient.Client) (uint64, error) {
+		return client.EstimateGas(ctx, msg)
+	})
+}
+
+// SuggestGasPrice 查询建议Gas价格
+func (p *Pool) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return call(p, ctx, false, func(ctx context.Context, client *ethclient.Client) (*big.Int, error) {
+		return client.SuggestGasPrice(ctx)
+	})
+}
+
+// SuggestGasTipCap 查询建议小费
+func (p *Pool) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return call(p, ctx, false, func(ctx context.Context, client *ethclient.Client) (*big.Int, error) {
+		return client.SuggestGasTipCap(ctx)
+	})
+}
+
+// FeeHistory 查询历史费用
+func (p *Pool) FeeHistory(ctx context.Context, blockCount uint64, lastBlock *big.Int, rewardPercentiles []float64) (*ethereum.FeeHistory, error) {
+	return call(p, ctx, false, func(ctx context.Context, client *ethclient.Client) (*ethereum.FeeHistory, error) {
+		return client.FeeHistory(ctx, blockCount, lastBlock, rewardPercentiles)
+	})
+}
+
+// FilterLogs 按过滤条件查询日志
+func (p *Pool) FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error) {
+	return call(p, ctx, false, func(ctx context.Context, client *ethclient.Client) ([]types.Log, error) {
+		return client.FilterLogs(ctx, q)
+	})
+}
+
+// TransactionReceipt 查询交易收据
+func (p *Pool) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	return call(p, ctx, false, func(ctx context.Context, client *ethclient.Client) (*types.Receipt, error) {
+		return client.TransactionReceipt(ctx, txHash)
+	})
+}
+
+// SubscribeNewHead 订阅新区块头（仅WS/IPC节点）
+func (p *Pool) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	return call(p, ctx, true, func(ctx context.Context, client *ethclient.Client) (ethereum.Subscription, error) {
+		return client.SubscribeNewHead(ctx, ch)
+	})
+}
+
+// SubscribeFilterLogs 订阅日志（仅WS/IPC节点）
+func (p *Pool) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	return call(p, ctx, true, func(ctx context.Context, client *ethclient.Client) (ethereum.Subscription, error) {
+		return client.SubscribeFilterLogs(ctx, q, ch)
+	})
+}
+
+// TransactionByHash 按哈希查询交易
+func (p *Pool) TransactionByHash(ctx context.Context, txHash common.Hash) (*types.Transaction, bool, error) {
+	type result struct {
+		tx      *types.Transaction
+		pending bool
+	}
+	res, err := call(p, ctx, false, func(ctx context.Context, client *ethclient.Client) (result, error) {
+		tx, pending, err := client.TransactionByHash(ctx, txHash)
+		return result{tx, pending}, err
+	})
+	return res.tx, res.pending, err
+}
+
+// SendTransaction 广播已签名交易
+// 切换节点重发同一笔交易是幂等的：节点返回"already known"说明交易已在交易池中，视为成功
+func (p *Pool) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	_, err := call(p, ctx, false, func(ctx context.Context, client *ethclient.Client) (struct{}, error) {
+		err := client.SendTransaction(ctx, tx)
+		if err != nil && strings.Contains(strings.ToLower(err.Error()), "already known") {
+			return struct{}{}, nil
+		}
+		return struct{}{}, err
+	})
+	return err
+}
+
+// CallContext 发送原始JSON-RPC请求（如 eth_newFilter）
+// 过滤器只存在于创建它的节点上，切换节点后查询会返回 filter not found，调用方需要重新创建
+func (p *Pool) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	_, err := call(p, ctx, false, func(ctx context.Context, client *ethclient.Client) (struct{}, error) {
+		return struct{}{}, client.Client().CallContext(ctx, result, method, args...)
+	})
+	return err
+}
@@ -0,0 +1,289 @@
+package rpc_pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/nonce_manager"
+)
+
+// Options 节点池配置
+type Options struct {
+	CallTimeout         time.Duration // 单次调用超时
+	HealthCheckInterval time.Duration // 后台健康检查间隔
+	MaxHeadLag          uint64        // 允许落后最高区块的数量，超过则视为不健康（0表示不限制）
+	FailureThreshold    int           // 连续失败多少次后进入冷却期
+	Cooldown            time.Duration // 冷却期时长
+}
+
+// DefaultOptions 默认节点池配置
+var DefaultOptions = Options{
+	CallTimeout:         10 * time.Second,
+	HealthCheckInterval: 15 * time.Second,
+	MaxHeadLag:          5,
+	FailureThreshold:    3,
+	Cooldown:            30 * time.Second,
+}
+
+// ErrNoProvider 没有可用节点
+var ErrNoProvider = errors.New("没有可用的RPC节点")
+
+// Pool 多RPC节点池
+// 按延迟、错误率和区块落后数为每个节点打分，调用总是路由到分数最好的节点，
+// 节点故障时自动切换到下一个节点。Pool实现了backend.Client接口。
+type Pool struct {
+	providers []*Provider
+	opts      Options
+
+	nonces *nonce_manager.Manager
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// 编译期检查：Pool 满足 backend.Client 接口
+var _ backend.Client = (*Pool)(nil)
+
+// 编译期检查：Pool 持有自己的nonce管理器
+var _ nonce_manager.Owner = (*Pool)(nil)
+
+// NewPool 使用多个HTTP/WS节点地址创建节点池
+func NewPool(urls []string, opts Options) (*Pool, error) {
+	if opts.CallTimeout == 0 {
+		opts.CallTimeout = DefaultOptions.CallTimeout
+	}
+	if opts.HealthCheckInterval == 0 {
+		opts.HealthCheckInterval = DefaultOptions.HealthCheckInterval
+	}
+	if opts.FailureThreshold == 0 {
+		opts.FailureThreshold = DefaultOptions.FailureThreshold
+	}
+	if opts.Cooldown == 0 {
+		opts.Cooldown = DefaultOptions.Cooldown
+	}
+
+	pool := &Pool{opts: opts}
+	pool.nonces = nonce_manager.NewManager(pool)
+	seen := make(map[string]bool)
+	for _, rawurl := range urls {
+		rawurl = strings.TrimSpace(rawurl)
+		if rawurl == "" || seen[rawurl] {
+			continue
+		}
+		seen[rawurl] = true
+		pool.providers = append(pool.providers, newProvider(rawurl))
+	}
+	if len(pool.providers) == 0 {
+		return nil, ErrNoProvider
+	}
+	return pool, nil
+}
+
+// NonceManager 返回节点池持有的共享nonce管理器，通过同一个节点池发送的交易从它领取nonce
+func (p *Pool) NonceManager() *nonce_manager.Manager {
+	return p.nonces
+}
+
+// Start 启动后台健康检查（先同步执行一轮检查）
+func (p *Pool) Start() {
+	if p.stop != nil {
+		return
+	}
+	p.CheckHealth(context.Background())
+
+	p.stop = make(chan struct{})
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(p.opts.HealthCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				p.CheckHealth(context.Background())
+			}
+		}
+	}()
+}
+
+// Close 停止健康检查并关闭所有连接
+func (p *Pool) Close() {
+	if p.stop != nil {
+		close(p.stop)
+		p.wg.Wait()
+		p.stop = nil
+	}
+	for _, provider := range p.providers {
+		provider.resetConnection()
+	}
+}
+
+// CheckHealth 对所有节点执行一轮健康检查：查询最新区块高度并记录延迟
+func (p *Pool) CheckHealth(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, provider := range p.providers {
+		wg.Add(1)
+		go func(provider *Provider) {
+			defer wg.Done()
+			callCtx, cancel := context.WithTimeout(ctx, p.opts.CallTimeout)
+			defer cancel()
+
+			start := time.Now()
+			client, err := provider.dial(callCtx)
+			if err == nil {
+				var head uint64
+				head, err = client.BlockNumber(callCtx)
+				if err == nil {
+					provider.recordHead(head)
+					provider.recordSuccess(time.Since(start))
+					return
+				}
+			}
+			p.fail(provider, err)
+		}(provider)
+	}
+	wg.Wait()
+}
+
+// fail 记录节点故障
+// WS/IPC连接由该节点上的全部订阅共享，只在连接已断开或连续失败达到阈值时才重建，偶发的超时不关闭连接
+func (p *Pool) fail(provider *Provider, err error) {
+	down := provider.recordFailure(p.opts.FailureThreshold, p.opts.Cooldown)
+	if provider.SupportsSubscriptions() && (down || isConnectionLost(err)) {
+		provider.resetConnection()
+	}
+}
+
+// isConnectionLost 错误是否表示连接已经断开（继续使用该连接不会恢复）
+func isConnectionLost(err error) bool {
+	return errors.Is(err, rpc.ErrClientQuit) || errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// Stats 返回所有节点的健康快照，按分数从好到差排序
+func (p *Pool) Stats() []ProviderStats {
+	bestHead := p.bestHead()
+	stats := make([]ProviderStats, 0, len(p.providers))
+	for _, provider := range p.providers {
+		stats = append(stats, provider.stats(bestHead, p.opts.MaxHeadLag))
+	}
+	sort.SliceStable(stats, func(i, j int) bool { return stats[i].Score < stats[j].Score })
+	return stats
+}
+
+// bestHead 全部节点中观测到的最高区块
+func (p *Pool) bestHead() uint64 {
+	var best uint64
+	for _, provider := range p.providers {
+		provider.mu.Lock()
+		if provider.head > best {
+			best = provider.head
+		}
+		provider.mu.Unlock()
+	}
+	return best
+}
+
+// ranked 按分数排序的候选节点，subscribe为true时只返回支持订阅的节点
+func (p *Pool) ranked(subscribe bool) []*Provider {
+	bestHead := p.bestHead()
+	type candidate struct {
+		provider *Provider
+		score    float64
+	}
+	candidates := make([]candidate, 0, len(p.providers))
+	for _, provider := range p.providers {
+		if subscribe && !provider.SupportsSubscriptions() {
+			continue
+		}
+		candidates = append(candidates, candidate{provider, provider.stats(bestHead, p.opts.MaxHeadLag).Score})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score < candidates[j].score })
+
+	ranked := make([]*Provider, len(candidates))
+	for i, c := range candidates {
+		ranked[i] = c.provider
+	}
+	return ranked
+}
+
+// call 在最健康的节点上执行调用，节点故障时依次切换到下一个节点
+func call[T any](p *Pool, ctx context.Context, subscribe bool, fn func(ctx context.Context, client *ethclient.Client) (T, error)) (T, error) {
+	var zero T
+	providers := p.ranked(subscribe)
+	if len(providers) == 0 {
+		return zero, ErrNoProvider
+	}
+
+	var errs []error
+	for _, provider := range providers {
+		if err := ctx.Err(); err != nil {
+			return zero, err
+		}
+		callCtx, cancel := ctx, context.CancelFunc(func() {})
+		if !subscribe {
+			// 订阅的生命周期由调用方的ctx控制，不能设置单次调用超时
+			callCtx, cancel = context.WithTimeout(ctx, p.opts.CallTimeout)
+		}
+
+		start := time.Now()
+		client, err := provider.dial(callCtx)
+		if err == nil {
+			var result T
+			result, err = fn(callCtx, client)
+			if err == nil || !IsProviderError(err) || ctx.Err() != nil {
+				cancel()
+				if err == nil {
+					provider.recordSuccess(time.Since(start))
+				}
+				return result, err
+			}
+		}
+		cancel()
+
+		p.fail(provider, err)
+		errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+	}
+	return zero, fmt.Errorf("所有RPC节点调用失败: %w", errors.Join(errs...))
+}
+
+// IsProviderError 判断错误是否由节点本身引起（网络错误、超时、限流、5xx），
+// 这类错误需要切换节点重试；JSON-RPC业务错误（如执行回滚、nonce过低）会直接返回给调用方
+func IsProviderError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == 429 || httpErr.StatusCode >= 500
+	}
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) {
+		// -32005: 请求超出节点限制（限流）
+		return rpcErr.ErrorCode() == -32005
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, rpc.ErrClientQuit) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "connection refused") ||
+		strings.Contains(msg, "connection reset") ||
+		strings.Contains(msg, "EOF")
+}
@@ -0,0 +1,257 @@
+package rpc_pool
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"ethclient_tutorial/nonce_manager"
+)
+
+// fakeNode 本地JSON-RPC节点替身
+type fakeNode struct {
+	server  *httptest.Server
+	head    atomic.Uint64
+	delay   time.Duration
+	down    atomic.Bool
+	calls   atomic.Int64
+	balance int64
+}
+
+func newFakeNode(t *testing.T, head uint64, delay time.Duration) *fakeNode {
+	t.Helper()
+	n := &fakeNode{delay: delay, balance: 42}
+	n.head.Store(head)
+	n.server = httptest.NewServer(http.HandlerFunc(n.serve))
+	t.Cleanup(n.server.Close)
+	return n
+}
+
+func (n *fakeNode) serve(w http.ResponseWriter, r *http.Request) {
+	n.calls.Add(1)
+	if n.down.Load() {
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+		return
+	}
+	time.Sleep(n.delay)
+
+	var req struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+	switch req.Method {
+	case "eth_blockNumber":
+		resp["result"] = hexutil.Uint64(n.head.Load())
+	case "eth_chainId":
+		resp["result"] = hexutil.Uint64(11155111)
+	case "eth_getBalance":
+		resp["result"] = hexutil.EncodeBig(big.NewInt(n.balance))
+	case "eth_estimateGas":
+		resp["error"] = map[string]interface{}{"code": 3, "message": "execution reverted"}
+	default:
+		resp["error"] = map[string]interface{}{"code": -32601, "message": "method not found"}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func testOptions() Options {
+	return Options{
+		CallTimeout:         time.Second,
+		HealthCheckInterval: time.Hour,
+		MaxHeadLag:          5,
+		FailureThreshold:    2,
+		Cooldown:            time.Minute,
+	}
+}
+
+func TestPoolPrefersFastestProvider(t *testing.T) {
+	slow := newFakeNode(t, 100, 50*time.Millisecond)
+	fast := newFakeNode(t, 100, 0)
+
+	pool, err := NewPool([]string{slow.server.URL, fast.server.URL}, testOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	pool.CheckHealth(context.Background())
+
+	slowBefore, fastBefore := slow.calls.Load(), fast.calls.Load()
+	for i := 0; i < 5; i++ {
+		if _, err := pool.BlockNumber(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if slow.calls.Load() != slowBefore {
+		t.Errorf("slow provider received %d calls, want 0", slow.calls.Load()-slowBefore)
+	}
+	if fast.calls.Load()-fastBefore != 5 {
+		t.Errorf("fast provider received %d calls, want 5", fast.calls.Load()-fastBefore)
+	}
+}
+
+func TestPoolFailsOver(t *testing.T) {
+	primary := newFakeNode(t, 100, 0)
+	backup := newFakeNode(t, 100, 20*time.Millisecond)
+
+	pool, err := NewPool([]string{primary.server.URL, backup.server.URL}, testOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	pool.CheckHealth(context.Background())
+
+	primary.down.Store(true)
+	for i := 0; i < 3; i++ {
+		balance, err := pool.BalanceAt(context.Background(), common.Address{}, nil)
+		if err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+		if balance.Int64() != 42 {
+			t.Fatalf("balance = %s, want 42", balance)
+		}
+	}
+
+	// 连续失败达到阈值后主节点进入冷却期，不再被调用
+	stats := pool.Stats()
+	if stats[0].Name != RedactURL(backup.server.URL) {
+		t.Fatalf("best provider = %s, want backup", stats[0].Name)
+	}
+	for _, s := range stats {
+		if s.Name == RedactURL(primary.server.URL) && s.Healthy {
+			t.Fatal("primary should be marked unhealthy")
+		}
+	}
+	before := primary.calls.Load()
+	if _, err := pool.ChainID(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if primary.calls.Load() != before {
+		t.Fatal("unhealthy primary should not receive calls during cooldown")
+	}
+}
+
+func TestPoolSkipsLaggingProvider(t *testing.T) {
+	lagging := newFakeNode(t, 90, 0)
+	synced := newFakeNode(t, 100, 20*time.Millisecond)
+
+	pool, err := NewPool([]string{lagging.server.URL, synced.server.URL}, testOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	pool.CheckHealth(context.Background())
+
+	head, err := pool.BlockNumber(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head != 100 {
+		t.Fatalf("head = %d, want 100 from synced provider", head)
+	}
+}
+
+func TestPoolDoesNotFailOverOnRPCError(t *testing.T) {
+	first := newFakeNode(t, 100, 0)
+	second := newFakeNode(t, 100, 20*time.Millisecond)
+
+	pool, err := NewPool([]string{first.server.URL, second.server.URL}, testOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	pool.CheckHealth(context.Background())
+
+	before := second.calls.Load()
+	if _, err := pool.EstimateGas(context.Background(), callMsg()); err == nil {
+		t.Fatal("expected execution reverted error")
+	} else if IsProviderError(err) {
+		t.Fatalf("revert should not be a provider error: %v", err)
+	}
+	if second.calls.Load() != before {
+		t.Fatal("application errors must not trigger failover")
+	}
+}
+
+func TestPoolAllProvidersDown(t *testing.T) {
+	node := newFakeNode(t, 100, 0)
+	node.down.Store(true)
+
+	pool, err := NewPool([]string{node.server.URL}, testOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	if _, err := pool.BlockNumber(context.Background()); err == nil {
+		t.Fatal("expected error when all providers are down")
+	}
+}
+
+func TestPoolOwnsNonceManager(t *testing.T) {
+	pool, err := NewPool([]string{"http://127.0.0.1:1"}, testOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	if nonce_manager.ForClient(pool) != pool.NonceManager() {
+		t.Fatal("ForClient did not return the pool's own nonce manager")
+	}
+	if nonce_manager.ForClient(pool) != nonce_manager.ForClient(pool) {
+		t.Fatal("ForClient returned different managers for the same pool")
+	}
+}
+
+func callMsg() ethereum.CallMsg {
+	to := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	return ethereum.CallMsg{To: &to}
+}
+
+func TestPoolKeepsSharedConnectionOnTransientFailure(t *testing.T) {
+	pool, err := NewPool([]string{"ws://127.0.0.1:1"}, testOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	provider := pool.providers[0]
+	server := rpc.NewServer()
+	defer server.Stop()
+	client := ethclient.NewClient(rpc.DialInProc(server))
+	provider.client = client
+
+	// 一次超时不关闭WS连接（其上的订阅不受影响），连续失败达到阈值后才重建
+	pool.fail(provider, context.DeadlineExceeded)
+	if provider.client != client {
+		t.Fatal("connection reset after a single timeout")
+	}
+	pool.fail(provider, context.DeadlineExceeded)
+	if provider.client != nil {
+		t.Fatal("connection kept after reaching the failure threshold")
+	}
+
+	// 连接已断开时立即重建
+	provider.recordSuccess(time.Millisecond)
+	provider.client = ethclient.NewClient(rpc.DialInProc(server))
+	pool.fail(provider, rpc.ErrClientQuit)
+	if provider.client != nil {
+		t.Fatal("closed connection kept")
+	}
+}
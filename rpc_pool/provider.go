@@ -0,0 +1,172 @@
+package rpc_pool
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// ewmaWeight 延迟与错误率指数滑动平均中新样本的权重
+const ewmaWeight = 0.3
+
+// Provider 单个RPC节点及其健康状态
+type Provider struct {
+	url  string
+	name string
+
+	mu                  sync.Mutex
+	client              *ethclient.Client
+	latency             time.Duration
+	errorRate           float64
+	head                uint64
+	calls               uint64
+	failures            uint64
+	consecutiveFailures int
+	downUntil           time.Time
+}
+
+// ProviderStats 节点健康状态快照
+type ProviderStats struct {
+	Name      string        `json:"name"`
+	Latency   time.Duration `json:"latency"`
+	ErrorRate float64       `json:"errorRate"`
+	Head      uint64        `json:"head"`
+	HeadLag   uint64        `json:"headLag"`
+	Calls     uint64        `json:"calls"`
+	Failures  uint64        `json:"failures"`
+	Healthy   bool          `json:"healthy"`
+	Score     float64       `json:"score"`
+}
+
+// newProvider 创建节点，连接在第一次使用时建立
+func newProvider(rawurl string) *Provider {
+	return &Provider{url: rawurl, name: RedactURL(rawurl)}
+}
+
+// Name 返回隐藏了API密钥的节点名称，可安全打印
+func (p *Provider) Name() string {
+	return p.name
+}
+
+// SupportsSubscriptions 是否支持订阅（WebSocket/IPC连接）
+func (p *Provider) SupportsSubscriptions() bool {
+	return !strings.HasPrefix(p.url, "http://") && !strings.HasPrefix(p.url, "https://")
+}
+
+// dial 获取（必要时建立）节点连接
+// 建立连接时不持有锁，避免一次缓慢的连接阻塞该节点的其他调用；并发建立的多余连接会被关闭
+func (p *Provider) dial(ctx context.Context) (*ethclient.Client, error) {
+	p.mu.Lock()
+	client := p.client
+	p.mu.Unlock()
+	if client != nil {
+		return client, nil
+	}
+
+	client, err := ethclient.DialContext(ctx, p.url)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.client != nil {
+		client.Close()
+		return p.client, nil
+	}
+	p.client = client
+	return client, nil
+}
+
+// resetConnection 关闭连接，下次使用时重新建立（用于WS断线后的恢复）
+func (p *Provider) resetConnection() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.client != nil {
+		p.client.Close()
+		p.client = nil
+	}
+}
+
+// recordSuccess 记录一次成功调用
+func (p *Provider) recordSuccess(latency time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	p.consecutiveFailures = 0
+	p.downUntil = time.Time{}
+	p.errorRate = p.errorRate * (1 - ewmaWeight)
+	if p.latency == 0 {
+		p.latency = latency
+	} else {
+		p.latency = time.Duration(float64(p.latency)*(1-ewmaWeight) + float64(latency)*ewmaWeight)
+	}
+}
+
+// recordFailure 记录一次节点故障，连续失败达到阈值后节点进入冷却期，返回是否达到阈值
+func (p *Provider) recordFailure(threshold int, cooldown time.Duration) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	p.failures++
+	p.consecutiveFailures++
+	p.errorRate = p.errorRate*(1-ewmaWeight) + ewmaWeight
+	if p.consecutiveFailures < threshold {
+		return false
+	}
+	p.downUntil = time.Now().Add(cooldown)
+	return true
+}
+
+// recordHead 记录节点最新区块高度
+func (p *Provider) recordHead(head uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if head > p.head {
+		p.head = head
+	}
+}
+
+// stats 计算节点健康快照，bestHead为全部节点中的最高区块
+func (p *Provider) stats(bestHead uint64, maxHeadLag uint64) ProviderStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var lag uint64
+	if bestHead > p.head {
+		lag = bestHead - p.head
+	}
+	healthy := time.Now().After(p.downUntil) && (maxHeadLag == 0 || lag <= maxHeadLag)
+
+	// 分数越低越好：以毫秒延迟为基础，按错误率和区块落后数放大
+	score := float64(p.latency)/float64(time.Millisecond) + 1
+	score *= 1 + 10*p.errorRate
+	score += float64(lag) * 100
+	if !healthy {
+		score += 1e9
+	}
+
+	return ProviderStats{
+		Name:      p.name,
+		Latency:   p.latency,
+		ErrorRate: p.errorRate,
+		Head:      p.head,
+		HeadLag:   lag,
+		Calls:     p.calls,
+		Failures:  p.failures,
+		Healthy:   healthy,
+		Score:     score,
+	}
+}
+
+// RedactURL 去掉URL中的用户信息、路径和查询参数（通常包含API密钥），用于打印节点地址
+func RedactURL(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil || u.Host == "" {
+		return rawurl
+	}
+	return u.Scheme + "://" + u.Host
+}
@@ -0,0 +1,165 @@
+package signer
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// RemoteSigner 通过Clef外部签名API（account_* JSON-RPC）签名，私钥始终留在签名服务中
+//
+// 签名服务返回的结果都会在本地校验：交易签名哈希必须与请求一致且由本账户签名，
+// 消息签名必须能恢复出本账户地址
+type RemoteSigner struct {
+	client  *rpc.Client
+	address common.Address
+}
+
+// signTransactionResult account_signTransaction 的返回值
+type signTransactionResult struct {
+	Raw hexutil.Bytes      `json:"raw"`
+	Tx  *types.Transaction `json:"tx"`
+}
+
+// DialRemote 连接签名服务（http(s)://、ws(s):// 或IPC路径）
+// address 为零地址时通过 account_list 使用签名服务的第一个账户
+func DialRemote(ctx context.Context, url string, address common.Address) (*RemoteSigner, error) {
+	client, err := rpc.DialContext(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("连接签名服务失败: %v", err)
+	}
+	s := &RemoteSigner{client: client, address: address}
+	if address == (common.Address{}) {
+		list, err := s.Accounts(ctx)
+		if err != nil {
+			client.Close()
+			return nil, err
+		}
+		if len(list) == 0 {
+			client.Close()
+			return nil, fmt.Errorf("签名服务没有可用账户")
+		}
+		s.address = list[0]
+	}
+	return s, nil
+}
+
+// Accounts 签名服务管理的账户（account_list）
+func (s *RemoteSigner) Accounts(ctx context.Context) ([]common.Address, error) {
+	var list []common.Address
+	if err := s.client.CallContext(ctx, &list, "account_list"); err != nil {
+		return nil, fmt.Errorf("获取签名服务账户失败: %v", err)
+	}
+	return list, nil
+}
+
+// Close 关闭连接
+func (s *RemoteSigner) Close() {
+	s.client.Close()
+}
+
+// Address 签名账户地址
+func (s *RemoteSigner) Address() common.Address {
+	return s.address
+}
+
+// SignTx 调用 account_signTransaction 签名交易
+func (s *RemoteSigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	args := apitypes.SendTxArgs{
+		From:    common.NewMixedcaseAddress(s.address),
+		Gas:     hexutil.Uint64(tx.Gas()),
+		Value:   hexutil.Big(*tx.Value()),
+		Nonce:   hexutil.Uint64(tx.Nonce()),
+		ChainID: (*hexutil.Big)(chainID),
+	}
+	if to := tx.To(); to != nil {
+		mixed := common.NewMixedcaseAddress(*to)
+		args.To = &mixed
+	}
+	if data := tx.Data(); len(data) > 0 {
+		input := hexutil.Bytes(data)
+		args.Input = &input
+	}
+	switch tx.Type() {
+	case types.LegacyTxType:
+		args.GasPrice = (*hexutil.Big)(tx.GasPrice())
+	case types.AccessListTxType:
+		accessList := tx.AccessList()
+		args.GasPrice = (*hexutil.Big)(tx.GasPrice())
+		args.AccessList = &accessList
+	case types.DynamicFeeTxType:
+		accessList := tx.AccessList()
+		args.MaxFeePerGas = (*hexutil.Big)(tx.GasFeeCap())
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(tx.GasTipCap())
+		args.AccessList = &accessList
+	default:
+		return nil, fmt.Errorf("远程签名不支持交易类型 %d", tx.Type())
+	}
+
+	var result signTransactionResult
+	if err := s.client.CallContext(ctx, &result, "account_signTransaction", args, nil); err != nil {
+		return nil, fmt.Errorf("远程签名交易失败: %v", err)
+	}
+	signedTx := new(types.Transaction)
+	if err := signedTx.UnmarshalBinary(result.Raw); err != nil {
+		return nil, fmt.Errorf("解析远程签名交易失败: %v", err)
+	}
+
+	// 签名服务可能修改交易（如Clef规则调整Gas），只接受与请求完全一致的交易
+	txSigner := types.LatestSignerForChainID(chainID)
+	if txSigner.Hash(signedTx) != txSigner.Hash(tx) {
+		return nil, fmt.Errorf("签名服务返回的交易与请求不一致")
+	}
+	sender, err := types.Sender(txSigner, signedTx)
+	if err != nil {
+		return nil, fmt.Errorf("解析远程签名交易发送方失败: %v", err)
+	}
+	if sender != s.address {
+		return nil, fmt.Errorf("%w: 期望 %s，得到 %s", ErrWrongSigner, s.address.Hex(), sender.Hex())
+	}
+	return signedTx, nil
+}
+
+// SignMessage 调用 account_signData（text/plain）按 personal_sign 签名消息
+func (s *RemoteSigner) SignMessage(ctx context.Context, message []byte) ([]byte, error) {
+	var signature hexutil.Bytes
+	if err := s.client.CallContext(ctx, &signature, "account_signData", accounts.MimetypeTextPlain, common.NewMixedcaseAddress(s.address), hexutil.Bytes(message)); err != nil {
+		return nil, fmt.Errorf("远程签名消息失败: %v", err)
+	}
+	return s.verify(accounts.TextHash(message), signature)
+}
+
+// SignTypedData 调用 account_signTypedData 按EIP-712签名结构化数据
+func (s *RemoteSigner) SignTypedData(ctx context.Context, data apitypes.TypedData) ([]byte, error) {
+	hash, _, err := apitypes.TypedDataAndHash(data)
+	if err != nil {
+		return nil, fmt.Errorf("计算EIP-712哈希失败: %v", err)
+	}
+	var signature hexutil.Bytes
+	if err := s.client.CallContext(ctx, &signature, "account_signTypedData", common.NewMixedcaseAddress(s.address), data); err != nil {
+		return nil, fmt.Errorf("远程签名EIP-712数据失败: %v", err)
+	}
+	return s.verify(hash, signature)
+}
+
+// verify 校验签名由本账户产生，并把V统一为27/28
+func (s *RemoteSigner) verify(hash []byte, signature []byte) ([]byte, error) {
+	recovered, err := RecoverAddress(hash, signature)
+	if err != nil {
+		return nil, err
+	}
+	if recovered != s.address {
+		return nil, fmt.Errorf("%w: 期望 %s，得到 %s", ErrWrongSigner, s.address.Hex(), recovered.Hex())
+	}
+	if signature[64] < 27 {
+		signature[64] += 27
+	}
+	return signature, nil
+}
@@ -0,0 +1,168 @@
+package signer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+
+	"ethclient_tutorial/wallet_management"
+)
+
+// ErrWrongSigner 签名结果不是由预期账户产生的
+var ErrWrongSigner = errors.New("签名账户不匹配")
+
+// Signer 账户签名接口
+// 交易发送、消息签名和EIP-712签名都只依赖该接口，私钥可以在本进程内存中、keystore文件中，
+// 也可以在远程签名服务（如Clef）中
+type Signer interface {
+	// Address 签名账户地址
+	Address() common.Address
+	// SignTx 签名交易（EIP-155/EIP-1559，按交易类型选择签名方式）
+	SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+	// SignMessage 按 personal_sign（EIP-191）签名消息，返回65字节签名，V为27或28
+	SignMessage(ctx context.Context, message []byte) ([]byte, error)
+	// SignTypedData 按EIP-712签名结构化数据，返回65字节签名，V为27或28
+	SignTypedData(ctx context.Context, data apitypes.TypedData) ([]byte, error)
+}
+
+// KeySigner 使用内存中私钥签名
+type KeySigner struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+}
+
+// NewKeySigner 创建私钥签名器
+func NewKeySigner(key *ecdsa.PrivateKey) *KeySigner {
+	return &KeySigner{key: key, address: crypto.PubkeyToAddress(key.PublicKey)}
+}
+
+// FromAccount 使用已解锁账户签名
+func FromAccount(account *wallet_management.UnlockedAccount) *KeySigner {
+	return &KeySigner{key: account.PrivateKey, address: account.Address}
+}
+
+// Address 签名账户地址
+func (s *KeySigner) Address() common.Address {
+	return s.address
+}
+
+// SignTx 签名交易
+func (s *KeySigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	signedTx, err := types.SignTx(tx, types.LatestSignerForChainID(chainID), s.key)
+	if err != nil {
+		return nil, fmt.Errorf("交易签名失败: %v", err)
+	}
+	return signedTx, nil
+}
+
+// SignMessage 按 personal_sign 签名消息
+func (s *KeySigner) SignMessage(ctx context.Context, message []byte) ([]byte, error) {
+	return s.signHash(accounts.TextHash(message))
+}
+
+// SignTypedData 按EIP-712签名结构化数据
+func (s *KeySigner) SignTypedData(ctx context.Context, data apitypes.TypedData) ([]byte, error) {
+	hash, _, err := apitypes.TypedDataAndHash(data)
+	if err != nil {
+		return nil, fmt.Errorf("计算EIP-712哈希失败: %v", err)
+	}
+	return s.signHash(hash)
+}
+
+func (s *KeySigner) signHash(hash []byte) ([]byte, error) {
+	signature, err := crypto.Sign(hash, s.key)
+	if err != nil {
+		return nil, fmt.Errorf("签名失败: %v", err)
+	}
+	signature[crypto.RecoveryIDOffset] += 27
+	return signature, nil
+}
+
+// KeystoreSigner 使用keystore账户签名
+// 每次签名时用口令临时解密私钥，签名完成后私钥不保留在内存中
+type KeystoreSigner struct {
+	keystore   *wallet_management.Keystore
+	address    common.Address
+	passphrase string
+}
+
+// NewKeystoreSigner 创建keystore签名器，会先用口令验证账户能否解锁
+func NewKeystoreSigner(ks *wallet_management.Keystore, address common.Address, passphrase string) (*KeystoreSigner, error) {
+	s := &KeystoreSigner{keystore: ks, address: address, passphrase: passphrase}
+	if _, err := ks.SignHashWithPassphrase(address, passphrase, make([]byte, 32)); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Address 签名账户地址
+func (s *KeystoreSigner) Address() common.Address {
+	return s.address
+}
+
+// SignTx 签名交易
+func (s *KeystoreSigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return s.keystore.SignTxWithPassphrase(s.address, s.passphrase, tx, chainID)
+}
+
+// SignMessage 按 personal_sign 签名消息
+func (s *KeystoreSigner) SignMessage(ctx context.Context, message []byte) ([]byte, error) {
+	return s.signHash(accounts.TextHash(message))
+}
+
+// SignTypedData 按EIP-712签名结构化数据
+func (s *KeystoreSigner) SignTypedData(ctx context.Context, data apitypes.TypedData) ([]byte, error) {
+	hash, _, err := apitypes.TypedDataAndHash(data)
+	if err != nil {
+		return nil, fmt.Errorf("计算EIP-712哈希失败: %v", err)
+	}
+	return s.signHash(hash)
+}
+
+func (s *KeystoreSigner) signHash(hash []byte) ([]byte, error) {
+	signature, err := s.keystore.SignHashWithPassphrase(s.address, s.passphrase, hash)
+	if err != nil {
+		return nil, err
+	}
+	signature[crypto.RecoveryIDOffset] += 27
+	return signature, nil
+}
+
+// TransactOpts 创建使用 Signer 签名的合约绑定交易参数
+func TransactOpts(ctx context.Context, s Signer, chainID *big.Int) *bind.TransactOpts {
+	return &bind.TransactOpts{
+		From:    s.Address(),
+		Context: ctx,
+		Signer: func(address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if address != s.Address() {
+				return nil, bind.ErrNotAuthorized
+			}
+			return s.SignTx(ctx, tx, chainID)
+		},
+	}
+}
+
+// RecoverAddress 从65字节签名（V为0/1或27/28）恢复签名账户
+func RecoverAddress(hash, signature []byte) (common.Address, error) {
+	if len(signature) != crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("签名长度必须是 %d 字节，得到 %d", crypto.SignatureLength, len(signature))
+	}
+	sig := common.CopyBytes(signature)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	publicKey, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("恢复签名账户失败: %v", err)
+	}
+	return crypto.PubkeyToAddress(*publicKey), nil
+}
@@ -0,0 +1,245 @@
+package signer
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/backend/backendtest"
+	"ethclient_tutorial/wallet_management"
+)
+
+// mailTypedData EIP-712 规范中的 Mail 示例
+func mailTypedData() apitypes.TypedData {
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": {
+				{Name: "name", Type: "string"},
+				{Name: "version", Type: "string"},
+				{Name: "chainId", Type: "uint256"},
+				{Name: "verifyingContract", Type: "address"},
+			},
+			"Person": {
+				{Name: "name", Type: "string"},
+				{Name: "wallet", Type: "address"},
+			},
+			"Mail": {
+				{Name: "from", Type: "Person"},
+				{Name: "to", Type: "Person"},
+				{Name: "contents", Type: "string"},
+			},
+		},
+		PrimaryType: "Mail",
+		Domain: apitypes.TypedDataDomain{
+			Name:              "Ether Mail",
+			Version:           "1",
+			ChainId:           math.NewHexOrDecimal256(1337),
+			VerifyingContract: "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC",
+		},
+		Message: apitypes.TypedDataMessage{
+			"from":     map[string]interface{}{"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+			"to":       map[string]interface{}{"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+			"contents": "Hello, Bob!",
+		},
+	}
+}
+
+// sendSigned 用 Signer 签名一笔转账并在模拟链上确认
+func sendSigned(t *testing.T, sim *backend.Simulated, s Signer) {
+	t.Helper()
+	ctx := context.Background()
+	chainID, _ := sim.ChainID(ctx)
+	nonce, _ := sim.PendingNonceAt(ctx, s.Address())
+	tip, _ := sim.SuggestGasTipCap(ctx)
+	head, _ := sim.HeaderByNumber(ctx, nil)
+	to := common.HexToAddress("0x000000000000000000000000000000000000dEaD")
+	tx := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     nonce,
+		GasTipCap: tip,
+		GasFeeCap: new(big.Int).Add(tip, new(big.Int).Mul(head.BaseFee, big.NewInt(2))),
+		Gas:       21000,
+		To:        &to,
+		Value:     big.NewInt(1),
+	})
+	signedTx, err := s.SignTx(ctx, tx, chainID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sim.SendTransaction(ctx, signedTx); err != nil {
+		t.Fatal(err)
+	}
+	sim.Commit()
+	receipt, err := sim.TransactionReceipt(ctx, signedTx.Hash())
+	if err != nil || receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatalf("receipt = %+v, %v", receipt, err)
+	}
+}
+
+// checkSignatures 校验消息和EIP-712签名都能恢复出签名账户
+func checkSignatures(t *testing.T, s Signer) {
+	t.Helper()
+	ctx := context.Background()
+	message := []byte("hello ethclient")
+	signature, err := s.SignMessage(ctx, message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := signature[64]; v != 27 && v != 28 {
+		t.Fatalf("message signature V = %d", v)
+	}
+	if recovered, err := RecoverAddress(accounts.TextHash(message), signature); err != nil || recovered != s.Address() {
+		t.Fatalf("message signer = %s, %v", recovered.Hex(), err)
+	}
+
+	data := mailTypedData()
+	signature, err = s.SignTypedData(ctx, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, _, _ := apitypes.TypedDataAndHash(data)
+	if recovered, err := RecoverAddress(hash, signature); err != nil || recovered != s.Address() {
+		t.Fatalf("typed data signer = %s, %v", recovered.Hex(), err)
+	}
+}
+
+func TestKeyAndKeystoreSigners(t *testing.T) {
+	sim := backendtest.New(t, 2)
+
+	keySigner := NewKeySigner(sim.Accounts[0].Key)
+	if keySigner.Address() != sim.Accounts[0].Address {
+		t.Fatal("key signer address mismatch")
+	}
+	sendSigned(t, sim, keySigner)
+	checkSignatures(t, keySigner)
+
+	ks := wallet_management.OpenLightKeystore(t.TempDir())
+	if _, err := ks.ImportKey(sim.Accounts[1].KeyHex(), "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewKeystoreSigner(ks, sim.Accounts[1].Address, "wrong"); err == nil {
+		t.Fatal("wrong passphrase should be rejected")
+	}
+	keystoreSigner, err := NewKeystoreSigner(ks, sim.Accounts[1].Address, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sendSigned(t, sim, keystoreSigner)
+	checkSignatures(t, keystoreSigner)
+
+	// 两种签名器对同一私钥的消息签名一致
+	other := NewKeySigner(sim.Accounts[1].Key)
+	a, _ := other.SignMessage(context.Background(), []byte("same"))
+	b, _ := keystoreSigner.SignMessage(context.Background(), []byte("same"))
+	if common.Bytes2Hex(a) != common.Bytes2Hex(b) {
+		t.Fatal("key and keystore signatures differ")
+	}
+}
+
+func TestRemoteSigner(t *testing.T) {
+	sim := backendtest.New(t, 2)
+	ctx := context.Background()
+
+	standIn, err := NewStandIn(sim.Accounts[0].Key, sim.Accounts[1].Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer standIn.Close()
+
+	// 零地址使用签名服务的第一个账户
+	remote, err := DialRemote(ctx, standIn.URL, common.Address{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remote.Close()
+	if remote.Address() != sim.Accounts[0].Address {
+		t.Fatalf("default account = %s", remote.Address().Hex())
+	}
+	list, err := remote.Accounts(ctx)
+	if err != nil || len(list) != 2 || list[1] != sim.Accounts[1].Address {
+		t.Fatalf("accounts = %v, %v", list, err)
+	}
+	sendSigned(t, sim, remote)
+	checkSignatures(t, remote)
+
+	// 远程签名的消息与本地私钥签名一致
+	local, _ := NewKeySigner(sim.Accounts[0].Key).SignMessage(ctx, []byte("same"))
+	signed, _ := remote.SignMessage(ctx, []byte("same"))
+	if common.Bytes2Hex(local) != common.Bytes2Hex(signed) {
+		t.Fatal("remote and local signatures differ")
+	}
+
+	// 指定账户
+	second, err := DialRemote(ctx, standIn.URL, sim.Accounts[1].Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	sendSigned(t, sim, second)
+
+	// 签名服务拒绝请求
+	standIn.Approve = func(method string) error { return ErrRequestDenied }
+	if _, err := remote.SignMessage(ctx, []byte("denied")); err == nil || !strings.Contains(err.Error(), ErrRequestDenied.Error()) {
+		t.Fatalf("denied message err = %v", err)
+	}
+	chainID, _ := sim.ChainID(ctx)
+	to := common.Address{1}
+	tx := types.NewTx(&types.LegacyTx{Nonce: 0, GasPrice: big.NewInt(1), Gas: 21000, To: &to})
+	if _, err := remote.SignTx(ctx, tx, chainID); err == nil {
+		t.Fatal("denied transaction should fail")
+	}
+	standIn.Approve = nil
+
+	// 签名服务不管理的账户
+	unknown, err := DialRemote(ctx, standIn.URL, common.Address{0xaa})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unknown.Close()
+	if _, err := unknown.SignTx(ctx, tx, chainID); err == nil || !strings.Contains(err.Error(), "unknown account") {
+		t.Fatalf("unknown account err = %v", err)
+	}
+}
+
+func TestRemoteSignerRejectsWrongAccount(t *testing.T) {
+	sim := backendtest.New(t, 2)
+	ctx := context.Background()
+
+	standIn, err := NewStandIn(sim.Accounts[0].Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer standIn.Close()
+
+	// 签名服务用其他账户的私钥签名时，本地校验必须拒绝
+	standIn.keys[sim.Accounts[0].Address] = sim.Accounts[1].Key
+	remote, err := DialRemote(ctx, standIn.URL, sim.Accounts[0].Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remote.Close()
+	if _, err := remote.SignMessage(ctx, []byte("hello")); !errors.Is(err, ErrWrongSigner) {
+		t.Fatalf("message err = %v, want ErrWrongSigner", err)
+	}
+	chainID, _ := sim.ChainID(ctx)
+	to := common.Address{1}
+	tx := types.NewTx(&types.LegacyTx{Nonce: 0, GasPrice: big.NewInt(1), Gas: 21000, To: &to})
+	if _, err := remote.SignTx(ctx, tx, chainID); !errors.Is(err, ErrWrongSigner) {
+		t.Fatalf("tx err = %v, want ErrWrongSigner", err)
+	}
+}
+
+func TestRecoverAddressLength(t *testing.T) {
+	if _, err := RecoverAddress(make([]byte, 32), make([]byte, 64)); err == nil {
+		t.Fatal("short signature should be rejected")
+	}
+}
@@ -0,0 +1,132 @@
+package signer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// ErrRequestDenied 签名请求被拒绝（与Clef拒绝请求时返回的错误信息一致）
+var ErrRequestDenied = errors.New("request denied")
+
+// StandIn 本地替身签名服务，实现Clef外部签名API中的
+// account_list、account_signTransaction、account_signData（text/plain）和 account_signTypedData，
+// 用于测试和开发链，不做任何用户确认
+type StandIn struct {
+	URL string
+
+	// Approve 每个签名请求前调用，返回错误时拒绝该请求；nil 时全部批准
+	Approve func(method string) error
+
+	keys     map[common.Address]*ecdsa.PrivateKey
+	order    []common.Address
+	server   *rpc.Server
+	listener net.Listener
+}
+
+// NewStandIn 在 127.0.0.1 的随机端口启动替身签名服务（HTTP）
+func NewStandIn(keys ...*ecdsa.PrivateKey) (*StandIn, error) {
+	s := &StandIn{keys: make(map[common.Address]*ecdsa.PrivateKey), server: rpc.NewServer()}
+	for _, key := range keys {
+		address := crypto.PubkeyToAddress(key.PublicKey)
+		s.keys[address] = key
+		s.order = append(s.order, address)
+	}
+	if err := s.server.RegisterName("account", &standInAPI{s}); err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("启动替身签名服务失败: %v", err)
+	}
+	s.listener = listener
+	s.URL = "http://" + listener.Addr().String()
+	go http.Serve(listener, s.server)
+	return s, nil
+}
+
+// Close 停止服务
+func (s *StandIn) Close() {
+	s.listener.Close()
+	s.server.Stop()
+}
+
+// key 检查请求并返回账户私钥
+func (s *StandIn) key(method string, address common.Address) (*ecdsa.PrivateKey, error) {
+	if s.Approve != nil {
+		if err := s.Approve(method); err != nil {
+			return nil, err
+		}
+	}
+	key, ok := s.keys[address]
+	if !ok {
+		return nil, fmt.Errorf("unknown account %s", address.Hex())
+	}
+	return key, nil
+}
+
+// standInAPI account 命名空间的RPC方法
+type standInAPI struct {
+	s *StandIn
+}
+
+// List account_list
+func (api *standInAPI) List() []common.Address {
+	return api.s.order
+}
+
+// SignTransaction account_signTransaction
+func (api *standInAPI) SignTransaction(args apitypes.SendTxArgs, methodSelector *string) (*signTransactionResult, error) {
+	key, err := api.s.key("account_signTransaction", args.From.Address())
+	if err != nil {
+		return nil, err
+	}
+	if args.ChainID == nil {
+		return nil, fmt.Errorf("chainId is required")
+	}
+	tx, err := args.ToTransaction()
+	if err != nil {
+		return nil, err
+	}
+	signedTx, err := NewKeySigner(key).SignTx(context.Background(), tx, args.ChainID.ToInt())
+	if err != nil {
+		return nil, err
+	}
+	raw, err := signedTx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &signTransactionResult{Raw: raw, Tx: signedTx}, nil
+}
+
+// SignData account_signData，只支持 text/plain（personal_sign）
+func (api *standInAPI) SignData(contentType string, address common.MixedcaseAddress, data hexutil.Bytes) (hexutil.Bytes, error) {
+	if contentType != accounts.MimetypeTextPlain {
+		return nil, fmt.Errorf("unsupported content type %s", contentType)
+	}
+	key, err := api.s.key("account_signData", address.Address())
+	if err != nil {
+		return nil, err
+	}
+	return NewKeySigner(key).SignMessage(context.Background(), data)
+}
+
+// SignTypedData account_signTypedData
+func (api *standInAPI) SignTypedData(address common.MixedcaseAddress, data apitypes.TypedData) (hexutil.Bytes, error) {
+	key, err := api.s.key("account_signTypedData", address.Address())
+	if err != nil {
+		return nil, err
+	}
+	return NewKeySigner(key).SignTypedData(context.Background(), data)
+}
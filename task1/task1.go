@@ -3,7 +3,10 @@ package task1
 import (
 	"context"
 	"crypto/ecdsa"
+	"ethclient_tutorial/amount"
+	"ethclient_tutorial/backend"
 	"ethclient_tutorial/config"
+	"ethclient_tutorial/nonce_manager"
 	"ethclient_tutorial/utils"
 	"fmt"
 	"math/big"
@@ -11,11 +14,10 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
 )
 
 // 查询指定区块号的区块信息，包括区块的哈希、时间戳、交易数量等
-func QueryBlockByNum(client *ethclient.Client, num *big.Int) {
+func QueryBlockByNum(client backend.Client, num *big.Int) {
 	var block *types.Block
 	//如果传入的num为nil 直接查询最新的区块信息
 	//否则查询指定区块号的区块信息
@@ -35,13 +37,18 @@ func QueryBlockByNum(client *ethclient.Client, num *big.Int) {
 }
 
 // TransferETH 发送ETH转账
-func TransferETH(client *ethclient.Client, privateKeyHex string, toAddress common.Address, amount float64) (common.Hash, error) {
-	return TransferETHWithConfig(client, privateKeyHex, toAddress, amount, config.GlobalConfig)
+func TransferETH(client backend.Client, privateKeyHex string, toAddress common.Address, ethAmount amount.Amount) (common.Hash, error) {
+	return TransferETHWithConfig(client, privateKeyHex, toAddress, ethAmount, config.GlobalConfig)
 }
 
 // TransferETHWithConfig 使用配置发送ETH转账 - 支持EIP-1559
-func TransferETHWithConfig(client *ethclient.Client, privateKeyHex string, toAddress common.Address, amount float64, cfg *config.Config) (common.Hash, error) {
+func TransferETHWithConfig(client backend.Client, privateKeyHex string, toAddress common.Address, ethAmount amount.Amount, cfg *config.Config) (common.Hash, error) {
 	fmt.Println("\n=== 开始ETH转账流程 ===")
+	//精确换算转账金额（1 ETH = 10^18 wei）
+	value, err := utils.TokenToWei(ethAmount, amount.EtherDecimals)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("invalid amount: %v", err)
+	}
 	//根据私钥转换为ECDSA
 	privateKeyECDSA, err := crypto.HexToECDSA(privateKeyHex)
 	if err != nil {
@@ -58,11 +65,13 @@ func TransferETHWithConfig(client *ethclient.Client, privateKeyHex string, toAdd
 	fromAddress := crypto.PubkeyToAddress(*publicKeyECDSA)
 	fmt.Printf("✓ 发送方地址: %s\n", fromAddress.Hex())
 	fmt.Printf("✓ 接收方地址: %s\n", fromAddress.Hex())
-	//使用client 获取nonce
-	nonce, err := client.PendingNonceAt(context.Background(), fromAddress)
+	//从nonce管理器领取nonce
+	reservation, err := nonce_manager.ForClient(client).Reserve(context.Background(), fromAddress)
 	if err != nil {
 		return common.Hash{}, fmt.Errorf("failed to get nonce: %v", err)
 	}
+	defer reservation.Release(nil)
+	nonce := reservation.Nonce
 	fmt.Printf("✓ Nonce: %d\n", nonce)
 	//使用client 获取最新header
 	header, err := client.HeaderByNumber(context.Background(), nil)
@@ -79,7 +88,7 @@ func TransferETHWithConfig(client *ethclient.Client, privateKeyHex string, toAdd
 	//获取SuggestGasTipCap
 	tipCap, err := client.SuggestGasTipCap(context.Background())
 	if err != nil {
-		fmt.Printf("Warning: 获取小费建议失败，使用默认值: %s Gwei\n", new(big.Float).Quo(new(big.Float).SetInt(tipCap), big.NewFloat(1e9)))
+		fmt.Printf("Warning: 获取小费建议失败，使用默认值: %s Gwei\n", amount.Format(tipCap, 9))
 	}
 	//计算gasFeeCap = baseFee * 2 + tipCap
 	gasFeeCap := new(big.Int).Add(
@@ -96,7 +105,7 @@ func TransferETHWithConfig(client *ethclient.Client, privateKeyHex string, toAdd
 		GasFeeCap: gasFeeCap,
 		Gas:       gasLimit,
 		To:        &toAddress,
-		Value:     utils.TokenToWei(amount, 18),
+		Value:     value,
 		// 转账金额转换为Wei
 		Data: nil,
 	}
@@ -109,7 +118,9 @@ func TransferETHWithConfig(client *ethclient.Client, privateKeyHex string, toAdd
 	//发送交易
 	err = client.SendTransaction(context.Background(), signedTx)
 	if err != nil {
+		reservation.Release(err)
 		return common.Hash{}, fmt.Errorf("failed to send transaction: %v", err)
 	}
+	reservation.Commit(signedTx.Hash())
 	return signedTx.Hash(), nil
 }
@@ -1,33 +1,34 @@
 package task2
 
 import (
-	"ethclient_tutorial/contracts"
+	"context"
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/erc20"
 	"ethclient_tutorial/utils"
 	"fmt"
-	"math/big"
 
-	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
 )
 
 // GetTotalSupply 查询总供应量
-// 该函数用于查询指定地址的总供应量，使用EIP-1559
-func GetTotalSupply(client *ethclient.Client, tokenAddress common.Address) (*big.Float, error) {
-	//根据tokenAddress获取合约实例
-	instance, err := contracts.NewMYERC20(tokenAddress, client)
-	if err != nil {
-		return big.NewFloat(0.0), fmt.Errorf("创建合约实例失败: %v", err)
-	}
+// 该函数用于查询任意ERC20代币的总供应量，并按代币实际精度换算
+func GetTotalSupply(client backend.Client, tokenAddress common.Address) (string, error) {
+	//根据tokenAddress创建通用ERC20客户端
+	token := erc20.New(client, tokenAddress)
 	//调用合约的TotalSupply方法获取总供应量
-	totalSupply, err := instance.TotalSupply(&bind.CallOpts{})
+	totalSupply, err := token.TotalSupply(context.Background())
 	if err != nil {
-		return big.NewFloat(0.0), fmt.Errorf("获取总供应量失败: %v", err)
+		return "", fmt.Errorf("获取总供应量失败: %v", err)
 	}
 	fmt.Printf("✓ 总供应量: %s\n", totalSupply.String())
-	//将总供应量转换为Wei格式
-	totalSupplyWei := utils.WeiToToken(totalSupply, 18) // 假设代币精度为18
-	fmt.Printf("✓ 总供应量 (Wei): %s\n", totalSupplyWei.String())
+	//查询代币精度（未实现decimals的代币按0处理）
+	decimals, err := token.Decimals(context.Background())
+	if err != nil {
+		return "", fmt.Errorf("获取代币精度失败: %v", err)
+	}
+	//将总供应量按精度转换为代币单位
+	totalSupplyTokens := utils.WeiToToken(totalSupply, int(decimals))
+	fmt.Printf("✓ 总供应量 (精度 %d): %s\n", decimals, totalSupplyTokens)
 	//返回总供应量
-	return totalSupplyWei, nil
+	return totalSupplyTokens, nil
 }
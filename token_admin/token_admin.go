@@ -0,0 +1,252 @@
+package token_admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/contracts"
+	"ethclient_tutorial/fee_strategy"
+	"ethclient_tutorial/signer"
+	"ethclient_tutorial/tx_builder"
+	"ethclient_tutorial/utils"
+)
+
+var (
+	ErrNotOwner        = errors.New("发送账户不是合约owner")
+	ErrNotConfirmed    = errors.New("危险操作未确认")
+	ErrZeroOwner       = errors.New("新owner不能是零地址（放弃所有权请使用 renounce）")
+	ErrEventMissing    = errors.New("交易收据中没有预期的事件")
+	ErrAlreadyPaused   = errors.New("合约已经处于暂停状态")
+	ErrAlreadyUnpaused = errors.New("合约没有暂停")
+)
+
+// Confirm 危险操作的确认回调，reason 说明风险；返回nil表示继续
+type Confirm func(reason string) error
+
+// Admin MyToken 的owner管理操作
+// 每个操作都先检查发送账户是否为owner并模拟执行，发送后等待确认并从收据中读取对应事件
+type Admin struct {
+	Address   common.Address
+	Strategy  fee_strategy.Strategy // 为nil时使用默认策略
+	Multisigs []common.Address      // 已知的多签合约，转移所有权给它们不需要确认
+	Confirm   Confirm               // 为nil时危险操作返回 ErrNotConfirmed
+
+	client   backend.Client
+	abi      *abi.ABI
+	contract *contracts.MYERC20
+}
+
+// New 创建管理客户端
+func New(client backend.Client, address common.Address) (*Admin, error) {
+	parsed, err := contracts.MYERC20MetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	contract, err := contracts.NewMYERC20(address, client)
+	if err != nil {
+		return nil, fmt.Errorf("创建合约实例失败: %v", err)
+	}
+	return &Admin{
+		Address:  address,
+		client:   client,
+		abi:      parsed,
+		contract: contract,
+	}, nil
+}
+
+// Owner 查询合约owner
+func (a *Admin) Owner(ctx context.Context) (common.Address, error) {
+	owner, err := a.contract.Owner(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return common.Address{}, fmt.Errorf("查询owner失败: %v", err)
+	}
+	return owner, nil
+}
+
+// Paused 查询合约是否暂停
+func (a *Admin) Paused(ctx context.Context) (bool, error) {
+	paused, err := a.contract.Paused(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return false, fmt.Errorf("查询暂停状态失败: %v", err)
+	}
+	return paused, nil
+}
+
+// Action 一次管理操作的结果
+type Action struct {
+	Method      string
+	TxHash      common.Hash
+	BlockNumber uint64
+	Event       string // 收据中的事件，如 Paused(0x...)
+
+	Paused               *contracts.MYERC20Paused
+	Unpaused             *contracts.MYERC20Unpaused
+	OwnershipTransferred *contracts.MYERC20OwnershipTransferred
+	Minted               *contracts.MYERC20Transfer
+}
+
+// Pause 暂停合约（暂停期间所有转账都会回滚）
+func (a *Admin) Pause(ctx context.Context, s signer.Signer, wait utils.WaitOptions) (*Action, error) {
+	paused, err := a.Paused(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if paused {
+		return nil, ErrAlreadyPaused
+	}
+	return a.execute(ctx, s, wait, "pause")
+}
+
+// Unpause 恢复合约
+func (a *Admin) Unpause(ctx context.Context, s signer.Signer, wait utils.WaitOptions) (*Action, error) {
+	paused, err := a.Paused(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !paused {
+		return nil, ErrAlreadyUnpaused
+	}
+	return a.execute(ctx, s, wait, "unpause")
+}
+
+// Mint 给to增发amount数量（最小单位）的代币
+func (a *Admin) Mint(ctx context.Context, s signer.Signer, to common.Address, amount *big.Int, wait utils.WaitOptions) (*Action, error) {
+	return a.execute(ctx, s, wait, "mint", to, amount)
+}
+
+// TransferOwnership 把所有权转移给newOwner
+// newOwner 是合约但不在 Multisigs 中时需要确认：合约无法调用 owner 方法时所有权将永久丢失
+func (a *Admin) TransferOwnership(ctx context.Context, s signer.Signer, newOwner common.Address, wait utils.WaitOptions) (*Action, error) {
+	if newOwner == (common.Address{}) {
+		return nil, ErrZeroOwner
+	}
+	if err := a.checkOwner(ctx, s.Address()); err != nil {
+		return nil, err
+	}
+	reason, err := a.OwnerRisk(ctx, newOwner)
+	if err != nil {
+		return nil, err
+	}
+	if reason != "" {
+		if err := a.confirm(reason); err != nil {
+			return nil, err
+		}
+	}
+	return a.execute(ctx, s, wait, "transferOwnership", newOwner)
+}
+
+// RenounceOwnership 放弃所有权（不可撤销，之后无法再暂停、恢复或增发），总是需要确认
+func (a *Admin) RenounceOwnership(ctx context.Context, s signer.Signer, wait utils.WaitOptions) (*Action, error) {
+	if err := a.checkOwner(ctx, s.Address()); err != nil {
+		return nil, err
+	}
+	if err := a.confirm(fmt.Sprintf("放弃合约 %s 的所有权后无法恢复，将不能再暂停、恢复或增发", a.Address.Hex())); err != nil {
+		return nil, err
+	}
+	return a.execute(ctx, s, wait, "renounceOwnership")
+}
+
+// OwnerRisk 检查把所有权转移给newOwner的风险，返回空字符串表示普通账户或已知多签
+func (a *Admin) OwnerRisk(ctx context.Context, newOwner common.Address) (string, error) {
+	code, err := a.client.CodeAt(ctx, newOwner, nil)
+	if err != nil {
+		return "", fmt.Errorf("查询 %s 的代码失败: %v", newOwner.Hex(), err)
+	}
+	if len(code) == 0 {
+		return "", nil
+	}
+	for _, multisig := range a.Multisigs {
+		if multisig == newOwner {
+			return "", nil
+		}
+	}
+	return fmt.Sprintf("新owner %s 是合约且不在已知多签列表中，如果它无法调用owner方法，所有权将永久丢失", newOwner.Hex()), nil
+}
+
+func (a *Admin) confirm(reason string) error {
+	if a.Confirm == nil {
+		return fmt.Errorf("%w: %s", ErrNotConfirmed, reason)
+	}
+	return a.Confirm(reason)
+}
+
+// checkOwner 检查发送账户是否为owner
+func (a *Admin) checkOwner(ctx context.Context, from common.Address) error {
+	owner, err := a.Owner(ctx)
+	if err != nil {
+		return err
+	}
+	if owner != from {
+		return fmt.Errorf("%w: owner为 %s，发送账户为 %s", ErrNotOwner, owner.Hex(), from.Hex())
+	}
+	return nil
+}
+
+// execute 检查owner后通过 tx_builder 模拟执行、估算Gas并发送交易，确认后读取事件
+func (a *Admin) execute(ctx context.Context, s signer.Signer, wait utils.WaitOptions, method string, args ...interface{}) (*Action, error) {
+	if err := a.checkOwner(ctx, s.Address()); err != nil {
+		return nil, err
+	}
+
+	data, err := a.abi.Pack(method, args...)
+	if err != nil {
+		return nil, fmt.Errorf("构造%s调用数据失败: %v", method, err)
+	}
+	status, err := tx_builder.TransactAndWait(ctx, a.client, a.Strategy, s, ethereum.CallMsg{To: &a.Address, Data: data}, method, wait)
+	if status == nil {
+		return nil, err
+	}
+	action := &Action{Method: method, TxHash: status.TxHash, BlockNumber: status.BlockNumber}
+	if err != nil {
+		return action, err
+	}
+	if err := a.recordEvent(action, status.Receipt); err != nil {
+		return action, err
+	}
+	return action, nil
+}
+
+// recordEvent 从收据中读取操作对应的事件（Parse* 会检查事件签名）
+func (a *Admin) recordEvent(action *Action, receipt *types.Receipt) error {
+	for _, log := range receipt.Logs {
+		if log.Address != a.Address {
+			continue
+		}
+		switch action.Method {
+		case "pause":
+			if event, err := a.contract.ParsePaused(*log); err == nil {
+				action.Paused = event
+				action.Event = fmt.Sprintf("Paused(account=%s)", event.Account.Hex())
+				return nil
+			}
+		case "unpause":
+			if event, err := a.contract.ParseUnpaused(*log); err == nil {
+				action.Unpaused = event
+				action.Event = fmt.Sprintf("Unpaused(account=%s)", event.Account.Hex())
+				return nil
+			}
+		case "mint":
+			if event, err := a.contract.ParseTransfer(*log); err == nil {
+				action.Minted = event
+				action.Event = fmt.Sprintf("Transfer(from=%s, to=%s, value=%s)", event.From.Hex(), event.To.Hex(), event.Value)
+				return nil
+			}
+		case "transferOwnership", "renounceOwnership":
+			if event, err := a.contract.ParseOwnershipTransferred(*log); err == nil {
+				action.OwnershipTransferred = event
+				action.Event = fmt.Sprintf("OwnershipTransferred(previousOwner=%s, newOwner=%s)", event.PreviousOwner.Hex(), event.NewOwner.Hex())
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("%w: %s（交易 %s）", ErrEventMissing, action.Method, action.TxHash.Hex())
+}
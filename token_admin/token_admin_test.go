@@ -0,0 +1,108 @@
+package token_admin
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"ethclient_tutorial/backend/backendtest"
+	"ethclient_tutorial/erc20"
+	"ethclient_tutorial/revert_decoder"
+	"ethclient_tutorial/signer"
+	"ethclient_tutorial/utils"
+)
+
+func TestOwnerAdministration(t *testing.T) {
+	sim := backendtest.New(t, 2)
+	ctx := context.Background()
+	owner, other := sim.Accounts[0], sim.Accounts[1]
+
+	tokenAddress, _ := backendtest.DeployMyToken(t, sim)
+	sim.AutoMine(100 * time.Millisecond)
+	utils.PollInterval = 100 * time.Millisecond
+	wait := utils.WaitOptions{Timeout: 10 * time.Second}
+
+	admin, err := New(sim, tokenAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ownerSigner, otherSigner := signer.NewKeySigner(owner.Key), signer.NewKeySigner(other.Key)
+
+	// 非owner在发送前就被拒绝
+	if _, err := admin.Pause(ctx, otherSigner, wait); !errors.Is(err, ErrNotOwner) {
+		t.Fatalf("pause by non-owner err = %v", err)
+	}
+
+	action, err := admin.Pause(ctx, ownerSigner, wait)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if action.Paused == nil || action.Paused.Account != owner.Address || action.BlockNumber == 0 {
+		t.Fatalf("pause = %+v", action)
+	}
+	if _, err := admin.Pause(ctx, ownerSigner, wait); !errors.Is(err, ErrAlreadyPaused) {
+		t.Fatalf("double pause err = %v", err)
+	}
+	// 暂停期间转账在模拟执行时回滚
+	var revertErr *revert_decoder.RevertError
+	if _, err := erc20.New(sim, tokenAddress).Transfer(ctx, owner.Key, other.Address, big.NewInt(1)); !errors.As(err, &revertErr) || revertErr.Name != "EnforcedPause" {
+		t.Fatalf("transfer while paused err = %v", err)
+	}
+	if action, err = admin.Unpause(ctx, ownerSigner, wait); err != nil || action.Unpaused == nil {
+		t.Fatalf("unpause = %+v, %v", action, err)
+	}
+
+	minted := big.NewInt(1e18)
+	if action, err = admin.Mint(ctx, ownerSigner, other.Address, minted, wait); err != nil || action.Minted == nil ||
+		action.Minted.From != (common.Address{}) || action.Minted.Value.Cmp(minted) != 0 {
+		t.Fatalf("mint = %+v, %v", action, err)
+	}
+
+	// 转移给未知合约需要确认
+	if _, err := admin.TransferOwnership(ctx, ownerSigner, tokenAddress, wait); !errors.Is(err, ErrNotConfirmed) {
+		t.Fatalf("transfer to contract err = %v", err)
+	}
+	if _, err := admin.TransferOwnership(ctx, ownerSigner, common.Address{}, wait); !errors.Is(err, ErrZeroOwner) {
+		t.Fatalf("transfer to zero err = %v", err)
+	}
+	if action, err = admin.TransferOwnership(ctx, ownerSigner, other.Address, wait); err != nil ||
+		action.OwnershipTransferred == nil || action.OwnershipTransferred.NewOwner != other.Address {
+		t.Fatalf("transfer ownership = %+v, %v", action, err)
+	}
+
+	// 放弃所有权总是需要确认
+	declined := errors.New("declined")
+	admin.Confirm = func(string) error { return declined }
+	if _, err := admin.RenounceOwnership(ctx, otherSigner, wait); !errors.Is(err, declined) {
+		t.Fatalf("declined renounce err = %v", err)
+	}
+	admin.Confirm = func(string) error { return nil }
+	if action, err = admin.RenounceOwnership(ctx, otherSigner, wait); err != nil || action.OwnershipTransferred.NewOwner != (common.Address{}) {
+		t.Fatalf("renounce = %+v, %v", action, err)
+	}
+	if newOwner, _ := admin.Owner(ctx); newOwner != (common.Address{}) {
+		t.Fatalf("owner after renounce = %s", newOwner.Hex())
+	}
+}
+
+func TestKnownMultisigNeedsNoConfirmation(t *testing.T) {
+	sim := backendtest.New(t, 1)
+	owner := sim.Accounts[0]
+	tokenAddress, _ := backendtest.DeployMyToken(t, sim)
+
+	admin, _ := New(sim, tokenAddress)
+	if reason, err := admin.OwnerRisk(context.Background(), owner.Address); err != nil || reason != "" {
+		t.Fatalf("EOA risk = %q, %v", reason, err)
+	}
+	if reason, _ := admin.OwnerRisk(context.Background(), tokenAddress); reason == "" {
+		t.Fatal("unknown contract should be risky")
+	}
+	admin.Multisigs = []common.Address{tokenAddress}
+	if reason, _ := admin.OwnerRisk(context.Background(), tokenAddress); reason != "" {
+		t.Fatalf("known multisig risk = %q", reason)
+	}
+}
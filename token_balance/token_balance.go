@@ -1,18 +1,21 @@
 package token_balance
 
 import (
+	"context"
 	"fmt"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/ethclient"
 
+	"ethclient_tutorial/amount"
+	"ethclient_tutorial/backend"
 	"ethclient_tutorial/contracts"
+	"ethclient_tutorial/erc20"
 )
 
 // CheckTokenBalance 使用ABI绑定查询代币余额和基本信息
-func CheckTokenBalance(client *ethclient.Client, tokenAddress common.Address, holderAddress common.Address) {
+func CheckTokenBalance(client backend.Client, tokenAddress common.Address, holderAddress common.Address) {
 	fmt.Printf("\n=== 代币信息查询 (使用ABI绑定) ===\n")
 	fmt.Printf("代币合约地址: %s\n", tokenAddress.Hex())
 	fmt.Printf("持有者地址: %s\n", holderAddress.Hex())
@@ -97,58 +100,32 @@ func CheckTokenBalance(client *ethclient.Client, tokenAddress common.Address, ho
 	}
 }
 
-// GetTokenInfo 获取代币基本信息
-func GetTokenInfo(client *ethclient.Client, tokenAddress common.Address) (*TokenInfo, error) {
-	// 创建合约实例
-	instance, err := contracts.NewMYERC20(tokenAddress, client)
-	if err != nil {
-		return nil, fmt.Errorf("创建合约实例失败: %v", err)
-	}
-
-	callOpts := &bind.CallOpts{}
-
-	// 获取代币基本信息
-	name, err := instance.Name(callOpts)
-	if err != nil {
-		return nil, fmt.Errorf("查询代币名称失败: %v", err)
-	}
-
-	symbol, err := instance.Symbol(callOpts)
-	if err != nil {
-		return nil, fmt.Errorf("查询代币符号失败: %v", err)
-	}
+// GetTokenInfo 获取代币基本信息（适用于任意标准ERC20代币）
+func GetTokenInfo(client backend.Client, tokenAddress common.Address) (*TokenInfo, error) {
+	token := erc20.New(client, tokenAddress)
 
-	decimals, err := instance.Decimals(callOpts)
+	meta, err := token.Metadata(context.Background())
 	if err != nil {
-		return nil, fmt.Errorf("查询代币精度失败: %v", err)
+		return nil, fmt.Errorf("查询代币信息失败: %v", err)
 	}
 
-	totalSupply, err := instance.TotalSupply(callOpts)
+	totalSupply, err := token.TotalSupply(context.Background())
 	if err != nil {
 		return nil, fmt.Errorf("查询总供应量失败: %v", err)
 	}
 
 	return &TokenInfo{
 		Address:     tokenAddress,
-		Name:        name,
-		Symbol:      symbol,
-		Decimals:    decimals,
+		Name:        meta.Name,
+		Symbol:      meta.Symbol,
+		Decimals:    meta.Decimals,
 		TotalSupply: totalSupply,
 	}, nil
 }
 
-// GetTokenBalance 获取指定地址的代币余额
-func GetTokenBalance(client *ethclient.Client, tokenAddress common.Address, holderAddress common.Address) (*big.Int, error) {
-	// 创建合约实例
-	instance, err := contracts.NewMYERC20(tokenAddress, client)
-	if err != nil {
-		return nil, fmt.Errorf("创建合约实例失败: %v", err)
-	}
-
-	callOpts := &bind.CallOpts{}
-
-	// 查询余额
-	balance, err := instance.BalanceOf(callOpts, holderAddress)
+// GetTokenBalance 获取指定地址的代币余额（适用于任意标准ERC20代币）
+func GetTokenBalance(client backend.Client, tokenAddress common.Address, holderAddress common.Address) (*big.Int, error) {
+	balance, err := erc20.New(client, tokenAddress).BalanceOf(context.Background(), holderAddress)
 	if err != nil {
 		return nil, fmt.Errorf("查询余额失败: %v", err)
 	}
@@ -165,20 +142,7 @@ type TokenInfo struct {
 	TotalSupply *big.Int
 }
 
-// TokenFromWei 将wei单位转换为代币单位（考虑精度）
+// TokenFromWei 将wei单位转换为代币单位（考虑精度），不做舍入
 func TokenFromWei(wei *big.Int, decimals int) string {
-	if decimals == 0 {
-		return wei.String()
-	}
-
-	// 创建除数 (10^decimals)
-	divisor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
-
-	// 转换为浮点数进行除法运算
-	weiFloat := new(big.Float).SetInt(wei)
-	divisorFloat := new(big.Float).SetInt(divisor)
-	result := new(big.Float).Quo(weiFloat, divisorFloat)
-
-	// 格式化输出，保留6位小数
-	return result.Text('f', 6)
+	return amount.Format(wei, decimals)
 }
@@ -2,37 +2,45 @@ package token_transfer
 
 import (
 	"context"
-	"crypto/ecdsa"
+	"errors"
 	"fmt"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
 
+	"ethclient_tutorial/amount"
+	"ethclient_tutorial/backend"
 	"ethclient_tutorial/contracts"
+	"ethclient_tutorial/fee_strategy"
+	"ethclient_tutorial/nonce_manager"
+	"ethclient_tutorial/revert_decoder"
+	"ethclient_tutorial/signer"
 	"ethclient_tutorial/utils"
+	"ethclient_tutorial/wallet_management"
 )
 
 // TransferERC20WithABI 使用ABI绑定进行EIP-1559 ERC20转账
-func TransferERC20WithABI(client *ethclient.Client, privateKeyHex string, toAddress common.Address, tokenAddress common.Address, amount float64) (common.Hash, error) {
-	fmt.Println("\n=== 开始ABI绑定ERC20转账 (EIP-1559) ===")
-
-	// 1. 加载私钥并获取发送方地址
-	privateKey, err := crypto.HexToECDSA(privateKeyHex)
+func TransferERC20WithABI(client backend.Client, privateKeyHex string, toAddress common.Address, tokenAddress common.Address, value amount.Amount) (common.Hash, error) {
+	account, err := wallet_management.AccountFromHex(privateKeyHex)
 	if err != nil {
-		return common.Hash{}, fmt.Errorf("加载私钥失败: %v", err)
+		return common.Hash{}, err
 	}
+	return TransferERC20WithABIFromAccount(client, account, toAddress, tokenAddress, value)
+}
 
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		return common.Hash{}, fmt.Errorf("公钥类型转换失败")
-	}
+// TransferERC20WithABIFromAccount 使用已解锁的账户进行ABI绑定ERC20转账
+func TransferERC20WithABIFromAccount(client backend.Client, account *wallet_management.UnlockedAccount, toAddress common.Address, tokenAddress common.Address, value amount.Amount) (common.Hash, error) {
+	return TransferERC20WithABIWithSigner(client, signer.FromAccount(account), toAddress, tokenAddress, value)
+}
 
-	fromAddress := crypto.PubkeyToAddress(*publicKeyECDSA)
+// TransferERC20WithABIWithSigner 使用签名器进行ABI绑定ERC20转账
+func TransferERC20WithABIWithSigner(client backend.Client, s signer.Signer, toAddress common.Address, tokenAddress common.Address, value amount.Amount) (common.Hash, error) {
+	fmt.Println("\n=== 开始ABI绑定ERC20转账 (EIP-1559) ===")
+
+	// 1. 发送方地址
+	fromAddress := s.Address()
 	fmt.Printf("✓ 发送方: %s\n", fromAddress.Hex())
 	fmt.Printf("✓ 接收方: %s\n", toAddress.Hex())
 	fmt.Printf("✓ 代币合约: %s\n", tokenAddress.Hex())
@@ -51,14 +59,19 @@ func TransferERC20WithABI(client *ethclient.Client, privateKeyHex string, toAddr
 	fmt.Printf("✓ 代币精度: %d\n", decimals)
 
 	// 4. 转换代币数量
-	tokenAmount := utils.TokenToWei(amount, int(decimals))
-	fmt.Printf("✓ 转账数量: %s (原始: %.6f)\n", tokenAmount.String(), amount)
+	tokenAmount, err := utils.TokenToWei(value, int(decimals))
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("转换代币数量失败: %v", err)
+	}
+	fmt.Printf("✓ 转账数量: %s (原始: %s)\n", tokenAmount.String(), value)
 
-	// 5. 获取当前nonce
-	nonce, err := client.PendingNonceAt(context.Background(), fromAddress)
+	// 5. 从nonce管理器领取nonce
+	reservation, err := nonce_manager.ForClient(client).Reserve(context.Background(), fromAddress)
 	if err != nil {
 		return common.Hash{}, fmt.Errorf("获取nonce失败: %v", err)
 	}
+	defer reservation.Release(nil) // 未成功发送时归还nonce
+	nonce := reservation.Nonce
 	fmt.Printf("✓ Nonce: %d\n", nonce)
 
 	// 6. 获取链ID
@@ -68,43 +81,30 @@ func TransferERC20WithABI(client *ethclient.Client, privateKeyHex string, toAddr
 	}
 	fmt.Printf("✓ 链ID: %s\n", chainID.String())
 
-	// 7. 获取EIP-1559费用参数
-	header, err := client.HeaderByNumber(context.Background(), nil)
+	// 7. 按配置的费用策略获取EIP-1559费用参数
+	strategy := fee_strategy.Default()
+	fees, err := strategy.SuggestFees(context.Background(), client)
 	if err != nil {
-		return common.Hash{}, fmt.Errorf("获取区块头失败: %v", err)
+		return common.Hash{}, fmt.Errorf("获取费用参数失败: %v", err)
 	}
-
-	tipCap, err := client.SuggestGasTipCap(context.Background())
-	if err != nil {
-		tipCap = big.NewInt(2e9) // 2 Gwei 默认小费
-		fmt.Printf("Warning: 获取小费建议失败，使用默认值: %s Gwei\n", new(big.Float).Quo(new(big.Float).SetInt(tipCap), big.NewFloat(1e9)))
-	}
-
-	// 计算gasFeeCap = baseFee * 2 + tipCap
-	gasFeeCap := new(big.Int).Add(
-		new(big.Int).Mul(header.BaseFee, big.NewInt(2)),
-		tipCap,
-	)
-
-	fmt.Printf("✓ 基础费用: %s Gwei\n", new(big.Float).Quo(new(big.Float).SetInt(header.BaseFee), big.NewFloat(1e9)))
-	fmt.Printf("✓ 小费上限: %s Gwei\n", new(big.Float).Quo(new(big.Float).SetInt(tipCap), big.NewFloat(1e9)))
-	fmt.Printf("✓ 费用上限: %s Gwei\n", new(big.Float).Quo(new(big.Float).SetInt(gasFeeCap), big.NewFloat(1e9)))
+	fees.Print()
 
 	// 8. 设置交易选项 (EIP-1559)
-	auth, err := bind.NewKeyedTransactorWithChainID(privateKey, chainID)
-	if err != nil {
-		return common.Hash{}, fmt.Errorf("创建交易授权失败: %v", err)
-	}
+	auth := signer.TransactOpts(context.Background(), s, chainID)
 
 	// 配置EIP-1559参数
 	auth.Nonce = big.NewInt(int64(nonce))
 	auth.Value = big.NewInt(0) // ERC20转账ETH值为0
-	auth.GasTipCap = tipCap
-	auth.GasFeeCap = gasFeeCap
+	auth.GasTipCap = fees.GasTipCap
+	auth.GasFeeCap = fees.GasFeeCap
 
 	// 9. 估算Gas (重用auth对象)
-	gasLimit, err := estimateTransferGas(client, instance, fromAddress, toAddress, tokenAmount, auth)
-	if err != nil {
+	gasLimit, err := estimateTransferGas(client, fromAddress, tokenAddress, toAddress, tokenAmount)
+	var revertErr *revert_decoder.RevertError
+	if errors.As(err, &revertErr) {
+		// 合约在估算时就已回滚，交易上链也必然失败
+		return common.Hash{}, fmt.Errorf("转账将会失败: %w", revertErr)
+	} else if err != nil {
 		auth.GasLimit = 60000 // ERC20转账默认Gas限制
 		fmt.Printf("Warning: Gas估算失败，使用默认值: %d\n", auth.GasLimit)
 	} else {
@@ -112,12 +112,21 @@ func TransferERC20WithABI(client *ethclient.Client, privateKeyHex string, toAddr
 		fmt.Printf("✓ 估算Gas: %d\n", gasLimit)
 	}
 
+	// 签名前报告预计花费和最坏情况花费，并检查花费上限
+	cost, err := fee_strategy.EstimateCost(strategy, fees, auth.GasLimit, nil)
+	cost.Print()
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("超出花费上限: %v", err)
+	}
+
 	// 10. 执行转账
 	fmt.Println("✓ 开始执行转账交易...")
 	tx, err := instance.Transfer(auth, toAddress, tokenAmount)
 	if err != nil {
+		reservation.Release(err)
 		return common.Hash{}, fmt.Errorf("转账交易失败: %v", err)
 	}
+	reservation.Commit(tx.Hash())
 
 	fmt.Printf("✅ ERC20转账交易提交成功!\n")
 	fmt.Printf("交易哈希: %s\n", tx.Hash().Hex())
@@ -131,37 +140,18 @@ func TransferERC20WithABI(client *ethclient.Client, privateKeyHex string, toAddr
 	}
 
 	if !status.Success {
-		// 添加详细的失败诊断
 		fmt.Printf("❌ 转账交易执行失败!\n")
-		fmt.Printf("   交易哈希: %s\n", tx.Hash().Hex())
+		fmt.Printf("   交易哈希: %s\n", status.TxHash.Hex())
 		fmt.Printf("   区块号: #%d\n", status.BlockNumber)
 		fmt.Printf("   Gas使用: %d\n", status.GasUsed)
 
-		// 检查可能的失败原因
-		fmt.Println("\n--- 失败原因诊断 ---")
-
-		// 1. 检查发送方余额
-		senderBalance, err := instance.BalanceOf(&bind.CallOpts{}, fromAddress)
-		if err == nil {
-			fmt.Printf("发送方代币余额: %s\n", senderBalance.String())
-			if senderBalance.Cmp(tokenAmount) < 0 {
-				fmt.Printf("❌ 余额不足! 需要: %s, 当前: %s\n", tokenAmount.String(), senderBalance.String())
-			} else {
-				fmt.Printf("✓ 余额充足: %s >= %s\n", senderBalance.String(), tokenAmount.String())
-			}
-		}
-
-		// 2. 检查合约状态
-		paused, err := instance.Paused(&bind.CallOpts{})
-		if err == nil {
-			if paused {
-				fmt.Printf("❌ 合约已暂停!\n")
-			} else {
-				fmt.Printf("✓ 合约未暂停\n")
-			}
+		// 在失败区块上回放交易，解码回滚原因
+		revertErr, err := revert_decoder.Default().Replay(context.Background(), client, status.TxHash)
+		if err != nil {
+			return common.Hash{}, fmt.Errorf("转账交易执行失败，无法获取回滚原因: %v", err)
 		}
-
-		return common.Hash{}, fmt.Errorf("转账交易执行失败 - 请查看上述诊断信息")
+		fmt.Printf("❌ 回滚原因: %s\n", revertErr)
+		return common.Hash{}, fmt.Errorf("转账交易执行失败: %w", revertErr)
 	}
 
 	fmt.Printf("✅ 转账已确认!\n")
@@ -186,47 +176,28 @@ func TransferERC20WithABI(client *ethclient.Client, privateKeyHex string, toAddr
 }
 
 // estimateTransferGas 估算ERC20转账所需的Gas
-func estimateTransferGas(client *ethclient.Client, instance *contracts.MYERC20, from, to common.Address, amount *big.Int, auth *bind.TransactOpts) (uint64, error) {
-	// 使用合约绑定的ABI来生成调用数据进行Gas估算
-	callOpts := &bind.CallOpts{
-		From: from,
-	}
-
-	// 先验证发送方余额（可选验证）
-	balance, err := instance.BalanceOf(callOpts, from)
+// 合约回滚时返回 *revert_decoder.RevertError（如余额不足、合约已暂停），其他估算失败返回普通错误
+func estimateTransferGas(client backend.Client, from, tokenAddress, to common.Address, amount *big.Int) (uint64, error) {
+	// 使用合约绑定的ABI生成调用数据
+	parsedABI, err := contracts.MYERC20MetaData.GetAbi()
 	if err != nil {
-		return 0, fmt.Errorf("查询余额失败: %v", err)
+		return 0, fmt.Errorf("加载ABI失败: %v", err)
 	}
-
-	// 简单验证余额是否足够
-	if balance.Cmp(amount) < 0 {
-		return 0, fmt.Errorf("余额不足: 需要 %s, 当前 %s", amount.String(), balance.String())
-	}
-
-	// 1. 创建NoSend模式的授权对象
-	noSendAuth := *auth
-	noSendAuth.NoSend = true
-
-	// 2. 使用合约实例生成未签名的交易
-	tx, err := instance.Transfer(&noSendAuth, to, amount)
+	data, err := parsedABI.Pack("transfer", to, amount)
 	if err != nil {
 		return 0, fmt.Errorf("生成转账交易数据失败: %v", err)
 	}
 
-	// 3. 创建CallMsg用于估算
-	msg := ethereum.CallMsg{
-		From:  from,
-		To:    tx.To(),
-		Value: tx.Value(), // 应该是0
-		Data:  tx.Data(),
-	}
-
-	// 4. 实际估算Gas
-	gasLimit, err := client.EstimateGas(context.Background(), msg)
+	gasLimit, err := client.EstimateGas(context.Background(), ethereum.CallMsg{
+		From: from,
+		To:   &tokenAddress,
+		Data: data,
+	})
 	if err != nil {
-		// 如果估算失败，返回默认值
-		fmt.Printf("Warning: Gas估算失败，使用默认ERC20转账Gas: %v\n", err)
-		return 60000, nil
+		if revertErr, ok := revert_decoder.Default().FromError(err); ok {
+			return 0, revertErr
+		}
+		return 0, fmt.Errorf("Gas估算失败: %v", err)
 	}
 
 	// 为估算的Gas添加20%的安全缓冲
@@ -2,52 +2,57 @@ package token_transfer
 
 import (
 	"context"
-	"crypto/ecdsa"
+	"errors"
 	"fmt"
 	"math/big"
-	"os"
-	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
 
+	"ethclient_tutorial/amount"
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/config"
+	"ethclient_tutorial/contracts"
+	"ethclient_tutorial/fee_strategy"
+	"ethclient_tutorial/nonce_manager"
+	"ethclient_tutorial/revert_decoder"
+	"ethclient_tutorial/signer"
+	"ethclient_tutorial/tx_replacement"
 	"ethclient_tutorial/utils"
+	"ethclient_tutorial/wallet_management"
 )
 
 // TransferERC20WithABIFile 使用ABI文件进行EIP-1559 ERC20转账
-func TransferERC20WithABIFile(client *ethclient.Client, privateKeyHex string, toAddress common.Address, tokenAddress common.Address, amount float64) (common.Hash, error) {
-	fmt.Println("\n=== 开始ABI文件ERC20转账 (EIP-1559) ===")
-
-	// 1. 加载私钥并获取发送方地址
-	privateKey, err := crypto.HexToECDSA(privateKeyHex)
+func TransferERC20WithABIFile(client backend.Client, privateKeyHex string, toAddress common.Address, tokenAddress common.Address, value amount.Amount) (common.Hash, error) {
+	account, err := wallet_management.AccountFromHex(privateKeyHex)
 	if err != nil {
-		return common.Hash{}, fmt.Errorf("加载私钥失败: %v", err)
+		return common.Hash{}, err
 	}
+	return TransferERC20WithABIFileFromAccount(client, account, toAddress, tokenAddress, value)
+}
 
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		return common.Hash{}, fmt.Errorf("公钥类型转换失败")
-	}
+// TransferERC20WithABIFileFromAccount 使用已解锁的账户进行ABI文件ERC20转账
+func TransferERC20WithABIFileFromAccount(client backend.Client, account *wallet_management.UnlockedAccount, toAddress common.Address, tokenAddress common.Address, value amount.Amount) (common.Hash, error) {
+	return TransferERC20WithABIFileWithSigner(client, signer.FromAccount(account), toAddress, tokenAddress, value)
+}
 
-	fromAddress := crypto.PubkeyToAddress(*publicKeyECDSA)
+// TransferERC20WithABIFileWithSigner 使用签名器进行ABI文件ERC20转账
+func TransferERC20WithABIFileWithSigner(client backend.Client, s signer.Signer, toAddress common.Address, tokenAddress common.Address, value amount.Amount) (common.Hash, error) {
+	fmt.Println("\n=== 开始ABI文件ERC20转账 (EIP-1559) ===")
+
+	// 1. 发送方地址
+	fromAddress := s.Address()
 	fmt.Printf("✓ 发送方: %s\n", fromAddress.Hex())
 	fmt.Printf("✓ 接收方: %s\n", toAddress.Hex())
 	fmt.Printf("✓ 代币合约: %s\n", tokenAddress.Hex())
 
 	// 2. 读取并解析ABI文件
-	abiData, err := os.ReadFile("contracts/compiled/MyToken.abi")
-	if err != nil {
-		return common.Hash{}, fmt.Errorf("读取ABI文件失败: %v", err)
-	}
-
-	parsedABI, err := abi.JSON(strings.NewReader(string(abiData)))
+	parsedABI, err := contracts.LoadMyTokenABI(contracts.MyTokenABIPath)
 	if err != nil {
-		return common.Hash{}, fmt.Errorf("解析ABI失败: %v", err)
+		return common.Hash{}, fmt.Errorf("加载ABI失败: %v", err)
 	}
 	fmt.Printf("✓ ABI文件解析成功\n")
 
@@ -59,14 +64,19 @@ func TransferERC20WithABIFile(client *ethclient.Client, privateKeyHex string, to
 	fmt.Printf("✓ 代币精度: %d\n", decimals)
 
 	// 4. 转换代币数量
-	tokenAmount := utils.TokenToWei(amount, int(decimals))
-	fmt.Printf("✓ 转账数量: %s (原始: %.6f)\n", tokenAmount.String(), amount)
+	tokenAmount, err := utils.TokenToWei(value, int(decimals))
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("转换代币数量失败: %v", err)
+	}
+	fmt.Printf("✓ 转账数量: %s (原始: %s)\n", tokenAmount.String(), value)
 
-	// 5. 获取当前nonce
-	nonce, err := client.PendingNonceAt(context.Background(), fromAddress)
+	// 5. 从nonce管理器领取nonce
+	reservation, err := nonce_manager.ForClient(client).Reserve(context.Background(), fromAddress)
 	if err != nil {
 		return common.Hash{}, fmt.Errorf("获取nonce失败: %v", err)
 	}
+	defer reservation.Release(nil) // 未成功发送时归还nonce
+	nonce := reservation.Nonce
 	fmt.Printf("✓ Nonce: %d\n", nonce)
 
 	// 6. 获取链ID
@@ -76,27 +86,13 @@ func TransferERC20WithABIFile(client *ethclient.Client, privateKeyHex string, to
 	}
 	fmt.Printf("✓ 链ID: %s\n", chainID.String())
 
-	// 7. 获取EIP-1559费用参数
-	header, err := client.HeaderByNumber(context.Background(), nil)
-	if err != nil {
-		return common.Hash{}, fmt.Errorf("获取区块头失败: %v", err)
-	}
-
-	tipCap, err := client.SuggestGasTipCap(context.Background())
+	// 7. 按配置的费用策略获取EIP-1559费用参数
+	strategy := fee_strategy.Default()
+	fees, err := strategy.SuggestFees(context.Background(), client)
 	if err != nil {
-		tipCap = big.NewInt(2e9) // 2 Gwei 默认小费
-		fmt.Printf("Warning: 获取小费建议失败，使用默认值: %s Gwei\n", new(big.Float).Quo(new(big.Float).SetInt(tipCap), big.NewFloat(1e9)))
+		return common.Hash{}, fmt.Errorf("获取费用参数失败: %v", err)
 	}
-
-	// 计算gasFeeCap = baseFee * 2 + tipCap
-	gasFeeCap := new(big.Int).Add(
-		new(big.Int).Mul(header.BaseFee, big.NewInt(2)),
-		tipCap,
-	)
-
-	fmt.Printf("✓ 基础费用: %s Gwei\n", new(big.Float).Quo(new(big.Float).SetInt(header.BaseFee), big.NewFloat(1e9)))
-	fmt.Printf("✓ 小费上限: %s Gwei\n", new(big.Float).Quo(new(big.Float).SetInt(tipCap), big.NewFloat(1e9)))
-	fmt.Printf("✓ 费用上限: %s Gwei\n", new(big.Float).Quo(new(big.Float).SetInt(gasFeeCap), big.NewFloat(1e9)))
+	fees.Print()
 
 	// 8. 构造transfer函数调用数据
 	callData, err := parsedABI.Pack("transfer", toAddress, tokenAmount)
@@ -106,20 +102,31 @@ func TransferERC20WithABIFile(client *ethclient.Client, privateKeyHex string, to
 	fmt.Printf("✓ 调用数据构造成功，长度: %d bytes\n", len(callData))
 
 	// 9. 估算Gas
-	gasLimit, err := estimateGasForABITransfer(client, fromAddress, tokenAddress, callData)
-	if err != nil {
+	gasLimit, err := estimateGasForABITransfer(client, fromAddress, tokenAddress, callData, parsedABI)
+	var revertErr *revert_decoder.RevertError
+	if errors.As(err, &revertErr) {
+		// 合约在估算时就已回滚，交易上链也必然失败
+		return common.Hash{}, fmt.Errorf("转账将会失败: %w", revertErr)
+	} else if err != nil {
 		gasLimit = 60000 // ERC20转账默认Gas限制
 		fmt.Printf("Warning: Gas估算失败，使用默认值: %d\n", gasLimit)
 	} else {
 		fmt.Printf("✓ 估算Gas: %d\n", gasLimit)
 	}
 
+	// 签名前报告预计花费和最坏情况花费，并检查花费上限
+	cost, err := fee_strategy.EstimateCost(strategy, fees, gasLimit, nil)
+	cost.Print()
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("超出花费上限: %v", err)
+	}
+
 	// 10. 创建EIP-1559交易
 	tx := &types.DynamicFeeTx{
 		ChainID:   chainID,
 		Nonce:     nonce,
-		GasTipCap: tipCap,
-		GasFeeCap: gasFeeCap,
+		GasTipCap: fees.GasTipCap,
+		GasFeeCap: fees.GasFeeCap,
 		Gas:       gasLimit,
 		To:        &tokenAddress,
 		Value:     big.NewInt(0), // ERC20转账ETH值为0
@@ -131,9 +138,9 @@ func TransferERC20WithABIFile(client *ethclient.Client, privateKeyHex string, to
 
 	// 11. 签名交易
 	fmt.Println("✓ 开始签名交易...")
-	signedTx, err := types.SignTx(newTx, types.LatestSignerForChainID(chainID), privateKey)
+	signedTx, err := s.SignTx(context.Background(), newTx, chainID)
 	if err != nil {
-		return common.Hash{}, fmt.Errorf("交易签名失败: %v", err)
+		return common.Hash{}, err
 	}
 	fmt.Printf("✓ 交易签名成功\n")
 
@@ -141,52 +148,36 @@ func TransferERC20WithABIFile(client *ethclient.Client, privateKeyHex string, to
 	fmt.Println("✓ 开始发送交易...")
 	err = client.SendTransaction(context.Background(), signedTx)
 	if err != nil {
+		reservation.Release(err)
 		return common.Hash{}, fmt.Errorf("发送交易失败: %v", err)
 	}
+	reservation.Commit(signedTx.Hash())
 
 	fmt.Printf("✅ ERC20转账交易提交成功!\n")
 	fmt.Printf("交易哈希: %s\n", signedTx.Hash().Hex())
 	fmt.Printf("交易类型: %d (EIP-1559)\n", signedTx.Type())
 
-	// 13. 等待交易确认
+	// 13. 等待交易确认（配置了 FEE_BUMP_INTERVAL_SEC 时，长时间未被打包会自动加价）
 	fmt.Println("\n--- 等待转账确认 ---")
-	status, err := utils.WaitForTransactionQuick(client, signedTx.Hash())
+	bumpPolicy := tx_replacement.AutoBumpFromConfig(config.GlobalConfig, s)
+	status, err := utils.WaitForTransactionWithAutoBump(client, signedTx.Hash(), 1, 3*time.Minute, bumpPolicy)
 	if err != nil {
 		return common.Hash{}, fmt.Errorf("等待转账确认失败: %v", err)
 	}
 
 	if !status.Success {
-		// 添加详细的失败诊断
 		fmt.Printf("❌ 转账交易执行失败!\n")
-		fmt.Printf("   交易哈希: %s\n", signedTx.Hash().Hex())
+		fmt.Printf("   交易哈希: %s\n", status.TxHash.Hex())
 		fmt.Printf("   区块号: #%d\n", status.BlockNumber)
 		fmt.Printf("   Gas使用: %d\n", status.GasUsed)
 
-		// 检查可能的失败原因
-		fmt.Println("\n--- 失败原因诊断 ---")
-
-		// 1. 检查发送方余额
-		senderBalance, err := getTokenBalance(client, tokenAddress, fromAddress, parsedABI)
-		if err == nil {
-			fmt.Printf("发送方代币余额: %s\n", senderBalance.String())
-			if senderBalance.Cmp(tokenAmount) < 0 {
-				fmt.Printf("❌ 余额不足! 需要: %s, 当前: %s\n", tokenAmount.String(), senderBalance.String())
-			} else {
-				fmt.Printf("✓ 余额充足: %s >= %s\n", senderBalance.String(), tokenAmount.String())
-			}
-		}
-
-		// 2. 检查合约状态
-		paused, err := getTokenPaused(client, tokenAddress, parsedABI)
-		if err == nil {
-			if paused {
-				fmt.Printf("❌ 合约已暂停!\n")
-			} else {
-				fmt.Printf("✓ 合约未暂停\n")
-			}
+		// 在失败区块上回放交易，按ABI中的自定义错误解码回滚原因
+		revertErr, err := revert_decoder.NewDecoder(parsedABI).Replay(context.Background(), client, status.TxHash)
+		if err != nil {
+			return common.Hash{}, fmt.Errorf("转账交易执行失败，无法获取回滚原因: %v", err)
 		}
-
-		return common.Hash{}, fmt.Errorf("转账交易执行失败 - 请查看上述诊断信息")
+		fmt.Printf("❌ 回滚原因: %s\n", revertErr)
+		return common.Hash{}, fmt.Errorf("转账交易执行失败: %w", revertErr)
 	}
 
 	fmt.Printf("✅ 转账已确认!\n")
@@ -207,11 +198,12 @@ func TransferERC20WithABIFile(client *ethclient.Client, privateKeyHex string, to
 		fmt.Printf("✓ 接收方余额: %s\n", receiverBalance.String())
 	}
 
-	return signedTx.Hash(), nil
+	// 若发生过加价替换，返回实际被打包的交易哈希
+	return status.TxHash, nil
 }
 
 // getTokenDecimals 获取代币精度
-func getTokenDecimals(client *ethclient.Client, tokenAddress common.Address, parsedABI abi.ABI) (uint8, error) {
+func getTokenDecimals(client backend.Client, tokenAddress common.Address, parsedABI abi.ABI) (uint8, error) {
 	callData, err := parsedABI.Pack("decimals")
 	if err != nil {
 		return 0, fmt.Errorf("构造decimals调用数据失败: %v", err)
@@ -235,7 +227,7 @@ func getTokenDecimals(client *ethclient.Client, tokenAddress common.Address, par
 }
 
 // getTokenBalance 获取代币余额
-func getTokenBalance(client *ethclient.Client, tokenAddress common.Address, account common.Address, parsedABI abi.ABI) (*big.Int, error) {
+func getTokenBalance(client backend.Client, tokenAddress common.Address, account common.Address, parsedABI abi.ABI) (*big.Int, error) {
 	callData, err := parsedABI.Pack("balanceOf", account)
 	if err != nil {
 		return nil, fmt.Errorf("构造balanceOf调用数据失败: %v", err)
@@ -258,32 +250,9 @@ func getTokenBalance(client *ethclient.Client, tokenAddress common.Address, acco
 	return balance, nil
 }
 
-// getTokenPaused 检查合约是否暂停
-func getTokenPaused(client *ethclient.Client, tokenAddress common.Address, parsedABI abi.ABI) (bool, error) {
-	callData, err := parsedABI.Pack("paused")
-	if err != nil {
-		return false, fmt.Errorf("构造paused调用数据失败: %v", err)
-	}
-
-	result, err := client.CallContract(context.Background(), ethereum.CallMsg{
-		To:   &tokenAddress,
-		Data: callData,
-	}, nil)
-	if err != nil {
-		return false, fmt.Errorf("调用paused失败: %v", err)
-	}
-
-	var paused bool
-	err = parsedABI.UnpackIntoInterface(&paused, "paused", result)
-	if err != nil {
-		return false, fmt.Errorf("解析paused结果失败: %v", err)
-	}
-
-	return paused, nil
-}
-
 // estimateGasForABITransfer 估算ABI转账所需的Gas
-func estimateGasForABITransfer(client *ethclient.Client, from, to common.Address, data []byte) (uint64, error) {
+// 合约回滚时返回按parsedABI解码的 *revert_decoder.RevertError
+func estimateGasForABITransfer(client backend.Client, from, to common.Address, data []byte, parsedABI abi.ABI) (uint64, error) {
 	// 使用CallMsg估算Gas
 	msg := ethereum.CallMsg{
 		From: from,
@@ -293,6 +262,9 @@ func estimateGasForABITransfer(client *ethclient.Client, from, to common.Address
 
 	gasLimit, err := client.EstimateGas(context.Background(), msg)
 	if err != nil {
+		if revertErr, ok := revert_decoder.NewDecoder(parsedABI).FromError(err); ok {
+			return 0, revertErr
+		}
 		return 0, fmt.Errorf("Gas估算失败: %v", err)
 	}
 
@@ -2,7 +2,6 @@ package token_transfer
 
 import (
 	"context"
-	"crypto/ecdsa"
 	"fmt"
 	"log"
 	"math/big"
@@ -12,37 +11,47 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
 
+	"ethclient_tutorial/amount"
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/fee_strategy"
+	"ethclient_tutorial/nonce_manager"
+	"ethclient_tutorial/signer"
 	"ethclient_tutorial/utils"
+	"ethclient_tutorial/wallet_management"
 )
 
-// ModernERC20Transfer 现代化的ERC20转账 - 手动构造哈希，使用EIP-1559
-func ERC20Transfer(client *ethclient.Client, privateKeyHex string, toAddress common.Address, tokenAddress common.Address, amount *big.Int) (common.Hash, error) {
-	fmt.Println("\n=== 开始现代化ERC20转账 (手动哈希 + EIP-1559) ===")
-
-	// 1. 加载私钥并获取发送方地址
-	privateKey, err := crypto.HexToECDSA(privateKeyHex)
+// ERC20Transfer 现代化的ERC20转账 - 手动构造哈希，使用EIP-1559
+func ERC20Transfer(client backend.Client, privateKeyHex string, toAddress common.Address, tokenAddress common.Address, amount *big.Int) (common.Hash, error) {
+	account, err := wallet_management.AccountFromHex(privateKeyHex)
 	if err != nil {
-		log.Fatal(err)
+		return common.Hash{}, err
 	}
+	return ERC20TransferFromAccount(client, account, toAddress, tokenAddress, amount)
+}
 
-	publicKey := privateKey.Public()
-	publicKeyECDSA, ok := publicKey.(*ecdsa.PublicKey)
-	if !ok {
-		log.Fatal("cannot assert type: publicKey is not of type *ecdsa.PublicKey")
-	}
+// ERC20TransferFromAccount 使用已解锁的账户手动构造ERC20转账交易
+func ERC20TransferFromAccount(client backend.Client, account *wallet_management.UnlockedAccount, toAddress common.Address, tokenAddress common.Address, amount *big.Int) (common.Hash, error) {
+	return ERC20TransferWithSigner(client, signer.FromAccount(account), toAddress, tokenAddress, amount)
+}
 
-	fromAddress := crypto.PubkeyToAddress(*publicKeyECDSA)
+// ERC20TransferWithSigner 使用签名器手动构造ERC20转账交易
+func ERC20TransferWithSigner(client backend.Client, s signer.Signer, toAddress common.Address, tokenAddress common.Address, amount *big.Int) (common.Hash, error) {
+	fmt.Println("\n=== 开始现代化ERC20转账 (手动哈希 + EIP-1559) ===")
+
+	// 1. 发送方地址
+	fromAddress := s.Address()
 	fmt.Printf("✓ 发送方: %s\n", fromAddress.Hex())
 	fmt.Printf("✓ 接收方: %s\n", toAddress.Hex())
 	fmt.Printf("✓ 代币合约: %s\n", tokenAddress.Hex())
 
-	// 2. 获取当前nonce
-	nonce, err := client.PendingNonceAt(context.Background(), fromAddress)
+	// 2. 从nonce管理器领取nonce
+	reservation, err := nonce_manager.ForClient(client).Reserve(context.Background(), fromAddress)
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer reservation.Release(nil) // 未成功发送时归还nonce
+	nonce := reservation.Nonce
 	fmt.Printf("✓ Nonce: %d\n", nonce)
 
 	// 3. 手动构造transfer(address,uint256)函数调用数据
@@ -82,33 +91,26 @@ func ERC20Transfer(client *ethclient.Client, privateKeyHex string, toAddress com
 	}
 	fmt.Printf("✓ 链ID: %s\n", chainID.String())
 
-	// 7. 获取EIP-1559费用参数
-	header, err := client.HeaderByNumber(context.Background(), nil)
+	// 7. 按配置的费用策略获取EIP-1559费用参数，并报告花费
+	strategy := fee_strategy.Default()
+	fees, err := strategy.SuggestFees(context.Background(), client)
 	if err != nil {
 		log.Fatal(err)
 	}
+	fees.Print()
 
-	tipCap, err := client.SuggestGasTipCap(context.Background())
+	cost, err := fee_strategy.EstimateCost(strategy, fees, gasLimit, nil)
+	cost.Print()
 	if err != nil {
-		tipCap = big.NewInt(2e9) // 2 Gwei
+		log.Fatal(err)
 	}
 
-	// 计算gasFeeCap = baseFee * 2 + tipCap
-	gasFeeCap := new(big.Int).Add(
-		new(big.Int).Mul(header.BaseFee, big.NewInt(2)),
-		tipCap,
-	)
-
-	fmt.Printf("✓ 基础费用: %s Gwei\n", new(big.Float).Quo(new(big.Float).SetInt(header.BaseFee), big.NewFloat(1e9)))
-	fmt.Printf("✓ 小费上限: %s Gwei\n", new(big.Float).Quo(new(big.Float).SetInt(tipCap), big.NewFloat(1e9)))
-	fmt.Printf("✓ 费用上限: %s Gwei\n", new(big.Float).Quo(new(big.Float).SetInt(gasFeeCap), big.NewFloat(1e9)))
-
 	// 8. 创建EIP-1559交易
 	tx := &types.DynamicFeeTx{
 		ChainID:   chainID,
 		Nonce:     nonce,
-		GasTipCap: tipCap,
-		GasFeeCap: gasFeeCap,
+		GasTipCap: fees.GasTipCap,
+		GasFeeCap: fees.GasFeeCap,
 		Gas:       gasLimit,
 		To:        &tokenAddress,
 		Value:     big.NewInt(0), // ERC20转账ETH值为0
@@ -116,7 +118,7 @@ func ERC20Transfer(client *ethclient.Client, privateKeyHex string, toAddress com
 	}
 
 	// 9. 签名交易
-	signedTx, err := types.SignTx(types.NewTx(tx), types.LatestSignerForChainID(chainID), privateKey)
+	signedTx, err := s.SignTx(context.Background(), types.NewTx(tx), chainID)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -126,8 +128,10 @@ func ERC20Transfer(client *ethclient.Client, privateKeyHex string, toAddress com
 	// 10. 发送交易
 	err = client.SendTransaction(context.Background(), signedTx)
 	if err != nil {
+		reservation.Release(err)
 		log.Fatal(err)
 	}
+	reservation.Commit(signedTx.Hash())
 
 	fmt.Printf("✅ 交易发送成功: %s\n", signedTx.Hash().Hex())
 
@@ -149,8 +153,11 @@ func ERC20Transfer(client *ethclient.Client, privateKeyHex string, toAddress com
 	return signedTx.Hash(), nil
 }
 
-// TransferERC20WithAmount 使用浮点数金额的便捷函数
-func TransferERC20WithAmount(client *ethclient.Client, privateKeyHex string, toAddress common.Address, tokenAddress common.Address, amount float64, decimals int) (common.Hash, error) {
-	tokenAmount := utils.TokenToWei(amount, decimals)
+// TransferERC20WithAmount 使用十进制金额的便捷函数
+func TransferERC20WithAmount(client backend.Client, privateKeyHex string, toAddress common.Address, tokenAddress common.Address, value amount.Amount, decimals int) (common.Hash, error) {
+	tokenAmount, err := utils.TokenToWei(value, decimals)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("转换代币数量失败: %v", err)
+	}
 	return ERC20Transfer(client, privateKeyHex, toAddress, tokenAddress, tokenAmount)
 }
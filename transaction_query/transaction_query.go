@@ -7,11 +7,12 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
+
+	"ethclient_tutorial/backend"
 )
 
 // GetTransaction 通过交易哈希查询交易详情
-func GetTransaction(client *ethclient.Client, txHash common.Hash) *types.Transaction {
+func GetTransaction(client backend.Client, txHash common.Hash) *types.Transaction {
 	tx, pending, err := client.TransactionByHash(context.Background(), txHash)
 	if err != nil {
 		log.Fatalf("Failed to retrieve transaction %s: %v", txHash.Hex(), err)
@@ -23,7 +24,7 @@ func GetTransaction(client *ethclient.Client, txHash common.Hash) *types.Transac
 }
 
 // GetTransactionInfo 获取交易详细信息
-func GetTransactionInfo(client *ethclient.Client, txHash common.Hash) *TxInfo {
+func GetTransactionInfo(client backend.Client, txHash common.Hash) *TxInfo {
 	tx := GetTransaction(client, txHash)
 
 	// 获取交易收据来获取发送者信息
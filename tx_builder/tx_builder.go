@@ -0,0 +1,119 @@
+package tx_builder
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/fee_strategy"
+	"ethclient_tutorial/nonce_manager"
+	"ethclient_tutorial/revert_decoder"
+	"ethclient_tutorial/signer"
+	"ethclient_tutorial/utils"
+)
+
+// Build 构造合约调用或部署（msg.To 为nil）的未签名交易，Nonce由调用方填写：
+// 先模拟执行（回滚时返回解码后的原因），再估算Gas（增加20%缓冲），最后按费用策略（nil时使用默认策略）取得费用并检查花费上限。
+// 同时返回模拟执行的返回数据，供调用方检查返回值
+func Build(ctx context.Context, client backend.Client, strategy fee_strategy.Strategy, msg ethereum.CallMsg, action string) (*types.DynamicFeeTx, []byte, error) {
+	// 1. 模拟执行
+	result, err := client.CallContract(ctx, msg, nil)
+	if err != nil {
+		if revertErr, ok := revert_decoder.Default().FromError(err); ok {
+			return nil, nil, revertErr
+		}
+		return nil, nil, fmt.Errorf("模拟%s失败: %v", action, err)
+	}
+
+	// 2. 估算Gas（增加20%缓冲）
+	gasLimit, err := client.EstimateGas(ctx, msg)
+	if err != nil {
+		if revertErr, ok := revert_decoder.Default().FromError(err); ok {
+			return nil, nil, revertErr
+		}
+		return nil, nil, fmt.Errorf("Gas估算失败: %v", err)
+	}
+	gasLimit += gasLimit * 20 / 100
+
+	// 3. 费用与花费上限
+	if strategy == nil {
+		strategy = fee_strategy.Default()
+	}
+	fees, err := strategy.SuggestFees(ctx, client)
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取费用参数失败: %v", err)
+	}
+	if _, err := fee_strategy.EstimateCost(strategy, fees, gasLimit, msg.Value); err != nil {
+		return nil, nil, fmt.Errorf("超出花费上限: %v", err)
+	}
+
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取链ID失败: %v", err)
+	}
+	return &types.DynamicFeeTx{
+		ChainID:   chainID,
+		GasTipCap: fees.GasTipCap,
+		GasFeeCap: fees.GasFeeCap,
+		Gas:       gasLimit,
+		To:        msg.To,
+		Value:     msg.Value,
+		Data:      msg.Data,
+	}, result, nil
+}
+
+// Send 从 nonce_manager 领取nonce，签名并发送 Build 构造的交易；发送失败时释放nonce
+func Send(ctx context.Context, client backend.Client, s signer.Signer, tx *types.DynamicFeeTx, action string) (*types.Transaction, error) {
+	reservation, err := nonce_manager.ForClient(client).Reserve(ctx, s.Address())
+	if err != nil {
+		return nil, fmt.Errorf("获取nonce失败: %v", err)
+	}
+	defer reservation.Release(nil)
+
+	tx.Nonce = reservation.Nonce
+	signedTx, err := s.SignTx(ctx, types.NewTx(tx), tx.ChainID)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.SendTransaction(ctx, signedTx); err != nil {
+		reservation.Release(err)
+		return nil, fmt.Errorf("发送%s交易失败: %v", action, err)
+	}
+	reservation.Commit(signedTx.Hash())
+	return signedTx, nil
+}
+
+// Transact 以s的地址模拟执行msg，构造、签名并发送交易
+func Transact(ctx context.Context, client backend.Client, strategy fee_strategy.Strategy, s signer.Signer, msg ethereum.CallMsg, action string) (*types.Transaction, error) {
+	msg.From = s.Address()
+	tx, _, err := Build(ctx, client, strategy, msg, action)
+	if err != nil {
+		return nil, err
+	}
+	return Send(ctx, client, s, tx, action)
+}
+
+// TransactAndWait 发送交易并等待确认（支持 wait.AutoBump 自动加价），交易执行失败时返回错误
+// 交易已发送时总是返回状态，TxHash 为实际被打包的交易，便于调用方报告交易哈希
+func TransactAndWait(ctx context.Context, client backend.Client, strategy fee_strategy.Strategy, s signer.Signer, msg ethereum.CallMsg, action string, wait utils.WaitOptions) (*utils.TransactionStatus, error) {
+	tx, err := Transact(ctx, client, strategy, s, msg, action)
+	if err != nil {
+		return nil, err
+	}
+	wait.Report("✓ %s交易已发送: %s", action, tx.Hash().Hex())
+
+	status, err := utils.WaitSigned(ctx, client, tx, wait)
+	if status == nil {
+		status = &utils.TransactionStatus{TxHash: tx.Hash()}
+	}
+	if err != nil {
+		return status, fmt.Errorf("等待%s交易确认失败: %w", action, err)
+	}
+	if !status.Success {
+		return status, fmt.Errorf("%s交易执行失败: %s", action, status.TxHash.Hex())
+	}
+	return status, nil
+}
@@ -0,0 +1,99 @@
+package tx_builder
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/backend/backendtest"
+	"ethclient_tutorial/contracts"
+	"ethclient_tutorial/fee_strategy"
+	"ethclient_tutorial/nonce_manager"
+	"ethclient_tutorial/revert_decoder"
+	"ethclient_tutorial/signer"
+)
+
+// transferMsg 构造 from 调用MyToken transfer(to, value) 的消息
+func transferMsg(t *testing.T, token, from, to common.Address, value *big.Int) ethereum.CallMsg {
+	t.Helper()
+	parsed, err := contracts.MYERC20MetaData.GetAbi()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := parsed.Pack("transfer", to, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ethereum.CallMsg{From: from, To: &token, Data: data}
+}
+
+func TestBuildDecodesRevert(t *testing.T) {
+	sim := backendtest.New(t, 2)
+	token, _ := backendtest.DeployMyToken(t, sim)
+
+	// 账户1没有代币，转账会以 ERC20InsufficientBalance 回滚
+	msg := transferMsg(t, token, sim.Accounts[1].Address, sim.Accounts[0].Address, big.NewInt(1))
+	_, _, err := Build(context.Background(), sim, nil, msg, "transfer")
+	var revertErr *revert_decoder.RevertError
+	if !errors.As(err, &revertErr) {
+		t.Fatalf("err = %v, want RevertError", err)
+	}
+	if revertErr.Name != "ERC20InsufficientBalance" {
+		t.Fatalf("name = %q, want ERC20InsufficientBalance (%v)", revertErr.Name, revertErr)
+	}
+}
+
+func TestBuildRejectsCostOverCeiling(t *testing.T) {
+	sim := backendtest.New(t, 2)
+	token, _ := backendtest.DeployMyToken(t, sim)
+	msg := transferMsg(t, token, sim.Accounts[0].Address, sim.Accounts[1].Address, big.NewInt(1))
+
+	tx, result, err := Build(context.Background(), sim, nil, msg, "transfer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Gas == 0 || tx.ChainID == nil || len(result) != 32 {
+		t.Fatalf("tx gas = %d, chainID = %v, result = %x", tx.Gas, tx.ChainID, result)
+	}
+
+	strategy := &fee_strategy.CeilingStrategy{Inner: fee_strategy.Default(), MaxCost: big.NewInt(1)}
+	_, _, err = Build(context.Background(), sim, strategy, msg, "transfer")
+	if err == nil || !strings.Contains(err.Error(), "超出花费上限") {
+		t.Fatalf("err = %v, want ceiling error", err)
+	}
+}
+
+func TestSendReleasesNonceOnFailure(t *testing.T) {
+	sim := backendtest.New(t, 2)
+	s := signer.NewKeySigner(sim.Accounts[0].Key)
+	to := sim.Accounts[1].Address
+
+	// 转账金额超过余额，节点拒绝交易
+	tx := &types.DynamicFeeTx{
+		ChainID:   big.NewInt(backend.SimulatedChainID),
+		GasTipCap: big.NewInt(1e9),
+		GasFeeCap: big.NewInt(10e9),
+		Gas:       21000,
+		To:        &to,
+		Value:     new(big.Int).Mul(big.NewInt(1e6), big.NewInt(1e18)),
+	}
+	if _, err := Send(context.Background(), sim, s, tx, "transfer"); err == nil {
+		t.Fatal("Send with insufficient funds succeeded")
+	}
+
+	reservation, err := nonce_manager.ForClient(sim).Reserve(context.Background(), s.Address())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reservation.Release(nil)
+	if reservation.Nonce != tx.Nonce {
+		t.Fatalf("nonce = %d, want released nonce %d", reservation.Nonce, tx.Nonce)
+	}
+}
@@ -0,0 +1,47 @@
+package tx_replacement
+
+import (
+	"context"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/config"
+	"ethclient_tutorial/fee_strategy"
+	"ethclient_tutorial/signer"
+)
+
+// AutoBumpPolicy 等待交易确认期间的自动加价策略
+// 交易每隔 Interval 仍未被打包，就按 Options 发送一笔加价的替换交易，直到达到 Options.MaxFeePerGas
+type AutoBumpPolicy struct {
+	Signer   signer.Signer
+	Interval time.Duration
+	Options  Options
+}
+
+// AutoBumpFromConfig 根据配置创建自动加价策略
+// FEE_BUMP_INTERVAL_SEC 为0时返回nil（不自动加价），上限取 FEE_MAX_FEE_GWEI
+func AutoBumpFromConfig(cfg *config.Config, s signer.Signer) *AutoBumpPolicy {
+	if cfg == nil || cfg.FeeBumpIntervalSec == 0 {
+		return nil
+	}
+	policy := &AutoBumpPolicy{
+		Signer:   s,
+		Interval: time.Duration(cfg.FeeBumpIntervalSec) * time.Second,
+		Options: Options{
+			Strategy:    fee_strategy.Default(),
+			BumpPercent: cfg.FeeBumpPercent,
+		},
+	}
+	if cfg.FeeMaxFeeGwei > 0 {
+		policy.Options.MaxFeePerGas = fee_strategy.FromGwei(cfg.FeeMaxFeeGwei)
+	}
+	return policy
+}
+
+// Bump 为仍在交易池中的交易发送一笔加价的替换交易
+func (p *AutoBumpPolicy) Bump(ctx context.Context, client backend.Client, txHash common.Hash) (*types.Transaction, error) {
+	return SpeedUpWithSigner(ctx, client, p.Signer, txHash, p.Options)
+}
@@ -0,0 +1,187 @@
+package tx_replacement
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/fee_strategy"
+	"ethclient_tutorial/nonce_manager"
+	"ethclient_tutorial/signer"
+)
+
+// MinBumpPercent 节点接受同nonce替换交易要求的最低加价比例（geth默认10%）
+const MinBumpPercent = 10
+
+// ErrNotPending 原交易已被打包或已从交易池消失，无需替换
+var ErrNotPending = errors.New("交易已不在交易池中")
+
+// ErrFeeCeiling 满足替换规则所需的费用超过了配置的上限
+var ErrFeeCeiling = errors.New("替换交易费用超过上限")
+
+// Options 替换交易的参数
+type Options struct {
+	Strategy     fee_strategy.Strategy // 用于获取当前网络费用和花费上限，nil 时使用全局配置（含 FEE_MAX_FEE_GWEI、FEE_MAX_COST_ETH）
+	BumpPercent  float64               // 相对原交易的加价比例，低于 MinBumpPercent 时按 MinBumpPercent 计算
+	MaxFeePerGas *big.Int              // 替换交易的费用上限，nil 时使用 Strategy 的上限（fee_strategy.CeilingStrategy）
+}
+
+// SpeedUp 以更高的费用重新签名同一nonce的原交易，使其尽快被打包
+func SpeedUp(client backend.Client, privateKeyHex string, txHash common.Hash) (*types.Transaction, error) {
+	privateKey, err := crypto.HexToECDSA(privateKeyHex)
+	if err != nil {
+		return nil, fmt.Errorf("加载私钥失败: %v", err)
+	}
+	return SpeedUpWithKey(context.Background(), client, privateKey, txHash, Options{})
+}
+
+// Cancel 以更高的费用向自己发送同一nonce的0金额交易，顶替原交易
+func Cancel(client backend.Client, privateKeyHex string, txHash common.Hash) (*types.Transaction, error) {
+	privateKey, err := crypto.HexToECDSA(privateKeyHex)
+	if err != nil {
+		return nil, fmt.Errorf("加载私钥失败: %v", err)
+	}
+	return CancelWithKey(context.Background(), client, privateKey, txHash, Options{})
+}
+
+// SpeedUpWithKey 使用私钥加速交易
+func SpeedUpWithKey(ctx context.Context, client backend.Client, privateKey *ecdsa.PrivateKey, txHash common.Hash, opts Options) (*types.Transaction, error) {
+	return SpeedUpWithSigner(ctx, client, signer.NewKeySigner(privateKey), txHash, opts)
+}
+
+// CancelWithKey 使用私钥取消交易
+func CancelWithKey(ctx context.Context, client backend.Client, privateKey *ecdsa.PrivateKey, txHash common.Hash, opts Options) (*types.Transaction, error) {
+	return CancelWithSigner(ctx, client, signer.NewKeySigner(privateKey), txHash, opts)
+}
+
+// SpeedUpWithSigner 加速交易：接收方、金额、数据和Gas限制保持不变，只提高费用
+func SpeedUpWithSigner(ctx context.Context, client backend.Client, s signer.Signer, txHash common.Hash, opts Options) (*types.Transaction, error) {
+	fmt.Printf("\n=== 加速交易 %s ===\n", txHash.Hex())
+	return replace(ctx, client, s, txHash, opts, func(original *types.Transaction, from common.Address) *types.DynamicFeeTx {
+		return &types.DynamicFeeTx{
+			Gas:        original.Gas(),
+			To:         original.To(),
+			Value:      original.Value(),
+			Data:       original.Data(),
+			AccessList: original.AccessList(),
+		}
+	})
+}
+
+// CancelWithSigner 取消交易：用同一nonce向自己发送0金额交易
+func CancelWithSigner(ctx context.Context, client backend.Client, s signer.Signer, txHash common.Hash, opts Options) (*types.Transaction, error) {
+	fmt.Printf("\n=== 取消交易 %s ===\n", txHash.Hex())
+	return replace(ctx, client, s, txHash, opts, func(original *types.Transaction, from common.Address) *types.DynamicFeeTx {
+		return &types.DynamicFeeTx{
+			Gas:   21000,
+			To:    &from,
+			Value: big.NewInt(0),
+		}
+	})
+}
+
+// replace 查询原交易、计算满足替换规则的费用并发送替换交易
+func replace(ctx context.Context, client backend.Client, s signer.Signer, txHash common.Hash, opts Options, build func(original *types.Transaction, from common.Address) *types.DynamicFeeTx) (*types.Transaction, error) {
+	original, isPending, err := client.TransactionByHash(ctx, txHash)
+	if err != nil {
+		return nil, fmt.Errorf("查询原交易失败: %v", err)
+	}
+	if !isPending {
+		return nil, ErrNotPending
+	}
+
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取链ID失败: %v", err)
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(chainID), original)
+	if err != nil {
+		return nil, fmt.Errorf("解析原交易发送方失败: %v", err)
+	}
+	if from != s.Address() {
+		return nil, fmt.Errorf("签名账户与原交易发送方 %s 不匹配", from.Hex())
+	}
+
+	strategy := opts.Strategy
+	if strategy == nil {
+		strategy = fee_strategy.Default()
+	}
+	fees, err := strategy.SuggestFees(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("获取费用参数失败: %v", err)
+	}
+
+	// 加价后的费用可能高于策略截断后的建议值，需要重新检查单位Gas上限和总花费上限
+	maxFeePerGas := opts.MaxFeePerGas
+	if ceiling, ok := strategy.(*fee_strategy.CeilingStrategy); ok && maxFeePerGas == nil {
+		maxFeePerGas = ceiling.MaxFeePerGas
+	}
+	tipCap, feeCap := BumpFees(original, fees, opts.BumpPercent)
+	if maxFeePerGas != nil && feeCap.Cmp(maxFeePerGas) > 0 {
+		return nil, fmt.Errorf("%w: 需要 %s Gwei，上限 %s Gwei", ErrFeeCeiling, fee_strategy.ToGwei(feeCap), fee_strategy.ToGwei(maxFeePerGas))
+	}
+
+	tx := build(original, from)
+	tx.ChainID = chainID
+	tx.Nonce = original.Nonce()
+	tx.GasTipCap = tipCap
+	tx.GasFeeCap = feeCap
+
+	bumped := &fee_strategy.Fees{Strategy: fees.Strategy, BaseFee: fees.BaseFee, GasTipCap: tipCap, GasFeeCap: feeCap}
+	if _, err := fee_strategy.EstimateCost(strategy, bumped, tx.Gas, tx.Value); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFeeCeiling, err)
+	}
+
+	fmt.Printf("✓ Nonce: %d\n", tx.Nonce)
+	fmt.Printf("✓ 小费上限: %s -> %s Gwei\n", fee_strategy.ToGwei(original.GasTipCap()), fee_strategy.ToGwei(tipCap))
+	fmt.Printf("✓ 费用上限: %s -> %s Gwei\n", fee_strategy.ToGwei(original.GasFeeCap()), fee_strategy.ToGwei(feeCap))
+
+	signedTx, err := s.SignTx(ctx, types.NewTx(tx), chainID)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.SendTransaction(ctx, signedTx); err != nil {
+		return nil, fmt.Errorf("发送替换交易失败: %v", err)
+	}
+	nonce_manager.ForClient(client).Replace(from, tx.Nonce, signedTx.Hash())
+
+	fmt.Printf("✅ 替换交易已发送: %s\n", signedTx.Hash().Hex())
+	return signedTx, nil
+}
+
+// BumpFees 计算替换交易的小费和费用上限
+// 两者都至少比原交易高 bumpPercent（不低于 MinBumpPercent），并且不低于当前网络建议值
+func BumpFees(original *types.Transaction, fees *fee_strategy.Fees, bumpPercent float64) (*big.Int, *big.Int) {
+	if bumpPercent < MinBumpPercent {
+		bumpPercent = MinBumpPercent
+	}
+	tipCap := maxBig(bump(original.GasTipCap(), bumpPercent), fees.GasTipCap)
+	feeCap := maxBig(bump(original.GasFeeCap(), bumpPercent), fees.GasFeeCap)
+	if feeCap.Cmp(tipCap) < 0 {
+		feeCap = new(big.Int).Set(tipCap)
+	}
+	return tipCap, feeCap
+}
+
+// bump 按百分比提高数值（向上取整，保证满足节点的替换门槛）
+func bump(value *big.Int, percent float64) *big.Int {
+	// 以万分比计算，避免浮点误差
+	basisPoints := big.NewInt(10000 + int64(percent*100))
+	result := new(big.Int).Mul(value, basisPoints)
+	result.Add(result, big.NewInt(9999))
+	return result.Div(result, big.NewInt(10000))
+}
+
+func maxBig(a, b *big.Int) *big.Int {
+	if a.Cmp(b) >= 0 {
+		return new(big.Int).Set(a)
+	}
+	return new(big.Int).Set(b)
+}
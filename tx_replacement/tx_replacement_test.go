@@ -0,0 +1,156 @@
+package tx_replacement
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/backend/backendtest"
+	"ethclient_tutorial/fee_strategy"
+)
+
+// sendStuckTransfer 发送一笔费用上限低于基础费用的转账，它会一直停留在交易池中
+func sendStuckTransfer(t *testing.T, sim *backend.Simulated) *types.Transaction {
+	t.Helper()
+	header, err := sim.HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	feeCap := new(big.Int).Div(header.BaseFee, big.NewInt(2))
+	to := sim.Accounts[1].Address
+	tx, err := types.SignNewTx(sim.Accounts[0].Key, types.LatestSignerForChainID(big.NewInt(backend.SimulatedChainID)), &types.DynamicFeeTx{
+		ChainID:   big.NewInt(backend.SimulatedChainID),
+		Nonce:     0,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: feeCap,
+		Gas:       21000,
+		To:        &to,
+		Value:     big.NewInt(1e18),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sim.SendTransaction(context.Background(), tx); err != nil {
+		t.Fatal(err)
+	}
+	sim.Commit()
+	if _, err := sim.TransactionReceipt(context.Background(), tx.Hash()); err == nil {
+		t.Fatal("underpriced transaction should not be mined")
+	}
+	return tx
+}
+
+func TestSpeedUpReplacesStuckTransaction(t *testing.T) {
+	sim := backendtest.New(t, 2)
+	stuck := sendStuckTransfer(t, sim)
+
+	replacement, err := SpeedUpWithKey(context.Background(), sim, sim.Accounts[0].Key, stuck.Hash(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replacement.Nonce() != stuck.Nonce() || replacement.Value().Cmp(stuck.Value()) != 0 || *replacement.To() != *stuck.To() {
+		t.Fatal("speed-up must keep nonce, recipient and value")
+	}
+	sim.Commit()
+
+	receipt, err := sim.TransactionReceipt(context.Background(), replacement.Hash())
+	if err != nil {
+		t.Fatalf("replacement not mined: %v", err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		t.Fatal("replacement failed")
+	}
+	if _, err := sim.TransactionReceipt(context.Background(), stuck.Hash()); err == nil {
+		t.Fatal("original transaction should have been replaced")
+	}
+}
+
+func TestCancelSendsZeroValueSelfTransfer(t *testing.T) {
+	sim := backendtest.New(t, 2)
+	recipient := sim.Accounts[1].Address
+	before, _ := sim.BalanceAt(context.Background(), recipient, nil)
+	stuck := sendStuckTransfer(t, sim)
+
+	replacement, err := CancelWithKey(context.Background(), sim, sim.Accounts[0].Key, stuck.Hash(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *replacement.To() != sim.Accounts[0].Address || replacement.Value().Sign() != 0 {
+		t.Fatal("cancel must be a 0-value self transfer")
+	}
+	sim.Commit()
+
+	if _, err := sim.TransactionReceipt(context.Background(), replacement.Hash()); err != nil {
+		t.Fatalf("cancel not mined: %v", err)
+	}
+	after, _ := sim.BalanceAt(context.Background(), recipient, nil)
+	if after.Cmp(before) != 0 {
+		t.Fatalf("recipient balance changed from %s to %s", before, after)
+	}
+}
+
+func TestReplacementRespectsCeiling(t *testing.T) {
+	sim := backendtest.New(t, 2)
+	stuck := sendStuckTransfer(t, sim)
+
+	_, err := SpeedUpWithKey(context.Background(), sim, sim.Accounts[0].Key, stuck.Hash(), Options{MaxFeePerGas: stuck.GasFeeCap()})
+	if !errors.Is(err, ErrFeeCeiling) {
+		t.Fatalf("err = %v, want ErrFeeCeiling", err)
+	}
+}
+
+func TestReplacementChecksMaxCost(t *testing.T) {
+	sim := backendtest.New(t, 2)
+	stuck := sendStuckTransfer(t, sim)
+
+	// 替换交易的最坏情况花费（转账金额+Gas）超过策略的总花费上限
+	maxCost := &fee_strategy.CeilingStrategy{Inner: &fee_strategy.NodeStrategy{}, MaxCost: stuck.Value()}
+	if _, err := SpeedUpWithKey(context.Background(), sim, sim.Accounts[0].Key, stuck.Hash(), Options{Strategy: maxCost}); !errors.Is(err, ErrFeeCeiling) {
+		t.Fatalf("err = %v, want ErrFeeCeiling from the strategy's max cost", err)
+	}
+}
+
+func TestSpeedUpRejectsMinedTransaction(t *testing.T) {
+	sim := backendtest.New(t, 2)
+	to := common.HexToAddress("0x01")
+	tx, _ := types.SignNewTx(sim.Accounts[0].Key, types.LatestSignerForChainID(big.NewInt(backend.SimulatedChainID)), &types.DynamicFeeTx{
+		ChainID:   big.NewInt(backend.SimulatedChainID),
+		GasTipCap: big.NewInt(1e9),
+		GasFeeCap: big.NewInt(10e9),
+		Gas:       21000,
+		To:        &to,
+	})
+	if err := sim.SendTransaction(context.Background(), tx); err != nil {
+		t.Fatal(err)
+	}
+	sim.Commit()
+
+	if _, err := SpeedUpWithKey(context.Background(), sim, sim.Accounts[0].Key, tx.Hash(), Options{}); !errors.Is(err, ErrNotPending) {
+		t.Fatalf("err = %v, want ErrNotPending", err)
+	}
+}
+
+func TestBumpFeesMeetsReplacementRule(t *testing.T) {
+	original := types.NewTx(&types.DynamicFeeTx{GasTipCap: big.NewInt(1000000001), GasFeeCap: big.NewInt(3000000001)})
+	network := &fee_strategy.Fees{BaseFee: big.NewInt(1), GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(2)}
+
+	tipCap, feeCap := BumpFees(original, network, 0)
+	minTip := new(big.Int).Div(new(big.Int).Mul(original.GasTipCap(), big.NewInt(110)), big.NewInt(100))
+	minCap := new(big.Int).Div(new(big.Int).Mul(original.GasFeeCap(), big.NewInt(110)), big.NewInt(100))
+	if tipCap.Cmp(minTip) < 0 || feeCap.Cmp(minCap) < 0 {
+		t.Fatalf("bumped fees %s/%s below 10%% replacement threshold %s/%s", tipCap, feeCap, minTip, minCap)
+	}
+
+	// 网络建议高于加价结果时使用网络建议
+	network.GasTipCap = big.NewInt(5e9)
+	network.GasFeeCap = big.NewInt(20e9)
+	tipCap, feeCap = BumpFees(original, network, 0)
+	if tipCap.Cmp(network.GasTipCap) != 0 || feeCap.Cmp(network.GasFeeCap) != 0 {
+		t.Fatalf("fees = %s/%s, want network suggestion", tipCap, feeCap)
+	}
+}
@@ -2,113 +2,432 @@ package utils
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/big"
 	"time"
 
+	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
+
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/tx_replacement"
+)
+
+// Outcome 等待交易的最终结果
+type Outcome int
+
+const (
+	OutcomeConfirmed Outcome = iota // 已打包、执行成功并达到确认数
+	OutcomeReverted                 // 已打包并达到确认数，但执行失败
+	OutcomeReplaced                 // 同一nonce被其他交易占用（加速/取消或其他客户端发送）
+	OutcomeDropped                  // 交易从交易池消失，nonce仍未被占用
+	OutcomeTimeout                  // 超时仍未达到确认数
+)
+
+// String 结果名称
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeConfirmed:
+		return "confirmed"
+	case OutcomeReverted:
+		return "reverted"
+	case OutcomeReplaced:
+		return "replaced"
+	case OutcomeDropped:
+		return "dropped"
+	case OutcomeTimeout:
+		return "timeout"
+	default:
+		return "unknown"
+	}
+}
+
+var (
+	ErrReplaced = errors.New("交易已被同nonce的其他交易替换")
+	ErrDropped  = errors.New("交易已被交易池丢弃")
+	ErrTimeout  = errors.New("等待交易确认超时")
 )
 
 // TransactionStatus 交易状态
 type TransactionStatus struct {
-	Success     bool
-	BlockNumber uint64
-	GasUsed     uint64
-	TxHash      common.Hash
-	Receipt     *types.Receipt
+	Outcome       Outcome
+	Success       bool
+	BlockNumber   uint64
+	BlockHash     common.Hash
+	GasUsed       uint64
+	Confirmations uint64
+	TxHash        common.Hash // 实际被打包的交易（自动加价后可能与原交易不同）
+	ReplacedBy    common.Hash // OutcomeReplaced 时占用该nonce的交易（找不到时为空）
+	Receipt       *types.Receipt
+}
+
+// PollInterval 没有新区块订阅时查询交易状态的间隔
+var PollInterval = 3 * time.Second
+
+// DefaultDropAfter 连续多少次查询不到交易（且nonce未被占用）后判定为被丢弃
+const DefaultDropAfter = 5
+
+// replacementScanDepth 查找替换交易时向前扫描的最大区块数
+const replacementScanDepth = 64
+
+// WaitOptions 等待参数
+type WaitOptions struct {
+	Confirmations uint64
+	Timeout       time.Duration
+	AutoBump      *tx_replacement.AutoBumpPolicy // 可选：长时间未被打包时自动加价
+	DropAfter     int                            // 0 表示使用 DefaultDropAfter
+	Progress      func(message string)           // 可选：接收等待进度（已打包、确认数、重组、加价等），nil 时不输出
+}
+
+// Report 通过 Progress 报告一条进度，Progress 为nil时不输出
+// 发送交易的模块用它报告"交易已发送"等信息，调用方决定输出位置
+func (o WaitOptions) Report(format string, args ...any) {
+	if o.Progress != nil {
+		o.Progress(fmt.Sprintf(format, args...))
+	}
 }
 
 // WaitForTransaction 等待交易确认
-func WaitForTransaction(client *ethclient.Client, txHash common.Hash, confirmations uint64, timeout time.Duration) (*TransactionStatus, error) {
-	fmt.Printf("⏳ 等待交易确认: %s\n", txHash.Hex())
-	fmt.Printf("   需要确认数: %d, 超时时间: %v\n", confirmations, timeout)
+func WaitForTransaction(client backend.Client, txHash common.Hash, confirmations uint64, timeout time.Duration) (*TransactionStatus, error) {
+	return Wait(context.Background(), client, txHash, WaitOptions{Confirmations: confirmations, Timeout: timeout})
+}
+
+// WaitForTransactionWithAutoBump 等待交易确认，交易长时间未被打包时按策略自动加价替换
+// policy 为nil时不加价；原交易和所有替换交易中任意一笔被打包即视为完成，返回状态中的TxHash为实际被打包的交易
+func WaitForTransactionWithAutoBump(client backend.Client, txHash common.Hash, confirmations uint64, timeout time.Duration, policy *tx_replacement.AutoBumpPolicy) (*TransactionStatus, error) {
+	return Wait(context.Background(), client, txHash, WaitOptions{Confirmations: confirmations, Timeout: timeout, AutoBump: policy})
+}
+
+// Wait 等待交易达到确认数
+//
+// 交易已打包并达到确认数时返回 OutcomeConfirmed 或 OutcomeReverted（错误为nil，由调用方检查 Success）；
+// 被替换、被丢弃或超时时同时返回状态和对应的 ErrReplaced / ErrDropped / ErrTimeout。
+// 节点支持订阅（WS/IPC）时在每个新区块检查一次，否则按 PollInterval 轮询；
+// 计算确认数时会核对收据所在区块是否仍在主链上，区块被重组后重新等待交易被打包。
+// 进度通过 opts.Progress 报告，Wait 本身不输出。
+// 只有交易哈希时，发送方和nonce在第一次查到交易时取得；交易在此之前已被替换会判定为 OutcomeDropped，
+// 持有签名交易时应使用 WaitSigned。
+func Wait(ctx context.Context, client backend.Client, txHash common.Hash, opts WaitOptions) (*TransactionStatus, error) {
+	return newTxWaiter(client, txHash, opts).run(ctx)
+}
+
+// WaitSigned 等待已发送的签名交易，发送方和nonce直接取自交易，
+// 交易在第一次检查前就已被替换时也能判定为 OutcomeReplaced
+func WaitSigned(ctx context.Context, client backend.Client, tx *types.Transaction, opts WaitOptions) (*TransactionStatus, error) {
+	w := newTxWaiter(client, tx.Hash(), opts)
+	if err := w.learnSender(ctx, tx); err != nil {
+		return nil, fmt.Errorf("解析交易发送方失败: %v", err)
+	}
+	return w.run(ctx)
+}
+
+// WaitSuccess 按 WaitSigned 等待交易，交易执行失败时也返回错误
+// 返回的状态不为nil时，TxHash 为实际被打包的交易（自动加价后可能与tx不同）
+func WaitSuccess(ctx context.Context, client backend.Client, tx *types.Transaction, opts WaitOptions) (*TransactionStatus, error) {
+	status, err := WaitSigned(ctx, client, tx, opts)
+	if err != nil {
+		return status, err
+	}
+	if !status.Success {
+		return status, fmt.Errorf("交易 %s 执行失败", status.TxHash.Hex())
+	}
+	return status, nil
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
+// newTxWaiter 创建等待器并填写默认参数
+func newTxWaiter(client backend.Client, txHash common.Hash, opts WaitOptions) *txWaiter {
+	if opts.Confirmations == 0 {
+		opts.Confirmations = 1
+	}
+	if opts.DropAfter <= 0 {
+		opts.DropAfter = DefaultDropAfter
+	}
+	return &txWaiter{
+		client:   client,
+		opts:     opts,
+		hashes:   []common.Hash{txHash},
+		policy:   opts.AutoBump,
+		lastBump: time.Now(),
+		status:   &TransactionStatus{Outcome: OutcomeTimeout, TxHash: txHash},
+	}
+}
 
-	ticker := time.NewTicker(3 * time.Second) // 每3秒检查一次
-	defer ticker.Stop()
+// run 等待交易达到确认数、被替换、被丢弃或超时
+func (w *txWaiter) run(ctx context.Context) (*TransactionStatus, error) {
+	opts := w.opts
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
 
-	var receipt *types.Receipt
-	var err error
+	w.report("⏳ 等待交易确认: %s", w.status.TxHash.Hex())
+	w.report("   需要确认数: %d, 超时时间: %v", opts.Confirmations, opts.Timeout)
+
+	// 优先订阅新区块，节点不支持（HTTP）时退回轮询
+	heads := make(chan *types.Header, 16)
+	var subErr <-chan error
+	var ticker *time.Ticker
+	sub, err := w.client.SubscribeNewHead(ctx, heads)
+	if err == nil {
+		defer sub.Unsubscribe()
+		subErr = sub.Err()
+	} else {
+		ticker = time.NewTicker(PollInterval)
+		defer ticker.Stop()
+	}
+	tick := func() <-chan time.Time {
+		if ticker == nil {
+			return nil
+		}
+		return ticker.C
+	}
 
-	// 首先等待交易被包含在区块中
-	fmt.Print("等待交易被挖矿")
 	for {
+		if done, err := w.check(ctx); done {
+			return w.status, err
+		}
+
 		select {
 		case <-ctx.Done():
-			return nil, fmt.Errorf("等待交易超时: %v", ctx.Err())
-		case <-ticker.C:
-			receipt, err = client.TransactionReceipt(context.Background(), txHash)
-			if err == nil {
-				fmt.Printf("\n✓ 交易已被包含在区块 #%d 中\n", receipt.BlockNumber.Uint64())
-				goto receiptFound // 使用 goto 跳出外层循环
-			}
-			fmt.Print(".")
+			return w.status, fmt.Errorf("%w: %s (已确认 %d/%d)", ErrTimeout, w.status.TxHash.Hex(), w.status.Confirmations, opts.Confirmations)
+		case <-heads:
+		case err := <-subErr:
+			w.report("⚠️ 新区块订阅中断，改为轮询: %v", err)
+			subErr = nil
+			ticker = time.NewTicker(PollInterval)
+			defer ticker.Stop()
+		case <-tick():
 		}
 	}
+}
+
+// txWaiter 单笔交易的等待状态
+type txWaiter struct {
+	client backend.Client
+	opts   WaitOptions
+
+	hashes   []common.Hash // 同一nonce已发送的所有交易（原交易及加价替换交易）
+	policy   *tx_replacement.AutoBumpPolicy
+	lastBump time.Time
+
+	chainID *big.Int
+	sender  common.Address
+	nonce   uint64
+	known   bool // 是否已取得交易的发送方和nonce
+	misses  int  // 连续查询不到交易的次数
 
-receiptFound:
-	// 检查交易状态
-	status := &TransactionStatus{
-		Success:     receipt.Status == types.ReceiptStatusSuccessful,
-		BlockNumber: receipt.BlockNumber.Uint64(),
-		GasUsed:     receipt.GasUsed,
-		TxHash:      txHash,
-		Receipt:     receipt,
+	receipt *types.Receipt
+	status  *TransactionStatus
+}
+
+// report 通过 WaitOptions.Progress 报告进度
+func (w *txWaiter) report(format string, args ...any) {
+	w.opts.Report(format, args...)
+}
+
+// check 检查一次交易状态，返回true表示等待结束
+// 查询失败的情况（节点暂时不可用等）不会结束等待，留到下一次检查
+func (w *txWaiter) check(ctx context.Context) (bool, error) {
+	if w.receipt == nil {
+		w.findReceipt(ctx)
+	}
+	if w.receipt != nil {
+		return w.checkConfirmations(ctx)
+	}
+	if done, err := w.checkPending(ctx); done {
+		return true, err
 	}
+	w.autoBump(ctx)
+	return false, nil
+}
 
-	if !status.Success {
-		return status, fmt.Errorf("交易执行失败")
+// findReceipt 查询所有已发送交易的收据
+func (w *txWaiter) findReceipt(ctx context.Context) {
+	for _, hash := range w.hashes {
+		receipt, err := w.client.TransactionReceipt(ctx, hash)
+		if err != nil {
+			continue
+		}
+		w.receipt = receipt
+		w.status.TxHash = hash
+		w.status.Receipt = receipt
+		w.status.Success = receipt.Status == types.ReceiptStatusSuccessful
+		w.status.BlockNumber = receipt.BlockNumber.Uint64()
+		w.status.BlockHash = receipt.BlockHash
+		w.status.GasUsed = receipt.GasUsed
+		w.status.Confirmations = 0
+		w.report("✓ 交易 %s 已被包含在区块 #%d 中", hash.Hex(), w.status.BlockNumber)
+		return
 	}
+}
 
-	fmt.Printf("✓ 交易执行成功, Gas使用: %d\n", status.GasUsed)
+// checkConfirmations 核对收据所在区块仍在主链上，并计算确认数
+func (w *txWaiter) checkConfirmations(ctx context.Context) (bool, error) {
+	header, err := w.client.HeaderByNumber(ctx, w.receipt.BlockNumber)
+	if err != nil && !errors.Is(err, ethereum.NotFound) {
+		return false, nil
+	}
+	if err != nil || header.Hash() != w.receipt.BlockHash {
+		w.report("⚠️ 区块 #%d 已被重组，重新等待交易被打包", w.status.BlockNumber)
+		w.receipt = nil
+		w.status.Receipt = nil
+		w.status.Success = false
+		w.status.Confirmations = 0
+		return false, nil
+	}
 
-	// 如果只需要1个确认，直接返回
-	if confirmations <= 1 {
-		return status, nil
+	head, err := w.client.BlockNumber(ctx)
+	if err != nil || head < w.status.BlockNumber {
+		return false, nil
+	}
+	confirmations := head - w.status.BlockNumber + 1
+	if confirmations != w.status.Confirmations {
+		w.status.Confirmations = confirmations
+		if confirmations < w.opts.Confirmations {
+			w.report("   确认进度: %d/%d (当前区块: #%d)", confirmations, w.opts.Confirmations, head)
+		}
+	}
+	if confirmations < w.opts.Confirmations {
+		return false, nil
 	}
 
-	// 如果需要等待更多确认数，继续等待
-	fmt.Printf("⏳ 等待额外的 %d 个确认...\n", confirmations-1)
-	targetBlockNumber := status.BlockNumber + confirmations - 1
+	if w.status.Success {
+		w.status.Outcome = OutcomeConfirmed
+		w.report("✅ 交易执行成功并获得 %d 个确认, Gas使用: %d", confirmations, w.status.GasUsed)
+	} else {
+		w.status.Outcome = OutcomeReverted
+		w.report("❌ 交易已打包但执行失败 (区块 #%d)", w.status.BlockNumber)
+	}
+	return true, nil
+}
 
-	for {
-		select {
-		case <-ctx.Done():
-			// 即使超时，如果交易已经成功，也返回状态而不是错误
-			fmt.Printf("⚠️ 等待额外确认超时，但交易已执行成功\n")
-			return status, nil
-		case <-ticker.C:
-			currentBlock, err := client.BlockNumber(context.Background())
-			if err != nil {
-				fmt.Printf("获取当前区块号失败: %v\n", err)
-				continue
+// checkPending 交易尚未打包时，检测它是否被替换或丢弃
+func (w *txWaiter) checkPending(ctx context.Context) (bool, error) {
+	seen := false
+	for _, hash := range w.hashes {
+		tx, _, err := w.client.TransactionByHash(ctx, hash)
+		if errors.Is(err, ethereum.NotFound) {
+			continue
+		}
+		if err != nil {
+			return false, nil
+		}
+		seen = true
+		if !w.known {
+			if err := w.learnSender(ctx, tx); err != nil {
+				return false, nil
 			}
+		}
+	}
 
-			if currentBlock >= targetBlockNumber {
-				fmt.Printf("✅ 已获得 %d 个确认 (当前区块: #%d)\n", confirmations, currentBlock)
-				return status, nil
+	if w.known {
+		confirmedNonce, err := w.client.NonceAt(ctx, w.sender, nil)
+		if err != nil {
+			return false, nil
+		}
+		if confirmedNonce > w.nonce {
+			// nonce已被占用，先排除刚好在两次查询之间被打包的情况
+			if w.findReceipt(ctx); w.receipt != nil {
+				return false, nil
 			}
+			w.status.Outcome = OutcomeReplaced
+			w.status.ReplacedBy = w.findReplacement(ctx)
+			w.report("⚠️ nonce %d 已被交易 %s 占用", w.nonce, w.status.ReplacedBy.Hex())
+			return true, fmt.Errorf("%w: nonce %d", ErrReplaced, w.nonce)
+		}
+	}
+
+	if seen {
+		w.misses = 0
+		return false, nil
+	}
+	w.misses++
+	if w.misses >= w.opts.DropAfter {
+		w.status.Outcome = OutcomeDropped
+		w.report("⚠️ 连续 %d 次查询不到交易，判定为已被丢弃", w.misses)
+		return true, fmt.Errorf("%w: %s", ErrDropped, w.hashes[len(w.hashes)-1].Hex())
+	}
+	return false, nil
+}
 
-			confirmedBlocks := currentBlock - status.BlockNumber + 1
-			fmt.Printf("   确认进度: %d/%d (当前区块: #%d)\n", confirmedBlocks, confirmations, currentBlock)
+// learnSender 记录交易的发送方和nonce，用于检测替换
+func (w *txWaiter) learnSender(ctx context.Context, tx *types.Transaction) error {
+	if w.chainID == nil {
+		chainID, err := w.client.ChainID(ctx)
+		if err != nil {
+			return err
 		}
+		w.chainID = chainID
+	}
+	sender, err := types.Sender(types.LatestSignerForChainID(w.chainID), tx)
+	if err != nil {
+		return err
+	}
+	w.sender = sender
+	w.nonce = tx.Nonce()
+	w.known = true
+	return nil
+}
+
+// findReplacement 在最近的区块中查找占用同一nonce的交易
+func (w *txWaiter) findReplacement(ctx context.Context) common.Hash {
+	head, err := w.client.BlockNumber(ctx)
+	if err != nil {
+		return common.Hash{}
+	}
+	signer := types.LatestSignerForChainID(w.chainID)
+	for i := uint64(0); i < replacementScanDepth && i <= head; i++ {
+		block, err := w.client.BlockByNumber(ctx, new(big.Int).SetUint64(head-i))
+		if err != nil {
+			return common.Hash{}
+		}
+		for _, tx := range block.Transactions() {
+			if tx.Nonce() != w.nonce {
+				continue
+			}
+			if sender, err := types.Sender(signer, tx); err == nil && sender == w.sender {
+				return tx.Hash()
+			}
+		}
+	}
+	return common.Hash{}
+}
+
+// autoBump 按策略为长时间未被打包的交易发送加价替换交易
+func (w *txWaiter) autoBump(ctx context.Context) {
+	if w.policy == nil || time.Since(w.lastBump) < w.policy.Interval {
+		return
+	}
+	w.lastBump = time.Now()
+	replacement, err := w.policy.Bump(ctx, w.client, w.hashes[len(w.hashes)-1])
+	switch {
+	case err == nil:
+		w.hashes = append(w.hashes, replacement.Hash())
+		w.misses = 0
+	case errors.Is(err, tx_replacement.ErrFeeCeiling):
+		w.report("⚠️ 已达到费用上限，停止自动加价: %v", err)
+		w.policy = nil
+	case errors.Is(err, tx_replacement.ErrNotPending):
+		// 交易可能刚被打包，下一次检查收据
+	default:
+		w.report("⚠️ 自动加价失败: %v", err)
 	}
 }
 
 // WaitForTransactionQuick 快速等待交易（只等待1个确认）
-func WaitForTransactionQuick(client *ethclient.Client, txHash common.Hash) (*TransactionStatus, error) {
+func WaitForTransactionQuick(client backend.Client, txHash common.Hash) (*TransactionStatus, error) {
 	return WaitForTransaction(client, txHash, 1, 3*time.Minute) // 增加到3分钟
 }
 
 // WaitForTransactionSafe 安全等待交易（等待3个确认）
-func WaitForTransactionSafe(client *ethclient.Client, txHash common.Hash) (*TransactionStatus, error) {
+func WaitForTransactionSafe(client backend.Client, txHash common.Hash) (*TransactionStatus, error) {
 	return WaitForTransaction(client, txHash, 3, 10*time.Minute) // 增加到10分钟
 }
 
 // WaitForTransactionDeploy 专门用于合约部署的等待函数（更长的超时时间）
-func WaitForTransactionDeploy(client *ethclient.Client, txHash common.Hash) (*TransactionStatus, error) {
+func WaitForTransactionDeploy(client backend.Client, txHash common.Hash) (*TransactionStatus, error) {
 	return WaitForTransaction(client, txHash, 2, 8*time.Minute) // 等待2个确认，8分钟超时
 }
@@ -0,0 +1,183 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"ethclient_tutorial/backend"
+	"ethclient_tutorial/backend/backendtest"
+)
+
+var chainID = big.NewInt(backend.SimulatedChainID)
+
+// send 签名并发送一笔交易
+func send(t *testing.T, sim *backend.Simulated, tx *types.DynamicFeeTx) *types.Transaction {
+	t.Helper()
+	tx.ChainID = chainID
+	if tx.Gas == 0 {
+		tx.Gas = 21000
+	}
+	signed, err := types.SignNewTx(sim.Accounts[0].Key, types.LatestSignerForChainID(chainID), tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sim.SendTransaction(context.Background(), signed); err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+type waitResult struct {
+	status *TransactionStatus
+	err    error
+}
+
+// waitAsync 在后台等待交易，测试通过 Commit 手动驱动出块
+func waitAsync(sim *backend.Simulated, txHash common.Hash, opts WaitOptions) <-chan waitResult {
+	done := make(chan waitResult, 1)
+	go func() {
+		status, err := Wait(context.Background(), sim, txHash, opts)
+		done <- waitResult{status, err}
+	}()
+	return done
+}
+
+// mineUntil 持续出块直到等待结束
+func mineUntil(t *testing.T, sim *backend.Simulated, done <-chan waitResult) waitResult {
+	t.Helper()
+	deadline := time.After(10 * time.Second)
+	for {
+		select {
+		case r := <-done:
+			return r
+		case <-deadline:
+			t.Fatal("waiter did not finish")
+		case <-time.After(50 * time.Millisecond):
+			sim.Commit()
+		}
+	}
+}
+
+func TestWaitCountsConfirmations(t *testing.T) {
+	sim := backendtest.New(t, 2)
+	to := sim.Accounts[1].Address
+	tx := send(t, sim, &types.DynamicFeeTx{GasTipCap: big.NewInt(1e9), GasFeeCap: big.NewInt(10e9), To: &to})
+
+	var progress []string
+	opts := WaitOptions{Confirmations: 3, Progress: func(message string) { progress = append(progress, message) }}
+	r := mineUntil(t, sim, waitAsync(sim, tx.Hash(), opts))
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	if r.status.Outcome != OutcomeConfirmed || !r.status.Success || r.status.Confirmations < 3 {
+		t.Fatalf("status = %+v, want confirmed with 3 confirmations", r.status)
+	}
+	if len(progress) == 0 {
+		t.Fatal("no progress reported")
+	}
+}
+
+func TestWaitReportsRevert(t *testing.T) {
+	sim := backendtest.New(t, 2)
+	// 部署代码 PUSH1 0 PUSH1 0 REVERT
+	tx := send(t, sim, &types.DynamicFeeTx{GasTipCap: big.NewInt(1e9), GasFeeCap: big.NewInt(10e9), Gas: 100000, Data: common.FromHex("0x60006000fd")})
+
+	r := mineUntil(t, sim, waitAsync(sim, tx.Hash(), WaitOptions{Confirmations: 1}))
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	if r.status.Outcome != OutcomeReverted || r.status.Success {
+		t.Fatalf("outcome = %s, want reverted", r.status.Outcome)
+	}
+}
+
+func TestWaitDetectsReplacement(t *testing.T) {
+	sim := backendtest.New(t, 2)
+	to := sim.Accounts[1].Address
+	header, _ := sim.HeaderByNumber(context.Background(), nil)
+	stuck := send(t, sim, &types.DynamicFeeTx{GasTipCap: big.NewInt(1), GasFeeCap: new(big.Int).Div(header.BaseFee, big.NewInt(10)), To: &to})
+
+	done := waitAsync(sim, stuck.Hash(), WaitOptions{Confirmations: 1})
+	time.Sleep(100 * time.Millisecond)
+	// 其他客户端用同一nonce发送了费用更高的交易
+	replacement := send(t, sim, &types.DynamicFeeTx{GasTipCap: big.NewInt(2e9), GasFeeCap: big.NewInt(20e9), To: &sim.Accounts[0].Address})
+
+	r := mineUntil(t, sim, done)
+	if !errors.Is(r.err, ErrReplaced) || r.status.Outcome != OutcomeReplaced {
+		t.Fatalf("err = %v, outcome = %s, want replaced", r.err, r.status.Outcome)
+	}
+	if r.status.ReplacedBy != replacement.Hash() {
+		t.Fatalf("replaced by %s, want %s", r.status.ReplacedBy.Hex(), replacement.Hash().Hex())
+	}
+}
+
+func TestWaitSignedDetectsReplacementBeforeFirstCheck(t *testing.T) {
+	sim := backendtest.New(t, 2)
+	to := sim.Accounts[1].Address
+	header, _ := sim.HeaderByNumber(context.Background(), nil)
+	stuck := send(t, sim, &types.DynamicFeeTx{GasTipCap: big.NewInt(1), GasFeeCap: new(big.Int).Div(header.BaseFee, big.NewInt(10)), To: &to})
+	// 开始等待之前原交易已被替换并打包，只凭哈希无法得知发送方和nonce
+	replacement := send(t, sim, &types.DynamicFeeTx{GasTipCap: big.NewInt(2e9), GasFeeCap: big.NewInt(20e9), To: &sim.Accounts[0].Address})
+	sim.Commit()
+
+	status, err := WaitSigned(context.Background(), sim, stuck, WaitOptions{Confirmations: 1, Timeout: 10 * time.Second})
+	if !errors.Is(err, ErrReplaced) || status.Outcome != OutcomeReplaced {
+		t.Fatalf("err = %v, outcome = %s, want replaced", err, status.Outcome)
+	}
+	if status.ReplacedBy != replacement.Hash() {
+		t.Fatalf("replaced by %s, want %s", status.ReplacedBy.Hex(), replacement.Hash().Hex())
+	}
+}
+
+func TestWaitDetectsDrop(t *testing.T) {
+	sim := backendtest.New(t, 2)
+	to := sim.Accounts[1].Address
+	header, _ := sim.HeaderByNumber(context.Background(), nil)
+	stuck := send(t, sim, &types.DynamicFeeTx{GasTipCap: big.NewInt(1), GasFeeCap: new(big.Int).Div(header.BaseFee, big.NewInt(10)), To: &to})
+
+	done := waitAsync(sim, stuck.Hash(), WaitOptions{Confirmations: 1, DropAfter: 2})
+	time.Sleep(100 * time.Millisecond)
+	sim.Rollback() // 清空交易池
+
+	r := mineUntil(t, sim, done)
+	if !errors.Is(r.err, ErrDropped) || r.status.Outcome != OutcomeDropped {
+		t.Fatalf("err = %v, outcome = %s, want dropped", r.err, r.status.Outcome)
+	}
+}
+
+func TestWaitSurvivesReorg(t *testing.T) {
+	sim := backendtest.New(t, 2)
+	to := sim.Accounts[1].Address
+	parent, _ := sim.HeaderByNumber(context.Background(), nil)
+	tx := send(t, sim, &types.DynamicFeeTx{GasTipCap: big.NewInt(1e9), GasFeeCap: big.NewInt(10e9), To: &to})
+
+	done := waitAsync(sim, tx.Hash(), WaitOptions{Confirmations: 3})
+	orphaned := sim.Commit()
+	time.Sleep(200 * time.Millisecond)
+
+	// 从交易所在区块的父区块分叉，生成一条更长且不包含该区块的链
+	if err := sim.Fork(parent.Hash()); err != nil {
+		t.Fatal(err)
+	}
+	sim.Commit()
+	sim.Commit()
+
+	// 交易回到交易池后在新链上重新被打包
+	r := mineUntil(t, sim, done)
+	if r.err != nil || r.status.Outcome != OutcomeConfirmed {
+		t.Fatalf("err = %v, outcome = %s, want confirmed", r.err, r.status.Outcome)
+	}
+	if r.status.BlockHash == orphaned {
+		t.Fatal("waiter confirmed a transaction in an orphaned block")
+	}
+	canonical, err := sim.HeaderByNumber(context.Background(), new(big.Int).SetUint64(r.status.BlockNumber))
+	if err != nil || canonical.Hash() != r.status.BlockHash {
+		t.Fatal("confirmed block is not canonical")
+	}
+}
@@ -0,0 +1,174 @@
+package wallet_management
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"ethclient_tutorial/backend"
+)
+
+// DefaultHDPath 以太坊账户的BIP-44基础路径，第i个账户为 m/44'/60'/0'/0/i
+var DefaultHDPath = accounts.DefaultRootDerivationPath
+
+// extendedKey BIP-32 扩展私钥
+type extendedKey struct {
+	key       []byte // 32字节私钥
+	chainCode []byte
+}
+
+// masterKey 由种子生成主密钥
+func masterKey(seed []byte) (*extendedKey, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, fmt.Errorf("种子长度必须是 16~64 字节，得到 %d", len(seed))
+	}
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	k := new(big.Int).SetBytes(sum[:32])
+	if k.Sign() == 0 || k.Cmp(crypto.S256().Params().N) >= 0 {
+		return nil, fmt.Errorf("种子生成的主密钥无效")
+	}
+	return &extendedKey{key: sum[:32], chainCode: sum[32:]}, nil
+}
+
+// child 派生子私钥，index >= 0x80000000 为强化派生
+func (k *extendedKey) child(index uint32) (*extendedKey, error) {
+	var data []byte
+	if index >= 0x80000000 {
+		data = append([]byte{0}, k.key...)
+	} else {
+		private, err := crypto.ToECDSA(k.key)
+		if err != nil {
+			return nil, err
+		}
+		data = crypto.CompressPubkey(&private.PublicKey)
+	}
+	data = binary.BigEndian.AppendUint32(data, index)
+
+	mac := hmac.New(sha512.New, k.chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	// 极小概率得到无效密钥，BIP-32 规定此时应跳过该索引
+	n := crypto.S256().Params().N
+	il := new(big.Int).SetBytes(sum[:32])
+	if il.Cmp(n) >= 0 {
+		return nil, fmt.Errorf("索引 %d 派生的密钥无效", index)
+	}
+	childKey := il.Add(il, new(big.Int).SetBytes(k.key))
+	childKey.Mod(childKey, n)
+	if childKey.Sign() == 0 {
+		return nil, fmt.Errorf("索引 %d 派生的密钥无效", index)
+	}
+	return &extendedKey{key: childKey.FillBytes(make([]byte, 32)), chainCode: sum[32:]}, nil
+}
+
+// HDWallet BIP-32/BIP-44 分层确定性钱包，同一助记词总是派生出相同的账户
+type HDWallet struct {
+	master *extendedKey
+}
+
+// NewHDWallet 由助记词和可选口令（BIP-39 passphrase）创建钱包
+func NewHDWallet(mnemonic, passphrase string) (*HDWallet, error) {
+	if err := ValidateMnemonic(mnemonic); err != nil {
+		return nil, err
+	}
+	return NewHDWalletFromSeed(MnemonicToSeed(mnemonic, passphrase))
+}
+
+// NewHDWalletFromSeed 由种子创建钱包
+func NewHDWalletFromSeed(seed []byte) (*HDWallet, error) {
+	master, err := masterKey(seed)
+	if err != nil {
+		return nil, err
+	}
+	return &HDWallet{master: master}, nil
+}
+
+// ParseHDPath 解析派生路径，如 m/44'/60'/0'/0/1；相对路径接在 go-ethereum 的 DefaultRootDerivationPath（m/44'/60'/0'/0）之后，
+// 如 "1" 为 m/44'/60'/0'/0/1，"0/1" 为 m/44'/60'/0'/0/0/1
+func ParseHDPath(path string) (accounts.DerivationPath, error) {
+	parsed, err := accounts.ParseDerivationPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("无效的派生路径 %q: %v", path, err)
+	}
+	return parsed, nil
+}
+
+// HDPathAt 基础路径下第 index 个账户的路径
+func HDPathAt(base accounts.DerivationPath, index uint32) accounts.DerivationPath {
+	path := make(accounts.DerivationPath, len(base), len(base)+1)
+	copy(path, base)
+	return append(path, index)
+}
+
+// Derive 派生指定路径的账户
+func (w *HDWallet) Derive(path accounts.DerivationPath) (*UnlockedAccount, error) {
+	key := w.master
+	for _, index := range path {
+		child, err := key.child(index)
+		if err != nil {
+			return nil, fmt.Errorf("派生 %s 失败: %v", path, err)
+		}
+		key = child
+	}
+	private, err := crypto.ToECDSA(key.key)
+	if err != nil {
+		return nil, fmt.Errorf("派生 %s 失败: %v", path, err)
+	}
+	return AccountFromKey(private), nil
+}
+
+// DeriveIndex 派生基础路径下第 index 个账户（base 为 nil 时使用 DefaultHDPath）
+func (w *HDWallet) DeriveIndex(base accounts.DerivationPath, index uint32) (*UnlockedAccount, error) {
+	if base == nil {
+		base = DefaultHDPath
+	}
+	return w.Derive(HDPathAt(base, index))
+}
+
+// DerivedAccount 派生账户及其余额
+type DerivedAccount struct {
+	Index   uint32
+	Path    accounts.DerivationPath
+	Address common.Address
+	Balance *big.Int // client 为 nil 时不查询，保持为nil
+}
+
+// List 列出基础路径下从 start 开始的 count 个账户，client 不为nil时同时查询余额
+// 账户索引只使用非硬化范围（小于2^31），超出时返回错误
+func (w *HDWallet) List(ctx context.Context, client backend.Client, base accounts.DerivationPath, start, count uint32) ([]DerivedAccount, error) {
+	if uint64(start)+uint64(count) > 0x80000000 {
+		return nil, fmt.Errorf("账户索引 %d 起的 %d 个账户超出非硬化范围（索引需小于2147483648）", start, count)
+	}
+	if base == nil {
+		base = DefaultHDPath
+	}
+	result := make([]DerivedAccount, 0, count)
+	for index := start; index < start+count; index++ {
+		path := HDPathAt(base, index)
+		account, err := w.Derive(path)
+		if err != nil {
+			return nil, err
+		}
+		derived := DerivedAccount{Index: index, Path: path, Address: account.Address}
+		if client != nil {
+			balance, err := client.BalanceAt(ctx, account.Address, nil)
+			if err != nil {
+				return nil, fmt.Errorf("查询 %s 余额失败: %v", account.Address.Hex(), err)
+			}
+			derived.Balance = balance
+		}
+		result = append(result, derived)
+	}
+	return result, nil
+}
@@ -0,0 +1,238 @@
+package wallet_management
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"ethclient_tutorial/backend/backendtest"
+)
+
+// BIP-39 参考向量（trezor/python-mnemonic vectors.json，口令均为 "TREZOR"）
+var bip39Vectors = []struct {
+	entropy, mnemonic, seed string
+}{
+	{
+		"00000000000000000000000000000000",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about",
+		"c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+	},
+	{
+		"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+		"legal winner thank year wave sausage worth useful legal winner thank yellow",
+		"2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607",
+	},
+	{
+		"80808080808080808080808080808080",
+		"letter advice cage absurd amount doctor acoustic avoid letter advice cage above",
+		"d71de856f81a8acc65e6fc851a38d4d7ec216fd0796d0a6827a3ad6ed5511a30fa280f12eb2e47ed2ac03b5c462a0358d18d69fe4f985ec81778c1b370b652a8",
+	},
+	{
+		"ffffffffffffffffffffffffffffffff",
+		"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong",
+		"ac27495480225222079d7be181583751e86f571027b0497b5b5d11218e0a8a13332572917f0f8e5a589620c6f15b11c61dee327651a14c34e18231052e48c069",
+	},
+	{
+		"0000000000000000000000000000000000000000000000000000000000000000",
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon art",
+		"bda85446c68413707090a52022edd26a1c9462295029f2e60cd7c4f2bbd3097170af7a4d73245cafa9c3cca8d561a7c3de6f5d4a10be8ed2a5e608d68f92fcc8",
+	},
+	{
+		"9e885d952ad362caeb4efe34a8e91bd2",
+		"ozone drill grab fiber curtain grace pudding thank cruise elder eight picnic",
+		"274ddc525802f7c828d8ef7ddbcdc5304e87ac3535913611fbbfa986d0c9e5476c91689f9c8a54fd55bd38606aa6a8595ad213d4c9c9f9aca3fb217069a41028",
+	},
+}
+
+func TestMnemonicVectors(t *testing.T) {
+	for _, v := range bip39Vectors {
+		entropy, _ := hex.DecodeString(v.entropy)
+		mnemonic, err := EntropyToMnemonic(entropy)
+		if err != nil || mnemonic != v.mnemonic {
+			t.Fatalf("EntropyToMnemonic(%s) = %q, %v", v.entropy, mnemonic, err)
+		}
+		decoded, err := MnemonicToEntropy(v.mnemonic)
+		if err != nil || hex.EncodeToString(decoded) != v.entropy {
+			t.Fatalf("MnemonicToEntropy(%q) = %x, %v", v.mnemonic, decoded, err)
+		}
+		if seed := hex.EncodeToString(MnemonicToSeed(v.mnemonic, "TREZOR")); seed != v.seed {
+			t.Fatalf("seed of %q = %s", v.mnemonic, seed)
+		}
+	}
+}
+
+func TestNewAndInvalidMnemonic(t *testing.T) {
+	for _, count := range []int{12, 24} {
+		mnemonic, err := NewMnemonic(count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(strings.Fields(mnemonic)) != count || ValidateMnemonic(mnemonic) != nil {
+			t.Fatalf("NewMnemonic(%d) = %q", count, mnemonic)
+		}
+	}
+	if _, err := NewMnemonic(13); err == nil {
+		t.Fatal("13 words should be rejected")
+	}
+
+	for _, mnemonic := range []string{
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon", // 校验和错误
+		"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abou",    // 不在词表中
+		"abandon abandon abandon",
+	} {
+		if err := ValidateMnemonic(mnemonic); !errors.Is(err, ErrInvalidMnemonic) {
+			t.Errorf("ValidateMnemonic(%q) = %v, want ErrInvalidMnemonic", mnemonic, err)
+		}
+	}
+}
+
+// BIP-32 参考向量1
+func TestBIP32Vector(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	wallet, err := NewHDWalletFromSeed(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]string{
+		"m":                      "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35",
+		"m/0'":                   "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea",
+		"m/0'/1":                 "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368",
+		"m/0'/1/2'":              "cbce0d719ecf7431d88e6a89fa1483e02e35092af60c042b1df2ff59fa424dca",
+		"m/0'/1/2'/2":            "0f479245fb19a38a1954c5c7c0ebab2f9bdfd96a17563ef28a6a4b1a2a764ef4",
+		"m/0'/1/2'/2/1000000000": "471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8",
+	} {
+		var parsed accounts.DerivationPath
+		if path != "m" {
+			p, err := ParseHDPath(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed = p
+		}
+		account, err := wallet.Derive(parsed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(crypto.FromECDSA(account.PrivateKey)); got != want {
+			t.Errorf("%s = %s, want %s", path, got, want)
+		}
+	}
+}
+
+// 以太坊钱包常用的派生结果（MetaMask、Hardhat 等一致）
+func TestEthereumDerivation(t *testing.T) {
+	wallet, err := NewHDWallet("test test test test test test test test test test test junk", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for index, want := range []string{
+		"0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266",
+		"0x70997970C51812dc3A010C7d01b50e0d17dc79C8",
+		"0x3C44CdDdB6a900fa2b585dd299e03d12FA4293BC",
+	} {
+		account, err := wallet.DeriveIndex(nil, uint32(index))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if account.Address.Hex() != want {
+			t.Errorf("index %d = %s, want %s", index, account.Address.Hex(), want)
+		}
+	}
+	first, _ := wallet.DeriveIndex(nil, 0)
+	if hex.EncodeToString(crypto.FromECDSA(first.PrivateKey)) != testKeyHex {
+		t.Error("index 0 private key mismatch")
+	}
+
+	// 口令不同得到不同的钱包
+	abandon := strings.Repeat("abandon ", 11) + "about"
+	plain, _ := NewHDWallet(abandon, "")
+	account, _ := plain.DeriveIndex(nil, 0)
+	if account.Address != common.HexToAddress("0x9858EfFD232B4033E47d90003D41EC34EcaEda94") {
+		t.Errorf("abandon...about index 0 = %s", account.Address.Hex())
+	}
+	protected, _ := NewHDWallet(abandon, "TREZOR")
+	other, _ := protected.DeriveIndex(nil, 0)
+	if other.Address == account.Address {
+		t.Error("passphrase should change derived accounts")
+	}
+
+	// 自定义路径
+	custom, err := ParseHDPath("m/44'/60'/1'/0/0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if derived, _ := wallet.Derive(custom); derived.Address == first.Address {
+		t.Error("custom path should derive a different account")
+	}
+
+	// 相对路径接在 m/44'/60'/0'/0 之后
+	relative, err := ParseHDPath("0/1")
+	if err != nil || relative.String() != "m/44'/60'/0'/0/0/1" {
+		t.Fatalf("relative path = %s, %v", relative, err)
+	}
+}
+
+func TestListWithBalances(t *testing.T) {
+	sim := backendtest.New(t, 1)
+	ctx := context.Background()
+
+	wallet, _ := NewHDWallet("test test test test test test test test test test test junk", "")
+	target, _ := wallet.DeriveIndex(nil, 1)
+
+	// 向第1个派生账户转账
+	funder := sim.Accounts[0]
+	chainID, _ := sim.ChainID(ctx)
+	tip, _ := sim.SuggestGasTipCap(ctx)
+	head, _ := sim.HeaderByNumber(ctx, nil)
+	tx, err := types.SignNewTx(funder.Key, types.LatestSignerForChainID(chainID), &types.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     0,
+		GasTipCap: tip,
+		GasFeeCap: new(big.Int).Add(tip, new(big.Int).Mul(head.BaseFee, big.NewInt(2))),
+		Gas:       21000,
+		To:        &target.Address,
+		Value:     big.NewInt(12345),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sim.SendTransaction(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	sim.Commit()
+
+	list, err := wallet.List(ctx, sim, nil, 0, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 3 || list[1].Address != target.Address || list[1].Path.String() != "m/44'/60'/0'/0/1" {
+		t.Fatalf("list = %+v", list)
+	}
+	if list[1].Balance.Int64() != 12345 || list[2].Balance.Sign() != 0 {
+		t.Fatalf("balances = %s, %s", list[1].Balance, list[2].Balance)
+	}
+
+	offline, _ := wallet.List(ctx, nil, nil, 5, 1)
+	if offline[0].Index != 5 || offline[0].Balance != nil {
+		t.Fatalf("offline = %+v", offline)
+	}
+
+	// 索引范围溢出或进入硬化范围时报错，而不是回绕或派生硬化账户
+	last, err := wallet.List(ctx, nil, nil, 1<<31-1, 1)
+	if err != nil || last[0].Index != 1<<31-1 {
+		t.Fatalf("last non-hardened = %+v, %v", last, err)
+	}
+	for _, r := range [][2]uint32{{1<<31 - 1, 2}, {1 << 31, 1}, {1<<32 - 1, 2}} {
+		if _, err := wallet.List(ctx, nil, nil, r[0], r[1]); err == nil {
+			t.Fatalf("List(start=%d, count=%d) succeeded", r[0], r[1])
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package wallet_management
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ErrAccountNotFound keystore目录中没有指定地址的账户
+var ErrAccountNotFound = errors.New("keystore中没有该账户")
+
+// UnlockedAccount 已解锁的账户，转账、部署等API使用它签名交易
+type UnlockedAccount struct {
+	Address    common.Address
+	PrivateKey *ecdsa.PrivateKey
+}
+
+// AccountFromKey 由私钥创建已解锁账户
+func AccountFromKey(key *ecdsa.PrivateKey) *UnlockedAccount {
+	return &UnlockedAccount{Address: crypto.PubkeyToAddress(key.PublicKey), PrivateKey: key}
+}
+
+// AccountFromHex 由十六进制私钥（可带0x前缀）创建已解锁账户
+func AccountFromHex(privateKeyHex string) (*UnlockedAccount, error) {
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(strings.TrimSpace(privateKeyHex), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("加载私钥失败: %v", err)
+	}
+	return AccountFromKey(key), nil
+}
+
+// Keystore Web3 Secret Storage（keystore V3）目录，每个账户一个加密的JSON文件
+type Keystore struct {
+	Dir string
+	ks  *keystore.KeyStore
+}
+
+// OpenKeystore 打开（不存在时创建）keystore目录，使用标准scrypt参数加密
+func OpenKeystore(dir string) *Keystore {
+	return &Keystore{Dir: dir, ks: keystore.NewKeyStore(dir, keystore.StandardScryptN, keystore.StandardScryptP)}
+}
+
+// OpenLightKeystore 使用轻量scrypt参数打开keystore（加解密快但更易被暴力破解，仅用于测试和开发链）
+func OpenLightKeystore(dir string) *Keystore {
+	return &Keystore{Dir: dir, ks: keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)}
+}
+
+// List 列出keystore中的全部账户
+func (k *Keystore) List() []accounts.Account {
+	return k.ks.Accounts()
+}
+
+// Create 生成新账户并用口令加密保存
+func (k *Keystore) Create(passphrase string) (accounts.Account, error) {
+	if passphrase == "" {
+		return accounts.Account{}, fmt.Errorf("口令不能为空")
+	}
+	account, err := k.ks.NewAccount(passphrase)
+	if err != nil {
+		return accounts.Account{}, fmt.Errorf("创建账户失败: %v", err)
+	}
+	return account, nil
+}
+
+// ImportKey 导入十六进制私钥并用口令加密保存
+func (k *Keystore) ImportKey(privateKeyHex, passphrase string) (accounts.Account, error) {
+	if passphrase == "" {
+		return accounts.Account{}, fmt.Errorf("口令不能为空")
+	}
+	unlocked, err := AccountFromHex(privateKeyHex)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	account, err := k.ks.ImportECDSA(unlocked.PrivateKey, passphrase)
+	if err != nil {
+		return accounts.Account{}, fmt.Errorf("导入私钥失败: %v", err)
+	}
+	return account, nil
+}
+
+// ImportJSON 导入keystore JSON文件内容，passphrase 为原口令，newPassphrase 为保存时使用的新口令
+func (k *Keystore) ImportJSON(keyJSON []byte, passphrase, newPassphrase string) (accounts.Account, error) {
+	if newPassphrase == "" {
+		return accounts.Account{}, fmt.Errorf("口令不能为空")
+	}
+	account, err := k.ks.Import(keyJSON, passphrase, newPassphrase)
+	if err != nil {
+		return accounts.Account{}, fmt.Errorf("导入keystore文件失败: %v", err)
+	}
+	return account, nil
+}
+
+// Export 导出账户的keystore JSON，用 newPassphrase 重新加密
+func (k *Keystore) Export(address common.Address, passphrase, newPassphrase string) ([]byte, error) {
+	account, err := k.Find(address)
+	if err != nil {
+		return nil, err
+	}
+	keyJSON, err := k.ks.Export(account, passphrase, newPassphrase)
+	if err != nil {
+		return nil, fmt.Errorf("导出账户失败: %v", err)
+	}
+	return keyJSON, nil
+}
+
+// ChangePassphrase 修改账户口令
+func (k *Keystore) ChangePassphrase(address common.Address, passphrase, newPassphrase string) error {
+	if newPassphrase == "" {
+		return fmt.Errorf("口令不能为空")
+	}
+	account, err := k.Find(address)
+	if err != nil {
+		return err
+	}
+	if err := k.ks.Update(account, passphrase, newPassphrase); err != nil {
+		return fmt.Errorf("修改口令失败: %v", err)
+	}
+	return nil
+}
+
+// Unlock 用口令解密账户私钥
+func (k *Keystore) Unlock(address common.Address, passphrase string) (*UnlockedAccount, error) {
+	account, err := k.Find(address)
+	if err != nil {
+		return nil, err
+	}
+	keyJSON, err := os.ReadFile(account.URL.Path)
+	if err != nil {
+		return nil, fmt.Errorf("读取keystore文件失败: %v", err)
+	}
+	key, err := keystore.DecryptKey(keyJSON, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("解锁账户 %s 失败: %v", address.Hex(), err)
+	}
+	return &UnlockedAccount{Address: key.Address, PrivateKey: key.PrivateKey}, nil
+}
+
+// SignTxWithPassphrase 用口令临时解密私钥签名交易，签名后私钥不保留在内存中
+func (k *Keystore) SignTxWithPassphrase(address common.Address, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	account, err := k.Find(address)
+	if err != nil {
+		return nil, err
+	}
+	signedTx, err := k.ks.SignTxWithPassphrase(account, passphrase, tx, chainID)
+	if err != nil {
+		return nil, fmt.Errorf("账户 %s 签名交易失败: %v", address.Hex(), err)
+	}
+	return signedTx, nil
+}
+
+// SignHashWithPassphrase 用口令临时解密私钥签名32字节哈希，返回 [R || S || V] 格式签名（V为0或1）
+func (k *Keystore) SignHashWithPassphrase(address common.Address, passphrase string, hash []byte) ([]byte, error) {
+	account, err := k.Find(address)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := k.ks.SignHashWithPassphrase(account, passphrase, hash)
+	if err != nil {
+		return nil, fmt.Errorf("账户 %s 签名失败: %v", address.Hex(), err)
+	}
+	return signature, nil
+}
+
+// Find 按地址查找账户
+func (k *Keystore) Find(address common.Address) (accounts.Account, error) {
+	account, err := k.ks.Find(accounts.Account{Address: address})
+	if err != nil {
+		return accounts.Account{}, fmt.Errorf("%w: %s", ErrAccountNotFound, address.Hex())
+	}
+	return account, nil
+}
@@ -0,0 +1,114 @@
+package wallet_management
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// 公开的测试私钥（hardhat 账户0）
+const testKeyHex = "ac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80"
+
+var testKeyAddress = common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266")
+
+func TestKeystoreLifecycle(t *testing.T) {
+	ks := OpenLightKeystore(t.TempDir())
+
+	account, err := ks.Create("first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accounts := ks.List(); len(accounts) != 1 || accounts[0].Address != account.Address {
+		t.Fatalf("List() = %v", accounts)
+	}
+
+	if _, err := ks.Unlock(account.Address, "wrong"); err == nil {
+		t.Fatal("unlock with wrong passphrase should fail")
+	}
+	unlocked, err := ks.Unlock(account.Address, "first")
+	if err != nil || unlocked.Address != account.Address {
+		t.Fatalf("Unlock = %v, %v", unlocked, err)
+	}
+
+	if err := ks.ChangePassphrase(account.Address, "first", "second"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.Unlock(account.Address, "first"); err == nil {
+		t.Fatal("old passphrase should no longer unlock")
+	}
+	if _, err := ks.Unlock(account.Address, "second"); err != nil {
+		t.Fatalf("new passphrase: %v", err)
+	}
+
+	// 导出后导入另一个keystore，私钥不变
+	keyJSON, err := ks.Export(account.Address, "second", "exported")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := OpenLightKeystore(t.TempDir())
+	imported, err := other.ImportJSON(keyJSON, "exported", "third")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reunlocked, err := other.Unlock(imported.Address, "third")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reunlocked.PrivateKey.Equal(unlocked.PrivateKey) {
+		t.Fatal("exported key differs from original")
+	}
+}
+
+func TestImportKeyAndMissingAccount(t *testing.T) {
+	ks := OpenLightKeystore(t.TempDir())
+
+	account, err := ks.ImportKey("0x"+testKeyHex, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Address != testKeyAddress {
+		t.Fatalf("imported address = %s, want %s", account.Address.Hex(), testKeyAddress.Hex())
+	}
+	if _, err := ks.Create(""); err == nil {
+		t.Fatal("empty passphrase should be rejected")
+	}
+	if _, err := ks.Unlock(common.HexToAddress("0x01"), "secret"); !errors.Is(err, ErrAccountNotFound) {
+		t.Fatalf("err = %v, want ErrAccountNotFound", err)
+	}
+}
+
+func TestPassphraseSourcePrecedence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(file, []byte("from-file\nignored\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_KEYSTORE_PASSWORD", "from-env")
+	prompt := func(input string) PassphraseSource {
+		return PassphraseSource{In: strings.NewReader(input), Out: new(strings.Builder)}
+	}
+
+	source := prompt("from-prompt\n")
+	source.File, source.Env = file, "TEST_KEYSTORE_PASSWORD"
+	if got, _ := source.Read(""); got != "from-file" {
+		t.Fatalf("file source = %q", got)
+	}
+	source.File = ""
+	if got, _ := source.Read(""); got != "from-env" {
+		t.Fatalf("env source = %q", got)
+	}
+	source.Env = "TEST_KEYSTORE_PASSWORD_UNSET"
+	if got, _ := source.Read(""); got != "from-prompt" {
+		t.Fatalf("prompt source = %q", got)
+	}
+
+	if got, err := prompt("same\nsame\n").ReadNew(""); err != nil || got != "same" {
+		t.Fatalf("ReadNew = %q, %v", got, err)
+	}
+	if _, err := prompt("one\ntwo\n").ReadNew(""); err == nil {
+		t.Fatal("mismatched confirmation should fail")
+	}
+}
@@ -0,0 +1,125 @@
+package wallet_management
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	_ "embed"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
+)
+
+// ErrInvalidMnemonic 助记词单词数、单词或校验和无效
+var ErrInvalidMnemonic = errors.New("无效的助记词")
+
+// BIP-39 英文词表（2048个单词，按字母序排列）
+//
+//go:embed wordlist_english.txt
+var englishWordlist string
+
+var (
+	wordlistOnce sync.Once
+	wordlist     []string
+	wordIndex    map[string]int
+)
+
+// words 解析词表
+func words() ([]string, map[string]int) {
+	wordlistOnce.Do(func() {
+		wordlist = strings.Fields(englishWordlist)
+		wordIndex = make(map[string]int, len(wordlist))
+		for i, word := range wordlist {
+			wordIndex[word] = i
+		}
+	})
+	return wordlist, wordIndex
+}
+
+// NewMnemonic 生成随机助记词，wordCount 为 12、15、18、21 或 24
+func NewMnemonic(wordCount int) (string, error) {
+	if wordCount < 12 || wordCount > 24 || wordCount%3 != 0 {
+		return "", fmt.Errorf("助记词单词数必须是 12、15、18、21 或 24，得到 %d", wordCount)
+	}
+	// 每3个单词对应32位熵
+	entropy := make([]byte, wordCount/3*4)
+	if _, err := rand.Read(entropy); err != nil {
+		return "", fmt.Errorf("生成随机熵失败: %v", err)
+	}
+	return EntropyToMnemonic(entropy)
+}
+
+// EntropyToMnemonic 把熵（16~32字节，4字节的倍数）编码为助记词
+// 熵后追加 SHA-256 的前 len/32 位作为校验和，每11位对应一个单词
+func EntropyToMnemonic(entropy []byte) (string, error) {
+	bits := len(entropy) * 8
+	if bits < 128 || bits > 256 || bits%32 != 0 {
+		return "", fmt.Errorf("熵长度必须是 128~256 位且为32的倍数，得到 %d 位", bits)
+	}
+	list, _ := words()
+
+	checksumBits := bits / 32
+	hash := sha256.Sum256(entropy)
+	value := new(big.Int).SetBytes(entropy)
+	value.Lsh(value, uint(checksumBits))
+	value.Or(value, big.NewInt(int64(hash[0]>>(8-checksumBits))))
+
+	wordCount := (bits + checksumBits) / 11
+	result := make([]string, wordCount)
+	mask := big.NewInt(2047)
+	for i := wordCount - 1; i >= 0; i-- {
+		result[i] = list[new(big.Int).And(value, mask).Int64()]
+		value.Rsh(value, 11)
+	}
+	return strings.Join(result, " "), nil
+}
+
+// MnemonicToEntropy 解码助记词并校验，返回原始熵
+func MnemonicToEntropy(mnemonic string) ([]byte, error) {
+	_, index := words()
+	fields := strings.Fields(mnemonic)
+	if len(fields) < 12 || len(fields) > 24 || len(fields)%3 != 0 {
+		return nil, fmt.Errorf("%w: 单词数必须是 12、15、18、21 或 24，得到 %d", ErrInvalidMnemonic, len(fields))
+	}
+
+	value := new(big.Int)
+	for i, word := range fields {
+		n, ok := index[word]
+		if !ok {
+			return nil, fmt.Errorf("%w: 第%d个单词 %q 不在词表中", ErrInvalidMnemonic, i+1, word)
+		}
+		value.Lsh(value, 11)
+		value.Or(value, big.NewInt(int64(n)))
+	}
+
+	checksumBits := len(fields) / 3
+	checksum := new(big.Int).And(value, big.NewInt(int64(1)<<checksumBits-1))
+	value.Rsh(value, uint(checksumBits))
+	entropy := value.FillBytes(make([]byte, checksumBits*4))
+
+	hash := sha256.Sum256(entropy)
+	if checksum.Int64() != int64(hash[0]>>(8-checksumBits)) {
+		return nil, fmt.Errorf("%w: 校验和不匹配", ErrInvalidMnemonic)
+	}
+	return entropy, nil
+}
+
+// ValidateMnemonic 验证助记词（单词数、单词和校验和）
+func ValidateMnemonic(mnemonic string) error {
+	_, err := MnemonicToEntropy(mnemonic)
+	return err
+}
+
+// MnemonicToSeed 由助记词和可选口令生成64字节种子
+// PBKDF2-HMAC-SHA512，2048次迭代，盐为 "mnemonic"+口令，均经过 NFKD 规范化。
+// 不同的口令会得到完全不同的钱包，口令本身没有对错之分
+func MnemonicToSeed(mnemonic, passphrase string) []byte {
+	mnemonic = norm.NFKD.String(strings.Join(strings.Fields(mnemonic), " "))
+	salt := norm.NFKD.String("mnemonic" + passphrase)
+	return pbkdf2.Key([]byte(mnemonic), []byte(salt), 2048, 64, sha512.New)
+}
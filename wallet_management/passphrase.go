@@ -0,0 +1,120 @@
+package wallet_management
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// DefaultPassphraseEnv 默认读取口令的环境变量
+const DefaultPassphraseEnv = "KEYSTORE_PASSWORD"
+
+// PassphraseSource keystore口令来源
+// 按 File → Env → 交互提示 的顺序使用第一个已配置的来源
+type PassphraseSource struct {
+	File string // 口令文件（只取第一行）
+	Env  string // 保存口令的环境变量名，变量未设置时跳过
+
+	// 交互提示使用的输入输出，nil 时使用 os.Stdin / os.Stderr
+	// 输入是终端时不回显，否则按行读取（便于通过管道传入）
+	In  io.Reader
+	Out io.Writer
+}
+
+// Read 读取解锁用的口令
+func (s PassphraseSource) Read(prompt string) (string, error) {
+	if passphrase, ok, err := s.configured(); ok || err != nil {
+		return passphrase, err
+	}
+	return s.prompt(prompt)
+}
+
+// ReadNew 读取新口令，交互提示时要求输入两次
+func (s PassphraseSource) ReadNew(prompt string) (string, error) {
+	if passphrase, ok, err := s.configured(); ok || err != nil {
+		return passphrase, err
+	}
+	passphrase, err := s.prompt(prompt)
+	if err != nil {
+		return "", err
+	}
+	confirm, err := s.prompt("请再次输入口令: ")
+	if err != nil {
+		return "", err
+	}
+	if passphrase != confirm {
+		return "", fmt.Errorf("两次输入的口令不一致")
+	}
+	return passphrase, nil
+}
+
+// configured 从文件或环境变量读取口令
+func (s PassphraseSource) configured() (string, bool, error) {
+	if s.File != "" {
+		content, err := os.ReadFile(s.File)
+		if err != nil {
+			return "", false, fmt.Errorf("读取口令文件失败: %v", err)
+		}
+		line, _, _ := strings.Cut(string(content), "\n")
+		return strings.TrimRight(line, "\r"), true, nil
+	}
+	if s.Env != "" {
+		if passphrase, ok := os.LookupEnv(s.Env); ok {
+			return passphrase, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// prompt 交互式读取一行口令
+func (s PassphraseSource) prompt(prompt string) (string, error) {
+	in, out := s.In, s.Out
+	if in == nil {
+		in = os.Stdin
+	}
+	if out == nil {
+		out = os.Stderr
+	}
+	fmt.Fprint(out, prompt)
+
+	if f, ok := in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		passphrase, err := term.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(out)
+		if err != nil {
+			return "", fmt.Errorf("读取口令失败: %v", err)
+		}
+		return string(passphrase), nil
+	}
+
+	passphrase, err := ReadLine(in)
+	if err != nil {
+		return "", fmt.Errorf("读取口令失败: %v", err)
+	}
+	return passphrase, nil
+}
+
+// ReadLine 逐字节读取一行（去掉行尾的\r），避免缓冲吞掉随后的输入（ReadNew 会连续读取两次）
+// 读到内容后遇到 EOF 视为最后一行，没有内容时返回读取错误
+func ReadLine(r io.Reader) (string, error) {
+	var line []byte
+	buf := make([]byte, 1)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if buf[0] == '\n' {
+				break
+			}
+			line = append(line, buf[0])
+		}
+		if err != nil {
+			if err == io.EOF && len(line) > 0 {
+				break
+			}
+			return "", err
+		}
+	}
+	return strings.TrimRight(string(line), "\r"), nil
+}